@@ -2,44 +2,166 @@ package main
 
 import (
 	"context"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/go-resty/resty/v2"
 
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/loadtest"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics_updater"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/status"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/agent"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/winservice"
+)
+
+const serviceName = "metrics-agent"
+
+var (
+	buildVersion string
+	buildDate    string
+	buildCommit  string
 )
 
 func main() {
-	sugarLogger, err := logger.New()
+	if handled, err := winservice.HandleCommand(serviceName, "Metrics agent"); handled {
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	info := buildinfo.New(buildVersion, buildDate, buildCommit)
+	buildinfo.Set(info)
+	info.Print(os.Stdout)
+
+	sugarLogger, err := logger.New(logger.Options{})
 	if err != nil {
 		panic(err)
 	}
 	sugarLogger.Debugf("The logger has been successfully initialized and configured.")
 
 	defer func() {
-		if err = sugarLogger.Sync(); err != nil {
-			panic(err)
-		}
+		_ = sugarLogger.Sync() // Sync у stderr возвращает ошибку, если это не обычный файл
 	}()
 
 	config.Load()
 	if err = config.Parse(); err != nil {
 		sugarLogger.Panicf("Failed loading config: %s", err)
 	}
+
+	configuredLogger, err := logger.New(config.LoggerOptions())
+	if err != nil {
+		sugarLogger.Panicf("Failed to configure logger: %s", err)
+	}
+	sugarLogger = configuredLogger
 	sugarLogger.Debugf("The config was successfully received and configured.")
 
-	collector := collectors.NewCollector(sugarLogger)
-	go collector.Run(context.Background())
+	isService, err := winservice.IsService()
+	if err != nil {
+		sugarLogger.Panicf("Failed to detect windows service: %s", err)
+	}
+
+	if config.Config.LoadTest {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		err = runLoadTest(ctx, sugarLogger)
+	} else if isService {
+		err = runService(sugarLogger)
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		err = run(ctx, sugarLogger)
+	}
+
+	if err != nil {
+		sugarLogger.Panicf("Failed start agent: %s", err)
+	}
+	sugarLogger.Infof("The agent has been stopped.")
+}
+
+func runService(log logger.Logger) error {
+	eventLogger, err := winservice.NewLogger(serviceName)
+	if err != nil {
+		log.Errorf("Failed to open windows event log, logs will be written to stderr: %s", err)
+	} else {
+		log = eventLogger
+	}
+
+	return winservice.Run(serviceName, func(ctx context.Context) error {
+		return run(ctx, log)
+	})
+}
 
-	client := resty.New()
-	updater := metricsupdater.New(client, collector, sugarLogger)
+func newClient() *resty.Client {
+	return resty.New().
+		SetHeader("User-Agent", buildinfo.Get().UserAgent(serviceName))
+}
+
+func runLoadTest(ctx context.Context, log logger.Logger) error {
+	// Коллектор не нужен: метрики генерирует сам нагрузочный тест
+	updater := metricsupdater.New(newClient(), nil, log.Named("exporter"))
+
+	report, err := loadtest.Run(ctx, updater, loadtest.Options{
+		Metrics:  config.Config.LoadTestMetrics,
+		Batch:    config.Config.LoadTestBatch,
+		Rate:     config.Config.LoadTestRate,
+		Duration: config.Config.LoadTestDuration,
+		Workers:  config.Config.RateLimit,
+	}, log.Named("loadtest"))
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Load test finished:\n%s", report)
+	return nil
+}
+
+func run(ctx context.Context, log logger.Logger) error {
+	a, err := agent.New(config.Config, nil, log)
+	if err != nil {
+		return err
+	}
 
-	sugarLogger.Debugf("Metrics updater successfully initialized.")
-	for {
-		time.Sleep(time.Second * time.Duration(config.Config.ReportInterval))
-		updater.UpdateMetrics()
+	if config.Config.StatusAddress != "" {
+		if _, err = status.Serve(ctx, config.Config.StatusAddress, log.Named("status")); err != nil {
+			return err
+		}
+	}
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reloads:
+				reload(ctx, a, log)
+			}
+		}
+	}()
+
+	return a.Run(ctx)
+}
+
+// reload перечитывает файл настроек и применяет настройки к работающему агенту.
+func reload(ctx context.Context, a *agent.Agent, log logger.Logger) {
+	cfg, err := config.Reread()
+	if err == nil {
+		err = a.Reload(ctx, cfg)
+	}
+
+	if err != nil {
+		log.Errorf("Failed to reload the config, keeping the previous one: %s", err)
+		return
 	}
+	log.Infof("The config has been reloaded.")
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/dump"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type command func(store models.Storage, log logger.Logger) error
+
+var (
+	commands = map[string]command{
+		"dump":            dumpCommand,
+		"export":          dumpCommand,
+		"load":            loadCommand,
+		"import":          loadCommand,
+		"normalize-names": normalizeNamesCommand,
+	}
+
+	// storagelessCommands не открывают хранилище
+	storagelessCommands = map[string]func(log logger.Logger) error{
+		"replay": replayCommand,
+	}
+)
+
+// parseCommand вырезает подкоманду из os.Args, чтобы флаги после неё разобрались как обычно.
+// Возвращает имя подкоманды или пустую строку, если запускается сервер.
+func parseCommand() string {
+	if len(os.Args) < 2 {
+		return ""
+	}
+
+	name := os.Args[1]
+	if _, ok := commands[name]; !ok {
+		if _, ok = storagelessCommands[name]; !ok {
+			return ""
+		}
+	}
+
+	os.Args = append(os.Args[:1], os.Args[2:]...)
+	return name
+}
+
+func runCommand(name string, log logger.Logger) error {
+	if cmd, ok := storagelessCommands[name]; ok {
+		return cmd(log)
+	}
+	cmd := commands[name]
+
+	store, err := storage.Setup(log)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Selected storage: %s", store)
+
+	return errors.Join(cmd(store, log), store.Close())
+}
+
+// replayCommand воспроизводит файл записи (первый аргумент, - или пусто - stdin) на сервере из второго
+// аргумента, по умолчанию на адресе из конфига.
+func replayCommand(log logger.Logger) error {
+	var r io.Reader = os.Stdin
+
+	if path := flag.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	target := flag.Arg(1)
+	if target == "" {
+		target = config.Config.Address
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	stats, err := record.Replay(ctx, record.NewReader(r), record.ReplayOptions{
+		Target: target,
+		Speed:  config.Config.ReplaySpeed,
+	}, log)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Requests (%d) have been replayed on %s, %d of them failed.", stats.Sent, target, stats.Failed)
+	return nil
+}
+
+// dumpCommand выгружает хранилище в файл из первого аргумента (- или пусто - stdout) в формате -format.
+// export - то же самое под привычным для миграций именем.
+func dumpCommand(store models.Storage, log logger.Logger) error {
+	var w io.Writer = os.Stdout
+
+	if path := flag.Arg(0); path != "" && path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	write := dump.Dump
+	if config.Config.DumpFormat == config.DumpFormatProm {
+		write = dump.DumpProm
+	}
+
+	count, err := write(store, w)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Metrics (%d) have been dumped from %s.", count, store)
+	return nil
+}
+
+// loadCommand загружает метрики из файла (- или пусто - stdin) в формате -format; import - его синоним.
+func loadCommand(store models.Storage, log logger.Logger) error {
+	var r io.Reader = os.Stdin
+
+	if path := flag.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	read := dump.Load
+	if config.Config.DumpFormat == config.DumpFormatProm {
+		read = dump.LoadProm
+	}
+
+	count, err := read(store, r)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Metrics (%d) have been loaded into %s.", count, store)
+	return nil
+}
+
+func normalizeNamesCommand(store models.Storage, log logger.Logger) error {
+	count, err := storage.NormalizeNames(store)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Metrics (%d) have been renamed to lowercase in %s.", count, store)
+	return nil
+}
@@ -1,49 +1,231 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/handlers"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/middlewares"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/handoff"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/server"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/systemd"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/winservice"
+)
+
+const (
+	shutdownTimeout = time.Second * 10
+	upgradeTimeout  = time.Second * 30
+	serviceName     = "metrics-server"
+)
+
+var (
+	buildVersion string
+	buildDate    string
+	buildCommit  string
 )
 
 func main() {
-	sugarLogger, err := logger.New()
+	if handled, err := winservice.HandleCommand(serviceName, "Metrics server"); handled {
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	command := parseCommand()
+
+	info := buildinfo.New(buildVersion, buildDate, buildCommit)
+	buildinfo.Set(info)
+
+	if command == "" {
+		info.Print(os.Stdout)
+	} else {
+		info.Print(os.Stderr) // stdout может быть занят выводом подкоманды
+	}
+
+	sugarLogger, err := logger.New(logger.Options{})
 	if err != nil {
 		panic(err)
 	}
 	sugarLogger.Debugf("The logger has been successfully initialized and configured.")
 
+	defer func() {
+		_ = sugarLogger.Sync() // Sync у stderr возвращает ошибку, если это не обычный файл
+	}()
+
 	config.Load()
 	if err = config.Parse(); err != nil {
 		sugarLogger.Panicf("Failed loading config: %s", err)
 	}
+
+	configuredLogger, err := logger.New(config.LoggerOptions())
+	if err != nil {
+		sugarLogger.Panicf("Failed to configure logger: %s", err)
+	}
+	sugarLogger = configuredLogger
 	sugarLogger.Debugf("The config was successfully received and configured.")
 
-	store, err := storage.Setup(sugarLogger)
+	if command != "" {
+		if err = runCommand(command, sugarLogger); err != nil {
+			sugarLogger.Panicf("Command failed: %s", err)
+		}
+
+		return
+	}
+
+	isService, err := winservice.IsService()
 	if err != nil {
-		sugarLogger.Panicf("Failed setup storage: %s", err)
+		sugarLogger.Panicf("Failed to detect windows service: %s", err)
 	}
-	sugarLogger.Debugf("Selected storage: %s", store)
 
-	defer func() {
-		if err = sugarLogger.Sync(); err != nil {
-			panic(err)
+	if isService {
+		err = runService(sugarLogger)
+	} else {
+		err = serve(context.Background(), sugarLogger)
+	}
+
+	if err != nil {
+		sugarLogger.Panicf("Failed start server: %s", err)
+	}
+	sugarLogger.Infof("The server has been stopped.")
+}
+
+func runService(log logger.Logger) error {
+	eventLogger, err := winservice.NewLogger(serviceName)
+	if err != nil {
+		log.Errorf("Failed to open windows event log, logs will be written to stderr: %s", err)
+	} else {
+		log = eventLogger
+	}
+
+	return winservice.Run(serviceName, func(ctx context.Context) error {
+		return serve(ctx, log)
+	})
+}
+
+func serve(ctx context.Context, log logger.Logger) error {
+	for {
+		restart, err := run(ctx, log)
+		if err != nil {
+			return err
 		}
 
-		if err = store.Close(); err != nil {
-			panic(err)
+		if !restart {
+			return nil
 		}
-	}()
+		log.Infof("The server is restarting...")
+	}
+}
 
-	r := router.New(store, sugarLogger)
-	middlewares.Setup(r)
-	handlers.Setup(r)
+func run(ctx context.Context, log logger.Logger) (restart bool, err error) {
+	srv, err := server.New(config.Config, log)
+	if err != nil {
+		return false, err
+	}
 
-	sugarLogger.Debugf("Server routing is configured and sent to launch on: %s", config.Config.Address)
-	if err = r.Run(config.Config.Address); err != nil {
-		sugarLogger.Panicf("Failed start server: %s", err)
+	if err = srv.Start(ctx); err != nil {
+		return false, errors.Join(err, srv.Shutdown(context.Background()))
+	}
+
+	if handoff.Inherited() {
+		// Процесс запущен старым через Upgrade: теперь главный он, о чём systemd нужно сообщить
+		if _, err = systemd.Notify(fmt.Sprintf("MAINPID=%d", os.Getpid())); err != nil {
+			log.Errorf("Failed to notify systemd about the new main pid: %s", err)
+		}
+
+		if err = handoff.Ready(); err != nil {
+			log.Errorf("Failed to report readiness to the previous process: %s", err)
+		}
+	}
+
+	if _, err = systemd.Notify(systemd.Ready); err != nil {
+		log.Errorf("Failed to notify systemd about readiness: %s", err)
+	}
+
+	watchdogCtx, stopWatchdog := context.WithCancel(ctx)
+	defer stopWatchdog()
+	go systemd.RunWatchdog(watchdogCtx, srv.Storage().Ping, log)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+
+	upgrades := make(chan os.Signal, 1)
+	notifyUpgrade(upgrades)
+	defer signal.Stop(upgrades)
+
+	var upgraded bool
+
+wait:
+	for {
+		select {
+		case err = <-srv.Err():
+			return false, errors.Join(err, srv.Shutdown(context.Background()))
+		case sig := <-signals:
+			log.Infof("Received signal %s, shutting down the server...", sig)
+			break wait
+		case <-reloads:
+			if err = srv.ReloadAccessList(); err != nil {
+				log.Errorf("Failed to reload the access list, keeping the previous one: %s", err)
+			} else {
+				log.Infof("The access list has been reloaded.")
+			}
+
+			if err = srv.ReloadCertificate(); err != nil {
+				log.Errorf("Failed to reload the tls certificate, keeping the previous one: %s", err)
+			}
+		case <-upgrades:
+			if upgraded = upgrade(srv, log); upgraded {
+				break wait
+			}
+		case <-ctx.Done():
+			log.Infof("The service has been stopped, shutting down the server...")
+			break wait
+		case restart = <-srv.Stopped():
+			break wait
+		}
+	}
+
+	state := systemd.Stopping
+	if restart {
+		state = systemd.Reloading
+	}
+
+	// После передачи сокетов сервис продолжает работать в новом процессе
+	if !upgraded {
+		if _, err = systemd.Notify(state); err != nil {
+			log.Errorf("Failed to notify systemd about %s: %s", state, err)
+		}
 	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return restart, srv.Shutdown(shutdownCtx)
+}
+
+// upgrade передаёт сокеты новому процессу сервера. Возвращает true, если новый процесс готов
+// и текущий можно останавливать.
+func upgrade(srv *server.Server, log logger.Logger) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), upgradeTimeout)
+	defer cancel()
+
+	pid, err := srv.Upgrade(ctx)
+	if err != nil {
+		log.Errorf("Failed to hand the sockets over to a new process, this one keeps serving: %s", err)
+		return false
+	}
+
+	log.Infof("The new process %d has taken over the sockets, shutting down this one...", pid)
+	return true
 }
@@ -0,0 +1,8 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyUpgrade ничего не делает: на windows передать сокеты другому процессу нельзя.
+func notifyUpgrade(_ chan<- os.Signal) {}
@@ -0,0 +1,140 @@
+// Package backpressure замедляет отправку метрик, когда сервер перегружен: реже отправляет, меньшими
+// пакетами и не раньше Retry-After, а после успешных отправок постепенно возвращается к обычному темпу.
+package backpressure
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// minBatch - меньше этого пакеты не дробятся, иначе запросов станет только больше.
+const minBatch = 10
+
+type Controller struct {
+	maxSlowdown float64
+	maxBatch    int
+
+	mx         sync.Mutex
+	slowdown   float64 // во сколько раз увеличен интервал отправки
+	batch      int     // текущий размер пакета, 0 - без ограничения
+	full       int     // сколько метрик было в пакете до первого уменьшения
+	pauseUntil time.Time
+}
+
+// New создаёт контроллер, который увеличивает интервал отправки не больше чем в maxSlowdown раз.
+// maxBatch ограничивает размер пакета и в обычном режиме, 0 - без ограничения.
+func New(maxSlowdown float64, maxBatch int) *Controller {
+	if maxSlowdown < 1 {
+		maxSlowdown = 1
+	}
+
+	return &Controller{maxSlowdown: maxSlowdown, maxBatch: maxBatch, slowdown: 1, batch: maxBatch}
+}
+
+// Overloaded - сервер ответил, что перегружен. Интервал удваивается, пакет уменьшается вдвое,
+// а до retryAfter, если сервер его передал, отправлять ничего не стоит.
+func (c *Controller) Overloaded(now time.Time, retryAfter time.Duration, sent int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.slowdown *= 2
+	if c.slowdown > c.maxSlowdown {
+		c.slowdown = c.maxSlowdown
+	}
+
+	batch := c.batch
+	if batch == c.maxBatch {
+		c.full = sent
+	}
+	if batch == 0 || batch > sent {
+		batch = sent
+	}
+	if batch /= 2; batch < minBatch {
+		batch = minBatch
+	}
+	c.batch = batch
+
+	if until := now.Add(retryAfter); until.After(c.pauseUntil) {
+		c.pauseUntil = until
+	}
+}
+
+// Succeeded - пакет принят. Интервал и размер пакета возвращаются к обычным шагами по четверти.
+func (c *Controller) Succeeded() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.slowdown > 1 {
+		if c.slowdown *= 0.75; c.slowdown < 1 {
+			c.slowdown = 1
+		}
+	}
+
+	if c.batch != c.maxBatch {
+		c.batch += c.batch/4 + 1
+		if (c.maxBatch > 0 && c.batch > c.maxBatch) || (c.maxBatch == 0 && c.batch >= c.full) {
+			c.batch = c.maxBatch
+		}
+	}
+}
+
+// Interval возвращает интервал до следующей отправки с учётом замедления и Retry-After.
+func (c *Controller) Interval(now time.Time, base time.Duration) time.Duration {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	interval := time.Duration(float64(base) * c.slowdown)
+	if pause := c.pauseUntil.Sub(now); pause > interval {
+		interval = pause
+	}
+
+	return interval
+}
+
+// Pause возвращает, сколько ещё просил подождать сервер.
+func (c *Controller) Pause(now time.Time) time.Duration {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if pause := c.pauseUntil.Sub(now); pause > 0 {
+		return pause
+	}
+
+	return 0
+}
+
+// BatchSize возвращает, по сколько метрик отправлять; 0 - все одним пакетом.
+func (c *Controller) BatchSize() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.batch
+}
+
+// Overload сообщает, что ответ означает перегрузку сервера.
+func Overload(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// RetryAfter разбирает заголовок Retry-After: число секунд или дату. Без заголовка возвращает 0.
+func RetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
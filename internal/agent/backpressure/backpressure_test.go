@@ -0,0 +1,63 @@
+package backpressure
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestController(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := New(8, 0)
+
+	assert.Equal(t, 0, c.BatchSize())
+	assert.Equal(t, 10*time.Second, c.Interval(now, 10*time.Second))
+
+	c.Overloaded(now, 30*time.Second, 200)
+	assert.Equal(t, 100, c.BatchSize())
+	assert.Equal(t, 30*time.Second, c.Interval(now, 10*time.Second), "Retry-After is longer than the slowed down interval")
+	assert.Equal(t, 20*time.Second, c.Interval(now.Add(time.Minute), 10*time.Second))
+	assert.Equal(t, 30*time.Second, c.Pause(now))
+
+	for i := 0; i < 5; i++ {
+		c.Overloaded(now, 0, c.BatchSize())
+	}
+	assert.Equal(t, minBatch, c.BatchSize())
+	assert.Equal(t, 80*time.Second, c.Interval(now.Add(time.Minute), 10*time.Second), "the slowdown is capped")
+
+	// После успешных отправок всё возвращается к обычному режиму
+	for i := 0; i < 50; i++ {
+		c.Succeeded()
+	}
+	assert.Equal(t, 0, c.BatchSize())
+	assert.Equal(t, 10*time.Second, c.Interval(now.Add(time.Minute), 10*time.Second))
+}
+
+func TestControllerWithBatchLimit(t *testing.T) {
+	c := New(4, 50)
+	assert.Equal(t, 50, c.BatchSize())
+
+	c.Overloaded(time.Unix(0, 0), 0, 50)
+	assert.Equal(t, 25, c.BatchSize())
+
+	for i := 0; i < 10; i++ {
+		c.Succeeded()
+	}
+	assert.Equal(t, 50, c.BatchSize())
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), RetryAfter("", now))
+	assert.Equal(t, 120*time.Second, RetryAfter("120", now))
+	assert.Equal(t, time.Duration(0), RetryAfter("-5", now))
+	assert.Equal(t, 90*time.Second, RetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), RetryAfter("soon", now))
+
+	assert.True(t, Overload(http.StatusTooManyRequests))
+	assert.True(t, Overload(http.StatusServiceUnavailable))
+	assert.False(t, Overload(http.StatusInternalServerError))
+}
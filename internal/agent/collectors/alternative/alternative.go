@@ -4,17 +4,36 @@ import (
 	"math/rand"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/state"
 )
 
 type AlternativeMetricsCollector struct {
 	pollCount   metrics.Metric
 	randomValue metrics.Metric
+
+	stateFile string
 }
 
 func NewAlternativeCollector() *AlternativeMetricsCollector {
 	return &AlternativeMetricsCollector{}
 }
 
+// NewPersistentCollector продолжает PollCount с значения из файла состояния и сохраняет его после каждого опроса,
+// чтобы перезапуск агента не сбрасывал счётчик.
+func NewPersistentCollector(stateFile string) (*AlternativeMetricsCollector, error) {
+	s, err := state.Load(stateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &AlternativeMetricsCollector{stateFile: stateFile}
+	if s.PollCount > 0 {
+		c.pollCount = metrics.NewMetric("PollCount", metrics.CounterType, s.PollCount, 0)
+	}
+
+	return c, nil
+}
+
 func (c *AlternativeMetricsCollector) Collect() error {
 	var currentPollCount int64
 	if !c.pollCount.IsNil() {
@@ -24,6 +43,10 @@ func (c *AlternativeMetricsCollector) Collect() error {
 	c.pollCount = metrics.NewMetric("PollCount", metrics.CounterType, currentPollCount+1, 0)
 	c.randomValue = metrics.NewMetric("RandomValue", metrics.GaugeType, 0, rand.Float64())
 
+	if c.stateFile != "" {
+		return state.Save(c.stateFile, state.State{PollCount: *c.pollCount.Delta})
+	}
+
 	return nil
 }
 
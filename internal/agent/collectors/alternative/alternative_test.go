@@ -1,8 +1,10 @@
 package alternative
 
 import (
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -10,3 +12,18 @@ func TestAlternativeMetricsCollector(t *testing.T) {
 	collector := NewAlternativeCollector()
 	require.NoError(t, collector.Collect())
 }
+
+func TestPersistentCollector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.state")
+
+	collector, err := NewPersistentCollector(path)
+	require.NoError(t, err)
+	require.NoError(t, collector.Collect())
+	require.NoError(t, collector.Collect())
+
+	// Новый коллектор, как после перезапуска агента, продолжает счёт
+	collector, err = NewPersistentCollector(path)
+	require.NoError(t, err)
+	require.NoError(t, collector.Collect())
+	assert.Equal(t, int64(3), *collector.GetResults()[0].Delta)
+}
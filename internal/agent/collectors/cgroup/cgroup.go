@@ -0,0 +1,286 @@
+// Package cgroup собирает лимиты и потребление ресурсов контейнера из cgroup v1 и v2.
+// Внутри контейнера gopsutil видит ресурсы всей машины, а эти метрики - то, что доступно агенту на самом деле.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+const (
+	DefaultRoot = "/sys/fs/cgroup"
+	selfCgroup  = "/proc/self/cgroup"
+
+	// unlimitedV1 - «без лимита» в cgroup v1 записывается числом около 2^63, выровненным по странице.
+	unlimitedV1 = 1 << 62
+)
+
+var ErrNoCgroup = errors.New("cgroup filesystem not found")
+
+type CgroupMetricsCollector struct {
+	v2   bool
+	dirs map[string]string // каталог cgroup процесса по контроллеру; для v2 один каталог под ключом ""
+
+	results []metrics.Metric
+}
+
+// NewCgroupCollector находит cgroup текущего процесса. Если cgroup не смонтирована, например вне Linux,
+// возвращает ErrNoCgroup.
+func NewCgroupCollector() (*CgroupMetricsCollector, error) {
+	return newCollector(DefaultRoot, selfCgroup)
+}
+
+func newCollector(root, self string) (*CgroupMetricsCollector, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoCgroup, root)
+	}
+
+	paths, err := readSelfCgroup(self)
+	if err != nil {
+		return nil, err
+	}
+
+	if exists(filepath.Join(root, "cgroup.controllers")) {
+		return &CgroupMetricsCollector{v2: true, dirs: map[string]string{"": processDir(root, paths[""])}}, nil
+	}
+
+	dirs := make(map[string]string)
+	for _, controller := range []string{"memory", "cpu", "cpuacct"} {
+		if mount := filepath.Join(root, controller); exists(mount) {
+			dirs[controller] = processDir(mount, paths[controller])
+		}
+	}
+
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("%w: no memory or cpu controllers in %s", ErrNoCgroup, root)
+	}
+
+	return &CgroupMetricsCollector{dirs: dirs}, nil
+}
+
+func (c *CgroupMetricsCollector) Collect() error {
+	var (
+		results []metrics.Metric
+		err     error
+	)
+
+	if c.v2 {
+		results, err = c.collectV2()
+	} else {
+		results, err = c.collectV1()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.results = results
+	return nil
+}
+
+func (c *CgroupMetricsCollector) GetResults() []metrics.Metric {
+	return c.results
+}
+
+func (c *CgroupMetricsCollector) collectV2() ([]metrics.Metric, error) {
+	dir := c.dirs[""]
+	var results []metrics.Metric
+
+	if usage, ok, err := readUint(filepath.Join(dir, "memory.current")); err != nil {
+		return nil, err
+	} else if ok {
+		results = append(results, gauge("CgroupMemoryUsage", float64(usage)))
+	}
+
+	if limit, ok, err := readUint(filepath.Join(dir, "memory.max")); err != nil {
+		return nil, err
+	} else if ok {
+		results = append(results, gauge("CgroupMemoryLimit", float64(limit)))
+	}
+
+	// cpu.max: "<квота> <период>" в микросекундах, квота "max" - без ограничения
+	if fields, err := readFields(filepath.Join(dir, "cpu.max")); err != nil {
+		return nil, err
+	} else if len(fields) == 2 && fields[0] != "max" {
+		quota, quotaErr := strconv.ParseFloat(fields[0], 64)
+		period, periodErr := strconv.ParseFloat(fields[1], 64)
+		if quotaErr == nil && periodErr == nil && period > 0 {
+			results = append(results, gauge("CgroupCPUQuota", quota/period))
+		}
+	}
+
+	stat, err := readStat(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+
+	if usage, ok := stat["usage_usec"]; ok {
+		results = append(results, gauge("CgroupCPUUsageSeconds", float64(usage)/1e6))
+	}
+	if throttled, ok := stat["nr_throttled"]; ok {
+		results = append(results, gauge("CgroupCPUThrottledPeriods", float64(throttled)))
+	}
+	if throttled, ok := stat["throttled_usec"]; ok {
+		results = append(results, gauge("CgroupCPUThrottledSeconds", float64(throttled)/1e6))
+	}
+
+	return results, nil
+}
+
+func (c *CgroupMetricsCollector) collectV1() ([]metrics.Metric, error) {
+	var results []metrics.Metric
+
+	if dir, ok := c.dirs["memory"]; ok {
+		if usage, ok, err := readUint(filepath.Join(dir, "memory.usage_in_bytes")); err != nil {
+			return nil, err
+		} else if ok {
+			results = append(results, gauge("CgroupMemoryUsage", float64(usage)))
+		}
+
+		if limit, ok, err := readUint(filepath.Join(dir, "memory.limit_in_bytes")); err != nil {
+			return nil, err
+		} else if ok && limit < unlimitedV1 {
+			results = append(results, gauge("CgroupMemoryLimit", float64(limit)))
+		}
+	}
+
+	if dir, ok := c.dirs["cpu"]; ok {
+		// cfs_quota_us равен -1 без ограничения, readUint его отбрасывает
+		quota, quotaOK, err := readUint(filepath.Join(dir, "cpu.cfs_quota_us"))
+		if err != nil {
+			return nil, err
+		}
+
+		period, periodOK, err := readUint(filepath.Join(dir, "cpu.cfs_period_us"))
+		if err != nil {
+			return nil, err
+		}
+
+		if quotaOK && periodOK && period > 0 {
+			results = append(results, gauge("CgroupCPUQuota", float64(quota)/float64(period)))
+		}
+
+		stat, err := readStat(filepath.Join(dir, "cpu.stat"))
+		if err != nil {
+			return nil, err
+		}
+
+		if throttled, ok := stat["nr_throttled"]; ok {
+			results = append(results, gauge("CgroupCPUThrottledPeriods", float64(throttled)))
+		}
+		if throttled, ok := stat["throttled_time"]; ok {
+			results = append(results, gauge("CgroupCPUThrottledSeconds", float64(throttled)/1e9))
+		}
+	}
+
+	if dir, ok := c.dirs["cpuacct"]; ok {
+		if usage, ok, err := readUint(filepath.Join(dir, "cpuacct.usage")); err != nil {
+			return nil, err
+		} else if ok {
+			results = append(results, gauge("CgroupCPUUsageSeconds", float64(usage)/1e9))
+		}
+	}
+
+	return results, nil
+}
+
+func gauge(name string, value float64) metrics.Metric {
+	return metrics.NewMetric(name, metrics.GaugeType, 0, value)
+}
+
+// readSelfCgroup разбирает /proc/self/cgroup в пути по контроллерам; путь cgroup v2 лежит под ключом "".
+func readSelfCgroup(path string) (map[string]string, error) {
+	paths := make(map[string]string)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return paths, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Строка вида "4:memory:/docker/<id>" или "0::/user.slice" для v2
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[strings.TrimPrefix(controller, "name=")] = parts[2]
+		}
+	}
+
+	return paths, scanner.Err()
+}
+
+// processDir возвращает каталог cgroup процесса. В контейнере с собственным пространством имён cgroup
+// точка монтирования уже и есть cgroup процесса, а путь из /proc/self/cgroup в ней не существует.
+func processDir(mount, path string) string {
+	if path == "" || path == "/" {
+		return mount
+	}
+
+	if dir := filepath.Join(mount, path); exists(dir) {
+		return dir
+	}
+
+	return mount
+}
+
+// readUint читает число из файла. Отсутствующий файл, "max" и отрицательные значения означают, что значения нет.
+func readUint(path string) (uint64, bool, error) {
+	fields, err := readFields(path)
+	if err != nil || len(fields) == 0 {
+		return 0, false, err
+	}
+
+	value, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	return value, true, nil
+}
+
+func readFields(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(string(data)), nil
+}
+
+// readStat разбирает файлы вида "ключ значение" по строке на пару.
+func readStat(path string) (map[string]uint64, error) {
+	fields, err := readFields(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat := make(map[string]uint64, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		if value, err := strconv.ParseUint(fields[i+1], 10, 64); err == nil {
+			stat[fields[i]] = value
+		}
+	}
+
+	return stat, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,132 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func values(results []metrics.Metric) map[string]float64 {
+	values := make(map[string]float64, len(results))
+	for _, metric := range results {
+		values[metric.ID] = *metric.Value
+	}
+
+	return values
+}
+
+func TestCgroupCollector(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		selfCgroup   string
+		wantedValues map[string]float64
+	}{
+		{
+			name: "v2 in a container",
+			files: map[string]string{
+				"cgroup.controllers": "cpu memory",
+				"memory.current":     "104857600\n",
+				"memory.max":         "536870912\n",
+				"cpu.max":            "150000 100000\n",
+				"cpu.stat":           "usage_usec 2500000\nnr_periods 10\nnr_throttled 3\nthrottled_usec 500000\n",
+			},
+			selfCgroup: "0::/\n",
+			wantedValues: map[string]float64{
+				"CgroupMemoryUsage":         104857600,
+				"CgroupMemoryLimit":         536870912,
+				"CgroupCPUQuota":            1.5,
+				"CgroupCPUUsageSeconds":     2.5,
+				"CgroupCPUThrottledPeriods": 3,
+				"CgroupCPUThrottledSeconds": 0.5,
+			},
+		},
+		{
+			name: "v2 without limits on the host",
+			files: map[string]string{
+				"cgroup.controllers":                        "cpu memory",
+				"system.slice/agent.service/memory.current": "4096\n",
+				"system.slice/agent.service/memory.max":     "max\n",
+				"system.slice/agent.service/cpu.max":        "max 100000\n",
+				"system.slice/agent.service/cpu.stat":       "usage_usec 1000000\n",
+			},
+			selfCgroup: "0::/system.slice/agent.service\n",
+			wantedValues: map[string]float64{
+				"CgroupMemoryUsage":     4096,
+				"CgroupCPUUsageSeconds": 1,
+			},
+		},
+		{
+			name: "v1",
+			files: map[string]string{
+				"memory/memory.usage_in_bytes": "2048\n",
+				"memory/memory.limit_in_bytes": "1073741824\n",
+				"cpu/cpu.cfs_quota_us":         "50000\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+				"cpu/cpu.stat":                 "nr_periods 20\nnr_throttled 4\nthrottled_time 2000000000\n",
+				"cpuacct/cpuacct.usage":        "3000000000\n",
+			},
+			selfCgroup: "5:memory:/docker/abc\n4:cpu,cpuacct:/docker/abc\n",
+			wantedValues: map[string]float64{
+				"CgroupMemoryUsage":         2048,
+				"CgroupMemoryLimit":         1073741824,
+				"CgroupCPUQuota":            0.5,
+				"CgroupCPUThrottledPeriods": 4,
+				"CgroupCPUThrottledSeconds": 2,
+				"CgroupCPUUsageSeconds":     3,
+			},
+		},
+		{
+			name: "v1 without limits",
+			files: map[string]string{
+				"memory/memory.usage_in_bytes": "2048\n",
+				"memory/memory.limit_in_bytes": "9223372036854771712\n",
+				"cpu/cpu.cfs_quota_us":         "-1\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+			},
+			wantedValues: map[string]float64{
+				"CgroupMemoryUsage": 2048,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "cgroup")
+			writeFiles(t, root, tt.files)
+
+			self := filepath.Join(dir, "self")
+			require.NoError(t, os.WriteFile(self, []byte(tt.selfCgroup), 0644))
+
+			collector, err := newCollector(root, self)
+			require.NoError(t, err)
+			require.NoError(t, collector.Collect())
+
+			assert.Equal(t, tt.wantedValues, values(collector.GetResults()))
+		})
+	}
+}
+
+func TestCgroupCollectorWithoutCgroup(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := newCollector(filepath.Join(dir, "missing"), filepath.Join(dir, "self"))
+	require.ErrorIs(t, err, ErrNoCgroup)
+
+	_, err = newCollector(dir, filepath.Join(dir, "self"))
+	require.ErrorIs(t, err, ErrNoCgroup)
+}
@@ -6,21 +6,25 @@ import (
 	"sync"
 	"time"
 
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/alternative"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/gopsutil"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/runtime"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/filter"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/rename"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 type Collector struct {
-	collectors map[string]collector
+	names      []string
+	collectors map[string]MetricsCollector
+	filter     *filter.Filter
+	rename     *rename.Rules
 
 	mx      sync.Mutex
 	metrics []metrics.Metric
 
-	log logger.Logger
+	log   logger.Logger
+	clock clock.Clock
 }
 
 type workerResult struct {
@@ -28,25 +32,63 @@ type workerResult struct {
 	Res []metrics.Metric
 }
 
-type collector interface {
-	Collect() error
-	GetResults() []metrics.Metric
+// NewCollector создаёт коллектор из источников, выбранных в настройках (см. Register).
+func NewCollector(log logger.Logger) (*Collector, error) {
+	c := &Collector{
+		log:   log,
+		clock: clock.Real(),
+	}
+
+	if err := c.Reconfigure(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
-func NewCollector(log logger.Logger) *Collector {
-	alternativeCollector := alternative.NewAlternativeCollector()
-	gopsutilCollector := gopsutil.NewGopsutilCollector()
-	runtimeCollector := runtime.NewRuntimeCollector()
+// Reconfigure применяет текущие настройки источников, фильтра и переименования. Источники, которые
+// были выбраны и раньше, остаются вместе со своим состоянием, например счётчиком PollCount.
+// Вызывать можно, только пока не работает Run.
+func (c *Collector) Reconfigure() error {
+	names := ParseNames(config.Config.Collectors)
+
+	metricsFilter, err := filter.Parse(config.Config.MetricsInclude, config.Config.MetricsExclude)
+	if err != nil {
+		return err
+	}
+
+	renameRules, err := rename.Parse(config.Config.MetricsPrefix, config.Config.MetricsRename)
+	if err != nil {
+		return err
+	}
+
+	var added []string
+	for _, name := range names {
+		if _, ok := c.collectors[name]; !ok {
+			added = append(added, name)
+		}
+	}
 
-	return &Collector{
-		collectors: map[string]collector{
-			"alternative": alternativeCollector,
-			"gopsutil":    gopsutilCollector,
-			"runtime":     runtimeCollector,
-		},
+	cols := make(map[string]MetricsCollector, len(names))
+	if len(added) > 0 || len(names) == 0 {
+		if cols, err = newCollectors(added); err != nil {
+			return err
+		}
+	}
 
-		log: log,
+	for _, name := range names {
+		if col, ok := c.collectors[name]; ok {
+			cols[name] = col
+		}
 	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.names, c.collectors = names, cols
+	c.filter, c.rename = metricsFilter, renameRules
+
+	return nil
 }
 
 func (c *Collector) GetMetrics() []metrics.Metric {
@@ -57,7 +99,7 @@ func (c *Collector) GetMetrics() []metrics.Metric {
 }
 
 func (c *Collector) Run(ctx context.Context) {
-	collectors := []string{"alternative", "gopsutil", "runtime"}
+	collectors := c.names
 
 	jobs := make(chan string, len(collectors))
 	results := make(chan workerResult, len(collectors))
@@ -70,12 +112,10 @@ func (c *Collector) Run(ctx context.Context) {
 	}
 
 	for {
-		select {
-		case <-ctx.Done():
+		c.runWorkers(collectors, jobs, results)
+
+		if err := clock.Sleep(ctx, c.clock, time.Second*time.Duration(config.Config.PollInterval)); err != nil {
 			return
-		default:
-			c.runWorkers(collectors, jobs, results)
-			time.Sleep(time.Second * time.Duration(config.Config.PollInterval))
 		}
 	}
 }
@@ -96,7 +136,8 @@ func (c *Collector) runWorkers(collectors []string, jobs chan<- string, results
 			continue
 		}
 
-		c.metrics = append(c.metrics, result.Res...)
+		// Фильтр проверяет исходные имена, переименование - после него
+		c.metrics = append(c.metrics, c.rename.Apply(c.filter.Apply(result.Res))...)
 	}
 
 	c.mx.Unlock()
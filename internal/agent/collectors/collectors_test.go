@@ -7,23 +7,46 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
+// run запускает Run и возвращает функцию, которая останавливает его и дожидается выхода:
+// Run читает config.Config, поэтому менять настройки можно только после неё.
+func run(ctx context.Context, c *Collector) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 func TestCollector(t *testing.T) {
 	config.Config.RateLimit = 3
 	config.Config.PollInterval = 2
+	config.Config.Collectors = "alternative,gopsutil,runtime"
 
-	log, err := logger.New()
+	log, err := logger.New(logger.Options{})
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 	defer cancel()
 
-	c := NewCollector(log)
-	go c.Run(ctx)
+	c, err := NewCollector(log)
+	require.NoError(t, err)
+
+	stop := run(ctx, c)
+	defer stop()
 
 	var countMetrics int
 
@@ -43,3 +66,77 @@ outerLoop:
 
 	assert.Greater(t, countMetrics, 0)
 }
+
+type staticCollector struct {
+	collected int
+}
+
+func (c *staticCollector) Collect() error {
+	c.collected++
+	return nil
+}
+
+func (c *staticCollector) GetResults() []metrics.Metric {
+	return []metrics.Metric{metrics.NewMetric("Static", metrics.CounterType, int64(c.collected), 0)}
+}
+
+func TestRegister(t *testing.T) {
+	Register("static", func() (MetricsCollector, error) { return &staticCollector{}, nil })
+	assert.Contains(t, Registered(), "static")
+
+	assert.Panics(t, func() {
+		Register("static", func() (MetricsCollector, error) { return &staticCollector{}, nil })
+	})
+	assert.Panics(t, func() { Register("a,b", func() (MetricsCollector, error) { return nil, nil }) })
+	assert.Panics(t, func() { Register("nil", nil) })
+
+	config.Config.RateLimit = 1
+	config.Config.PollInterval = 1
+	config.Config.Collectors = " static , static"
+
+	c, err := NewCollector(logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"static"}, c.names)
+
+	stop := run(context.Background(), c)
+
+	require.Eventually(t, func() bool {
+		return len(c.GetMetrics()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Static", c.GetMetrics()[0].ID)
+	stop()
+
+	config.Config.Collectors = "static,unknown"
+	_, err = NewCollector(logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.ErrorContains(t, err, `unknown collector "unknown"`)
+
+	config.Config.Collectors = ""
+	_, err = NewCollector(logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
+
+func TestReconfigure(t *testing.T) {
+	Register("counting", func() (MetricsCollector, error) { return &staticCollector{}, nil })
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Collectors = "counting"
+
+	c, err := NewCollector(logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	counting := c.collectors["counting"]
+	require.NoError(t, counting.Collect())
+
+	config.Config.Collectors = "counting,alternative"
+	config.Config.MetricsPrefix = "svc."
+	require.NoError(t, c.Reconfigure())
+
+	assert.Equal(t, []string{"counting", "alternative"}, c.names)
+	assert.Same(t, counting, c.collectors["counting"], "the collector keeps its state")
+	assert.Equal(t, "svc.Static", c.rename.Name("Static"))
+
+	config.Config.Collectors = "unknown"
+	require.Error(t, c.Reconfigure())
+	assert.Equal(t, []string{"counting", "alternative"}, c.names)
+}
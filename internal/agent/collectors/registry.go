@@ -0,0 +1,130 @@
+package collectors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/alternative"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/cgroup"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/gopsutil"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors/runtime"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+type (
+	// MetricsCollector - источник метрик. Collect вызывается раз в PollInterval,
+	// после успешного Collect коллектор забирает метрики через GetResults.
+	MetricsCollector interface {
+		Collect() error
+		GetResults() []metrics.Metric
+	}
+
+	// CollectorFactory создаёт источник метрик при создании коллектора.
+	CollectorFactory func() (MetricsCollector, error)
+)
+
+var (
+	registryMx sync.RWMutex
+	registry   = make(map[string]CollectorFactory)
+)
+
+func init() {
+	Register("alternative", func() (MetricsCollector, error) {
+		if path := config.Config.StateFile; path != "" {
+			col, err := alternative.NewPersistentCollector(path)
+			if err != nil {
+				return nil, err
+			}
+
+			return col, nil
+		}
+
+		return alternative.NewAlternativeCollector(), nil
+	})
+	Register("cgroup", func() (MetricsCollector, error) {
+		col, err := cgroup.NewCgroupCollector()
+		if err != nil {
+			return nil, err
+		}
+
+		return col, nil
+	})
+	Register("gopsutil", func() (MetricsCollector, error) { return gopsutil.NewGopsutilCollector(), nil })
+	Register("runtime", func() (MetricsCollector, error) { return runtime.NewRuntimeCollector(), nil })
+}
+
+// Register добавляет источник метрик, который можно выбрать по имени в настройках.
+// Обычно вызывается из init пакета с источником. Повторная регистрация имени - ошибка программиста, поэтому паникует.
+func Register(name string, factory CollectorFactory) {
+	registryMx.Lock()
+	defer registryMx.Unlock()
+
+	if name == "" || strings.Contains(name, ",") {
+		panic(fmt.Sprintf("collectors: invalid collector name %q", name))
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("collectors: nil factory for collector %q", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("collectors: collector %q is already registered", name))
+	}
+
+	registry[name] = factory
+}
+
+// Registered возвращает отсортированные имена всех зарегистрированных источников.
+func Registered() []string {
+	registryMx.RLock()
+	defer registryMx.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseNames разбирает список источников через запятую, повторы отбрасываются.
+func ParseNames(list string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func newCollectors(names []string) (map[string]MetricsCollector, error) {
+	registryMx.RLock()
+	defer registryMx.RUnlock()
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no collectors selected")
+	}
+
+	cols := make(map[string]MetricsCollector, len(names))
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q", name)
+		}
+
+		col, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("collector %q: %w", name, err)
+		}
+		cols[name] = col
+	}
+
+	return cols, nil
+}
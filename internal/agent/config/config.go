@@ -1,29 +1,322 @@
 package config
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/caarlos0/env/v6"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/encoding"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/endpoints"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/filter"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/rename"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/schedule"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/secrets"
 )
 
-var Config struct {
+// Settings - настройки агента. Процесс использует одни настройки на всех: они лежат в Config.
+type Settings struct {
+	ConfigFile string `env:"CONFIG"`
+
 	Address        string `env:"ADDRESS"`
 	ReportInterval int    `env:"REPORT_INTERVAL"`
 	PollInterval   int    `env:"POLL_INTERVAL"`
-	Key            string `env:"KEY"`
+	Key            string `env:"KEY" secret:"true"`
+	KeyID          string `env:"KEY_ID"`
+	SignNonce      bool   `env:"SIGN_NONCE"`
+	APIKey         string `env:"API_KEY" secret:"true"`
 	RateLimit      int    `env:"RATE_LIMIT"`
+	Collectors     string `env:"COLLECTORS"`
+	AgentID        string `env:"AGENT_ID"`
+	DryRun         bool   `env:"DRY_RUN"`
+	StateFile      string `env:"STATE_FILE"`
+	StatusAddress  string `env:"STATUS_ADDRESS"`
+
+	MetricsInclude string `env:"METRICS_INCLUDE"`
+	MetricsExclude string `env:"METRICS_EXCLUDE"`
+	MetricsPrefix  string `env:"METRICS_PREFIX"`
+	MetricsRename  string `env:"METRICS_RENAME"`
+
+	H2C      bool   `env:"H2C"`
+	Encoding string `env:"ENCODING"`
+
+	FailoverMode     string        `env:"FAILOVER_MODE"`
+	EndpointCooldown time.Duration `env:"ENDPOINT_COOLDOWN"`
+
+	ReportWindows string `env:"REPORT_WINDOWS"`
+	QuietHours    string `env:"QUIET_HOURS"`
+
+	BatchSize     int `env:"BATCH_SIZE"`
+	MaxBatchBytes int `env:"MAX_BATCH_BYTES"`
+	MaxSlowdown   int `env:"MAX_SLOWDOWN"`
+
+	LoadTest         bool          `env:"LOADTEST"`
+	LoadTestMetrics  int           `env:"LOADTEST_METRICS"`
+	LoadTestBatch    int           `env:"LOADTEST_BATCH"`
+	LoadTestRate     int           `env:"LOADTEST_RATE"`
+	LoadTestDuration time.Duration `env:"LOADTEST_DURATION"`
+
+	LogLevels string `env:"LOG_LEVELS"`
+
+	LogFile       string        `env:"LOG_FILE"`
+	LogMaxSize    int           `env:"LOG_MAX_SIZE"`
+	LogMaxBackups int           `env:"LOG_MAX_BACKUPS"`
+	LogMaxAge     time.Duration `env:"LOG_MAX_AGE"`
+	LogCompress   bool          `env:"LOG_COMPRESS"`
+
+	LogSink          string `env:"LOG_SINK"`
+	LogSyslogAddress string `env:"LOG_SYSLOG_ADDRESS"`
+	LogTag           string `env:"LOG_TAG"`
+
+	LogEncoding   string `env:"LOG_ENCODING"`
+	LogTimeFormat string `env:"LOG_TIME_FORMAT"`
+
+	LogDedupWindow time.Duration `env:"LOG_DEDUP_WINDOW"`
+	LogAsyncBuffer int           `env:"LOG_ASYNC_BUFFER"`
+
+	ErrorTrackerDSN string `env:"ERROR_TRACKER_DSN" secret:"true"`
 }
 
+var Config Settings
+
 func Load() {
-	flag.StringVar(&Config.Address, "a", "localhost:8080", "server address")
-	flag.IntVar(&Config.ReportInterval, "r", 10, "report interval")
-	flag.IntVar(&Config.PollInterval, "p", 2, "poll interval")
-	flag.StringVar(&Config.Key, "k", "", "key for hash")
-	flag.IntVar(&Config.RateLimit, "l", 2, "rate limit for worker pool")
+	registerFlags(flag.CommandLine, &Config)
+}
+
+func registerFlags(fs *flag.FlagSet, s *Settings) {
+	fs.StringVar(&s.ConfigFile, "config", "", "path to a JSON file with settings keyed by flag names, reread on SIGHUP; flags and environment variables take precedence")
+	fs.StringVar(&s.Address, "a", "localhost:8080", "comma-separated server addresses as host:port or URLs like https://host:port; see -failover-mode")
+	fs.BoolVar(&s.H2C, "h2c", false, "talk HTTP/2 without TLS (h2c) to http:// servers started with -h2c; https servers negotiate HTTP/2 anyway")
+	fs.StringVar(&s.Encoding, "encoding", encoding.JSON, "encoding of the sent metrics: json, protobuf or msgpack (both smaller and faster to parse)")
+	fs.StringVar(&s.FailoverMode, "failover-mode", "ordered", "how servers are picked: ordered tries them in the listed order, round-robin starts from the next one each time")
+	fs.DurationVar(&s.EndpointCooldown, "endpoint-cooldown", time.Second*30, "how long a server that failed is skipped while other servers are available")
+	fs.IntVar(&s.ReportInterval, "r", 10, "report interval")
+	fs.StringVar(&s.ReportWindows, "report-windows", "", "semicolon-separated local time windows when metrics are sent, like Mon-Fri 09:00-18:00 (empty sends at any time)")
+	fs.StringVar(&s.QuietHours, "quiet-hours", "", "semicolon-separated local time windows when metrics are not sent, like Sat 02:00-04:00")
+	fs.IntVar(&s.PollInterval, "p", 2, "poll interval")
+	fs.StringVar(&s.Key, "k", "", "key for hash")
+	fs.StringVar(&s.KeyID, "key-id", "", "id of the hash key in the server's SIGNING_KEYS, sent in HashKeyID (empty means the server's KEY)")
+	fs.BoolVar(&s.SignNonce, "sign-nonce", true, "sign HashTimestamp and HashNonce along with the body so the server can reject replayed requests")
+	fs.IntVar(&s.BatchSize, "batch-size", 0, "maximum metrics in one request (0 sends all metrics in one request)")
+	fs.IntVar(&s.MaxBatchBytes, "max-batch-bytes", 0, "maximum size of the JSON body of one request; larger reports are split into several requests (0 disables the limit)")
+	fs.IntVar(&s.MaxSlowdown, "max-slowdown", 8, "how many times the report interval may grow while the server answers 429 or 503")
+	fs.StringVar(&s.APIKey, "api-key", "", "API key with the write role, sent to the server as a bearer token (empty sends none)")
+	fs.IntVar(&s.RateLimit, "l", 2, "rate limit for worker pool")
+	fs.StringVar(&s.AgentID, "agent-id", hostname(), "agent identifier sent to the server in X-Agent-ID (empty sends none)")
+	fs.StringVar(&s.StateFile, "state-file", "", "file where PollCount is kept between agent restarts (empty starts from zero on every start)")
+	fs.StringVar(&s.StatusAddress, "status-address", "", "address like localhost:9091 serving GET /api/buildinfo; read once at start (empty disables the listener)")
+	fs.BoolVar(&s.DryRun, "dry-run", false, "print the requests that would be sent to stdout instead of sending them")
+	fs.StringVar(&s.Collectors, "collectors", "alternative,gopsutil,runtime", "comma-separated names of the metric collectors to run")
+	fs.StringVar(&s.MetricsInclude, "metrics-include", "", "comma-separated metric names or globs like Heap* to send (empty sends all)")
+	fs.StringVar(&s.MetricsExclude, "metrics-exclude", "", "comma-separated metric names or globs not to send, checked after -metrics-include")
+	fs.StringVar(&s.MetricsPrefix, "metrics-prefix", "", "prefix added to all metric names, {hostname} is replaced with the host name (empty adds none)")
+	fs.StringVar(&s.MetricsRename, "metrics-rename", "", "comma-separated rules like HeapAlloc=heap_alloc applied before -metrics-prefix")
+
+	fs.BoolVar(&s.LoadTest, "loadtest", false, "send synthetic metrics instead of collected ones and report send statistics")
+	fs.IntVar(&s.LoadTestMetrics, "loadtest-metrics", 1000, "number of unique synthetic metrics in the load test")
+	fs.IntVar(&s.LoadTestBatch, "loadtest-batch", 100, "number of metrics in one load test request")
+	fs.IntVar(&s.LoadTestRate, "loadtest-rate", 10, "load test requests per second")
+	fs.DurationVar(&s.LoadTestDuration, "loadtest-duration", time.Minute, "duration of the load test")
+
+	fs.StringVar(&s.LogLevels, "log-levels", "", "log levels by module, e.g. info,dbstorage=debug,handlers=warn (empty logs everything)")
+	fs.StringVar(&s.LogFile, "log-file", "", "path to the log file (empty writes logs to stderr)")
+	fs.IntVar(&s.LogMaxSize, "log-max-size", 100, "size of the log file in megabytes after which it is rotated (0 disables rotation)")
+	fs.IntVar(&s.LogMaxBackups, "log-max-backups", 0, "number of rotated log files to keep (0 keeps all)")
+	fs.DurationVar(&s.LogMaxAge, "log-max-age", 0, "how long rotated log files are kept (0 keeps them forever)")
+	fs.BoolVar(&s.LogCompress, "log-compress", false, "compress rotated log files with gzip")
+	fs.StringVar(&s.LogSink, "log-sink", "stderr", "where logs are shipped: stderr, syslog or journald")
+	fs.StringVar(&s.LogSyslogAddress, "log-syslog-address", "", "syslog address like udp://host:514 (empty uses the local syslog)")
+	fs.StringVar(&s.LogTag, "log-tag", "", "program identifier in syslog/journald (empty uses the executable name)")
+	fs.StringVar(&s.LogEncoding, "log-encoding", "console", "log encoding: console or json")
+	fs.StringVar(&s.LogTimeFormat, "log-time-format", "iso8601", "log timestamp format: iso8601, rfc3339, rfc3339nano, epoch, millis, nanos or a time layout")
+	fs.IntVar(&s.LogAsyncBuffer, "log-async-buffer", 0, "size of the queue for background log writing; entries are dropped when it is full (0 writes synchronously)")
+	fs.StringVar(&s.ErrorTrackerDSN, "error-tracker-dsn", "", "sentry dsn or http endpoint receiving logged errors (empty disables it)")
+	fs.DurationVar(&s.LogDedupWindow, "log-dedup-window", 0, "window in which repeated warnings and errors are collapsed into one entry with a repeat count (0 disables it)")
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+
+	return name
+}
+
+// Defaults возвращает настройки со значениями флагов по умолчанию, не трогая Config.
+func Defaults() Settings {
+	var s Settings
+
+	registerFlags(flag.NewFlagSet("defaults", flag.ContinueOnError), &s)
+	return s
+}
+
+// Set проверяет настройки и делает их текущими. При ошибке Config не меняется.
+func Set(settings Settings) error {
+	if err := secrets.ResolveStruct(context.Background(), &settings); err != nil {
+		return err
+	}
+
+	saved := Config
+	Config = settings
+
+	if err := validate(); err != nil {
+		Config = saved
+		return err
+	}
+
+	return nil
 }
 
 func Parse() error {
 	flag.Parse()
 
-	return env.Parse(&Config)
+	if err := load(flag.CommandLine, &Config); err != nil {
+		return err
+	}
+
+	if err := secrets.ResolveStruct(context.Background(), &Config); err != nil {
+		return err
+	}
+
+	return validate()
+}
+
+// Reread заново читает флаги, файл настроек и переменные окружения и возвращает результат, не меняя Config.
+// Проверяет настройки Set, когда их применяют.
+func Reread() (Settings, error) {
+	var s Settings
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	registerFlags(fs, &s)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return Settings{}, err
+	}
+
+	if err := load(fs, &s); err != nil {
+		return Settings{}, err
+	}
+
+	return s, nil
+}
+
+// load дополняет разобранные флаги настройками из файла и переменных окружения.
+func load(fs *flag.FlagSet, s *Settings) error {
+	path := s.ConfigFile
+	if value, ok := os.LookupEnv("CONFIG"); ok {
+		path = value
+	}
+
+	if path != "" {
+		if err := applyFile(fs, path); err != nil {
+			return err
+		}
+	}
+
+	return env.Parse(s)
+}
+
+func validate() error {
+	if Config.ReportInterval <= 0 {
+		return fmt.Errorf("invalid report interval %d: must be positive", Config.ReportInterval)
+	}
+
+	if Config.PollInterval <= 0 {
+		return fmt.Errorf("invalid poll interval %d: must be positive", Config.PollInterval)
+	}
+
+	if _, err := endpoints.Parse(Config.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", Config.Address, err)
+	}
+
+	if !encoding.Valid(Config.Encoding) {
+		return fmt.Errorf("invalid encoding %q: expected %s, %s or %s", Config.Encoding, encoding.JSON, encoding.Protobuf, encoding.MsgPack)
+	}
+
+	if !endpoints.ValidMode(Config.FailoverMode) {
+		return fmt.Errorf("invalid failover mode %q: expected %s or %s", Config.FailoverMode, endpoints.Ordered, endpoints.RoundRobin)
+	}
+
+	if Config.EndpointCooldown < 0 {
+		return fmt.Errorf("invalid endpoint cooldown %s: must not be negative", Config.EndpointCooldown)
+	}
+
+	if _, err := filter.Parse(Config.MetricsInclude, Config.MetricsExclude); err != nil {
+		return fmt.Errorf("invalid metrics filter: %w", err)
+	}
+
+	if _, err := rename.Parse(Config.MetricsPrefix, Config.MetricsRename); err != nil {
+		return fmt.Errorf("invalid metrics renaming: %w", err)
+	}
+
+	if _, err := schedule.Parse(Config.ReportWindows, Config.QuietHours); err != nil {
+		return fmt.Errorf("invalid report schedule: %w", err)
+	}
+
+	if Config.BatchSize < 0 {
+		return fmt.Errorf("invalid batch size %d: must not be negative", Config.BatchSize)
+	}
+
+	if Config.MaxBatchBytes < 0 {
+		return fmt.Errorf("invalid max batch bytes %d: must not be negative", Config.MaxBatchBytes)
+	}
+
+	if Config.MaxSlowdown < 1 {
+		return fmt.Errorf("invalid max slowdown %d: must be at least 1", Config.MaxSlowdown)
+	}
+
+	if Config.LoadTest {
+		if Config.DryRun {
+			return fmt.Errorf("the dry run cannot be combined with the load test")
+		}
+
+		if err := validateLoadTest(); err != nil {
+			return err
+		}
+	}
+
+	if Config.LogAsyncBuffer < 0 {
+		return fmt.Errorf("invalid log async buffer %d: must not be negative", Config.LogAsyncBuffer)
+	}
+
+	if Config.LogDedupWindow < 0 {
+		return fmt.Errorf("invalid log dedup window %s: must not be negative", Config.LogDedupWindow)
+	}
+
+	if _, err := logger.ParseLevels(Config.LogLevels); err != nil {
+		return err
+	}
+
+	if _, err := logger.ParseSink(Config.LogSink); err != nil {
+		return err
+	}
+
+	if _, err := logger.ParseEncoding(Config.LogEncoding); err != nil {
+		return err
+	}
+
+	_, err := logger.ParseTimeFormat(Config.LogTimeFormat)
+	return err
+}
+
+func validateLoadTest() error {
+	switch {
+	case Config.LoadTestMetrics <= 0:
+		return fmt.Errorf("invalid load test metrics %d: must be positive", Config.LoadTestMetrics)
+	case Config.LoadTestBatch <= 0:
+		return fmt.Errorf("invalid load test batch %d: must be positive", Config.LoadTestBatch)
+	case Config.LoadTestRate <= 0:
+		return fmt.Errorf("invalid load test rate %d: must be positive", Config.LoadTestRate)
+	case Config.LoadTestDuration <= 0:
+		return fmt.Errorf("invalid load test duration %s: must be positive", Config.LoadTestDuration)
+	}
+
+	return nil
 }
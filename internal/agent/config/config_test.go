@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -110,3 +111,38 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"a": "file:8080", "r": 5, "p": 7, "sign-nonce": false, "metrics-exclude": "GC*"}`), 0600))
+
+	os.Clearenv()
+	t.Setenv("POLL_INTERVAL", "9")
+
+	loadFile := func(args ...string) (Settings, error) {
+		var s Settings
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		registerFlags(fs, &s)
+		require.NoError(t, fs.Parse(append([]string{"-config", path}, args...)))
+
+		return s, load(fs, &s)
+	}
+
+	s, err := loadFile("-r", "3")
+	require.NoError(t, err)
+
+	assert.Equal(t, "file:8080", s.Address)
+	assert.Equal(t, 3, s.ReportInterval, "flags take precedence over the file")
+	assert.Equal(t, 9, s.PollInterval, "environment variables take precedence over the file")
+	assert.False(t, s.SignNonce)
+	assert.Equal(t, "GC*", s.MetricsExclude)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"report-interval": 5}`), 0600))
+	_, err = loadFile()
+	require.ErrorContains(t, err, `unknown setting "report-interval"`)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"p": "often"}`), 0600))
+	_, err = loadFile()
+	require.ErrorContains(t, err, "p: ")
+}
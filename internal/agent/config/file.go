@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// applyFile задаёт настройки из JSON-файла. Ключи - имена флагов, значения - как в командной строке,
+// числа и true/false можно писать без кавычек. Флаги, заданные в командной строке, файл не меняет.
+func applyFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var values map[string]json.RawMessage
+	if err = json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("config file %s: unknown setting %q", path, name)
+		}
+
+		if explicit[name] {
+			continue
+		}
+
+		var value string
+		if err = json.Unmarshal(values[name], &value); err != nil {
+			value = string(values[name])
+		}
+
+		if err = fs.Set(name, value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import "github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+
+func LoggerOptions() logger.Options {
+	// Значения проверены при разборе конфига
+	levels, _ := logger.ParseLevels(Config.LogLevels)
+	sink, _ := logger.ParseSink(Config.LogSink)
+	encoding, _ := logger.ParseEncoding(Config.LogEncoding)
+
+	return logger.Options{
+		Levels: levels,
+		File:   Config.LogFile,
+		Rotation: logger.Rotation{
+			MaxSize:    Config.LogMaxSize,
+			MaxBackups: Config.LogMaxBackups,
+			MaxAge:     Config.LogMaxAge,
+			Compress:   Config.LogCompress,
+		},
+		Encoding:        encoding,
+		TimeFormat:      Config.LogTimeFormat,
+		Sink:            sink,
+		SyslogAddress:   Config.LogSyslogAddress,
+		Tag:             Config.LogTag,
+		AsyncBuffer:     Config.LogAsyncBuffer,
+		ErrorTrackerDSN: Config.ErrorTrackerDSN,
+		DedupWindow:     Config.LogDedupWindow,
+	}
+}
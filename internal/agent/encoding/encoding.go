@@ -0,0 +1,50 @@
+// Package encoding кодирует пакет метрик для тела запроса /updates в выбранном формате.
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+const (
+	JSON     = "json"
+	Protobuf = "protobuf"
+	MsgPack  = "msgpack"
+)
+
+// Valid сообщает, умеет ли агент кодировать метрики в формате name.
+func Valid(name string) bool {
+	switch name {
+	case JSON, Protobuf, MsgPack:
+		return true
+	default:
+		return false
+	}
+}
+
+// Encode возвращает тело запроса и его Content-Type. Подпись считается по этим же байтам.
+func Encode(name string, list []metrics.Metric) ([]byte, string, error) {
+	switch name {
+	case "", JSON:
+		body, err := json.Marshal(list)
+		return body, "application/json", err
+	case Protobuf:
+		encoded := make([]metricspb.Metric, 0, len(list))
+		for _, metric := range list {
+			encoded = append(encoded, metricspb.Metric{ID: metric.ID, Type: string(metric.MType), Delta: metric.Delta, Value: metric.Value})
+		}
+
+		return metricspb.MarshalList(encoded), metricspb.ContentType, nil
+	case MsgPack:
+		var body []byte
+		err := codec.NewEncoderBytes(&body, new(codec.MsgpackHandle)).Encode(list)
+		return body, "application/msgpack", err
+	default:
+		return nil, "", fmt.Errorf("unknown encoding %q", name)
+	}
+}
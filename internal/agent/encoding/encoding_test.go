@@ -0,0 +1,61 @@
+package encoding
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+func TestEncode(t *testing.T) {
+	delta := int64(3)
+	list := []metrics.Metric{
+		metrics.NewMetric("Alloc", metrics.GaugeType, 0, 1.5),
+		{ID: "PollCount", MType: metrics.CounterType, Delta: &delta},
+	}
+
+	decoders := map[string]struct {
+		contentType string
+		decode      func(body []byte) ([]metrics.Metric, error)
+	}{
+		JSON: {"application/json", func(body []byte) (decoded []metrics.Metric, err error) {
+			err = json.Unmarshal(body, &decoded)
+			return
+		}},
+		MsgPack: {"application/msgpack", func(body []byte) (decoded []metrics.Metric, err error) {
+			err = codec.NewDecoderBytes(body, new(codec.MsgpackHandle)).Decode(&decoded)
+			return
+		}},
+		Protobuf: {metricspb.ContentType, func(body []byte) ([]metrics.Metric, error) {
+			list, err := metricspb.UnmarshalList(body)
+			decoded := make([]metrics.Metric, 0, len(list))
+			for _, metric := range list {
+				decoded = append(decoded, metrics.Metric{ID: metric.ID, MType: metrics.MetricType(metric.Type), Delta: metric.Delta, Value: metric.Value})
+			}
+			return decoded, err
+		}},
+	}
+
+	for name, decoder := range decoders {
+		t.Run(name, func(t *testing.T) {
+			require.True(t, Valid(name))
+
+			body, contentType, err := Encode(name, list)
+			require.NoError(t, err)
+			assert.Equal(t, decoder.contentType, contentType)
+
+			decoded, err := decoder.decode(body)
+			require.NoError(t, err)
+			assert.Equal(t, list, decoded)
+		})
+	}
+
+	assert.False(t, Valid("xml"))
+	_, _, err := Encode("xml", list)
+	assert.Error(t, err)
+}
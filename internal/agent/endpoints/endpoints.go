@@ -0,0 +1,124 @@
+// Package endpoints выбирает, на какой из нескольких серверов отправлять метрики, и временно
+// исключает серверы, которые не отвечают.
+package endpoints
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Порядок обхода серверов.
+const (
+	Ordered    = "ordered"     // всегда начинать с первого здорового сервера в списке
+	RoundRobin = "round-robin" // начинать каждый раз со следующего сервера
+)
+
+type (
+	Pool struct {
+		mode     string
+		cooldown time.Duration
+
+		mx        sync.Mutex
+		endpoints []*endpoint
+		next      int
+	}
+
+	endpoint struct {
+		url       string
+		downUntil time.Time
+	}
+)
+
+// Parse разбирает список адресов через запятую. Адрес без схемы считается host:port по http,
+// адрес unix:/path - unix-сокетом.
+func Parse(addresses string) ([]string, error) {
+	var urls []string
+	for _, address := range strings.Split(addresses, ",") {
+		address = strings.TrimSuffix(strings.TrimSpace(address), "/")
+		if address == "" {
+			continue
+		}
+
+		if strings.HasPrefix(address, unixPrefix) {
+			if address == unixPrefix {
+				return nil, fmt.Errorf("empty unix socket path")
+			}
+		} else if !strings.Contains(address, "://") {
+			address = "http://" + address
+		}
+		urls = append(urls, address)
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no server addresses")
+	}
+
+	return urls, nil
+}
+
+func ValidMode(mode string) bool {
+	return mode == Ordered || mode == RoundRobin
+}
+
+func New(urls []string, mode string, cooldown time.Duration) *Pool {
+	p := &Pool{mode: mode, cooldown: cooldown}
+	for _, url := range urls {
+		p.endpoints = append(p.endpoints, &endpoint{url: url})
+	}
+
+	return p
+}
+
+// Order возвращает серверы в порядке, в котором их стоит пробовать: сначала здоровые, затем
+// исключённые, чтобы при недоступности всех серверов данные всё равно были куда отправить.
+func (p *Pool) Order(now time.Time) []string {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if len(p.endpoints) == 0 {
+		return nil
+	}
+
+	start := 0
+	if p.mode == RoundRobin {
+		start = p.next
+		p.next = (p.next + 1) % len(p.endpoints)
+	}
+
+	healthy := make([]string, 0, len(p.endpoints))
+	var down []string
+
+	for i := range p.endpoints {
+		e := p.endpoints[(start+i)%len(p.endpoints)]
+		if now.Before(e.downUntil) {
+			down = append(down, e.url)
+		} else {
+			healthy = append(healthy, e.url)
+		}
+	}
+
+	return append(healthy, down...)
+}
+
+// Failed исключает сервер на время cooldown.
+func (p *Pool) Failed(url string, now time.Time) {
+	p.set(url, now.Add(p.cooldown))
+}
+
+// Succeeded возвращает сервер в число здоровых.
+func (p *Pool) Succeeded(url string) {
+	p.set(url, time.Time{})
+}
+
+func (p *Pool) set(url string, downUntil time.Time) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for _, e := range p.endpoints {
+		if e.url == url {
+			e.downUntil = downUntil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package endpoints
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	urls, err := Parse("localhost:8080, https://metrics.example.com/ ,,10.0.0.1:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://localhost:8080", "https://metrics.example.com", "http://10.0.0.1:8080"}, urls)
+
+	_, err = Parse(" , ")
+	require.Error(t, err)
+
+	urls, err = Parse("unix:/run/metrics.sock,localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unix:/run/metrics.sock", "http://localhost:8080"}, urls)
+	assert.True(t, HasSockets(urls))
+	assert.NotEqual(t, HTTPURL("unix:/run/a.sock"), HTTPURL("unix:/run/b.sock"))
+	assert.Equal(t, "http://localhost:8080", HTTPURL(urls[1]))
+
+	_, err = Parse("unix:")
+	require.Error(t, err)
+}
+
+func TestPoolOrdered(t *testing.T) {
+	now := time.Unix(0, 0)
+	p := New([]string{"a", "b", "c"}, Ordered, time.Minute)
+
+	assert.Equal(t, []string{"a", "b", "c"}, p.Order(now))
+
+	p.Failed("a", now)
+	assert.Equal(t, []string{"b", "c", "a"}, p.Order(now))
+
+	// После cooldown сервер снова первый
+	assert.Equal(t, []string{"a", "b", "c"}, p.Order(now.Add(time.Minute)))
+
+	p.Failed("a", now)
+	p.Succeeded("a")
+	assert.Equal(t, []string{"a", "b", "c"}, p.Order(now))
+
+	// Если недоступны все, их всё равно пробуют по порядку
+	for _, url := range []string{"a", "b", "c"} {
+		p.Failed(url, now)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, p.Order(now))
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	now := time.Unix(0, 0)
+	p := New([]string{"a", "b", "c"}, RoundRobin, time.Minute)
+
+	assert.Equal(t, []string{"a", "b", "c"}, p.Order(now))
+	assert.Equal(t, []string{"b", "c", "a"}, p.Order(now))
+
+	p.Failed("a", now)
+	assert.Equal(t, []string{"c", "b", "a"}, p.Order(now))
+	assert.Equal(t, []string{"b", "c", "a"}, p.Order(now))
+}
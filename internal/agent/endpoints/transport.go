@@ -0,0 +1,61 @@
+package endpoints
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// schemeTransport отправляет запросы по http и https через разные транспорты.
+type schemeTransport struct {
+	plain   http.RoundTripper
+	secured http.RoundTripper
+}
+
+// Transport возвращает транспорт для серверов из urls: с unix-сокетами он соединяется вместо TCP,
+// а с h2c по http сразу говорит на HTTP/2 без TLS. По https HTTP/2 выбирается при установке соединения.
+func Transport(urls []string, h2c bool) http.RoundTripper {
+	sockets := make(map[string]string)
+	for _, url := range urls {
+		if path, ok := SocketPath(url); ok {
+			sockets[socketHost(path)+":80"] = path
+		}
+	}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var dialer net.Dialer
+		if path, ok := sockets[addr]; ok {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dial
+
+	if !h2c {
+		return transport
+	}
+
+	return &schemeTransport{
+		plain: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		},
+		secured: transport,
+	}
+}
+
+func (t *schemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.plain.RoundTrip(req)
+	}
+
+	return t.secured.RoundTrip(req)
+}
@@ -0,0 +1,44 @@
+package endpoints
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// unixPrefix - адрес вида unix:/path/to.sock означает unix-сокет сервера на том же хосте.
+const unixPrefix = "unix:"
+
+// SocketPath возвращает путь к сокету, если адрес сервера - unix-сокет.
+func SocketPath(url string) (string, bool) {
+	return strings.CutPrefix(url, unixPrefix)
+}
+
+// HTTPURL возвращает URL, по которому отправлять запросы серверу. Для unix-сокета хост условный:
+// у каждого сокета свой, чтобы транспорт не смешивал их соединения.
+func HTTPURL(url string) string {
+	path, ok := SocketPath(url)
+	if !ok {
+		return url
+	}
+
+	return "http://" + socketHost(path)
+}
+
+// HasSockets сообщает, что среди адресов есть unix-сокеты.
+func HasSockets(urls []string) bool {
+	for _, url := range urls {
+		if _, ok := SocketPath(url); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func socketHost(path string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(path))
+
+	return fmt.Sprintf("unix-%08x", h.Sum32())
+}
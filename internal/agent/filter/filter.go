@@ -0,0 +1,88 @@
+// Package filter отбирает метрики, которые агент отправляет на сервер, по спискам имён и шаблонов.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+// Filter пропускает метрику, если она подходит под include (пустой include пропускает все)
+// и не подходит под exclude. Шаблоны - как в path.Match: *, ? и [...].
+type Filter struct {
+	include []string
+	exclude []string
+}
+
+// Parse разбирает списки имён и шаблонов через запятую.
+func Parse(include, exclude string) (*Filter, error) {
+	f := &Filter{}
+
+	var err error
+	if f.include, err = parsePatterns(include); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	if f.exclude, err = parsePatterns(exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	return f, nil
+}
+
+func parsePatterns(list string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// Empty сообщает, что фильтр пропускает все метрики.
+func (f *Filter) Empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+func (f *Filter) Allowed(name string) bool {
+	if len(f.include) > 0 && !match(f.include, name) {
+		return false
+	}
+
+	return !match(f.exclude, name)
+}
+
+// Apply возвращает только пропущенные метрики. Исходный срез не меняется.
+func (f *Filter) Apply(list []metrics.Metric) []metrics.Metric {
+	if f.Empty() {
+		return list
+	}
+
+	allowed := make([]metrics.Metric, 0, len(list))
+	for _, metric := range list {
+		if f.Allowed(metric.ID) {
+			allowed = append(allowed, metric)
+		}
+	}
+
+	return allowed
+}
+
+func match(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
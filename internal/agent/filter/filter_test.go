@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+func TestFilter(t *testing.T) {
+	f, err := Parse("Heap*, PollCount ,RandomValue", "HeapReleased")
+	require.NoError(t, err)
+
+	assert.True(t, f.Allowed("HeapAlloc"))
+	assert.True(t, f.Allowed("PollCount"))
+	assert.False(t, f.Allowed("HeapReleased"), "exclude wins over include")
+	assert.False(t, f.Allowed("Mallocs"))
+
+	list := []metrics.Metric{
+		metrics.NewMetric("HeapAlloc", metrics.GaugeType, 0, 1),
+		metrics.NewMetric("HeapReleased", metrics.GaugeType, 0, 2),
+		metrics.NewMetric("Mallocs", metrics.GaugeType, 0, 3),
+		metrics.NewMetric("PollCount", metrics.CounterType, 4, 0),
+	}
+
+	var names []string
+	for _, metric := range f.Apply(list) {
+		names = append(names, metric.ID)
+	}
+	assert.Equal(t, []string{"HeapAlloc", "PollCount"}, names)
+	assert.Len(t, list, 4)
+}
+
+func TestFilterExcludeOnly(t *testing.T) {
+	f, err := Parse("", "GC*,Lookups")
+	require.NoError(t, err)
+
+	assert.False(t, f.Empty())
+	assert.True(t, f.Allowed("Alloc"))
+	assert.False(t, f.Allowed("GCSys"))
+	assert.False(t, f.Allowed("Lookups"))
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(" , ", "")
+	require.NoError(t, err)
+	assert.True(t, f.Empty())
+
+	_, err = Parse("Heap[", "")
+	require.ErrorContains(t, err, "include: invalid pattern")
+
+	_, err = Parse("", "[a-")
+	require.ErrorContains(t, err, "exclude: invalid pattern")
+}
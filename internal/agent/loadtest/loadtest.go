@@ -0,0 +1,264 @@
+// Package loadtest генерирует синтетическую нагрузку на сервер метрик и собирает статистику отправки.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	metricsupdater "github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics_updater"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+type (
+	Options struct {
+		Metrics  int           // число уникальных метрик
+		Batch    int           // метрик в одном запросе
+		Rate     int           // запросов в секунду
+		Duration time.Duration // длительность теста
+		Workers  int           // число одновременных запросов
+	}
+
+	Sender interface {
+		Send(id string, batch []metrics.Metric) error
+	}
+
+	Report struct {
+		Sent      int
+		Succeeded int
+		Failed    int
+		// Skipped запросы, которые не отправлены, потому что все воркеры были заняты
+		Skipped int
+		Errors  map[string]int
+
+		Elapsed   time.Duration
+		Latencies []time.Duration
+	}
+
+	result struct {
+		latency time.Duration
+		err     error
+	}
+)
+
+// Run отправляет пакеты синтетических метрик с заданной частотой, пока не истечёт Duration или не отменится ctx.
+func Run(ctx context.Context, sender Sender, opts Options, log logger.Logger) (Report, error) {
+	if err := opts.validate(); err != nil {
+		return Report{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	gen := newGenerator(opts.Metrics)
+
+	jobs := make(chan []metrics.Metric)
+	results := make(chan result, opts.Workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(sender, jobs, results)
+		}()
+	}
+
+	report := Report{Errors: make(map[string]int)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for res := range results {
+			report.add(res)
+		}
+	}()
+
+	log.Infof("Load test started: %d metrics, %d per request, %d requests/s for %s with %d workers.",
+		opts.Metrics, opts.Batch, opts.Rate, opts.Duration, opts.Workers)
+
+	started := time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			select {
+			case jobs <- gen.next(opts.Batch):
+			default:
+				report.Skipped++
+			}
+		}
+	}
+
+	ticker.Stop()
+	close(jobs)
+	wg.Wait()
+	close(results)
+	<-done
+
+	report.Elapsed = time.Since(started)
+	return report, nil
+}
+
+func worker(sender Sender, jobs <-chan []metrics.Metric, results chan<- result) {
+	for batch := range jobs {
+		started := time.Now()
+		err := sender.Send(requestid.New(), batch)
+
+		results <- result{latency: time.Since(started), err: err}
+	}
+}
+
+func (opts Options) validate() error {
+	switch {
+	case opts.Metrics <= 0:
+		return fmt.Errorf("invalid load test metrics %d: must be positive", opts.Metrics)
+	case opts.Batch <= 0:
+		return fmt.Errorf("invalid load test batch %d: must be positive", opts.Batch)
+	case opts.Rate <= 0:
+		return fmt.Errorf("invalid load test rate %d: must be positive", opts.Rate)
+	case opts.Duration <= 0:
+		return fmt.Errorf("invalid load test duration %s: must be positive", opts.Duration)
+	case opts.Workers <= 0:
+		return fmt.Errorf("invalid load test workers %d: must be positive", opts.Workers)
+	}
+
+	return nil
+}
+
+func (r *Report) add(res result) {
+	r.Sent++
+	r.Latencies = append(r.Latencies, res.latency)
+
+	if res.err == nil {
+		r.Succeeded++
+		return
+	}
+
+	r.Failed++
+	r.Errors[errorKind(res.err)]++
+}
+
+// errorKind группирует ошибки, чтобы в отчёте не было отдельной строки на каждый порт или адрес.
+func errorKind(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, metricsupdater.ErrorInvalidStatusCode):
+		return err.Error()
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &netErr):
+		return "connection error"
+	default:
+		return "other error"
+	}
+}
+
+// Percentile возвращает задержку по методу ближайшего ранга, p от 0 до 100.
+func (r Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration(nil), r.Latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(sorted) {
+		rank = len(sorted)
+	}
+
+	return sorted[rank-1]
+}
+
+func (r Report) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	var sum time.Duration
+	for _, latency := range r.Latencies {
+		sum += latency
+	}
+
+	return sum / time.Duration(len(r.Latencies))
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+
+	rate := 0.0
+	if r.Elapsed > 0 {
+		rate = float64(r.Sent) / r.Elapsed.Seconds()
+	}
+
+	fmt.Fprintf(&b, "requests: %d sent, %d succeeded, %d failed, %d skipped in %s (%.1f requests/s)\n",
+		r.Sent, r.Succeeded, r.Failed, r.Skipped, r.Elapsed.Round(time.Millisecond), rate)
+	fmt.Fprintf(&b, "latency: mean %s, p50 %s, p95 %s, p99 %s, max %s",
+		r.Mean(), r.Percentile(50), r.Percentile(95), r.Percentile(99), r.Percentile(100))
+
+	kinds := make([]string, 0, len(r.Errors))
+	for kind := range r.Errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "\nerror %q: %d", kind, r.Errors[kind])
+	}
+
+	return b.String()
+}
+
+// generator по кругу выдаёт пакеты из фиксированного набора уникальных метрик:
+// чётные метрики gauge со случайным значением, нечётные counter с дельтой 1.
+type generator struct {
+	metrics int
+	offset  int
+	rnd     *rand.Rand
+}
+
+func newGenerator(count int) *generator {
+	return &generator{
+		metrics: count,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (g *generator) next(size int) []metrics.Metric {
+	if size > g.metrics {
+		size = g.metrics
+	}
+
+	batch := make([]metrics.Metric, 0, size)
+	for i := 0; i < size; i++ {
+		n := (g.offset + i) % g.metrics
+		id := fmt.Sprintf("LoadTest%d", n)
+
+		if n%2 == 0 {
+			value := g.rnd.Float64() * 1000
+			batch = append(batch, metrics.Metric{ID: id, MType: metrics.GaugeType, Value: &value})
+		} else {
+			delta := int64(1)
+			batch = append(batch, metrics.Metric{ID: id, MType: metrics.CounterType, Delta: &delta})
+		}
+	}
+	g.offset = (g.offset + size) % g.metrics
+
+	return batch
+}
@@ -0,0 +1,91 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	metricsupdater "github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics_updater"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type recordingSender struct {
+	mx    sync.Mutex
+	ids   map[string]struct{}
+	calls int
+}
+
+func (s *recordingSender) Send(_ string, batch []metrics.Metric) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.calls++
+	for _, metric := range batch {
+		s.ids[metric.ID] = struct{}{}
+	}
+
+	if s.calls%2 == 0 {
+		return fmt.Errorf("%w: %d", metricsupdater.ErrorInvalidStatusCode, 503)
+	}
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	sender := &recordingSender{ids: make(map[string]struct{})}
+
+	report, err := Run(context.Background(), sender, Options{
+		Metrics:  10,
+		Batch:    4,
+		Rate:     200,
+		Duration: 200 * time.Millisecond,
+		Workers:  2,
+	}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	require.Greater(t, report.Sent, 3)
+	assert.Equal(t, sender.calls, report.Sent)
+	assert.Equal(t, report.Sent, report.Succeeded+report.Failed)
+	assert.Equal(t, report.Failed, report.Errors["invalid status code: 503"])
+	assert.Len(t, sender.ids, 10)
+	assert.Contains(t, report.String(), "requests:")
+}
+
+func TestRunInvalidOptions(t *testing.T) {
+	_, err := Run(context.Background(), &recordingSender{}, Options{Metrics: 10}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
+
+func TestReportPercentile(t *testing.T) {
+	report := Report{}
+	for i := 10; i >= 1; i-- {
+		report.Latencies = append(report.Latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 5*time.Millisecond, report.Percentile(50))
+	assert.Equal(t, 10*time.Millisecond, report.Percentile(95))
+	assert.Equal(t, 10*time.Millisecond, report.Percentile(100))
+	assert.Equal(t, 1*time.Millisecond, report.Percentile(0))
+	assert.Equal(t, 5500*time.Microsecond, report.Mean())
+}
+
+func TestGenerator(t *testing.T) {
+	gen := newGenerator(3)
+
+	batch := gen.next(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, "LoadTest0", batch[0].ID)
+	assert.Equal(t, metrics.GaugeType, batch[0].MType)
+	assert.Equal(t, metrics.CounterType, batch[1].MType)
+
+	batch = gen.next(5)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "LoadTest2", batch[0].ID)
+	assert.Equal(t, "LoadTest0", batch[1].ID)
+}
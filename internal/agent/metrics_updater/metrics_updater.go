@@ -1,27 +1,43 @@
 package metricsupdater
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/backpressure"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/encoding"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/endpoints"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/schedule"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
 )
 
+// AgentIDHeader - заголовок, по которому сервер определяет, от какого агента пришли метрики.
+const AgentIDHeader = "X-Agent-ID"
+
 var (
 	retries = []int{1, 3, 5}
 
 	ErrorNotNeedHash       = errors.New("not need hash")
 	ErrorInvalidStatusCode = errors.New("invalid status code")
+	ErrorNoEndpoints       = errors.New("no server addresses")
 )
 
 type (
@@ -29,6 +45,11 @@ type (
 		client *resty.Client
 		col    collector
 		log    logger.Logger
+		clock  clock.Clock
+		pool   *endpoints.Pool
+		bp     *backpressure.Controller
+		sched  *schedule.Schedule
+		out    io.Writer // куда -dry-run печатает запросы
 	}
 
 	collector interface {
@@ -37,76 +58,284 @@ type (
 )
 
 func New(client *resty.Client, col collector, log logger.Logger) *Updater {
+	// Адреса и расписание проверены при загрузке настроек
+	urls, _ := endpoints.Parse(config.Config.Address)
+	sched, _ := schedule.Parse(config.Config.ReportWindows, config.Config.QuietHours)
+
+	if endpoints.HasSockets(urls) || config.Config.H2C {
+		client.SetTransport(endpoints.Transport(urls, config.Config.H2C))
+	}
+
 	return &Updater{
 		client: client,
 		col:    col,
 		log:    log,
+		clock:  clock.Real(),
+		pool:   endpoints.New(urls, config.Config.FailoverMode, config.Config.EndpointCooldown),
+		bp:     backpressure.New(float64(config.Config.MaxSlowdown), config.Config.BatchSize),
+		sched:  sched,
+		out:    os.Stdout,
 	}
 }
 
+// Run отправляет метрики раз в ReportInterval до отмены ctx. Пока сервер перегружен, интервал растёт,
+// а вне окон отправки и в тихие часы отправка пропускается.
+func (u Updater) Run(ctx context.Context) {
+	base := time.Second * time.Duration(config.Config.ReportInterval)
+
+	for {
+		if err := clock.Sleep(ctx, u.clock, u.bp.Interval(u.clock.Now(), base)); err != nil {
+			return
+		}
+
+		if !u.sched.Active(u.clock.Now()) {
+			u.log.Debugf("Reporting is paused by the schedule.")
+			continue
+		}
+
+		u.UpdateMetrics()
+	}
+}
+
+// UpdateMetrics отправляет текущие метрики пакетами не больше BATCH_SIZE метрик и MAX_BATCH_BYTES байт.
+// Если сервер перегружен, оставшиеся пакеты не отправляются: свежие значения уйдут в следующий раз.
 func (u Updater) UpdateMetrics() {
 	currentMetrics := u.col.GetMetrics()
-	if err := u.updateMetrics(currentMetrics); err != nil {
-		u.log.Errorf("Failed to update collectors: %s (%T)", err, err)
+
+	for len(currentMetrics) > 0 {
+		batch := currentMetrics
+		if size := u.bp.BatchSize(); size > 0 && size < len(batch) {
+			batch = batch[:size]
+		}
+		if maxBytes := config.Config.MaxBatchBytes; maxBytes > 0 {
+			batch = batch[:fitBytes(batch, maxBytes)]
+		}
+		currentMetrics = currentMetrics[len(batch):]
+
+		// Идентификатор пакета уходит в заголовке, по нему пакет можно найти в логах сервера
+		id := requestid.New()
+		if err := u.updateMetrics(id, batch); err != nil {
+			u.log.With("request_id", id).Errorf("Failed to update collectors: %s (%T)", err, err)
+			return
+		}
+	}
+}
+
+// fitBytes возвращает, сколько первых метрик помещается в тело запроса размером maxBytes.
+// Одна метрика отправляется всегда, даже если сама не помещается в лимит. Размер считается по JSON:
+// в protobuf и MessagePack тело только короче, так что лимит соблюдается при любом формате.
+func fitBytes(metricsForRequest []metrics.Metric, maxBytes int) int {
+	size := len("[]")
+	for i, metric := range metricsForRequest {
+		encoded, err := json.Marshal(metric)
+		if err != nil {
+			continue // такую метрику не закодировать, ошибку вернёт отправка
+		}
+
+		if i > 0 {
+			size++ // запятая между элементами
+		}
+
+		if size += len(encoded); size > maxBytes && i > 0 {
+			return i
+		}
 	}
+
+	return len(metricsForRequest)
 }
 
-func (u Updater) updateMetrics(metricForUpdate []metrics.Metric) error {
-	url := fmt.Sprintf("http://%s/updates", config.Config.Address)
+// Send отправляет пакет метрик без пауз между попытками: каждому серверу достаётся одна попытка.
+func (u Updater) Send(id string, metricForUpdate []metrics.Metric) error {
+	req, err := u.compileRequest(metricForUpdate)
+	if err != nil {
+		return err
+	}
+	req.SetHeader(requestid.Header, id)
+
+	_, err = u.post(req, metricForUpdate)
+	return err
+}
 
+func (u Updater) updateMetrics(id string, metricForUpdate []metrics.Metric) error {
 	req, err := u.compileRequest(metricForUpdate)
 	if err != nil {
 		return err
 	}
+	req.SetHeader(requestid.Header, id)
 
-	for _, timeSleep := range retries {
-		resp, err := req.Post(url)
-		if err != nil {
-			u.log.Errorf("Failed to send collectors to server: %s. Retrying after %ds...", err, timeSleep)
-			time.Sleep(time.Duration(timeSleep) * time.Second)
+	if config.Config.DryRun {
+		return u.print(req, metricForUpdate)
+	}
 
-			continue
+	for i, timeSleep := range retries {
+		// Подпись с nonce одноразовая, поэтому повтор подписываем заново
+		if i > 0 {
+			if err = u.sign(req); err != nil {
+				return err
+			}
 		}
 
-		if resp.StatusCode() != http.StatusOK {
-			return ErrorInvalidStatusCode
-		} else {
-			return nil
+		var retryable bool
+		if retryable, err = u.post(req, metricForUpdate); !retryable {
+			return err
 		}
+
+		pause := time.Duration(timeSleep) * time.Second
+		if retryAfter := u.bp.Pause(u.clock.Now()); retryAfter > pause {
+			pause = retryAfter
+		}
+
+		u.log.With("request_id", id).Errorf("Failed to send collectors to server: %s. Retrying after %s...", err, pause)
+		_ = clock.Sleep(context.Background(), u.clock, pause)
+	}
+
+	return err
+}
+
+// print выводит запрос вместо отправки: адрес, заголовки с подписью и тело в читаемом виде.
+func (u Updater) print(req *resty.Request, metricForUpdate []metrics.Metric) error {
+	body, err := json.MarshalIndent(metricForUpdate, "", "  ")
+	if err != nil {
+		return fmt.Errorf("dry run: %w", err)
+	}
+
+	header := u.client.Header.Clone()
+	for key, values := range req.Header {
+		header[key] = values
 	}
+	if req.Token != "" {
+		header.Set("Authorization", "Bearer <redacted>")
+	}
+
+	urls, _ := endpoints.Parse(config.Config.Address)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "POST %s/updates\n", urls[0])
+	_ = header.Write(&buf)
+	fmt.Fprintf(&buf, "\n%s\n\n", body)
 
+	_, err = u.out.Write(buf.Bytes())
 	return err
 }
 
+// post пробует отправить запрос на серверы по очереди, пока один не примет его. Сервер, который не ответил
+// или ответил 5xx, исключается на ENDPOINT_COOLDOWN. retryable сообщает, что не ответил ни один сервер
+// и отправку стоит повторить позже; ответ 4xx повторять бессмысленно.
+func (u Updater) post(req *resty.Request, metricForUpdate []metrics.Metric) (retryable bool, err error) {
+	urls := u.pool.Order(u.clock.Now())
+	if len(urls) == 0 {
+		return false, ErrorNoEndpoints
+	}
+
+	for i, url := range urls {
+		if i > 0 {
+			if err = u.sign(req); err != nil {
+				return false, err
+			}
+		}
+
+		var resp *resty.Response
+		if resp, err = req.Post(endpoints.HTTPURL(url) + "/updates"); err != nil {
+			u.pool.Failed(url, u.clock.Now())
+			continue
+		}
+
+		switch code := resp.StatusCode(); {
+		case code == http.StatusOK:
+			u.pool.Succeeded(url)
+			u.bp.Succeeded()
+			return false, nil
+		case backpressure.Overload(code):
+			now := u.clock.Now()
+			u.pool.Failed(url, now)
+			u.bp.Overloaded(now, backpressure.RetryAfter(resp.Header().Get("Retry-After"), now), len(metricForUpdate))
+			err = fmt.Errorf("%w: %d from %s, the server is overloaded", ErrorInvalidStatusCode, code, url)
+		case code >= http.StatusInternalServerError:
+			u.pool.Failed(url, u.clock.Now())
+			err = fmt.Errorf("%w: %d from %s", ErrorInvalidStatusCode, code, url)
+		default:
+			return false, fmt.Errorf("%w: %d", ErrorInvalidStatusCode, code)
+		}
+
+		if len(urls) > 1 {
+			u.log.Errorf("Server %s is unavailable, trying the next one: %s", url, err)
+		}
+	}
+
+	return true, err
+}
+
 func (u Updater) compileRequest(metricsForRequest []metrics.Metric) (*resty.Request, error) {
+	body, contentType, err := encoding.Encode(config.Config.Encoding, metricsForRequest)
+	if err != nil {
+		return nil, fmt.Errorf("encode metrics: %w", err)
+	}
+
 	req := u.client.R().
-		SetBody(metricsForRequest)
+		SetHeader("Content-Type", contentType).
+		SetBody(body)
 
-	hash, err := u.hashMetrics(metricsForRequest)
-	if err != nil {
-		if !errors.Is(err, ErrorNotNeedHash) {
-			return nil, err
-		}
-	} else {
-		req.SetHeader("HashSHA256", hash)
+	if agentID := config.Config.AgentID; agentID != "" {
+		req.SetHeader(AgentIDHeader, agentID)
+	}
+
+	if apiKey := config.Config.APIKey; apiKey != "" {
+		req.SetAuthToken(apiKey)
+	}
+
+	if err = u.sign(req); err != nil {
+		return nil, err
 	}
 
 	return req, nil
 }
 
-func (u Updater) hashMetrics(metricsForHash []metrics.Metric) (string, error) {
+// sign добавляет к запросу подпись. С SIGN_NONCE подписываются ещё время отправки и случайный nonce,
+// чтобы перехваченный запрос нельзя было повторить.
+func (u Updater) sign(req *resty.Request) error {
+	var timestamp, nonce string
+	if config.Config.SignNonce {
+		nonceBytes := make([]byte, 16)
+		if _, err := rand.Read(nonceBytes); err != nil {
+			return fmt.Errorf("sign: %w", err)
+		}
+
+		timestamp = strconv.FormatInt(u.clock.Now().Unix(), 10)
+		nonce = hex.EncodeToString(nonceBytes)
+	}
+
+	body, _ := req.Body.([]byte) // тело уже закодировал compileRequest
+	hash, err := u.hashBody(body, timestamp, nonce)
+	if errors.Is(err, ErrorNotNeedHash) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	req.SetHeader("HashSHA256", hash)
+	if keyID := config.Config.KeyID; keyID != "" {
+		req.SetHeader("HashKeyID", keyID)
+	}
+
+	if nonce != "" {
+		req.SetHeader("HashTimestamp", timestamp)
+		req.SetHeader("HashNonce", nonce)
+	}
+
+	return nil
+}
+
+func (u Updater) hashBody(body []byte, timestamp, nonce string) (string, error) {
 	secureKey := config.Config.Key
 	if secureKey == "" {
 		return "", ErrorNotNeedHash
 	}
 
-	bodyBytes, err := json.Marshal(metricsForHash)
-	if err != nil {
-		return "", fmt.Errorf("hashMetrics: %w", err)
-	}
-
 	hash := hmac.New(sha256.New, []byte(secureKey))
-	hash.Write(bodyBytes)
+	if nonce != "" {
+		hash.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	}
+	hash.Write(body)
 
 	hashed := hash.Sum(nil)
 	hexHashed := hex.EncodeToString(hashed)
@@ -2,23 +2,41 @@ package metricsupdater
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
 )
 
-func handlerServer(w http.ResponseWriter, _ *http.Request) {
+func handlerServer(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get(requestid.Header) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -67,11 +85,11 @@ func TestUpdater_updateMetric(t *testing.T) {
 	)
 
 	client := resty.New()
-	updater := New(client, nil, log.Sugar())
+	updater := New(client, nil, logger.Wrap(log.Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.NoError(t, updater.updateMetrics(tt.metrics))
+			assert.NoError(t, updater.updateMetrics(requestid.New(), tt.metrics))
 
 			if tt.wantedErr {
 				require.Contains(t, buf.String(), "Invalid metric type:")
@@ -81,3 +99,265 @@ func TestUpdater_updateMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdater_retries(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close()) // Порт гарантированно никто не слушает
+
+	previous := config.Config.Address
+	config.Config.Address = address
+	defer func() { config.Config.Address = previous }()
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	updater := New(resty.New(), nil, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.clock = clk
+
+	done := make(chan error)
+	go func() {
+		done <- updater.updateMetrics(requestid.New(), []metrics.Metric{metrics.NewMetric("PollCount", metrics.CounterType, 1, 0)})
+	}()
+
+	for _, delay := range retries {
+		clk.BlockUntil(1)
+		clk.Advance(time.Duration(delay) * time.Second)
+	}
+
+	require.Error(t, <-done)
+	assert.Equal(t, time.Unix(9, 0), clk.Now())
+}
+
+func TestUpdater_failover(t *testing.T) {
+	var down, up int
+	downServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		down++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer downServer.Close()
+
+	upServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		up++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upServer.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = downServer.URL + "," + upServer.URL
+	config.Config.FailoverMode = "ordered"
+	config.Config.EndpointCooldown = time.Minute
+
+	updater := New(resty.New(), nil, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	batch := []metrics.Metric{metrics.NewMetric("PollCount", metrics.CounterType, 1, 0)}
+
+	require.NoError(t, updater.updateMetrics(requestid.New(), batch))
+	assert.Equal(t, 1, down)
+	assert.Equal(t, 1, up)
+
+	// Недоступный сервер пропускается до конца cooldown
+	require.NoError(t, updater.Send(requestid.New(), batch))
+	assert.Equal(t, 1, down)
+	assert.Equal(t, 2, up)
+}
+
+type staticCollector []metrics.Metric
+
+func (c staticCollector) GetMetrics() []metrics.Metric {
+	return c
+}
+
+func TestUpdater_backpressure(t *testing.T) {
+	var batches []int
+	overloaded := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if overloaded {
+			overloaded = false
+			w.Header().Set("Retry-After", "20")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		var batch []metrics.Metric
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		batches = append(batches, len(batch))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = server.URL
+	config.Config.MaxSlowdown = 4
+
+	col := make(staticCollector, 0, 30)
+	for i := 0; i < 30; i++ {
+		col = append(col, metrics.NewMetric(fmt.Sprintf("Gauge%d", i), metrics.GaugeType, 0, float64(i)))
+	}
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	updater := New(resty.New(), col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.clock = clk
+
+	done := make(chan struct{})
+	go func() {
+		updater.UpdateMetrics()
+		close(done)
+	}()
+
+	// Повтор ждёт Retry-After, а не обычную паузу в секунду
+	clk.BlockUntil(1)
+	clk.Advance(19 * time.Second)
+	assert.Equal(t, 1, clk.Waiters())
+	clk.Advance(time.Second)
+	<-done
+
+	// Первый пакет отправлен целиком после паузы, а размер следующих уже уменьшен
+	assert.Equal(t, []int{30}, batches)
+	assert.Equal(t, 19, updater.bp.BatchSize())
+
+	batches = nil
+	updater.UpdateMetrics()
+	assert.Equal(t, []int{19, 11}, batches)
+}
+
+func TestUpdater_maxBatchBytes(t *testing.T) {
+	var bodies []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, len(body))
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = server.URL
+	config.Config.MaxBatchBytes = 1024
+
+	col := make(staticCollector, 0, 100)
+	for i := 0; i < 100; i++ {
+		col = append(col, metrics.NewMetric(fmt.Sprintf("Gauge%d", i), metrics.GaugeType, 0, float64(i)))
+	}
+
+	updater := New(resty.New(), col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.UpdateMetrics()
+
+	require.Greater(t, len(bodies), 1)
+	for _, size := range bodies {
+		assert.LessOrEqual(t, size, 1024)
+	}
+
+	// Метрика больше лимита всё равно отправляется, но отдельным запросом
+	assert.Equal(t, 1, fitBytes(col, 10))
+	assert.Equal(t, len(col), fitBytes(col, 1<<20))
+}
+
+func TestUpdater_protobuf(t *testing.T) {
+	type request struct {
+		contentType string
+		hash        string
+		body        []byte
+	}
+	received := make(chan request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		received <- request{contentType: r.Header.Get("Content-Type"), hash: r.Header.Get("HashSHA256"), body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = server.URL
+	config.Config.Encoding = "protobuf"
+	config.Config.Key = "secret"
+	config.Config.SignNonce = false
+
+	col := staticCollector{metrics.NewMetric("Alloc", metrics.GaugeType, 0, 1.5)}
+	updater := New(resty.New(), col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.UpdateMetrics()
+
+	req := <-received
+	assert.Equal(t, metricspb.ContentType, req.contentType)
+
+	decoded, err := metricspb.UnmarshalList(req.body)
+	require.NoError(t, err)
+	require.Len(t, decoded, 1)
+	assert.Equal(t, "Alloc", decoded[0].ID)
+	assert.Equal(t, 1.5, *decoded[0].Value)
+
+	// Подпись считается по байтам protobuf, которые дошли до сервера
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(req.body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), req.hash)
+}
+
+func TestUpdater_dryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the dry run must not send requests")
+	}))
+	defer server.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = server.URL
+	config.Config.Key = "secret"
+	config.Config.APIKey = "api-key"
+	config.Config.DryRun = true
+
+	col := staticCollector{metrics.NewMetric("Alloc", metrics.GaugeType, 0, 1.5)}
+
+	var out bytes.Buffer
+	updater := New(resty.New(), col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.out = &out
+	updater.UpdateMetrics()
+
+	printed := out.String()
+	assert.Contains(t, printed, "POST "+server.URL+"/updates\n")
+	assert.Contains(t, printed, "Hashsha256: ")
+	assert.Contains(t, printed, "Authorization: Bearer <redacted>")
+	assert.NotContains(t, printed, "api-key")
+	assert.Contains(t, printed, "[\n  {\n    \"id\": \"Alloc\",")
+}
+
+func TestUpdater_schedule(t *testing.T) {
+	sent := make(chan time.Time, 10)
+
+	clk := clock.NewFake(time.Date(2023, time.October, 16, 8, 59, 50, 0, time.Local)) // понедельник
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent <- clk.Now()
+	}))
+	defer server.Close()
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Address = server.URL
+	config.Config.ReportInterval = 5
+	config.Config.ReportWindows = "Mon 09:00-10:00"
+
+	updater := New(resty.New(), staticCollector{metrics.NewMetric("Alloc", metrics.GaugeType, 0, 1)}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	updater.clock = clk
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go updater.Run(ctx)
+
+	// В 08:59:55 отправка пропускается, в 09:00:00 уже разрешена
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Second)
+	clk.BlockUntil(1)
+	assert.Empty(t, sent)
+
+	clk.Advance(5 * time.Second)
+	assert.Equal(t, 9, (<-sent).Hour())
+}
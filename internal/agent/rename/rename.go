@@ -0,0 +1,79 @@
+// Package rename переименовывает метрики перед отправкой, чтобы метрики разных сервисов
+// не смешивались на одном сервере.
+package rename
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+// HostnamePlaceholder в префиксе заменяется на имя хоста.
+const HostnamePlaceholder = "{hostname}"
+
+// Rules сначала переименовывает отдельные метрики, а потом добавляет префикс ко всем.
+type Rules struct {
+	prefix string
+	names  map[string]string
+}
+
+// Parse разбирает префикс и правила вида Old=New через запятую.
+func Parse(prefix, renames string) (*Rules, error) {
+	if strings.Contains(prefix, HostnamePlaceholder) {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("prefix: %w", err)
+		}
+		prefix = strings.ReplaceAll(prefix, HostnamePlaceholder, hostname)
+	}
+
+	rules := &Rules{prefix: prefix, names: make(map[string]string)}
+	for _, rule := range strings.Split(renames, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+
+		from, to, ok := strings.Cut(rule, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename rule %q: expected Old=New", rule)
+		}
+
+		if _, ok = rules.names[from]; ok {
+			return nil, fmt.Errorf("metric %q is renamed twice", from)
+		}
+		rules.names[from] = to
+	}
+
+	return rules, nil
+}
+
+// Empty сообщает, что правила не меняют ни одного имени.
+func (r *Rules) Empty() bool {
+	return r.prefix == "" && len(r.names) == 0
+}
+
+func (r *Rules) Name(name string) string {
+	if renamed, ok := r.names[name]; ok {
+		name = renamed
+	}
+
+	return r.prefix + name
+}
+
+// Apply возвращает метрики с новыми именами. Исходный срез не меняется.
+func (r *Rules) Apply(list []metrics.Metric) []metrics.Metric {
+	if r.Empty() {
+		return list
+	}
+
+	renamed := make([]metrics.Metric, len(list))
+	for i, metric := range list {
+		metric.ID = r.Name(metric.ID)
+		renamed[i] = metric
+	}
+
+	return renamed
+}
@@ -0,0 +1,50 @@
+package rename
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+)
+
+func TestRules(t *testing.T) {
+	rules, err := Parse("billing.", "HeapAlloc=heap_alloc, PollCount = polls")
+	require.NoError(t, err)
+
+	assert.Equal(t, "billing.heap_alloc", rules.Name("HeapAlloc"))
+	assert.Equal(t, "billing.polls", rules.Name("PollCount"))
+	assert.Equal(t, "billing.Mallocs", rules.Name("Mallocs"))
+
+	list := []metrics.Metric{
+		metrics.NewMetric("HeapAlloc", metrics.GaugeType, 0, 1),
+		metrics.NewMetric("Mallocs", metrics.GaugeType, 0, 2),
+	}
+
+	renamed := rules.Apply(list)
+	assert.Equal(t, "billing.heap_alloc", renamed[0].ID)
+	assert.Equal(t, "billing.Mallocs", renamed[1].ID)
+	assert.Equal(t, "HeapAlloc", list[0].ID)
+}
+
+func TestHostnamePrefix(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	rules, err := Parse("{hostname}.", "")
+	require.NoError(t, err)
+	assert.Equal(t, hostname+".Alloc", rules.Name("Alloc"))
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse("", " , ")
+	require.NoError(t, err)
+	assert.True(t, rules.Empty())
+
+	for _, renames := range []string{"HeapAlloc", "=heap", "HeapAlloc=", "A=b,A=c"} {
+		_, err = Parse("", renames)
+		assert.Error(t, err, renames)
+	}
+}
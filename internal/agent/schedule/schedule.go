@@ -0,0 +1,181 @@
+// Package schedule решает, можно ли агенту отправлять метрики в данный момент: по окнам отправки
+// и тихим часам вида "Mon-Fri 09:00-18:00".
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type (
+	// Schedule разрешает отправку внутри окон allow (пустой список разрешает всегда)
+	// и запрещает внутри окон deny. Время - местное время агента.
+	Schedule struct {
+		allow []window
+		deny  []window
+	}
+
+	// window - промежуток времени в выбранные дни недели. Если конец не позже начала,
+	// окно переходит через полночь и заканчивается на следующий день.
+	window struct {
+		days       [7]bool
+		start, end time.Duration // от начала суток
+	}
+)
+
+// Parse разбирает списки окон через точку с запятой. Окно - "[дни] ЧЧ:ММ-ЧЧ:ММ", где дни -
+// "Mon-Fri", "Sat,Sun" и т. п.; без дней окно действует каждый день.
+func Parse(allow, deny string) (*Schedule, error) {
+	s := &Schedule{}
+
+	var err error
+	if s.allow, err = parseWindows(allow); err != nil {
+		return nil, err
+	}
+
+	if s.deny, err = parseWindows(deny); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func parseWindows(list string) ([]window, error) {
+	var windows []window
+	for _, value := range strings.Split(list, ";") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		w, err := parseWindow(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", value, err)
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
+
+func parseWindow(value string) (window, error) {
+	var w window
+
+	hours := value
+	if days, rest, ok := strings.Cut(value, " "); ok {
+		if err := w.parseDays(days); err != nil {
+			return w, err
+		}
+		hours = strings.TrimSpace(rest)
+	} else {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return w, fmt.Errorf("expected hours like 09:00-18:00")
+	}
+
+	var err error
+	if w.start, err = parseClock(from); err != nil {
+		return w, err
+	}
+
+	if w.end, err = parseClock(to); err != nil {
+		return w, err
+	}
+
+	return w, nil
+}
+
+func (w *window) parseDays(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, ok := weekdays[strings.ToLower(from)]
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", from)
+		}
+
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(to)]; !ok {
+				return fmt.Errorf("unknown weekday %q", to)
+			}
+		}
+
+		// Диапазон вроде Fri-Mon переходит через воскресенье
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	var hours, minutes int
+	if n, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes); err != nil || n != 2 || len(value) != 5 {
+		return 0, fmt.Errorf("invalid time %q: expected HH:MM", value)
+	}
+
+	if hours > 24 || minutes > 59 || (hours == 24 && minutes > 0) {
+		return 0, fmt.Errorf("invalid time %q: out of range", value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// Empty сообщает, что расписание ничего не ограничивает.
+func (s *Schedule) Empty() bool {
+	return len(s.allow) == 0 && len(s.deny) == 0
+}
+
+// Active сообщает, можно ли отправлять метрики в момент t.
+func (s *Schedule) Active(t time.Time) bool {
+	for _, w := range s.deny {
+		if w.contains(t) {
+			return false
+		}
+	}
+
+	if len(s.allow) == 0 {
+		return true
+	}
+
+	for _, w := range s.allow {
+		if w.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w window) contains(t time.Time) bool {
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	day := t.Weekday()
+
+	if w.start < w.end {
+		return w.days[day] && sinceMidnight >= w.start && sinceMidnight < w.end
+	}
+
+	// Окно через полночь: вечер его дня и утро следующего
+	previous := (day + 6) % 7
+	return (w.days[day] && sinceMidnight >= w.start) || (w.days[previous] && sinceMidnight < w.end)
+}
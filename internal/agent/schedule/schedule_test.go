@@ -0,0 +1,60 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 16 октября 2023 года - понедельник.
+func at(day int, hour, minute int) time.Time {
+	return time.Date(2023, time.October, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestSchedule(t *testing.T) {
+	s, err := Parse("Mon-Fri 09:00-18:00; Sat 10:00-12:00", "Wed 12:00-13:00")
+	require.NoError(t, err)
+
+	assert.True(t, s.Active(at(16, 9, 0)))
+	assert.False(t, s.Active(at(16, 18, 0)))
+	assert.False(t, s.Active(at(16, 8, 59)))
+	assert.True(t, s.Active(at(18, 11, 59)))
+	assert.False(t, s.Active(at(18, 12, 30)), "quiet hours win")
+	assert.True(t, s.Active(at(21, 10, 30)))
+	assert.False(t, s.Active(at(22, 10, 30)))
+}
+
+func TestOvernightWindow(t *testing.T) {
+	s, err := Parse("", "fri,sat 22:00-06:00")
+	require.NoError(t, err)
+
+	assert.True(t, s.Active(at(20, 21, 59)))
+	assert.False(t, s.Active(at(20, 23, 0)))
+	assert.False(t, s.Active(at(21, 5, 59)), "the window continues on the next day")
+	assert.True(t, s.Active(at(21, 6, 0)))
+	assert.False(t, s.Active(at(22, 1, 0)))
+	assert.True(t, s.Active(at(23, 1, 0)))
+}
+
+func TestWeekdayRangeWraps(t *testing.T) {
+	s, err := Parse("Fri-Mon 00:00-24:00", "")
+	require.NoError(t, err)
+
+	assert.True(t, s.Active(at(22, 12, 0)))
+	assert.True(t, s.Active(at(16, 12, 0)))
+	assert.False(t, s.Active(at(17, 12, 0)))
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(" ; ", "")
+	require.NoError(t, err)
+	assert.True(t, s.Empty())
+	assert.True(t, s.Active(at(16, 3, 0)))
+
+	for _, value := range []string{"09:00", "Mon", "Funday 09:00-10:00", "9:00-10:00", "09:00-25:00", "09:60-10:00"} {
+		_, err = Parse(value, "")
+		assert.Error(t, err, value)
+	}
+}
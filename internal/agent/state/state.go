@@ -0,0 +1,59 @@
+// Package state хранит в небольшом файле то, что агент не должен терять при перезапуске.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type State struct {
+	PollCount int64 `json:"poll_count"`
+}
+
+// Load читает файл состояния. Если файла ещё нет, возвращает пустое состояние.
+func Load(path string) (State, error) {
+	var s State
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return s, fmt.Errorf("state file: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("state file %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Save записывает состояние через временный файл, чтобы при сбое не остался обрезанный файл.
+func Save(path string, s State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("state file: %w", err)
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	return nil
+}
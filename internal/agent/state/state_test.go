@@ -0,0 +1,32 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.state")
+
+	s, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, State{}, s)
+
+	require.NoError(t, Save(path, State{PollCount: 42}))
+
+	s, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), s.PollCount)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the temporary file is renamed")
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0600))
+	_, err = Load(path)
+	require.Error(t, err)
+}
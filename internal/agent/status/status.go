@@ -0,0 +1,60 @@
+// Package status - служебный HTTP listener агента. Он включается только явно, через STATUS_ADDRESS.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+const shutdownTimeout = time.Second * 5
+
+// Handler отдаёт служебные маршруты агента: GET /api/buildinfo, как у сервера.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/buildinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(buildinfo.Get())
+	})
+
+	return mux
+}
+
+// Serve слушает addr и отдаёт Handler, пока не отменён ctx. Ошибка возвращается, только если адрес не удалось занять.
+func Serve(ctx context.Context, addr string, log logger.Logger) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: Handler(), ReadHeaderTimeout: time.Second * 10}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("The status listener has stopped: %s", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Debugf("Status routes are served on: %s", listener.Addr())
+	return listener.Addr(), nil
+}
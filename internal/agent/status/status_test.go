@@ -0,0 +1,61 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestServe(t *testing.T) {
+	previous := buildinfo.Get()
+	buildinfo.Set(buildinfo.New("v1.2.3", "2023-10-15", "abcdef"))
+	defer buildinfo.Set(previous)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addr, err := Serve(ctx, "127.0.0.1:0", logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + addr.String() + "/api/buildinfo")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	var info buildinfo.Info
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, buildinfo.New("v1.2.3", "2023-10-15", "abcdef"), info)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantedStatus int
+	}{
+		{name: "Build info", method: http.MethodGet, path: "/api/buildinfo", wantedStatus: http.StatusOK},
+		{name: "Wrong method", method: http.MethodPost, path: "/api/buildinfo", wantedStatus: http.StatusMethodNotAllowed},
+		{name: "Unknown route", method: http.MethodGet, path: "/metrics", wantedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.wantedStatus, w.Code)
+		})
+	}
+}
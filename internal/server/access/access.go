@@ -0,0 +1,163 @@
+// Package access пропускает или отклоняет запросы по адресу клиента: для каждой группы маршрутов
+// задаются разрешённые и запрещённые подсети.
+package access
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Группы маршрутов, для которых задаются списки.
+const (
+	Reads  = "reads"
+	Writes = "writes"
+	Admin  = "admin"
+)
+
+type (
+	// Rule - списки одной группы в файле. Адрес без маски означает одну машину.
+	Rule struct {
+		Allow []string `json:"allow,omitempty"`
+		Deny  []string `json:"deny,omitempty"`
+	}
+
+	// Lists - разобранные списки всех групп.
+	Lists struct {
+		groups map[string]rule
+	}
+
+	rule struct {
+		allow []*net.IPNet
+		deny  []*net.IPNet
+	}
+
+	// Guard держит списки из файла и перечитывает их по Reload, не прерывая обработку запросов.
+	Guard struct {
+		path  string
+		lists atomic.Pointer[Lists]
+	}
+)
+
+// Open читает списки из JSON-файла вида {"writes": {"allow": ["10.0.0.0/8"], "deny": ["10.0.0.5"]}}.
+func Open(path string) (*Guard, error) {
+	g := &Guard{path: path}
+	if err := g.Reload(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// Reload перечитывает файл. Если файл не разобрался, продолжают действовать прежние списки.
+func (g *Guard) Reload() error {
+	data, err := os.ReadFile(g.path)
+	if err != nil {
+		return err
+	}
+
+	lists, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("access list %s: %w", g.path, err)
+	}
+
+	g.lists.Store(lists)
+	return nil
+}
+
+func (g *Guard) Allowed(group string, ip net.IP) bool {
+	return g.lists.Load().Allowed(group, ip)
+}
+
+func Parse(data []byte) (*Lists, error) {
+	var rules map[string]Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	lists := &Lists{groups: make(map[string]rule, len(rules))}
+	for group, r := range rules {
+		switch group {
+		case Reads, Writes, Admin:
+		default:
+			return nil, fmt.Errorf("unknown route group %q: expected %s, %s or %s", group, Reads, Writes, Admin)
+		}
+
+		allow, err := parseNets(r.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+
+		deny, err := parseNets(r.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group, err)
+		}
+
+		lists.groups[group] = rule{allow: allow, deny: deny}
+	}
+
+	return lists, nil
+}
+
+// Allowed сообщает, пускать ли адрес к группе. Запрет важнее разрешения; если разрешённых подсетей
+// нет, пускают всех, кого не запретили. Группы без списков открыты для всех.
+func (l *Lists) Allowed(group string, ip net.IP) bool {
+	r, ok := l.groups[group]
+	if !ok {
+		return true
+	}
+
+	if ip == nil {
+		return false
+	}
+
+	if contains(r.deny, ip) {
+		return false
+	}
+
+	return len(r.allow) == 0 || contains(r.allow, ip)
+}
+
+func parseNets(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", value)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", value, err)
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package access
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListsAllowed(t *testing.T) {
+	lists, err := Parse([]byte(`{
+		"writes": {"allow": ["10.0.0.0/8", "192.168.1.10", "fd00::/8"], "deny": ["10.0.0.5"]},
+		"admin": {"allow": ["127.0.0.1"]},
+		"reads": {"deny": ["203.0.113.0/24"]}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		group  string
+		ip     string
+		wanted bool
+	}{
+		{name: "Allowed subnet", group: Writes, ip: "10.1.2.3", wanted: true},
+		{name: "Allowed address", group: Writes, ip: "192.168.1.10", wanted: true},
+		{name: "Allowed IPv6 subnet", group: Writes, ip: "fd00::1", wanted: true},
+		{name: "Deny wins over allow", group: Writes, ip: "10.0.0.5", wanted: false},
+		{name: "Outside allowlist", group: Writes, ip: "192.168.1.11", wanted: false},
+		{name: "Admin from localhost", group: Admin, ip: "127.0.0.1", wanted: true},
+		{name: "Admin from elsewhere", group: Admin, ip: "10.1.2.3", wanted: false},
+		{name: "Reads without allowlist", group: Reads, ip: "198.51.100.1", wanted: true},
+		{name: "Reads from denied subnet", group: Reads, ip: "203.0.113.7", wanted: false},
+		{name: "Unknown address", group: Writes, ip: "", wanted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wanted, lists.Allowed(tt.group, net.ParseIP(tt.ip)))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Invalid json", data: `{`},
+		{name: "Unknown group", data: `{"updates": {"allow": ["10.0.0.0/8"]}}`},
+		{name: "Invalid subnet", data: `{"writes": {"allow": ["10.0.0.0/33"]}}`},
+		{name: "Invalid address", data: `{"admin": {"deny": ["localhost"]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestGuardReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"writes": {"allow": ["10.0.0.0/8"]}}`), 0600))
+
+	guard, err := Open(path)
+	require.NoError(t, err)
+	assert.False(t, guard.Allowed(Writes, net.ParseIP("192.168.0.1")))
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"writes": {"allow": ["192.168.0.0/16"]}}`), 0600))
+	require.NoError(t, guard.Reload())
+	assert.True(t, guard.Allowed(Writes, net.ParseIP("192.168.0.1")))
+
+	// Сломанный файл не сбрасывает действующие списки
+	require.NoError(t, os.WriteFile(path, []byte(`{"writes": {"allow": ["oops"]}}`), 0600))
+	require.Error(t, guard.Reload())
+	assert.True(t, guard.Allowed(Writes, net.ParseIP("192.168.0.1")))
+	assert.False(t, guard.Allowed(Writes, net.ParseIP("10.0.0.1")))
+}
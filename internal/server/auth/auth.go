@@ -0,0 +1,140 @@
+// Package auth проверяет ключи API и их роли: read читает метрики, write ещё и пишет, admin ещё и управляет сервером.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+// Роли ключей, каждая следующая включает предыдущие.
+const (
+	RoleRead  = "read"
+	RoleWrite = "write"
+	RoleAdmin = "admin"
+)
+
+// keyPrefix помогает узнать ключ в логах и сканерах секретов.
+const keyPrefix = "mk_"
+
+var (
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrInvalidRole = errors.New("invalid role")
+
+	roleLevels = map[string]int{RoleRead: 1, RoleWrite: 2, RoleAdmin: 3}
+)
+
+type (
+	// KeyStore хранит ключи. Его реализуют FileStore и хранилище метрик в базе данных.
+	KeyStore interface {
+		CreateAPIKey(key models.APIKey) error
+		ListAPIKeys() ([]models.APIKey, error)
+		// FindAPIKey ищет ключ по хешу, включая отозванные. Если ключа нет, возвращает errs.ErrAPIKeyNotFound.
+		FindAPIKey(hash string) (*models.APIKey, error)
+		// RevokeAPIKey отзывает ключ. Если ключа нет, возвращает errs.ErrAPIKeyNotFound.
+		RevokeAPIKey(id string) error
+	}
+
+	Keys struct {
+		store KeyStore
+		clock clock.Clock
+	}
+)
+
+func New(store KeyStore, clk clock.Clock) *Keys {
+	return &Keys{store: store, clock: clock.Or(clk)}
+}
+
+// Allows сообщает, хватает ли роли role для действия, которому нужна роль required.
+func Allows(role, required string) bool {
+	level, ok := roleLevels[role]
+	return ok && level >= roleLevels[required]
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// Create выпускает ключ. Сам ключ возвращается только здесь, сохраняется лишь его хеш.
+func (k *Keys) Create(name, role string) (models.APIKey, string, error) {
+	if !ValidRole(role) {
+		return models.APIKey{}, "", fmt.Errorf("%w %q: expected read, write or admin", ErrInvalidRole, role)
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	token := keyPrefix + secret
+	key := models.APIKey{
+		ID:        id,
+		Name:      name,
+		Role:      role,
+		Hash:      Hash(token),
+		CreatedAt: k.clock.Now().UTC(),
+	}
+
+	if err = k.store.CreateAPIKey(key); err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	return key, token, nil
+}
+
+func (k *Keys) List() ([]models.APIKey, error) {
+	return k.store.ListAPIKeys()
+}
+
+func (k *Keys) Revoke(id string) error {
+	return k.store.RevokeAPIKey(id)
+}
+
+// Authenticate возвращает действующий ключ по его значению или ErrInvalidKey.
+func (k *Keys) Authenticate(token string) (*models.APIKey, error) {
+	if !strings.HasPrefix(token, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := k.store.FindAPIKey(Hash(token))
+	if errors.Is(err, errs.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidKey
+	} else if err != nil {
+		return nil, err
+	}
+
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// Hash возвращает хеш ключа для хранения. Ключи случайные и длинные, поэтому соль не нужна.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return encode(buf), nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+func TestKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	clk := clock.NewFake(time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC))
+
+	store, err := OpenFile(path, clk)
+	require.NoError(t, err)
+	keys := New(store, clk)
+
+	_, _, err = keys.Create("agent", "owner")
+	require.ErrorIs(t, err, ErrInvalidRole)
+
+	created, token, err := keys.Create("agent", RoleWrite)
+	require.NoError(t, err)
+	assert.Equal(t, RoleWrite, created.Role)
+	assert.NotContains(t, created.Hash, token)
+
+	key, err := keys.Authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+
+	_, err = keys.Authenticate(token + "x")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = keys.Authenticate("")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	// Ключи переживают перезапуск, а отзыв действует сразу
+	store, err = OpenFile(path, clk)
+	require.NoError(t, err)
+	keys = New(store, clk)
+
+	_, err = keys.Authenticate(token)
+	require.NoError(t, err)
+
+	require.NoError(t, keys.Revoke(created.ID))
+	require.ErrorIs(t, keys.Revoke("missing"), errs.ErrAPIKeyNotFound)
+
+	_, err = keys.Authenticate(token)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	list, err := keys.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.NotNil(t, list[0].RevokedAt)
+	assert.Equal(t, clk.Now(), *list[0].RevokedAt)
+}
+
+func TestAllows(t *testing.T) {
+	assert.True(t, Allows(RoleAdmin, RoleWrite))
+	assert.True(t, Allows(RoleWrite, RoleRead))
+	assert.True(t, Allows(RoleRead, RoleRead))
+	assert.False(t, Allows(RoleRead, RoleWrite))
+	assert.False(t, Allows(RoleWrite, RoleAdmin))
+	assert.False(t, Allows("", RoleRead))
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+type (
+	// FileStore держит ключи в памяти и переписывает JSON-файл целиком при каждом изменении.
+	FileStore struct {
+		path  string
+		clock clock.Clock
+
+		mx   sync.RWMutex
+		keys map[string]fileKey
+	}
+
+	// fileKey - ключ в файле: в отличие от ответов API, в файле нужен хеш.
+	fileKey struct {
+		models.APIKey
+		Hash string `json:"hash"`
+	}
+)
+
+// OpenFile читает ключи из файла. Если файла нет, он будет создан при выпуске первого ключа.
+func OpenFile(path string, clk clock.Clock) (*FileStore, error) {
+	s := &FileStore{path: path, clock: clock.Or(clk), keys: make(map[string]fileKey)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var keys []fileKey
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &keys); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		key.APIKey.Hash = key.Hash
+		s.keys[key.ID] = key
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) CreateAPIKey(key models.APIKey) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.keys[key.ID] = fileKey{APIKey: key, Hash: key.Hash}
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) ListAPIKeys() ([]models.APIKey, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key.APIKey)
+	}
+	sortKeys(keys)
+
+	return keys, nil
+}
+
+func (s *FileStore) FindAPIKey(hash string) (*models.APIKey, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			found := key.APIKey
+			return &found, nil
+		}
+	}
+
+	return nil, errs.ErrAPIKeyNotFound
+}
+
+func (s *FileStore) RevokeAPIKey(id string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return errs.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	now := s.clock.Now().UTC()
+	key.RevokedAt = &now
+	s.keys[id] = key
+
+	if err := s.save(); err != nil {
+		key.RevokedAt = nil
+		s.keys[id] = key
+		return err
+	}
+
+	return nil
+}
+
+// save записывает ключи во временный файл и переименовывает его, чтобы не оставить файл недописанным.
+func (s *FileStore) save() error {
+	keys := make([]fileKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+
+	body, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if err = tmp.Chmod(0600); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	if _, err = tmp.Write(body); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	return nil
+}
+
+func sortKeys(keys []models.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// APIKeysDatabase - значение API_KEYS, при котором ключи хранятся в базе данных хранилища метрик.
+const APIKeysDatabase = "database"
+
+// APIKeysFile возвращает путь к файлу ключей API или false, если ключи хранятся не в файле.
+func APIKeysFile() (string, bool) {
+	return strings.CutPrefix(Config.APIKeys, "file://")
+}
+
+func validateAPIKeys() error {
+	if Config.APIKeys == "" || Config.APIKeys == APIKeysDatabase {
+		return nil
+	}
+
+	if path, ok := APIKeysFile(); !ok || path == "" {
+		return fmt.Errorf("invalid api keys %q: expected database or file:///path", Config.APIKeys)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+)
+
+func StorageBreakerEnabled() bool {
+	return Config.StorageBreakerThreshold > 0
+}
+
+func StorageBreakerSettings() breaker.Settings {
+	return breaker.Settings{
+		Threshold:   Config.StorageBreakerThreshold,
+		Window:      Config.StorageBreakerWindow,
+		MinRequests: Config.StorageBreakerMinRequests,
+		OpenTimeout: Config.StorageBreakerOpenTimeout,
+	}
+}
+
+func validateStorageBreaker() error {
+	if Config.StorageBreakerThreshold < 0 || Config.StorageBreakerThreshold > 1 {
+		return fmt.Errorf("invalid storage breaker threshold %v: must be between 0 and 1", Config.StorageBreakerThreshold)
+	}
+
+	if Config.StorageBreakerWindow < 0 {
+		return fmt.Errorf("invalid storage breaker window %d: must not be negative", Config.StorageBreakerWindow)
+	}
+
+	if Config.StorageBreakerMinRequests < 0 {
+		return fmt.Errorf("invalid storage breaker min requests %d: must not be negative", Config.StorageBreakerMinRequests)
+	}
+
+	if Config.StorageBreakerOpenTimeout < 0 {
+		return fmt.Errorf("invalid storage breaker open timeout %s: must not be negative", Config.StorageBreakerOpenTimeout)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"compress/gzip"
+	"fmt"
+	"strings"
+)
+
+// defaultCompressTypes - ответы сервера, которые имеет смысл сжимать: JSON, страница со списком метрик и текстовые значения.
+var defaultCompressTypes = []string{"application/json", "text/html", "text/plain"}
+
+func parseCompressTypes(value string) error {
+	Config.CompressTypes = nil
+	for _, contentType := range strings.Split(value, ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			Config.CompressTypes = append(Config.CompressTypes, strings.ToLower(contentType))
+		}
+	}
+
+	return nil
+}
+
+// CompressLevel возвращает уровень сжатия gzip. Без сжатия middleware не нужен, поэтому 0 означает самый быстрый уровень.
+func CompressLevel() int {
+	if Config.CompressLevel == 0 {
+		return gzip.BestSpeed
+	}
+
+	return Config.CompressLevel
+}
+
+func validateCompress() error {
+	if Config.CompressLevel < 0 || Config.CompressLevel > gzip.BestCompression {
+		return fmt.Errorf("invalid compress level %d: expected 1 (fastest) to 9 (smallest)", Config.CompressLevel)
+	}
+
+	if Config.CompressMinSize < 0 {
+		return fmt.Errorf("invalid compress min size %d: must not be negative", Config.CompressMinSize)
+	}
+
+	for _, contentType := range Config.CompressTypes {
+		if !strings.Contains(contentType, "/") {
+			return fmt.Errorf("invalid compressed content type %q: expected type/subtype or type/*", contentType)
+		}
+	}
+
+	return nil
+}
@@ -1,31 +1,489 @@
 package config
 
 import (
+	"compress/gzip"
+	"context"
 	"flag"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v6"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/retry"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/secrets"
 )
 
-var Config struct {
+// Settings - настройки сервера. Процесс использует одни настройки на всех: они лежат в Config.
+type Settings struct {
 	Address         string `env:"ADDRESS"`
 	StoreInterval   int64  `env:"STORE_INTERVAL"`
 	FileStoragePath string `env:"FILE_STORAGE_PATH"`
 	Restore         bool   `env:"RESTORE"`
-	DatabaseDSN     string `env:"DATABASE_DSN"`
-	Key             string `env:"KEY"`
+	DatabaseDSN     string `env:"DATABASE_DSN" secret:"dsn"`
+	StorageDSN      string `env:"STORAGE_DSN" secret:"dsn"`
+	Key             string `env:"KEY" secret:"true"`
+	SigningKeys     string `env:"SIGNING_KEYS" secret:"true"`
+	AdminKey        string `env:"ADMIN_KEY" secret:"true"`
+	APIKeys         string `env:"API_KEYS"`
+	ReadOnly        bool   `env:"READ_ONLY"`
+	AccessListFile  string `env:"ACCESS_LIST_FILE"`
+	LegacyUpdates   bool   `env:"LEGACY_UPDATES"`
+
+	AdminAddress   string `env:"ADMIN_ADDRESS"`
+	UnixSocketMode string `env:"UNIX_SOCKET_MODE"`
+	H2C            bool   `env:"H2C"`
+	HTTP3          bool   `env:"HTTP3"`
+
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT"`
+	HandlerTimeout    time.Duration `env:"HANDLER_TIMEOUT"`
+	StreamReadTimeout time.Duration `env:"STREAM_READ_TIMEOUT"`
+
+	MaxConnections int           `env:"MAX_CONNECTIONS"`
+	KeepAlive      bool          `env:"KEEP_ALIVE"`
+	TCPKeepAlive   time.Duration `env:"TCP_KEEP_ALIVE"`
+
+	TrustedProxies       []string `env:"TRUSTED_PROXIES" envSeparator:","`
+	ClientIPHeader       string   `env:"CLIENT_IP_HEADER"`
+	ForwardedForPosition string   `env:"FORWARDED_FOR_POSITION"`
+
+	TLSCertFile       string        `env:"TLS_CERT_FILE"`
+	TLSKeyFile        string        `env:"TLS_KEY_FILE"`
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL"`
+
+	ACMEDomains      []string `env:"ACME_DOMAINS" envSeparator:","`
+	ACMEEmail        string   `env:"ACME_EMAIL"`
+	ACMECacheDir     string   `env:"ACME_CACHE_DIR"`
+	ACMEChallenge    string   `env:"ACME_CHALLENGE"`
+	ACMEHTTPAddress  string   `env:"ACME_HTTP_ADDRESS"`
+	ACMEDirectoryURL string   `env:"ACME_DIRECTORY_URL"`
+
+	EncryptionKeys    string `env:"ENCRYPTION_KEYS" secret:"true"`
+	EncryptionKeyFile string `env:"ENCRYPTION_KEY_FILE"`
+	SnapshotFormat    string `env:"SNAPSHOT_FORMAT"`
+
+	ReplayWindow       time.Duration `env:"REPLAY_WINDOW"`
+	ReplayNonceCache   int           `env:"REPLAY_NONCE_CACHE"`
+	RequireReplayNonce bool          `env:"REQUIRE_REPLAY_NONCE"`
+
+	AuthFailureThreshold int           `env:"AUTH_FAILURE_THRESHOLD"`
+	AuthFailureWindow    time.Duration `env:"AUTH_FAILURE_WINDOW"`
+	AuthBlockDuration    time.Duration `env:"AUTH_BLOCK_DURATION"`
+
+	CompressLevel   int      `env:"COMPRESS_LEVEL"`
+	CompressMinSize int      `env:"COMPRESS_MIN_SIZE"`
+	CompressTypes   []string `env:"COMPRESS_TYPES" envSeparator:","`
+
+	SecurityHeaders       bool          `env:"SECURITY_HEADERS"`
+	ContentSecurityPolicy string        `env:"CONTENT_SECURITY_POLICY"`
+	FrameOptions          string        `env:"FRAME_OPTIONS"`
+	HSTSMaxAge            time.Duration `env:"HSTS_MAX_AGE"`
+
+	DatabaseSchema         string        `env:"DATABASE_SCHEMA"`
+	DatabaseTablePrefix    string        `env:"DATABASE_TABLE_PREFIX"`
+	DatabaseQueryTimeout   time.Duration `env:"DATABASE_QUERY_TIMEOUT"`
+	DatabaseTxTimeout      time.Duration `env:"DATABASE_TX_TIMEOUT"`
+	DatabaseWaitTimeout    time.Duration `env:"DATABASE_WAIT_TIMEOUT"`
+	DatabaseHealthInterval time.Duration `env:"DATABASE_HEALTH_INTERVAL"`
+	DatabaseSlowQuery      time.Duration `env:"DATABASE_SLOW_QUERY"`
+	DatabaseNotify         bool          `env:"DATABASE_NOTIFY"`
+	DatabaseIndexes        []string      `env:"DATABASE_INDEXES" envSeparator:","`
+	DatabaseSkipIndexes    bool          `env:"DATABASE_SKIP_INDEXES"`
+
+	DatabaseSSLMode     string `env:"DATABASE_SSLMODE"`
+	DatabaseSSLRootCert string `env:"DATABASE_SSLROOTCERT"`
+	DatabaseSSLCert     string `env:"DATABASE_SSLCERT"`
+	DatabaseSSLKey      string `env:"DATABASE_SSLKEY"`
+
+	CounterCoalesceWindow time.Duration `env:"COUNTER_COALESCE_WINDOW"`
+	ReadCacheTTL          time.Duration `env:"READ_CACHE_TTL"`
+	CounterPolicy         string        `env:"COUNTER_POLICY"`
+	GaugePolicy           string        `env:"GAUGE_POLICY"`
+	GaugeRounding         string        `env:"GAUGE_ROUNDING"`
+	GaugePrecision        int           `env:"GAUGE_PRECISION"`
+	CaseInsensitiveNames  bool          `env:"CASE_INSENSITIVE_NAMES"`
+	RejectTypeConflicts   bool          `env:"REJECT_TYPE_CONFLICTS"`
+
+	StorageRetryAttempts    int           `env:"STORAGE_RETRY_ATTEMPTS"`
+	StorageRetryBackoff     string        `env:"STORAGE_RETRY_BACKOFF"`
+	StorageRetryInterval    time.Duration `env:"STORAGE_RETRY_INTERVAL"`
+	StorageRetryMaxInterval time.Duration `env:"STORAGE_RETRY_MAX_INTERVAL"`
+	StorageRetryMaxElapsed  time.Duration `env:"STORAGE_RETRY_MAX_ELAPSED"`
+
+	StorageBreakerThreshold   float64       `env:"STORAGE_BREAKER_THRESHOLD"`
+	StorageBreakerWindow      int           `env:"STORAGE_BREAKER_WINDOW"`
+	StorageBreakerMinRequests int           `env:"STORAGE_BREAKER_MIN_REQUESTS"`
+	StorageBreakerOpenTimeout time.Duration `env:"STORAGE_BREAKER_OPEN_TIMEOUT"`
+
+	HistoryPartition string        `env:"HISTORY_PARTITION"`
+	HistoryRetention time.Duration `env:"HISTORY_RETENTION"`
+
+	Features []string `env:"FEATURES" envSeparator:","`
+
+	LogLevels string `env:"LOG_LEVELS"`
+
+	DebugBodies    bool `env:"DEBUG_BODIES"`
+	DebugBodyLimit int  `env:"DEBUG_BODY_LIMIT"`
+
+	RecordFile  string  `env:"RECORD_FILE"`
+	ReplaySpeed float64 `env:"REPLAY_SPEED"`
+	DumpFormat  string  `env:"DUMP_FORMAT"`
+
+	StaleCheckInterval time.Duration `env:"STALE_CHECK_INTERVAL"`
+	StaleFactor        float64       `env:"STALE_FACTOR"`
+	StaleWebhookURL    string        `env:"STALE_WEBHOOK_URL" secret:"true"`
+
+	DebugFaults        bool          `env:"DEBUG_FAULTS"`
+	FaultLatency       time.Duration `env:"FAULT_LATENCY"`
+	FaultLatencyJitter time.Duration `env:"FAULT_LATENCY_JITTER"`
+	FaultErrorRate     float64       `env:"FAULT_ERROR_RATE"`
+	FaultFailureRate   float64       `env:"FAULT_FAILURE_RATE"`
+
+	LogFile       string        `env:"LOG_FILE"`
+	LogMaxSize    int           `env:"LOG_MAX_SIZE"`
+	LogMaxBackups int           `env:"LOG_MAX_BACKUPS"`
+	LogMaxAge     time.Duration `env:"LOG_MAX_AGE"`
+	LogCompress   bool          `env:"LOG_COMPRESS"`
+
+	LogSink          string `env:"LOG_SINK"`
+	LogSyslogAddress string `env:"LOG_SYSLOG_ADDRESS"`
+	LogTag           string `env:"LOG_TAG"`
+
+	LogEncoding   string `env:"LOG_ENCODING"`
+	LogTimeFormat string `env:"LOG_TIME_FORMAT"`
+
+	LogDedupWindow time.Duration `env:"LOG_DEDUP_WINDOW"`
+	LogAsyncBuffer int           `env:"LOG_ASYNC_BUFFER"`
+
+	ErrorTrackerDSN string `env:"ERROR_TRACKER_DSN" secret:"true"`
 }
 
+var Config Settings
+
 func Load() {
-	flag.StringVar(&Config.Address, "a", "localhost:8080", "server address")
-	flag.Int64Var(&Config.StoreInterval, "i", 0, "store interval in seconds")
-	flag.StringVar(&Config.FileStoragePath, "f", "tmp/metrics-db.json", "json file mem_storage path")
-	flag.BoolVar(&Config.Restore, "r", true, "whether to load old values from a file")
-	flag.StringVar(&Config.DatabaseDSN, "d", "", "postgresql dsn")
-	flag.StringVar(&Config.Key, "k", "", "key for hash")
+	registerFlags(flag.CommandLine)
+}
+
+func registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Config.Address, "a", "localhost:8080", "server address as host:port or unix:/path/to.sock")
+	fs.StringVar(&Config.AdminAddress, "admin-address", "", "separate address like localhost:9090 for /ping, /readyz, /metrics, /debug, /api/buildinfo and /admin (empty serves them on the server address)")
+	fs.BoolVar(&Config.H2C, "h2c", false, "accept HTTP/2 without TLS (h2c); over TLS HTTP/2 is always available")
+	fs.BoolVar(&Config.HTTP3, "http3", false, "with TLS also serve HTTP/3 (QUIC) on the UDP port of the server address and advertise it with Alt-Svc (experimental)")
+	fs.DurationVar(&Config.ReadTimeout, "read-timeout", time.Second*30, "maximum time to read a whole request including the body (0 means no limit)")
+	fs.DurationVar(&Config.ReadHeaderTimeout, "read-header-timeout", time.Second*10, "maximum time to read request headers, protects from slowloris clients (0 uses read-timeout)")
+	fs.DurationVar(&Config.WriteTimeout, "write-timeout", time.Minute, "maximum time from the end of the request headers to the end of the response (0 means no limit)")
+	fs.DurationVar(&Config.IdleTimeout, "idle-timeout", time.Minute*2, "how long an idle keep-alive connection is kept open (0 uses read-timeout)")
+	fs.DurationVar(&Config.HandlerTimeout, "handler-timeout", 0, "maximum time of request handling after which 503 is returned (0 disables it)")
+	fs.DurationVar(&Config.StreamReadTimeout, "stream-read-timeout", time.Second*5, "maximum wait for the next part of a streamed /updates/ batch while its transaction is open (0 disables it)")
+	fs.IntVar(&Config.MaxConnections, "max-connections", 0, "maximum simultaneous connections to the server address, including idle keep-alive ones; others wait in the backlog (0 means no limit)")
+	fs.BoolVar(&Config.KeepAlive, "keep-alive", true, "reuse connections for several requests (HTTP keep-alive); false closes a connection after every response")
+	fs.DurationVar(&Config.TCPKeepAlive, "tcp-keep-alive", time.Second*15, "interval of TCP keep-alive probes that detect dead peers (0 disables them)")
+	fs.Func("trusted-proxies", "comma-separated addresses and subnets of proxies whose client ip header is trusted (empty uses the connection address)", parseTrustedProxies)
+	fs.StringVar(&Config.ClientIPHeader, "client-ip-header", ForwardedForHeader, "header with the client address set by trusted proxies: X-Forwarded-For or X-Real-IP")
+	fs.StringVar(&Config.ForwardedForPosition, "forwarded-for-position", ForwardedForRightmost, "which X-Forwarded-For address is the client: rightmost not trusted one or leftmost")
+	fs.StringVar(&Config.UnixSocketMode, "unix-socket-mode", defaultSocketMode, "octal permissions of the unix socket file when the address is unix:/path")
+	fs.Int64Var(&Config.StoreInterval, "i", 0, "store interval in seconds")
+	fs.StringVar(&Config.FileStoragePath, "f", "tmp/metrics-db.json", "json file mem_storage path")
+	fs.BoolVar(&Config.Restore, "r", true, "whether to load old values from a file")
+	fs.StringVar(&Config.DatabaseDSN, "d", "", "postgresql dsn")
+	fs.StringVar(&Config.StorageDSN, "storage-dsn", "", "storage dsn like postgres://..., file://path or mem:// (takes precedence over -d and -f)")
+	fs.StringVar(&Config.Key, "k", "", "key for hash")
+	fs.StringVar(&Config.TLSCertFile, "tls-cert-file", "", "PEM certificate chain for serving HTTPS (empty serves plain HTTP)")
+	fs.StringVar(&Config.TLSKeyFile, "tls-key-file", "", "PEM private key of the tls certificate")
+	fs.DurationVar(&Config.TLSReloadInterval, "tls-reload-interval", time.Second*30, "how often the tls files are checked for changes and reloaded without a restart (0 reloads only on SIGHUP)")
+	fs.Func("acme-domains", "comma-separated domains to obtain certificates for via ACME, e.g. Let's Encrypt (empty disables ACME)", parseACMEDomains)
+	fs.StringVar(&Config.ACMEEmail, "acme-email", "", "contact email of the ACME account for expiry notices")
+	fs.StringVar(&Config.ACMECacheDir, "acme-cache-dir", "tmp/acme", "directory where ACME certificates and the account key are kept between restarts")
+	fs.StringVar(&Config.ACMEChallenge, "acme-challenge", "tls-alpn-01", "ACME challenge: tls-alpn-01 (the server must be reachable on port 443) or http-01 (acme-http-address must be reachable on port 80)")
+	fs.StringVar(&Config.ACMEHTTPAddress, "acme-http-address", ":80", "address of the plain HTTP listener for http-01 challenges; other requests are redirected to HTTPS")
+	fs.StringVar(&Config.ACMEDirectoryURL, "acme-directory-url", "", "ACME directory URL, e.g. the Let's Encrypt staging one (empty uses Let's Encrypt production)")
+	fs.StringVar(&Config.EncryptionKeys, "encryption-keys", "", "AES keys for file snapshots as id:base64 pairs separated by commas; the first encrypts, all decrypt (empty stores snapshots in plain text)")
+	fs.StringVar(&Config.SnapshotFormat, "snapshot-format", SnapshotFormatJSON, "serialization of file snapshots: json, gob or protobuf; the format of an existing snapshot is detected on restore")
+	fs.StringVar(&Config.EncryptionKeyFile, "encryption-key-file", "", "file with id:base64 encryption keys, one per line, used instead of -encryption-keys")
+	fs.StringVar(&Config.SigningKeys, "signing-keys", "", "additional hash keys as id:key pairs separated by commas; agents pick one with the HashKeyID header")
+	fs.DurationVar(&Config.ReplayWindow, "replay-window", time.Minute*5, "allowed clock skew of the HashTimestamp of signed requests; nonces are remembered for twice as long")
+	fs.IntVar(&Config.ReplayNonceCache, "replay-nonce-cache", 100000, "maximum number of remembered nonces of signed requests; when it is full, new ones are rejected with 503 until old ones expire (0 means no limit)")
+	fs.BoolVar(&Config.RequireReplayNonce, "require-replay-nonce", false, "reject update requests without a signature, HashTimestamp and HashNonce when signing keys are set")
+	fs.IntVar(&Config.AuthFailureThreshold, "auth-failure-threshold", 10, "failed signature, API key or admin key checks from one address after which it is blocked (0 disables blocking)")
+	fs.DurationVar(&Config.AuthFailureWindow, "auth-failure-window", time.Minute, "window in which authentication failures of one address are counted")
+	fs.DurationVar(&Config.AuthBlockDuration, "auth-block-duration", time.Minute*15, "how long an address is blocked after too many authentication failures")
+	fs.IntVar(&Config.CompressLevel, "compress-level", gzip.BestSpeed, "gzip level of responses from 1 (fastest) to 9 (smallest)")
+	fs.IntVar(&Config.CompressMinSize, "compress-min-size", 512, "responses smaller than this many bytes are sent uncompressed (0 compresses everything)")
+	Config.CompressTypes = append([]string(nil), defaultCompressTypes...)
+	fs.Func("compress-types", "comma-separated content types of compressed responses, type/* matches a whole type, empty compresses all (default "+strings.Join(defaultCompressTypes, ",")+")", parseCompressTypes)
+	fs.BoolVar(&Config.SecurityHeaders, "security-headers", true, "add Content-Security-Policy, X-Content-Type-Options, X-Frame-Options and, over HTTPS, Strict-Transport-Security to responses")
+	fs.StringVar(&Config.ContentSecurityPolicy, "content-security-policy", DefaultContentSecurityPolicy, "value of the Content-Security-Policy header (empty omits it)")
+	fs.StringVar(&Config.FrameOptions, "frame-options", "DENY", "value of the X-Frame-Options header: DENY or SAMEORIGIN (empty omits it)")
+	fs.DurationVar(&Config.HSTSMaxAge, "hsts-max-age", time.Hour*24*180, "max-age of the Strict-Transport-Security header sent over HTTPS (0 omits it)")
+	fs.StringVar(&Config.AdminKey, "admin-key", "", "key for the admin API (empty disables it)")
+	fs.StringVar(&Config.APIKeys, "api-keys", "", "where API keys with roles are kept: database or file:///path/keys.json (empty disables API key checks)")
+	fs.BoolVar(&Config.ReadOnly, "read-only", false, "start in read-only maintenance mode: updates are rejected with 503")
+	fs.BoolVar(&Config.LegacyUpdates, "legacy-updates", false, "serve GET /update?type=&name=&value= for old scripts and devices that can only send simple GET requests")
+	fs.StringVar(&Config.AccessListFile, "access-list-file", "", "json file with allowed and denied subnets for reads, writes and admin routes, reloaded on SIGHUP (empty allows everyone)")
+
+	fs.StringVar(&Config.DatabaseSchema, "db-schema", "", "postgresql schema for the storage tables (empty uses the search_path)")
+	fs.StringVar(&Config.DatabaseTablePrefix, "db-table-prefix", "", "prefix added to the names of all storage tables")
+	fs.DurationVar(&Config.DatabaseQueryTimeout, "db-query-timeout", time.Second*5, "timeout of every storage query (0 disables it)")
+	fs.DurationVar(&Config.DatabaseTxTimeout, "db-tx-timeout", time.Second*30, "maximum lifetime of a storage transaction including its commit, after which it is rolled back (0 disables it)")
+	fs.DurationVar(&Config.DatabaseWaitTimeout, "db-wait-timeout", time.Second*30, "how long to wait for the database on startup (0 fails fast)")
+	fs.DurationVar(&Config.DatabaseHealthInterval, "db-health-interval", time.Second*5, "interval of the database health checks (0 disables them)")
+	fs.BoolVar(&Config.DatabaseNotify, "db-notify", false, "propagate writes to other server instances through postgresql LISTEN/NOTIFY to drop their cached values")
+	fs.Func("db-indexes", "comma-separated additional indexes of the metrics tables created on startup: name_prefix (LIKE 'prefix%' searches) and updated_at", parseDatabaseIndexes)
+	fs.BoolVar(&Config.DatabaseSkipIndexes, "db-skip-indexes", false, "do not create the additional indexes on startup, e.g. while bulk loading metrics; restart without it to build them")
+	fs.DurationVar(&Config.DatabaseSlowQuery, "db-slow-query", 0, "log database queries slower than this with redacted parameters (0 disables it)")
+
+	fs.StringVar(&Config.DatabaseSSLMode, "db-sslmode", "", "postgresql sslmode: disable, allow, prefer, require, verify-ca or verify-full")
+	fs.StringVar(&Config.DatabaseSSLRootCert, "db-sslrootcert", "", "path to the CA certificate used to verify the database server")
+	fs.StringVar(&Config.DatabaseSSLCert, "db-sslcert", "", "path to the client certificate for the database connection")
+	fs.StringVar(&Config.DatabaseSSLKey, "db-sslkey", "", "path to the client private key for the database connection")
+
+	fs.DurationVar(&Config.CounterCoalesceWindow, "counter-coalesce-window", 0, "window in which counter updates are merged before being written to storage (0 disables it)")
+	fs.DurationVar(&Config.ReadCacheTTL, "read-cache-ttl", 0, "how long metric values read from storage are served from memory; writes through this server reset them at once (0 disables the cache)")
+	fs.StringVar(&Config.CounterPolicy, "counter-policy", "allow", "handling of negative counter deltas and int64 overflow: allow, clamp or reject")
+	fs.StringVar(&Config.GaugePolicy, "gauge-policy", "reject", "handling of NaN and Inf gauge values: store, drop or reject")
+	fs.StringVar(&Config.GaugeRounding, "gauge-rounding", "none", "rounding of accepted gauge values: none, significant (digits) or decimal (places after the point)")
+	fs.IntVar(&Config.GaugePrecision, "gauge-precision", 15, "significant digits or decimal places kept by -gauge-rounding")
+	fs.BoolVar(&Config.CaseInsensitiveNames, "case-insensitive-names", false, "lowercase metric names on write and read")
+	fs.BoolVar(&Config.RejectTypeConflicts, "reject-type-conflicts", false, "reject writes of a metric that already exists with another type (409)")
+
+	fs.IntVar(&Config.StorageRetryAttempts, "storage-retry-attempts", 4, "maximum attempts of a failed storage operation (1 disables retries)")
+	fs.StringVar(&Config.StorageRetryBackoff, "storage-retry-backoff", string(retry.Exponential), "backoff between storage retries: constant, linear or exponential")
+	fs.DurationVar(&Config.StorageRetryInterval, "storage-retry-interval", time.Second, "initial pause between storage retries")
+	fs.DurationVar(&Config.StorageRetryMaxInterval, "storage-retry-max-interval", time.Second*5, "maximum pause between storage retries (0 means no limit)")
+	fs.DurationVar(&Config.StorageRetryMaxElapsed, "storage-retry-max-elapsed", time.Second*15, "maximum time spent retrying a storage operation (0 means no limit)")
+
+	fs.Float64Var(&Config.StorageBreakerThreshold, "storage-breaker-threshold", 0, "share of failed database calls (0..1] that opens the circuit breaker (0 disables it)")
+	fs.IntVar(&Config.StorageBreakerWindow, "storage-breaker-window", 20, "number of recent database calls the circuit breaker looks at")
+	fs.IntVar(&Config.StorageBreakerMinRequests, "storage-breaker-min-requests", 10, "minimum calls in the window before the circuit breaker may open")
+	fs.DurationVar(&Config.StorageBreakerOpenTimeout, "storage-breaker-open-timeout", time.Second*10, "how long the circuit breaker stays open before a probe call")
+
+	fs.StringVar(&Config.HistoryPartition, "history-partition", "", "partitioning of the metrics history table: week or month (empty disables history)")
+	fs.DurationVar(&Config.HistoryRetention, "history-retention", 0, "how long history partitions are kept (0 keeps them forever)")
+
+	fs.StringVar(&Config.LogLevels, "log-levels", "", "log levels by module, e.g. info,dbstorage=debug,handlers=warn (empty logs everything)")
+	fs.BoolVar(&Config.DebugBodies, "debug-bodies", false, "log request and response bodies of update endpoints at debug level")
+	fs.IntVar(&Config.DebugBodyLimit, "debug-body-limit", 4096, "maximum logged body size in bytes (0 means no limit)")
+	fs.StringVar(&Config.RecordFile, "record-file", "", "file where accepted update requests are recorded for the replay command (empty disables recording)")
+	fs.StringVar(&Config.DumpFormat, "format", DumpFormatJSON, "file format of the dump/export and load/import commands: json or prom (Prometheus text format)")
+	fs.Float64Var(&Config.ReplaySpeed, "replay-speed", 1, "speed of the replay command: 1 keeps the recorded pace, 2 is twice as fast, 0 sends without pauses")
+	fs.DurationVar(&Config.StaleCheckInterval, "stale-check-interval", 0, "how often metrics are checked for staleness (0 disables the staleness monitor)")
+	fs.Float64Var(&Config.StaleFactor, "stale-factor", 3, "a metric is stale when it has not been updated for this many of its usual update intervals")
+	fs.StringVar(&Config.StaleWebhookURL, "stale-webhook-url", "", "URL that receives a POST with the metrics that became stale (empty disables notifications)")
+	fs.BoolVar(&Config.DebugFaults, "debug-faults", false, "inject latency and errors into storage calls for chaos testing (never enable in production)")
+	fs.DurationVar(&Config.FaultLatency, "fault-latency", 0, "latency added to every storage call when fault injection is enabled")
+	fs.DurationVar(&Config.FaultLatencyJitter, "fault-latency-jitter", 0, "maximum random latency added on top of fault-latency")
+	fs.Float64Var(&Config.FaultErrorRate, "fault-error-rate", 0, "share of storage calls failing with a transient connection error, from 0 to 1")
+	fs.Float64Var(&Config.FaultFailureRate, "fault-failure-rate", 0, "share of storage calls failing with a non-retryable storage failure, from 0 to 1")
+	fs.StringVar(&Config.LogFile, "log-file", "", "path to the log file (empty writes logs to stderr)")
+	fs.IntVar(&Config.LogMaxSize, "log-max-size", 100, "size of the log file in megabytes after which it is rotated (0 disables rotation)")
+	fs.IntVar(&Config.LogMaxBackups, "log-max-backups", 0, "number of rotated log files to keep (0 keeps all)")
+	fs.DurationVar(&Config.LogMaxAge, "log-max-age", 0, "how long rotated log files are kept (0 keeps them forever)")
+	fs.BoolVar(&Config.LogCompress, "log-compress", false, "compress rotated log files with gzip")
+	fs.StringVar(&Config.LogSink, "log-sink", "stderr", "where logs are shipped: stderr, syslog or journald")
+	fs.StringVar(&Config.LogSyslogAddress, "log-syslog-address", "", "syslog address like udp://host:514 (empty uses the local syslog)")
+	fs.StringVar(&Config.LogTag, "log-tag", "", "program identifier in syslog/journald (empty uses the executable name)")
+	fs.StringVar(&Config.LogEncoding, "log-encoding", "console", "log encoding: console or json")
+	fs.StringVar(&Config.LogTimeFormat, "log-time-format", "iso8601", "log timestamp format: iso8601, rfc3339, rfc3339nano, epoch, millis, nanos or a time layout")
+	fs.IntVar(&Config.LogAsyncBuffer, "log-async-buffer", 0, "size of the queue for background log writing; entries are dropped when it is full (0 writes synchronously)")
+	fs.StringVar(&Config.ErrorTrackerDSN, "error-tracker-dsn", "", "sentry dsn or http endpoint receiving logged errors (empty disables it)")
+	fs.DurationVar(&Config.LogDedupWindow, "log-dedup-window", 0, "window in which repeated warnings and errors are collapsed into one entry with a repeat count (0 disables it)")
+
+	fs.Func("features", "comma-separated list of enabled experimental features: grpc, history, labels", parseFeatures)
+}
+
+// Defaults возвращает настройки со значениями флагов по умолчанию, не трогая Config.
+func Defaults() Settings {
+	saved := Config
+	defer func() { Config = saved }()
+
+	registerFlags(flag.NewFlagSet("defaults", flag.ContinueOnError))
+	return Config
+}
+
+// Set проверяет настройки и делает их текущими. При ошибке Config не меняется.
+func Set(settings Settings) error {
+	if err := secrets.ResolveStruct(context.Background(), &settings); err != nil {
+		return err
+	}
+
+	saved := Config
+	Config = settings
+
+	if err := validate(); err != nil {
+		Config = saved
+		return err
+	}
+
+	return nil
 }
 
 func Parse() error {
 	flag.Parse()
 
-	return env.Parse(&Config)
+	if err := env.Parse(&Config); err != nil {
+		return err
+	}
+
+	if err := secrets.ResolveStruct(context.Background(), &Config); err != nil {
+		return err
+	}
+
+	return validate()
+}
+
+func validate() error {
+	if Config.StorageDSN != "" && !strings.Contains(Config.StorageDSN, "://") {
+		return fmt.Errorf("invalid storage dsn: expected scheme://, e.g. postgres://, file:// or mem://")
+	}
+
+	switch Config.HistoryPartition {
+	case "", "week", "month":
+	default:
+		return fmt.Errorf("invalid history partition %q: expected week or month", Config.HistoryPartition)
+	}
+
+	switch Config.CounterPolicy {
+	case "", "allow", "clamp", "reject":
+	default:
+		return fmt.Errorf("invalid counter policy %q: expected allow, clamp or reject", Config.CounterPolicy)
+	}
+
+	switch Config.GaugePolicy {
+	case "", "store", "drop", "reject":
+	default:
+		return fmt.Errorf("invalid gauge policy %q: expected store, drop or reject", Config.GaugePolicy)
+	}
+
+	switch Config.GaugeRounding {
+	case "", "none":
+	case "significant":
+		if Config.GaugePrecision < 1 || Config.GaugePrecision > 17 {
+			return fmt.Errorf("invalid gauge precision %d: significant digits must be between 1 and 17", Config.GaugePrecision)
+		}
+	case "decimal":
+		if Config.GaugePrecision < 0 || Config.GaugePrecision > 17 {
+			return fmt.Errorf("invalid gauge precision %d: decimal places must be between 0 and 17", Config.GaugePrecision)
+		}
+	default:
+		return fmt.Errorf("invalid gauge rounding %q: expected none, significant or decimal", Config.GaugeRounding)
+	}
+
+	if Config.DatabaseQueryTimeout < 0 {
+		return fmt.Errorf("invalid database query timeout %s: must not be negative", Config.DatabaseQueryTimeout)
+	}
+
+	if Config.DatabaseTxTimeout < 0 {
+		return fmt.Errorf("invalid database transaction timeout %s: must not be negative", Config.DatabaseTxTimeout)
+	}
+
+	if Config.DatabaseHealthInterval < 0 {
+		return fmt.Errorf("invalid database health interval %s: must not be negative", Config.DatabaseHealthInterval)
+	}
+
+	if Config.DatabaseSlowQuery < 0 {
+		return fmt.Errorf("invalid database slow query threshold %s: must not be negative", Config.DatabaseSlowQuery)
+	}
+
+	if err := validateDatabaseIndexes(); err != nil {
+		return err
+	}
+
+	if Config.DatabaseWaitTimeout < 0 {
+		return fmt.Errorf("invalid database wait timeout %s: must not be negative", Config.DatabaseWaitTimeout)
+	}
+
+	if Config.CounterCoalesceWindow < 0 {
+		return fmt.Errorf("invalid counter coalesce window %s: must not be negative", Config.CounterCoalesceWindow)
+	}
+
+	if Config.ReadCacheTTL < 0 {
+		return fmt.Errorf("invalid read cache ttl %s: must not be negative", Config.ReadCacheTTL)
+	}
+
+	if err := validateStorageRetry(); err != nil {
+		return err
+	}
+
+	if err := validateStorageBreaker(); err != nil {
+		return err
+	}
+
+	if err := validateFaults(); err != nil {
+		return err
+	}
+
+	if Config.ReplaySpeed < 0 {
+		return fmt.Errorf("invalid replay speed %v: must not be negative", Config.ReplaySpeed)
+	}
+
+	switch Config.SnapshotFormat {
+	case "", SnapshotFormatJSON, SnapshotFormatGob, SnapshotFormatProtobuf:
+	default:
+		return fmt.Errorf("invalid snapshot format %q: expected %s, %s or %s", Config.SnapshotFormat, SnapshotFormatJSON, SnapshotFormatGob, SnapshotFormatProtobuf)
+	}
+
+	if Config.DumpFormat != "" && Config.DumpFormat != DumpFormatJSON && Config.DumpFormat != DumpFormatProm {
+		return fmt.Errorf("invalid dump format %q: expected %s or %s", Config.DumpFormat, DumpFormatJSON, DumpFormatProm)
+	}
+
+	if _, err := ParseSigningKeys(Config.SigningKeys); err != nil {
+		return err
+	}
+
+	if err := validateReplay(); err != nil {
+		return err
+	}
+
+	if err := validateLockout(); err != nil {
+		return err
+	}
+
+	if err := validateCompress(); err != nil {
+		return err
+	}
+
+	if err := validateSecurityHeaders(); err != nil {
+		return err
+	}
+
+	if err := validateListen(); err != nil {
+		return err
+	}
+
+	if err := validateTimeouts(); err != nil {
+		return err
+	}
+
+	if err := validateProxies(); err != nil {
+		return err
+	}
+
+	if err := validateTLS(); err != nil {
+		return err
+	}
+
+	if _, err := LoadEncryptionKeys(); err != nil {
+		return err
+	}
+
+	if err := validateAPIKeys(); err != nil {
+		return err
+	}
+
+	if err := validateStale(); err != nil {
+		return err
+	}
+
+	if Config.HistoryRetention < 0 {
+		return fmt.Errorf("invalid history retention %s: must not be negative", Config.HistoryRetention)
+	}
+
+	if err := validateLogger(); err != nil {
+		return err
+	}
+
+	return validateFeatures()
 }
@@ -80,3 +80,14 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSigningKeys(t *testing.T) {
+	keys, err := ParseSigningKeys("v1:first, v2:second:with:colons")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"v1": "first", "v2": "second:with:colons"}, keys)
+
+	for _, value := range []string{"v1", "v1:", ":key", "v1:a,v1:b", "default:key", "bad id:key"} {
+		_, err = ParseSigningKeys(value)
+		assert.Error(t, err, value)
+	}
+}
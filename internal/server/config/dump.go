@@ -0,0 +1,7 @@
+package config
+
+// Форматы файлов подкоманд dump/export и load/import.
+const (
+	DumpFormatJSON = "json"
+	DumpFormatProm = "prom"
+)
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptionKey - ключ AES для шифрования снапшотов. ID записывается в файл, чтобы знать, каким ключом его читать.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// LoadEncryptionKeys возвращает ключи из ENCRYPTION_KEY_FILE или ENCRYPTION_KEYS. Первый ключ шифрует новые
+// снапшоты, остальные нужны только для чтения старых, пока идёт смена ключа. Без ключей возвращает nil.
+func LoadEncryptionKeys() ([]EncryptionKey, error) {
+	var entries []string
+
+	switch {
+	case Config.EncryptionKeyFile != "" && Config.EncryptionKeys != "":
+		return nil, fmt.Errorf("invalid encryption keys: set either encryption keys or the encryption key file, not both")
+	case Config.EncryptionKeyFile != "":
+		data, err := os.ReadFile(Config.EncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file: %w", err)
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				entries = append(entries, line)
+			}
+		}
+	case Config.EncryptionKeys != "":
+		entries = strings.Split(Config.EncryptionKeys, ",")
+	}
+
+	keys := make([]EncryptionKey, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+
+	for _, entry := range entries {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || !signingKeyIDRegexp.MatchString(id) {
+			return nil, fmt.Errorf("invalid encryption key %q: expected id:base64 with an id of letters, digits, '.', '_' or '-'", id)
+		}
+
+		if _, ok = seen[id]; ok {
+			return nil, fmt.Errorf("invalid encryption keys: duplicate key id %q", id)
+		}
+		seen[id] = struct{}{}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+
+		if n := len(key); n != 16 && n != 24 && n != 32 {
+			return nil, fmt.Errorf("invalid encryption key %q: expected 16, 24 or 32 bytes, got %d", id, n)
+		}
+
+		keys = append(keys, EncryptionKey{ID: id, Key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return keys, nil
+}
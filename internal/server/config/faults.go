@@ -0,0 +1,23 @@
+package config
+
+import "fmt"
+
+func validateFaults() error {
+	if Config.FaultLatency < 0 || Config.FaultLatencyJitter < 0 {
+		return fmt.Errorf("invalid fault latency %s (jitter %s): must not be negative", Config.FaultLatency, Config.FaultLatencyJitter)
+	}
+
+	if Config.FaultErrorRate < 0 || Config.FaultErrorRate > 1 {
+		return fmt.Errorf("invalid fault error rate %v: must be between 0 and 1", Config.FaultErrorRate)
+	}
+
+	if Config.FaultFailureRate < 0 || Config.FaultFailureRate > 1 {
+		return fmt.Errorf("invalid fault failure rate %v: must be between 0 and 1", Config.FaultFailureRate)
+	}
+
+	if Config.FaultErrorRate+Config.FaultFailureRate > 1 {
+		return fmt.Errorf("invalid fault rates %v and %v: their sum must not exceed 1", Config.FaultErrorRate, Config.FaultFailureRate)
+	}
+
+	return nil
+}
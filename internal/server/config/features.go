@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Экспериментальные возможности, которые выключены, пока их явно не перечислят в FEATURES.
+const (
+	FeatureGRPC    = "grpc"
+	FeatureHistory = "history"
+	FeatureLabels  = "labels"
+)
+
+var knownFeatures = map[string]struct{}{
+	FeatureGRPC:    {},
+	FeatureHistory: {},
+	FeatureLabels:  {},
+}
+
+func FeatureEnabled(name string) bool {
+	for _, feature := range Config.Features {
+		if feature == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func Features() map[string]bool {
+	result := make(map[string]bool, len(knownFeatures))
+	for name := range knownFeatures {
+		result[name] = FeatureEnabled(name)
+	}
+
+	return result
+}
+
+func parseFeatures(value string) error {
+	Config.Features = nil
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			Config.Features = append(Config.Features, name)
+		}
+	}
+
+	return nil
+}
+
+func validateFeatures() error {
+	for _, name := range Config.Features {
+		if _, ok := knownFeatures[name]; !ok {
+			known := make([]string, 0, len(knownFeatures))
+			for feature := range knownFeatures {
+				known = append(known, feature)
+			}
+			sort.Strings(known)
+
+			return fmt.Errorf("unknown feature %q: expected one of %s", name, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatures(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		wantedFeatures map[string]bool
+		wantedErr      bool
+	}{
+		{
+			name:           "Without features",
+			wantedFeatures: map[string]bool{FeatureGRPC: false, FeatureHistory: false, FeatureLabels: false},
+		},
+		{
+			name:           "Enabled features",
+			value:          "grpc, labels",
+			wantedFeatures: map[string]bool{FeatureGRPC: true, FeatureHistory: false, FeatureLabels: true},
+		},
+		{
+			name:      "Unknown feature",
+			value:     "grpc,teleport",
+			wantedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { Config.Features = nil }()
+
+			assert.NoError(t, parseFeatures(tt.value))
+
+			err := validateFeatures()
+			if tt.wantedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantedFeatures, Features())
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Дополнительные индексы таблиц метрик, которые создаются при запуске, если их перечислить в DATABASE_INDEXES.
+const (
+	// IndexNamePrefix - индекс с text_pattern_ops для поиска по префиксу имени (LIKE 'cpu%').
+	IndexNamePrefix = "name_prefix"
+	// IndexUpdatedAt - индекс по времени последней записи для выборок давно не обновлявшихся метрик.
+	IndexUpdatedAt = "updated_at"
+)
+
+func parseDatabaseIndexes(value string) error {
+	Config.DatabaseIndexes = nil
+
+	for _, index := range strings.Split(value, ",") {
+		if index = strings.TrimSpace(index); index != "" {
+			Config.DatabaseIndexes = append(Config.DatabaseIndexes, index)
+		}
+	}
+
+	return nil
+}
+
+func validateDatabaseIndexes() error {
+	for _, index := range Config.DatabaseIndexes {
+		switch index {
+		case IndexNamePrefix, IndexUpdatedAt:
+		default:
+			return fmt.Errorf("unknown database index %q: expected %s or %s", index, IndexNamePrefix, IndexUpdatedAt)
+		}
+	}
+
+	return nil
+}
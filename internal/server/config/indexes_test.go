@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabaseIndexes(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		wantedIndexes []string
+		wantedErr     bool
+	}{
+		{
+			name: "Without indexes",
+		},
+		{
+			name:          "Known indexes",
+			value:         "name_prefix, updated_at",
+			wantedIndexes: []string{IndexNamePrefix, IndexUpdatedAt},
+		},
+		{
+			name:      "Unknown index",
+			value:     "name_prefix,value",
+			wantedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { Config.DatabaseIndexes = nil }()
+
+			assert.NoError(t, parseDatabaseIndexes(tt.value))
+
+			err := validateDatabaseIndexes()
+			if tt.wantedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantedIndexes, Config.DatabaseIndexes)
+		})
+	}
+}
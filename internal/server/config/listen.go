@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// unixPrefix - адрес вида unix:/path/to.sock означает unix-сокет вместо TCP.
+	unixPrefix = "unix:"
+
+	defaultSocketMode = "0660"
+)
+
+// SocketPath возвращает путь к unix-сокету, если адрес задан как unix:/path.
+func SocketPath(address string) (string, bool) {
+	return strings.CutPrefix(address, unixPrefix)
+}
+
+// SocketMode возвращает права на файл unix-сокета.
+func SocketMode() os.FileMode {
+	value := Config.UnixSocketMode
+	if value == "" {
+		value = defaultSocketMode
+	}
+
+	mode, _ := strconv.ParseUint(value, 8, 32) // проверено в validateListen
+	return os.FileMode(mode)
+}
+
+func validateListen() error {
+	for _, address := range []string{Config.Address, Config.AdminAddress} {
+		if path, ok := SocketPath(address); ok && path == "" {
+			return fmt.Errorf("invalid address %q: expected unix:/path/to.sock", address)
+		}
+	}
+
+	if Config.H2C && (TLSEnabled() || ACMEEnabled()) {
+		return fmt.Errorf("invalid h2c setting: h2c is HTTP/2 without TLS, over TLS HTTP/2 is negotiated anyway")
+	}
+
+	if Config.UnixSocketMode == "" {
+		return nil
+	}
+
+	if mode, err := strconv.ParseUint(Config.UnixSocketMode, 8, 32); err != nil || mode > 0777 {
+		return fmt.Errorf("invalid unix socket mode %q: expected octal permissions like 0660", Config.UnixSocketMode)
+	}
+
+	return nil
+}
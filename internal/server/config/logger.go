@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func LoggerOptions() logger.Options {
+	// Значения проверены при разборе конфига
+	levels, _ := logger.ParseLevels(Config.LogLevels)
+	sink, _ := logger.ParseSink(Config.LogSink)
+	encoding, _ := logger.ParseEncoding(Config.LogEncoding)
+
+	return logger.Options{
+		Levels: levels,
+		File:   Config.LogFile,
+		Rotation: logger.Rotation{
+			MaxSize:    Config.LogMaxSize,
+			MaxBackups: Config.LogMaxBackups,
+			MaxAge:     Config.LogMaxAge,
+			Compress:   Config.LogCompress,
+		},
+		Encoding:        encoding,
+		TimeFormat:      Config.LogTimeFormat,
+		Sink:            sink,
+		SyslogAddress:   Config.LogSyslogAddress,
+		Tag:             Config.LogTag,
+		AsyncBuffer:     Config.LogAsyncBuffer,
+		ErrorTrackerDSN: Config.ErrorTrackerDSN,
+		DedupWindow:     Config.LogDedupWindow,
+	}
+}
+
+func validateLogger() error {
+	if Config.LogMaxSize < 0 {
+		return fmt.Errorf("invalid log max size %d: must not be negative", Config.LogMaxSize)
+	}
+
+	if Config.LogMaxBackups < 0 {
+		return fmt.Errorf("invalid log max backups %d: must not be negative", Config.LogMaxBackups)
+	}
+
+	if Config.LogMaxAge < 0 {
+		return fmt.Errorf("invalid log max age %s: must not be negative", Config.LogMaxAge)
+	}
+
+	if Config.DebugBodyLimit < 0 {
+		return fmt.Errorf("invalid debug body limit %d: must not be negative", Config.DebugBodyLimit)
+	}
+
+	if Config.LogAsyncBuffer < 0 {
+		return fmt.Errorf("invalid log async buffer %d: must not be negative", Config.LogAsyncBuffer)
+	}
+
+	if Config.LogDedupWindow < 0 {
+		return fmt.Errorf("invalid log dedup window %s: must not be negative", Config.LogDedupWindow)
+	}
+
+	if _, err := logger.ParseLevels(Config.LogLevels); err != nil {
+		return err
+	}
+
+	if _, err := logger.ParseSink(Config.LogSink); err != nil {
+		return err
+	}
+
+	if _, err := logger.ParseEncoding(Config.LogEncoding); err != nil {
+		return err
+	}
+
+	if _, err := logger.ParseTimeFormat(Config.LogTimeFormat); err != nil {
+		return err
+	}
+
+	return nil
+}
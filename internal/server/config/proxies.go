@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	ForwardedForHeader = "X-Forwarded-For"
+	RealIPHeader       = "X-Real-IP"
+
+	// ForwardedForRightmost - клиент это ближайший к серверу адрес X-Forwarded-For, которого нет среди доверенных прокси.
+	ForwardedForRightmost = "rightmost"
+	// ForwardedForLeftmost - клиент это первый адрес X-Forwarded-For: годится, только если цепочку целиком собирают свои прокси.
+	ForwardedForLeftmost = "leftmost"
+)
+
+func parseTrustedProxies(value string) error {
+	Config.TrustedProxies = nil
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			Config.TrustedProxies = append(Config.TrustedProxies, proxy)
+		}
+	}
+
+	return nil
+}
+
+// ClientIPHeader возвращает заголовок с адресом клиента, регистр в настройках не важен.
+func ClientIPHeader() string {
+	switch {
+	case Config.ClientIPHeader == "", strings.EqualFold(Config.ClientIPHeader, ForwardedForHeader):
+		return ForwardedForHeader
+	case strings.EqualFold(Config.ClientIPHeader, RealIPHeader):
+		return RealIPHeader
+	default:
+		return Config.ClientIPHeader
+	}
+}
+
+func validateProxies() error {
+	for _, proxy := range Config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy %q: expected an ip address or a subnet like 10.0.0.0/8", proxy)
+		}
+	}
+
+	switch ClientIPHeader() {
+	case ForwardedForHeader, RealIPHeader:
+	default:
+		return fmt.Errorf("invalid client ip header %q: expected %s or %s", Config.ClientIPHeader, ForwardedForHeader, RealIPHeader)
+	}
+
+	switch Config.ForwardedForPosition {
+	case "", ForwardedForRightmost, ForwardedForLeftmost:
+	default:
+		return fmt.Errorf("invalid forwarded for position %q: expected %s or %s", Config.ForwardedForPosition, ForwardedForRightmost, ForwardedForLeftmost)
+	}
+
+	return nil
+}
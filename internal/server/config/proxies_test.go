@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProxies(t *testing.T) {
+	saved := Config
+	defer func() { Config = saved }()
+
+	tests := []struct {
+		name    string
+		modify  func(s *Settings)
+		wantErr bool
+	}{
+		{name: "Defaults", modify: func(s *Settings) {}},
+		{name: "Addresses and subnets", modify: func(s *Settings) { s.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1", "::1"} }},
+		{name: "Invalid proxy", modify: func(s *Settings) { s.TrustedProxies = []string{"proxy.local"} }, wantErr: true},
+		{name: "Lowercase header", modify: func(s *Settings) { s.ClientIPHeader = "x-real-ip" }},
+		{name: "Unknown header", modify: func(s *Settings) { s.ClientIPHeader = "Forwarded" }, wantErr: true},
+		{name: "Leftmost", modify: func(s *Settings) { s.ForwardedForPosition = ForwardedForLeftmost }},
+		{name: "Unknown position", modify: func(s *Settings) { s.ForwardedForPosition = "middle" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = Defaults()
+			Config.TrustedProxies = nil
+			tt.modify(&Config)
+
+			if tt.wantErr {
+				assert.Error(t, validateProxies())
+			} else {
+				assert.NoError(t, validateProxies())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const redactedValue = "redacted"
+
+var dsnPasswordRegexp = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+func Redacted() map[string]any {
+	value := reflect.ValueOf(Config)
+	typ := value.Type()
+
+	result := make(map[string]any, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			name = field.Name
+		}
+
+		result[name] = redactField(field, value.Field(i))
+	}
+
+	return result
+}
+
+func redactField(field reflect.StructField, value reflect.Value) any {
+	switch field.Tag.Get("secret") {
+	case "true":
+		if value.IsZero() {
+			return value.Interface()
+		}
+		return redactedValue
+	case "dsn":
+		return RedactDSN(value.String())
+	}
+
+	if d, ok := value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	return value.Interface()
+}
+
+func RedactDSN(dsn string) string {
+	if dsn == "" {
+		return dsn
+	}
+
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return redactedValue
+		}
+
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+		}
+
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", redactedValue)
+			u.RawQuery = query.Encode()
+		}
+
+		return u.String()
+	}
+
+	return dsnPasswordRegexp.ReplaceAllString(dsn, "${1}"+redactedValue)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/retry"
+)
+
+func StorageRetryPolicy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:     Config.StorageRetryAttempts,
+		Backoff:         retry.Backoff(Config.StorageRetryBackoff),
+		InitialInterval: Config.StorageRetryInterval,
+		MaxInterval:     Config.StorageRetryMaxInterval,
+		MaxElapsedTime:  Config.StorageRetryMaxElapsed,
+	}
+}
+
+func validateStorageRetry() error {
+	if Config.StorageRetryAttempts < 0 {
+		return fmt.Errorf("invalid storage retry attempts %d: must not be negative", Config.StorageRetryAttempts)
+	}
+
+	if Config.StorageRetryBackoff != "" {
+		if _, err := retry.ParseBackoff(Config.StorageRetryBackoff); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"interval", Config.StorageRetryInterval},
+		{"max interval", Config.StorageRetryMaxInterval},
+		{"max elapsed time", Config.StorageRetryMaxElapsed},
+	} {
+		if d.value < 0 {
+			return fmt.Errorf("invalid storage retry %s %s: must not be negative", d.name, d.value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package config
+
+import "fmt"
+
+// DefaultContentSecurityPolicy запрещает странице со списком метрик всё, кроме встроенных стилей.
+const DefaultContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'"
+
+func validateSecurityHeaders() error {
+	switch Config.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("invalid frame options %q: expected DENY or SAMEORIGIN", Config.FrameOptions)
+	}
+
+	if Config.HSTSMaxAge < 0 {
+		return fmt.Errorf("invalid hsts max age %s: must not be negative", Config.HSTSMaxAge)
+	}
+
+	return nil
+}
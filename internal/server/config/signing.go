@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultSigningKeyID - идентификатор ключа KEY, им подписаны запросы без заголовка HashKeyID.
+const DefaultSigningKeyID = "default"
+
+var signingKeyIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ParseSigningKeys разбирает SIGNING_KEYS вида id1:key1,id2:key2.
+func ParseSigningKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if value == "" {
+		return keys, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		id, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid signing keys: expected id:key pairs separated by commas")
+		}
+
+		if !signingKeyIDRegexp.MatchString(id) || id == DefaultSigningKeyID {
+			return nil, fmt.Errorf("invalid signing key id %q: expected letters, digits, '.', '_' or '-' and not %q", id, DefaultSigningKeyID)
+		}
+
+		if _, ok = keys[id]; ok {
+			return nil, fmt.Errorf("invalid signing keys: duplicate key id %q", id)
+		}
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+// SigningKeys возвращает все ключи подписи по идентификаторам, включая KEY под DefaultSigningKeyID.
+func SigningKeys() map[string]string {
+	keys, err := ParseSigningKeys(Config.SigningKeys)
+	if err != nil {
+		keys = make(map[string]string) // настройки проверены при загрузке
+	}
+
+	if Config.Key != "" {
+		keys[DefaultSigningKeyID] = Config.Key
+	}
+
+	return keys
+}
+
+func validateReplay() error {
+	if Config.ReplayWindow < 0 {
+		return fmt.Errorf("invalid replay window %s: must not be negative", Config.ReplayWindow)
+	}
+
+	if Config.ReplayNonceCache < 0 {
+		return fmt.Errorf("invalid replay nonce cache %d: must not be negative", Config.ReplayNonceCache)
+	}
+
+	if Config.RequireReplayNonce && Config.ReplayWindow == 0 {
+		return fmt.Errorf("invalid replay settings: require-replay-nonce needs a positive replay window")
+	}
+
+	return nil
+}
+
+func validateLockout() error {
+	if Config.AuthFailureThreshold < 0 {
+		return fmt.Errorf("invalid auth failure threshold %d: must not be negative", Config.AuthFailureThreshold)
+	}
+
+	if Config.AuthFailureThreshold == 0 {
+		return nil
+	}
+
+	if Config.AuthFailureWindow <= 0 {
+		return fmt.Errorf("invalid auth failure window %s: must be positive", Config.AuthFailureWindow)
+	}
+
+	if Config.AuthBlockDuration <= 0 {
+		return fmt.Errorf("invalid auth block duration %s: must be positive", Config.AuthBlockDuration)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+package config
+
+// Форматы сериализации снапшотов файлового хранилища. gob и protobuf читаются в разы быстрее JSON на больших снапшотах.
+const (
+	SnapshotFormatJSON     = "json"
+	SnapshotFormatGob      = "gob"
+	SnapshotFormatProtobuf = "protobuf"
+)
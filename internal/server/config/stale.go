@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+func validateStale() error {
+	if Config.StaleCheckInterval < 0 {
+		return fmt.Errorf("invalid stale check interval %s: must not be negative", Config.StaleCheckInterval)
+	}
+
+	if Config.StaleCheckInterval == 0 {
+		return nil
+	}
+
+	if Config.StaleFactor < 1 {
+		return fmt.Errorf("invalid stale factor %v: must be at least 1", Config.StaleFactor)
+	}
+
+	if Config.StaleWebhookURL != "" {
+		u, err := url.Parse(Config.StaleWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid stale webhook url: expected http:// or https:// URL")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+func validateTimeouts() error {
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read timeout", Config.ReadTimeout},
+		{"read header timeout", Config.ReadHeaderTimeout},
+		{"write timeout", Config.WriteTimeout},
+		{"idle timeout", Config.IdleTimeout},
+		{"handler timeout", Config.HandlerTimeout},
+		{"stream read timeout", Config.StreamReadTimeout},
+		{"tcp keep alive", Config.TCPKeepAlive},
+	}
+
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			return fmt.Errorf("invalid %s %s: must not be negative", timeout.name, timeout.value)
+		}
+	}
+
+	if Config.MaxConnections < 0 {
+		return fmt.Errorf("invalid max connections %d: must not be negative", Config.MaxConnections)
+	}
+
+	if Config.HandlerTimeout > 0 && Config.WriteTimeout > 0 && Config.HandlerTimeout >= Config.WriteTimeout {
+		return fmt.Errorf("invalid handler timeout %s: must be less than the write timeout %s, otherwise the timeout response is never sent", Config.HandlerTimeout, Config.WriteTimeout)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TLSEnabled сообщает, что сервер должен отвечать по HTTPS с сертификатом из файлов.
+func TLSEnabled() bool {
+	return Config.TLSCertFile != ""
+}
+
+// ACMEEnabled сообщает, что сертификаты получаются автоматически по ACME.
+func ACMEEnabled() bool {
+	return len(Config.ACMEDomains) > 0
+}
+
+func parseACMEDomains(value string) error {
+	Config.ACMEDomains = nil
+	for _, domain := range strings.Split(value, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			Config.ACMEDomains = append(Config.ACMEDomains, domain)
+		}
+	}
+
+	return nil
+}
+
+func validateTLS() error {
+	if (Config.TLSCertFile == "") != (Config.TLSKeyFile == "") {
+		return fmt.Errorf("invalid tls settings: tls-cert-file and tls-key-file must be set together")
+	}
+
+	if Config.TLSReloadInterval < 0 {
+		return fmt.Errorf("invalid tls reload interval %s: must not be negative", Config.TLSReloadInterval)
+	}
+
+	if !ACMEEnabled() {
+		return nil
+	}
+
+	if TLSEnabled() {
+		return fmt.Errorf("invalid tls settings: acme-domains can't be used with tls-cert-file")
+	}
+
+	if Config.ACMECacheDir == "" {
+		return fmt.Errorf("invalid acme settings: acme-cache-dir is required, otherwise certificates are requested on every start")
+	}
+
+	switch Config.ACMEChallenge {
+	case "tls-alpn-01":
+	case "http-01":
+		if Config.ACMEHTTPAddress == "" {
+			return fmt.Errorf("invalid acme settings: http-01 needs acme-http-address")
+		}
+	default:
+		return fmt.Errorf("invalid acme challenge %q: expected tls-alpn-01 or http-01", Config.ACMEChallenge)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTLS(t *testing.T) {
+	saved := Config
+	defer func() { Config = saved }()
+
+	tests := []struct {
+		name    string
+		modify  func(s *Settings)
+		wantErr bool
+	}{
+		{name: "Plain HTTP", modify: func(s *Settings) {}},
+		{name: "Certificate files", modify: func(s *Settings) { s.TLSCertFile, s.TLSKeyFile = "cert.pem", "key.pem" }},
+		{name: "Certificate without key", modify: func(s *Settings) { s.TLSCertFile = "cert.pem" }, wantErr: true},
+		{name: "ACME", modify: func(s *Settings) { s.ACMEDomains = []string{"metrics.example.com"} }},
+		{name: "ACME over http-01", modify: func(s *Settings) {
+			s.ACMEDomains, s.ACMEChallenge = []string{"metrics.example.com"}, "http-01"
+		}},
+		{name: "ACME with certificate files", modify: func(s *Settings) {
+			s.ACMEDomains, s.TLSCertFile, s.TLSKeyFile = []string{"metrics.example.com"}, "cert.pem", "key.pem"
+		}, wantErr: true},
+		{name: "ACME without cache", modify: func(s *Settings) {
+			s.ACMEDomains, s.ACMECacheDir = []string{"metrics.example.com"}, ""
+		}, wantErr: true},
+		{name: "Unknown challenge", modify: func(s *Settings) {
+			s.ACMEDomains, s.ACMEChallenge = []string{"metrics.example.com"}, "dns-01"
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = Defaults()
+			tt.modify(&Config)
+
+			if tt.wantErr {
+				assert.Error(t, validateTLS())
+			} else {
+				assert.NoError(t, validateTLS())
+			}
+		})
+	}
+}
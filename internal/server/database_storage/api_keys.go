@@ -0,0 +1,74 @@
+package dbstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+func (dbStorage *databaseStorage) createAPIKeys(ctx context.Context) error {
+	_, err := dbStorage.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"id" TEXT NOT NULL PRIMARY KEY,
+		"name" TEXT NOT NULL,
+		"role" VARCHAR(12) NOT NULL,
+		"hash" TEXT NOT NULL UNIQUE,
+		"created_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+		"revoked_at" TIMESTAMPTZ
+	)`, dbStorage.tables.apiKeys()))
+
+	return err
+}
+
+func (dbStorage *databaseStorage) CreateAPIKey(key models.APIKey) error {
+	return dbStorage.withRetry("CreateAPIKey", func(ctx context.Context) error {
+		_, err := dbStorage.db.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (id, name, role, hash, created_at)
+			VALUES (:id, :name, :role, :hash, :created_at)`, dbStorage.tables.apiKeys()), key)
+		return err
+	})
+}
+
+func (dbStorage *databaseStorage) ListAPIKeys() (keys []models.APIKey, err error) {
+	err = dbStorage.withRetry("ListAPIKeys", func(ctx context.Context) error {
+		keys = nil
+		return dbStorage.db.SelectContext(ctx, &keys, fmt.Sprintf(`SELECT id, name, role, hash, created_at, revoked_at
+			FROM %s ORDER BY created_at, id`, dbStorage.tables.apiKeys()))
+	})
+	return
+}
+
+func (dbStorage *databaseStorage) FindAPIKey(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	err := dbStorage.withRetry("FindAPIKey", func(ctx context.Context) error {
+		return dbStorage.db.GetContext(ctx, &key, fmt.Sprintf(`SELECT id, name, role, hash, created_at, revoked_at
+			FROM %s WHERE hash = $1`, dbStorage.tables.apiKeys()), hash)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errs.ErrAPIKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (dbStorage *databaseStorage) RevokeAPIKey(id string) error {
+	return dbStorage.withRetry("RevokeAPIKey", func(ctx context.Context) error {
+		var found bool
+		err := dbStorage.db.GetContext(ctx, &found, fmt.Sprintf(`WITH key AS (
+				UPDATE %s SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1 RETURNING id
+			) SELECT EXISTS (SELECT 1 FROM key)`, dbStorage.tables.apiKeys()), id)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			return errs.ErrAPIKeyNotFound
+		}
+		return nil
+	})
+}
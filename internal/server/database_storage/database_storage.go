@@ -2,157 +2,455 @@ package dbstorage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/health"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 type (
 	databaseStorage struct {
-		db  *sqlx.DB
-		log logger.Logger
+		db     *sqlx.DB
+		log    logger.Logger
+		tables tables
+		clock  clock.Clock
 
+		mx       sync.RWMutex
 		prepares prepares
+
+		healthy       atomic.Bool
+		stopHealth    context.CancelFunc
+		stopHistory   context.CancelFunc
+		stopListening context.CancelFunc
+
+		watchersMx sync.Mutex
+		watchers   []func(models.MetricsChange)
 	}
 
 	prepares struct {
-		getGaugeMetric    *sqlx.NamedStmt
-		getCounterMetric  *sqlx.NamedStmt
-		setOrUpdateMetric *sqlx.NamedStmt
+		getGaugeMetric   *sqlx.NamedStmt
+		getCounterMetric *sqlx.NamedStmt
+		setGaugeMetric   *sqlx.NamedStmt
+		addCounterMetric *sqlx.NamedStmt
+		lockGaugeMetric  *sqlx.NamedStmt
+		updateGaugeStats *sqlx.NamedStmt
+		insertHistory    *sqlx.NamedStmt
 	}
 )
 
 func New(db *sqlx.DB, log logger.Logger) (*databaseStorage, error) {
 	dbStorage := &databaseStorage{
-		db:  db,
-		log: log,
+		db:     db,
+		log:    log,
+		tables: newTables(),
+		clock:  clock.Real(),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
 	if err := dbStorage.Ping(ctx); err != nil {
-		dbStorage.log.Errorf("Failed to connect database to create table and prepare queries: %s", err)
-		return dbStorage, nil
+		return nil, err
 	}
 
-	const schema = `CREATE TABLE IF NOT EXISTS metrics (
-	    "_id" SERIAL,
-		"name" TEXT NOT NULL,
-		"mtype" VARCHAR(12) NOT NULL DEFAULT 'gauge',
-		"delta" BIGINT NOT NULL DEFAULT 0,
-		"value" DOUBLE PRECISION NOT NULL DEFAULT 0.0,
-		CONSTRAINT unique_id_mtype UNIQUE (name, mtype),
-		PRIMARY KEY (_id)
-	)`
+	if dbStorage.tables.schema != "" {
+		if _, err := dbStorage.db.ExecContext(ctx, fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", dbStorage.tables.ident(dbStorage.tables.schema))); err != nil {
+			return nil, err
+		}
+	}
 
-	if _, err := dbStorage.db.ExecContext(ctx, schema); err != nil {
+	if err := dbStorage.createMetrics(ctx); err != nil {
 		return nil, err
 	} else {
 		dbStorage.log.Debugf("The tables for the database were successfully created, if they not existed.")
 	}
 
-	if err := dbStorage.buildPrepares(ctx); err != nil {
+	statsSchema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"name" TEXT NOT NULL PRIMARY KEY,
+		"count" BIGINT NOT NULL DEFAULT 0,
+		"sum" DOUBLE PRECISION NOT NULL DEFAULT 0.0,
+		"min" DOUBLE PRECISION NOT NULL DEFAULT 0.0,
+		"max" DOUBLE PRECISION NOT NULL DEFAULT 0.0,
+		"since" TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`, dbStorage.tables.gaugeStats())
+
+	if _, err := dbStorage.db.ExecContext(ctx, statsSchema); err != nil {
+		return nil, err
+	}
+
+	if err := dbStorage.createSources(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := dbStorage.createAPIKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	if historyEnabled() {
+		if err := dbStorage.createHistory(ctx); err != nil {
+			return nil, err
+		}
+
+		// При запуске ждём, пока другой сервер закончит обслуживание, чтобы записи не копились в секции DEFAULT
+		if _, err := dbStorage.runExclusive(ctx, historyMaintenanceJob, true, dbStorage.maintainHistory); err != nil {
+			return nil, err
+		}
+
+		dbStorage.startHistoryMaintenance()
+		dbStorage.log.Debugf("The history table is partitioned by %s.", config.Config.HistoryPartition)
+	}
+
+	if p, err := dbStorage.buildPrepares(ctx); err != nil {
 		return nil, err
 	} else {
+		dbStorage.prepares = p
 		dbStorage.log.Debugf("SQL Requests are prepared.")
 	}
 
+	dbStorage.healthy.Store(true)
+	health.Set(healthComponent, nil)
+	dbStorage.startHealthWatchdog()
+
+	if notifyEnabled() {
+		dbStorage.startListening()
+		dbStorage.log.Debugf("Changes are propagated through the %s channel.", dbStorage.tables.changesChannel())
+	}
+
 	return dbStorage, nil
 }
 
-func (dbStorage *databaseStorage) buildPrepares(ctx context.Context) error {
+func (dbStorage *databaseStorage) buildPrepares(ctx context.Context) (prepares, error) {
+	var p prepares
+
 	preparesData := map[string]string{
-		"getGaugeMetric":   `SELECT value FROM metrics WHERE name = :name AND mtype = 'gauge'`,
-		"getCounterMetric": `SELECT delta FROM metrics WHERE name = :name AND mtype = 'counter'`,
-		"setOrUpdateMetric": `INSERT INTO metrics (name, mtype, delta, value) 
-				VALUES (:name, :mtype, :delta, :value)
-			ON CONFLICT (name, mtype) DO 
-			    UPDATE SET delta = metrics.delta + excluded.delta, value = excluded.value`,
+		"getGaugeMetric":   fmt.Sprintf(`SELECT value FROM %s WHERE name = :name`, dbStorage.tables.gauges()),
+		"getCounterMetric": fmt.Sprintf(`SELECT delta FROM %s WHERE name = :name`, dbStorage.tables.counters()),
+		"setGaugeMetric":   dbStorage.tables.setGaugeMetricQuery(),
+		"addCounterMetric": dbStorage.tables.addCounterMetricQuery(),
+		"lockGaugeMetric":  dbStorage.tables.lockGaugeMetricQuery(),
+		"updateGaugeStats": dbStorage.tables.updateGaugeStatsQuery(),
+	}
+
+	if historyEnabled() {
+		preparesData["insertHistory"] = dbStorage.tables.insertHistoryQuery()
 	}
 
 	for key, sql := range preparesData {
-		p, err := dbStorage.db.PrepareNamedContext(ctx, sql)
+		stmt, err := dbStorage.db.PrepareNamedContext(ctx, sql)
 		if err != nil {
-			return err
+			return p, errors.Join(err, p.close())
 		}
 
 		switch key {
 		case "getGaugeMetric":
-			dbStorage.prepares.getGaugeMetric = p
+			p.getGaugeMetric = stmt
 		case "getCounterMetric":
-			dbStorage.prepares.getCounterMetric = p
-		case "setOrUpdateMetric":
-			dbStorage.prepares.setOrUpdateMetric = p
+			p.getCounterMetric = stmt
+		case "setGaugeMetric":
+			p.setGaugeMetric = stmt
+		case "addCounterMetric":
+			p.addCounterMetric = stmt
+		case "lockGaugeMetric":
+			p.lockGaugeMetric = stmt
+		case "updateGaugeStats":
+			p.updateGaugeStats = stmt
+		case "insertHistory":
+			p.insertHistory = stmt
 		}
 	}
 
-	return nil
+	return p, nil
+}
+
+// rebuildPrepares заново готовит запросы и подменяет ими текущие, например после перезапуска базы.
+func (dbStorage *databaseStorage) rebuildPrepares(ctx context.Context) error {
+	p, err := dbStorage.buildPrepares(ctx)
+	if err != nil {
+		return err
+	}
+
+	dbStorage.mx.Lock()
+	old := dbStorage.prepares
+	dbStorage.prepares = p
+	dbStorage.mx.Unlock()
+
+	return old.close()
+}
+
+func (dbStorage *databaseStorage) statements() prepares {
+	dbStorage.mx.RLock()
+	defer dbStorage.mx.RUnlock()
+
+	return dbStorage.prepares
+}
+
+func (p prepares) close() error {
+	var closeErrs []error
+	for _, stmt := range []*sqlx.NamedStmt{p.getGaugeMetric, p.getCounterMetric, p.setGaugeMetric, p.addCounterMetric, p.lockGaugeMetric, p.updateGaugeStats, p.insertHistory} {
+		if stmt != nil {
+			closeErrs = append(closeErrs, stmt.Close())
+		}
+	}
+
+	return errors.Join(closeErrs...)
 }
 
 func (dbStorage *databaseStorage) Close() error {
 	var closeErrs []error
 
-	closeErrs = append(closeErrs, dbStorage.prepares.getGaugeMetric.Close())
-	closeErrs = append(closeErrs, dbStorage.prepares.getCounterMetric.Close())
-	closeErrs = append(closeErrs, dbStorage.prepares.setOrUpdateMetric.Close())
+	if dbStorage.stopHistory != nil {
+		dbStorage.stopHistory()
+	}
+	if dbStorage.stopHealth != nil {
+		dbStorage.stopHealth()
+	}
+	if dbStorage.stopListening != nil {
+		dbStorage.stopListening()
+	}
+	health.Remove(healthComponent)
+
+	closeErrs = append(closeErrs, dbStorage.statements().close())
 	closeErrs = append(closeErrs, dbStorage.db.Close())
 
 	return errors.Join(closeErrs...)
 }
 
 func (dbStorage *databaseStorage) NewTx() (models.StorageTx, error) {
-	txDB, err := dbStorage.db.Beginx()
+	var (
+		txDB   *sqlx.Tx
+		cancel context.CancelFunc
+	)
+	err := dbStorage.withRetry("NewTx", func(_ context.Context) (err error) {
+		// Контекст попытки нельзя передавать: его отмена откатит транзакцию
+		var txCtx context.Context
+		txCtx, cancel = txContext()
+
+		if txDB, err = dbStorage.db.BeginTxx(txCtx, nil); err != nil {
+			cancel()
+		}
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	t := &tx{
-		txDB: txDB,
-		log:  dbStorage.log,
+		txDB:   txDB,
+		cancel: cancel,
+		log:    dbStorage.log,
+
+		tables:  dbStorage.tables,
+		history: dbStorage.statements().insertHistory != nil,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := queryContext(context.Background())
 	defer cancel()
 
 	if err = t.buildPrepares(ctx); err != nil {
-		return nil, err
+		return nil, errors.Join(err, t.RollBack())
 	}
 
 	return t, nil
 }
 
-func (dbStorage *databaseStorage) SetGauge(name string, value *float64) (err error) {
-	_, err = dbStorage.prepares.setOrUpdateMetric.ExecContext(context.Background(), map[string]interface{}{"name": name, "mtype": "gauge", "delta": 0, "value": value})
-	return
+func (dbStorage *databaseStorage) SetGauge(name string, value *float64) error {
+	return dbStorage.withRetry("SetGauge", func(ctx context.Context) error {
+		if _, err := dbStorage.statements().setGaugeMetric.ExecContext(ctx, map[string]interface{}{"name": name, "value": value}); err != nil {
+			return err
+		}
+
+		dbStorage.writeGaugeStats(ctx, name)
+		dbStorage.writeHistory(ctx, name, "gauge")
+		dbStorage.notify(ctx, name, "gauge")
+		return nil
+	})
 }
 
-func (dbStorage *databaseStorage) AddCounter(name string, value *int64) (err error) {
-	_, err = dbStorage.prepares.setOrUpdateMetric.ExecContext(context.Background(), map[string]interface{}{"name": name, "mtype": "counter", "delta": value, "value": 0.0})
-	return
+// AddGauge прибавляет delta к gauge в транзакции с блокировкой строки, поэтому одновременные изменения
+// от разных клиентов не теряются. К сумме применяются те же правила, что и к записываемому значению gauge.
+func (dbStorage *databaseStorage) AddGauge(name string, delta *float64) error {
+	return dbStorage.withSafeRetry("AddGauge", func(ctx context.Context) error {
+		txDB, err := dbStorage.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = txDB.Rollback() // после Commit ничего не делает
+		}()
+
+		var current float64
+		if err = txDB.NamedStmtContext(ctx, dbStorage.statements().lockGaugeMetric).GetContext(ctx, &current, map[string]interface{}{"name": name}); err != nil {
+			return err
+		}
+
+		value, keep, err := policy.AddGauge(current, *delta)
+		if err != nil || !keep {
+			return err
+		}
+
+		if _, err = txDB.NamedStmtContext(ctx, dbStorage.statements().setGaugeMetric).ExecContext(ctx, map[string]interface{}{"name": name, "value": value}); err != nil {
+			return err
+		}
+
+		if err = txDB.Commit(); err != nil {
+			return err
+		}
+
+		dbStorage.writeGaugeStats(ctx, name)
+		dbStorage.writeHistory(ctx, name, "gauge")
+		dbStorage.notify(ctx, name, "gauge")
+		return nil
+	})
+}
+
+func (dbStorage *databaseStorage) AddCounter(name string, value *int64) error {
+	if value == nil {
+		return errs.ErrCounterMissingDelta
+	}
+
+	delta, err := policy.CounterDelta(*value)
+	if err != nil {
+		return err
+	}
+
+	return dbStorage.withSafeRetry("AddCounter", func(ctx context.Context) error {
+		if _, err := dbStorage.statements().addCounterMetric.ExecContext(ctx, map[string]interface{}{"name": name, "delta": delta}); err != nil {
+			return counterError(err)
+		}
+
+		dbStorage.writeHistory(ctx, name, "counter")
+		dbStorage.notify(ctx, name, "counter")
+		return nil
+	})
+}
+
+// writeGaugeStats обновляет статистику gauge. Её ошибка не отменяет запись значения, как и ошибка истории.
+func (dbStorage *databaseStorage) writeGaugeStats(ctx context.Context, name string) {
+	if _, err := dbStorage.statements().updateGaugeStats.ExecContext(ctx, map[string]interface{}{"name": name}); err != nil {
+		dbStorage.log.Errorf("Failed to update stats of gauge %s: %s", name, err)
+	}
+}
+
+func (dbStorage *databaseStorage) writeHistory(ctx context.Context, name, mType string) {
+	insertHistory := dbStorage.statements().insertHistory
+	if insertHistory == nil {
+		return
+	}
+
+	if _, err := insertHistory.ExecContext(ctx, map[string]interface{}{"name": name, "mtype": mType}); err != nil {
+		dbStorage.log.Errorf("Failed to write history of metric %s (%s): %s", name, mType, err)
+	}
 }
 
 func (dbStorage *databaseStorage) GetGauge(name string) (value *float64, err error) {
-	err = dbStorage.prepares.getGaugeMetric.GetContext(context.Background(), &value, map[string]interface{}{"name": name})
+	err = dbStorage.withRetry("GetGauge", func(ctx context.Context) error {
+		return dbStorage.statements().getGaugeMetric.GetContext(ctx, &value, map[string]interface{}{"name": name})
+	})
 	return
 }
 
 func (dbStorage *databaseStorage) GetCounter(name string) (value *int64, err error) {
-	err = dbStorage.prepares.getCounterMetric.GetContext(context.Background(), &value, map[string]interface{}{"name": name})
+	err = dbStorage.withRetry("GetCounter", func(ctx context.Context) error {
+		return dbStorage.statements().getCounterMetric.GetContext(ctx, &value, map[string]interface{}{"name": name})
+	})
 	return
 }
 
 func (dbStorage *databaseStorage) GetAll() (metrics []models.MetricsValue, err error) {
-	err = dbStorage.db.SelectContext(context.Background(), &metrics, "SELECT name, mtype, delta, value FROM metrics")
+	err = dbStorage.withRetry("GetAll", func(ctx context.Context) error {
+		metrics = nil
+		return dbStorage.db.SelectContext(ctx, &metrics, fmt.Sprintf("SELECT name, mtype, delta, value, updated_at FROM %s", dbStorage.tables.metrics()))
+	})
+	return
+}
+
+// GetUpdatedAt возвращает время последней записи метрики.
+func (dbStorage *databaseStorage) GetUpdatedAt(mType, name string) (updatedAt time.Time, err error) {
+	err = dbStorage.withRetry("GetUpdatedAt", func(ctx context.Context) error {
+		return dbStorage.db.GetContext(ctx, &updatedAt, fmt.Sprintf(`SELECT updated_at FROM %s WHERE name = $1 AND mtype = $2`,
+			dbStorage.tables.metrics()), name, mType)
+	})
 	return
 }
 
+func (dbStorage *databaseStorage) GetGaugeStats(name string) (stats *models.GaugeStats, err error) {
+	err = dbStorage.withRetry("GetGaugeStats", func(ctx context.Context) error {
+		stats = &models.GaugeStats{}
+		err := dbStorage.db.GetContext(ctx, stats, fmt.Sprintf(`SELECT name, count, min, max,
+			CASE WHEN count = 0 THEN 0 ELSE sum / count END AS avg, since
+			FROM %s WHERE name = $1`, dbStorage.tables.gaugeStats()), name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrStorageInvalidGaugeName
+		}
+
+		return err
+	})
+	if err != nil {
+		stats = nil
+	}
+	return
+}
+
+func (dbStorage *databaseStorage) ResetGaugeStats(name string) error {
+	return dbStorage.withRetry("ResetGaugeStats", func(ctx context.Context) error {
+		res, err := dbStorage.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET count = 0, sum = 0, min = 0, max = 0, since = now() WHERE name = $1`,
+			dbStorage.tables.gaugeStats()), name)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := res.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return errs.ErrStorageInvalidGaugeName
+		}
+
+		return nil
+	})
+}
+
+func counterError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22003" { // numeric_value_out_of_range
+		return fmt.Errorf("%w: %w", errs.ErrCounterOverflow, err)
+	}
+
+	return err
+}
+
+// txContext - контекст на всё время жизни транзакции, включая Commit: по его истечении транзакция откатывается.
+func txContext() (context.Context, context.CancelFunc) {
+	timeout := config.Config.DatabaseTxTimeout
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+func queryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := config.Config.DatabaseQueryTimeout
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, timeout)
+}
+
 func (dbStorage *databaseStorage) Ping(ctx context.Context) error {
 	return dbStorage.db.PingContext(ctx)
 }
@@ -162,8 +460,11 @@ func (dbStorage *databaseStorage) GetMiddleware() gin.HandlerFunc {
 }
 
 func (dbStorage *databaseStorage) String() string {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+
 	var databaseName string
-	_ = dbStorage.db.Get(&databaseName, "SELECT current_database()")
+	_ = dbStorage.db.GetContext(ctx, &databaseName, "SELECT current_database()")
 
 	if databaseName == "" {
 		databaseName = "(Error: Invalid database name)"
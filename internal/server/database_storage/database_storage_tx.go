@@ -2,46 +2,152 @@ package dbstorage
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jmoiron/sqlx"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/database"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 type tx struct {
-	txDB                     *sqlx.Tx
-	prepareSetOrUpdateMetric *sqlx.NamedStmt
+	txDB                    *sqlx.Tx
+	cancel                  context.CancelFunc
+	prepareSetGaugeMetric   *sqlx.NamedStmt
+	prepareAddCounterMetric *sqlx.NamedStmt
+	prepareUpdateGaugeStats *sqlx.NamedStmt
+	prepareInsertHistory    *sqlx.NamedStmt
 
-	log logger.Logger
+	tables  tables
+	history bool
+	log     logger.Logger
+
+	// changes - записанные метрики для уведомления при Commit
+	changes models.MetricsChange
 }
 
 func (t *tx) buildPrepares(ctx context.Context) (err error) {
-	t.prepareSetOrUpdateMetric, err = t.txDB.PrepareNamedContext(
-		ctx,
-		`INSERT INTO metrics (name, mtype, delta, value) 
-				VALUES (:name, :mtype, :delta, :value)
-			ON CONFLICT (name, mtype) DO 
-			    UPDATE SET delta = metrics.delta + excluded.delta, value = excluded.value`,
-	)
+	t.prepareSetGaugeMetric, err = t.txDB.PrepareNamedContext(ctx, t.tables.setGaugeMetricQuery())
+	if err != nil {
+		return
+	}
+
+	t.prepareAddCounterMetric, err = t.txDB.PrepareNamedContext(ctx, t.tables.addCounterMetricQuery())
+	if err != nil {
+		return
+	}
+
+	t.prepareUpdateGaugeStats, err = t.txDB.PrepareNamedContext(ctx, t.tables.updateGaugeStatsQuery())
+	if err != nil || !t.history {
+		return
+	}
+
+	t.prepareInsertHistory, err = t.txDB.PrepareNamedContext(ctx, t.tables.insertHistoryQuery())
 	return
 }
 
 func (t *tx) SetGauge(name string, value *float64) (err error) {
-	_, err = t.prepareSetOrUpdateMetric.ExecContext(context.Background(), map[string]interface{}{"name": name, "mtype": "gauge", "delta": 0, "value": value})
+	ctx, cancel := queryContext(database.WithQueryName(context.Background(), "Tx.SetGauge"))
+	defer cancel()
+
+	if _, err = t.prepareSetGaugeMetric.ExecContext(ctx, map[string]interface{}{"name": name, "value": value}); err != nil {
+		return
+	}
+
+	// В транзакции ошибка статистики отменяет пакет: после ошибки запроса транзакция всё равно непригодна
+	if _, err = t.prepareUpdateGaugeStats.ExecContext(ctx, map[string]interface{}{"name": name}); err != nil {
+		return
+	}
+
+	if err = t.writeHistory(ctx, name, "gauge"); err != nil {
+		return
+	}
+
+	t.changed(name, "gauge")
 	return
 }
 
 func (t *tx) AddCounter(name string, value *int64) (err error) {
-	_, err = t.prepareSetOrUpdateMetric.ExecContext(context.Background(), map[string]interface{}{"name": name, "mtype": "counter", "delta": value, "value": 0.0})
+	if value == nil {
+		return errs.ErrCounterMissingDelta
+	}
+
+	delta, err := policy.CounterDelta(*value)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := queryContext(database.WithQueryName(context.Background(), "Tx.AddCounter"))
+	defer cancel()
+
+	if _, err = t.prepareAddCounterMetric.ExecContext(ctx, map[string]interface{}{"name": name, "delta": delta}); err != nil {
+		return counterError(err)
+	}
+
+	if err = t.writeHistory(ctx, name, "counter"); err != nil {
+		return
+	}
+
+	t.changed(name, "counter")
 	return
 }
 
+func (t *tx) changed(name, mType string) {
+	if notifyEnabled() {
+		t.changes.Add(name, mType)
+	}
+}
+
+func (t *tx) writeHistory(ctx context.Context, name, mType string) error {
+	if t.prepareInsertHistory == nil {
+		return nil
+	}
+
+	return t.withHistorySavepoint(ctx, func() error {
+		_, err := t.prepareInsertHistory.ExecContext(ctx, map[string]interface{}{"name": name, "mtype": mType})
+		if err != nil {
+			t.log.Errorf("Failed to write history of metric %s (%s): %s", name, mType, err)
+		}
+		return err
+	})
+}
+
+// withHistorySavepoint выполняет запись истории внутри точки сохранения. Ошибка записи, как и вне транзакции, только логируется
+// в write, а откат к точке сохранения оставляет транзакцию пригодной. Возвращаются лишь ошибки самих точек сохранения.
+func (t *tx) withHistorySavepoint(ctx context.Context, write func() error) error {
+	if _, err := t.txDB.ExecContext(ctx, "SAVEPOINT history"); err != nil {
+		return err
+	}
+
+	if err := write(); err != nil {
+		_, err = t.txDB.ExecContext(ctx, "ROLLBACK TO SAVEPOINT history")
+		return err
+	}
+
+	_, err := t.txDB.ExecContext(ctx, "RELEASE SAVEPOINT history")
+	return err
+}
+
 func (t *tx) Commit() (err error) {
+	defer t.cancel()
+
+	ctx, cancel := queryContext(database.WithQueryName(context.Background(), "Tx.Notify"))
+	defer cancel()
+
+	if err = notifyChanges(ctx, t.txDB, t.tables, t.changes); err != nil {
+		return errors.Join(err, t.txDB.Rollback())
+	}
+
 	err = t.txDB.Commit()
 	return
 }
 
 func (t *tx) RollBack() (err error) {
+	defer t.cancel()
+
 	err = t.txDB.Rollback()
 	return
 }
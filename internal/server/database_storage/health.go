@@ -0,0 +1,55 @@
+package dbstorage
+
+import (
+	"context"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/health"
+)
+
+const healthComponent = "database"
+
+// startHealthWatchdog периодически проверяет базу, обновляет состояние для /readyz и после восстановления
+// соединения заново готовит запросы.
+func (dbStorage *databaseStorage) startHealthWatchdog() {
+	interval := config.Config.DatabaseHealthInterval
+	if interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dbStorage.stopHealth = cancel
+
+	go func() {
+		ticker := dbStorage.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				dbStorage.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (dbStorage *databaseStorage) checkHealth(ctx context.Context) {
+	pingCtx, cancel := queryContext(ctx)
+	err := dbStorage.Ping(pingCtx)
+	cancel()
+
+	if err == nil && !dbStorage.healthy.Load() {
+		dbStorage.log.Infof("The database is available again, rebuilding prepared statements...")
+
+		if err = dbStorage.rebuildPrepares(ctx); err != nil {
+			dbStorage.log.Errorf("Failed to rebuild prepared statements: %s", err)
+		}
+	}
+
+	if wasHealthy := dbStorage.healthy.Swap(err == nil); wasHealthy && err != nil {
+		dbStorage.log.Errorf("The database became unavailable: %s", err)
+	}
+	health.Set(healthComponent, err)
+}
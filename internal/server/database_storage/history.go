@@ -0,0 +1,261 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+const (
+	historyMaintenanceInterval = time.Hour
+	historyPartitionsAhead     = 2
+	historyPartitionLayout     = "20060102"
+
+	historyMaintenanceJob = "history_maintenance"
+)
+
+func historyEnabled() bool {
+	return config.Config.HistoryPartition != ""
+}
+
+func (dbStorage *databaseStorage) createHistory(ctx context.Context) error {
+	schema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"name" TEXT NOT NULL,
+		"mtype" VARCHAR(12) NOT NULL,
+		"delta" BIGINT NOT NULL DEFAULT 0,
+		"value" DOUBLE PRECISION NOT NULL DEFAULT 0.0,
+		"created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	) PARTITION BY RANGE (created_at)`, dbStorage.tables.history())
+
+	if _, err := dbStorage.db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	index := fmt.Sprintf(
+		`CREATE INDEX IF NOT EXISTS %s ON %s (name, mtype, created_at)`,
+		dbStorage.tables.ident(dbStorage.tables.name("metrics_history_name_mtype_created_at")), dbStorage.tables.history(),
+	)
+
+	if _, err := dbStorage.db.ExecContext(ctx, index); err != nil {
+		return err
+	}
+
+	// Сюда попадают записи, для которых ещё нет секции, например если обслуживание отстало
+	_, err := dbStorage.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s DEFAULT`, dbStorage.tables.historyDefault(), dbStorage.tables.history(),
+	))
+	return err
+}
+
+func (dbStorage *databaseStorage) startHistoryMaintenance() {
+	ctx, cancel := context.WithCancel(context.Background())
+	dbStorage.stopHistory = cancel
+
+	go func() {
+		ticker := dbStorage.clock.NewTicker(historyMaintenanceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				if _, err := dbStorage.runExclusive(ctx, historyMaintenanceJob, false, dbStorage.maintainHistory); err != nil {
+					dbStorage.log.Errorf("Failed to maintain history partitions: %s", err)
+				}
+			}
+		}
+	}()
+}
+
+// historyPartition - секция истории с границами из pg_class.relpartbound. У секции DEFAULT границ нет.
+type historyPartition struct {
+	Name  string     `db:"name"`
+	Start *time.Time `db:"range_start"`
+	End   *time.Time `db:"range_end"`
+}
+
+type historyRange struct {
+	start, end time.Time
+}
+
+func (dbStorage *databaseStorage) maintainHistory(ctx context.Context) error {
+	now := dbStorage.clock.Now().UTC()
+
+	partitions, err := dbStorage.historyPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range missingHistoryPartitions(partitions, now) {
+		if err = dbStorage.createHistoryPartition(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	retention := config.Config.HistoryRetention
+	if retention <= 0 {
+		return nil
+	}
+
+	threshold := now.Add(-retention)
+	for _, partition := range expiredHistoryPartitions(partitions, threshold) {
+		table := dbStorage.tables.qualified(strings.TrimPrefix(partition.Name, dbStorage.tables.prefix))
+		if _, err = dbStorage.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table)); err != nil {
+			return err
+		}
+		dbStorage.log.Infof("History partition %s has been dropped by retention policy.", partition.Name)
+	}
+
+	_, err = dbStorage.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE created_at < $1", dbStorage.tables.historyDefault()), threshold)
+	return err
+}
+
+// historyPartitions читает секции истории и их границы. Границы разбирает сам Postgres, поэтому
+// они не зависят от часового пояса сессии и от того, с каким HISTORY_PARTITION секция создавалась.
+func (dbStorage *databaseStorage) historyPartitions(ctx context.Context) (partitions []historyPartition, err error) {
+	err = dbStorage.db.SelectContext(ctx, &partitions, `SELECT child.relname AS name,
+			(regexp_match(pg_get_expr(child.relpartbound, child.oid), 'FROM \(''([^'']+)''\)'))[1]::timestamptz AS range_start,
+			(regexp_match(pg_get_expr(child.relpartbound, child.oid), 'TO \(''([^'']+)''\)'))[1]::timestamptz AS range_end
+		FROM pg_inherits
+			JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+			JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+			JOIN pg_namespace ns ON ns.oid = parent.relnamespace
+		WHERE parent.relname = $1 AND ns.nspname = COALESCE(NULLIF($2, ''), current_schema())`,
+		dbStorage.tables.name("metrics_history"), dbStorage.tables.schema,
+	)
+	return
+}
+
+// createHistoryPartition создаёт секцию и переносит в неё записи этого периода из секции DEFAULT:
+// пока они там, Postgres не даст подключить секцию с тем же диапазоном.
+func (dbStorage *databaseStorage) createHistoryPartition(ctx context.Context, r historyRange) error {
+	txDB, err := dbStorage.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = txDB.Rollback() // после Commit ничего не делает
+	}()
+
+	partition := dbStorage.tables.qualified(historyPartitionName(r.start))
+	create := fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, partition, dbStorage.tables.history())
+	if _, err = txDB.ExecContext(ctx, create); err != nil {
+		return err
+	}
+
+	move := fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE created_at >= $1 AND created_at < $2 RETURNING *)
+		INSERT INTO %s SELECT * FROM moved`, dbStorage.tables.historyDefault(), partition)
+	if _, err = txDB.ExecContext(ctx, move, r.start, r.end); err != nil {
+		return err
+	}
+
+	attach := fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')`,
+		dbStorage.tables.history(), partition, r.start.Format(time.RFC3339), r.end.Format(time.RFC3339))
+	if _, err = txDB.ExecContext(ctx, attach); err != nil {
+		return err
+	}
+
+	return txDB.Commit()
+}
+
+// missingHistoryPartitions возвращает диапазоны текущего и historyPartitionsAhead следующих периодов, которые не покрыты
+// секциями. Секции, созданные с другим HISTORY_PARTITION, остаются как есть, новые заполняют только промежутки между ними.
+func missingHistoryPartitions(partitions []historyPartition, now time.Time) []historyRange {
+	var bounded []historyPartition
+	for _, partition := range partitions {
+		if partition.Start != nil && partition.End != nil {
+			bounded = append(bounded, partition)
+		}
+	}
+	sort.Slice(bounded, func(i, j int) bool {
+		return bounded[i].Start.Before(*bounded[j].Start)
+	})
+
+	var missing []historyRange
+
+	start := historyPeriodStart(now)
+	for i := 0; i <= historyPartitionsAhead; i++ {
+		end := historyPeriodNext(start)
+
+		cursor := start
+		for _, partition := range bounded {
+			if !partition.End.After(cursor) {
+				continue
+			}
+			if !partition.Start.Before(end) {
+				break
+			}
+
+			if partition.Start.After(cursor) {
+				missing = append(missing, historyRange{start: cursor, end: *partition.Start})
+			}
+			cursor = *partition.End
+		}
+
+		if cursor.Before(end) {
+			missing = append(missing, historyRange{start: cursor, end: end})
+		}
+
+		start = end
+	}
+
+	return missing
+}
+
+// expiredHistoryPartitions возвращает секции, все записи которых старше threshold. Конец секции берётся из её
+// границ, а не из текущей длины периода.
+func expiredHistoryPartitions(partitions []historyPartition, threshold time.Time) []historyPartition {
+	var expired []historyPartition
+	for _, partition := range partitions {
+		if partition.End != nil && !partition.End.After(threshold) {
+			expired = append(expired, partition)
+		}
+	}
+
+	return expired
+}
+
+func historyPeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	if config.Config.HistoryPartition == "week" {
+		weekday := (int(day.Weekday()) + 6) % 7 // Неделя начинается с понедельника
+		return day.AddDate(0, 0, -weekday)
+	}
+
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func historyPeriodNext(start time.Time) time.Time {
+	if config.Config.HistoryPartition == "week" {
+		return start.AddDate(0, 0, 7)
+	}
+
+	return start.AddDate(0, 1, 0)
+}
+
+func historyPartitionName(start time.Time) string {
+	return "metrics_history_p" + start.Format(historyPartitionLayout)
+}
+
+// GetCounterHistory возвращает записи истории counter начиная с since.
+func (dbStorage *databaseStorage) GetCounterHistory(name string, since time.Time) (samples []models.CounterSample, err error) {
+	if !historyEnabled() {
+		return nil, errs.ErrHistoryUnsupported
+	}
+
+	err = dbStorage.withRetry("GetCounterHistory", func(ctx context.Context) error {
+		samples = nil
+		return dbStorage.db.SelectContext(ctx, &samples, fmt.Sprintf(`SELECT delta, created_at FROM %s
+			WHERE name = $1 AND mtype = 'counter' AND created_at >= $2 ORDER BY created_at`, dbStorage.tables.history()), name, since)
+	})
+	return
+}
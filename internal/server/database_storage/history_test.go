@@ -0,0 +1,113 @@
+package dbstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+func TestHistoryPartitions(t *testing.T) {
+	tests := []struct {
+		name            string
+		partition       string
+		now             time.Time
+		wantedStart     time.Time
+		wantedNext      time.Time
+		wantedPartition string
+	}{
+		{
+			name:            "Month",
+			partition:       "month",
+			now:             time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC),
+			wantedStart:     time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC),
+			wantedNext:      time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC),
+			wantedPartition: "metrics_history_p20231001",
+		},
+		{
+			name:            "Month (end of year)",
+			partition:       "month",
+			now:             time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC),
+			wantedStart:     time.Date(2023, time.December, 1, 0, 0, 0, 0, time.UTC),
+			wantedNext:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantedPartition: "metrics_history_p20231201",
+		},
+		{
+			name:            "Week",
+			partition:       "week",
+			now:             time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC),
+			wantedStart:     time.Date(2023, time.October, 9, 0, 0, 0, 0, time.UTC),
+			wantedNext:      time.Date(2023, time.October, 16, 0, 0, 0, 0, time.UTC),
+			wantedPartition: "metrics_history_p20231009",
+		},
+		{
+			name:            "Week (monday)",
+			partition:       "week",
+			now:             time.Date(2023, time.October, 16, 0, 0, 0, 0, time.UTC),
+			wantedStart:     time.Date(2023, time.October, 16, 0, 0, 0, 0, time.UTC),
+			wantedNext:      time.Date(2023, time.October, 23, 0, 0, 0, 0, time.UTC),
+			wantedPartition: "metrics_history_p20231016",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.HistoryPartition = tt.partition
+			defer func() { config.Config.HistoryPartition = "" }()
+
+			start := historyPeriodStart(tt.now)
+			assert.Equal(t, tt.wantedStart, start)
+			assert.Equal(t, tt.wantedNext, historyPeriodNext(start))
+
+			assert.Equal(t, tt.wantedPartition, historyPartitionName(start))
+		})
+	}
+}
+
+func historyTestPartition(name string, start, end time.Time) historyPartition {
+	return historyPartition{Name: name, Start: &start, End: &end}
+}
+
+func TestHistoryPartitionSwitch(t *testing.T) {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Секции, созданные при HISTORY_PARTITION=week
+	partitions := []historyPartition{
+		{Name: "metrics_history_default"},
+		historyTestPartition("metrics_history_p20231002", day(time.October, 2), day(time.October, 9)),
+		historyTestPartition("metrics_history_p20231009", day(time.October, 9), day(time.October, 16)),
+		historyTestPartition("metrics_history_p20231016", day(time.October, 16), day(time.October, 23)),
+		historyTestPartition("metrics_history_p20231023", day(time.October, 23), day(time.October, 30)),
+	}
+
+	config.Config.HistoryPartition = "month"
+	defer func() { config.Config.HistoryPartition = "" }()
+
+	missing := missingHistoryPartitions(partitions, day(time.October, 15))
+	assert.Equal(t, []historyRange{
+		{start: day(time.October, 1), end: day(time.October, 2)},
+		{start: day(time.October, 30), end: day(time.November, 1)},
+		{start: day(time.November, 1), end: day(time.December, 1)},
+		{start: day(time.December, 1), end: day(time.January, 1).AddDate(1, 0, 0)},
+	}, missing)
+
+	// Вернувшись к неделям, новые секции не пересекаются ни с неделями, ни с месяцами
+	for _, r := range missing {
+		partitions = append(partitions, historyTestPartition(historyPartitionName(r.start), r.start, r.end))
+	}
+	config.Config.HistoryPartition = "week"
+	assert.Equal(t, []historyRange{
+		{start: day(time.January, 1).AddDate(1, 0, 0), end: day(time.January, 8).AddDate(1, 0, 0)},
+	}, missingHistoryPartitions(partitions, day(time.December, 20)))
+
+	// Неделя истекает по своей границе, а не через месяц от начала
+	var expired []string
+	for _, partition := range expiredHistoryPartitions(partitions, day(time.October, 17)) {
+		expired = append(expired, partition.Name)
+	}
+	assert.ElementsMatch(t, []string{"metrics_history_p20231001", "metrics_history_p20231002", "metrics_history_p20231009"}, expired)
+}
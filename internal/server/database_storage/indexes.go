@@ -0,0 +1,68 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+const metricsIndexesJob = "metrics_indexes"
+
+// indexColumns - колонки дополнительных индексов из config.Config.DatabaseIndexes.
+var indexColumns = map[string]string{
+	config.IndexNamePrefix: `"name" text_pattern_ops`,
+	config.IndexUpdatedAt:  `"updated_at"`,
+}
+
+// createIndexes создаёт перечисленные в настройках индексы таблиц gauge и counter. Индексы, которые убрали
+// из настроек, не удаляются. При массовой загрузке создание можно отложить до перезапуска без DATABASE_SKIP_INDEXES.
+func (dbStorage *databaseStorage) createIndexes(ctx context.Context) error {
+	if len(config.Config.DatabaseIndexes) == 0 {
+		return nil
+	}
+
+	if config.Config.DatabaseSkipIndexes {
+		dbStorage.log.Infof("Creation of the %s indexes is skipped, restart without -db-skip-indexes to build them.", strings.Join(config.Config.DatabaseIndexes, ", "))
+		return nil
+	}
+
+	_, err := dbStorage.runExclusive(ctx, metricsIndexesJob, true, func(ctx context.Context) error {
+		for _, index := range config.Config.DatabaseIndexes {
+			for _, table := range []string{"gauges", "counters"} {
+				name := dbStorage.tables.ident(dbStorage.tables.name(fmt.Sprintf("%s_%s_idx", table, index)))
+
+				query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", name, dbStorage.tables.qualified(table), indexColumns[index])
+				if _, err := dbStorage.db.ExecContext(ctx, query); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	return err
+}
+
+// IndexStats возвращает статистику использования индексов таблиц хранилища, включая секции истории.
+func (dbStorage *databaseStorage) IndexStats(ctx context.Context) ([]models.IndexStats, error) {
+	tables := []string{dbStorage.tables.gauges(), dbStorage.tables.counters(), dbStorage.tables.gaugeStats(), dbStorage.tables.sources(), dbStorage.tables.apiKeys(), dbStorage.tables.history()}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	stats := make([]models.IndexStats, 0)
+	err := dbStorage.db.SelectContext(ctx, &stats, `SELECT s.relname AS table_name, s.indexrelname AS index_name,
+			s.idx_scan AS scans, s.idx_tup_read AS tuples_read, s.idx_tup_fetch AS tuples_fetched,
+			pg_relation_size(s.indexrelid) AS size_bytes
+		FROM unnest($1::text[]) AS t(name), pg_partition_tree(to_regclass(t.name)) AS tree
+			JOIN pg_stat_user_indexes s ON s.relid = tree.relid
+		ORDER BY s.relname, s.indexrelname`, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
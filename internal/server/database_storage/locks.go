@@ -0,0 +1,51 @@
+package dbstorage
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// jobLockKey - ключ advisory-блокировки фоновой задачи. В него входят схема и префикс таблиц, чтобы серверы
+// с разными таблицами в одной базе друг друга не блокировали.
+func (t tables) jobLockKey(job string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("job:" + t.qualified(job)))
+
+	return int64(h.Sum64())
+}
+
+// runExclusive выполняет фоновую задачу под advisory-блокировкой, чтобы из нескольких серверов с общей базой
+// её выполнял только один. Без wait задача пропускается, если блокировку уже держит другой сервер,
+// и возвращается false; с wait - дожидается её.
+func (dbStorage *databaseStorage) runExclusive(ctx context.Context, job string, wait bool, fn func(ctx context.Context) error) (bool, error) {
+	// Блокировка сессионная: снять её можно только на том же соединении
+	conn, err := dbStorage.db.Connx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	key := dbStorage.tables.jobLockKey(job)
+	if wait {
+		if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			return false, err
+		}
+	} else {
+		var locked bool
+		if err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", key); err != nil {
+			return false, err
+		} else if !locked {
+			dbStorage.log.Debugf("The %s job is running on another instance, skipping it.", job)
+			return false, nil
+		}
+	}
+
+	defer func() {
+		// Контекст задачи может быть уже отменён, а блокировку нужно снять в любом случае
+		if _, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); unlockErr != nil {
+			dbStorage.log.Errorf("Failed to release the lock of the %s job: %s", job, unlockErr)
+		}
+	}()
+
+	return true, fn(ctx)
+}
@@ -0,0 +1,16 @@
+package dbstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobLockKey(t *testing.T) {
+	plain := tables{}
+	prefixed := tables{prefix: "staging_"}
+
+	assert.Equal(t, plain.jobLockKey(historyMaintenanceJob), plain.jobLockKey(historyMaintenanceJob))
+	assert.NotEqual(t, plain.jobLockKey(historyMaintenanceJob), plain.jobLockKey("rollup"), "different jobs must not block each other")
+	assert.NotEqual(t, plain.jobLockKey(historyMaintenanceJob), prefixed.jobLockKey(historyMaintenanceJob), "servers with different tables must not block each other")
+}
@@ -0,0 +1,113 @@
+package dbstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const metricsMigrationJob = "metrics_migration"
+
+// createMetrics создаёт отдельные таблицы gauge и counter и представление с прежней таблицей метрик.
+// Если прежняя общая таблица ещё есть, метрики переносятся из неё (см. migrateMetrics), затем создаются
+// дополнительные индексы из настроек (см. createIndexes).
+func (dbStorage *databaseStorage) createMetrics(ctx context.Context) error {
+	gaugesSchema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"name" TEXT NOT NULL PRIMARY KEY,
+		"value" DOUBLE PRECISION NOT NULL,
+		"updated_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`, dbStorage.tables.gauges())
+
+	if _, err := dbStorage.db.ExecContext(ctx, gaugesSchema); err != nil {
+		return err
+	}
+
+	countersSchema := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"name" TEXT NOT NULL PRIMARY KEY,
+		"delta" BIGINT NOT NULL,
+		"updated_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`, dbStorage.tables.counters())
+
+	if _, err := dbStorage.db.ExecContext(ctx, countersSchema); err != nil {
+		return err
+	}
+
+	// Серверы, которые запускаются одновременно, не должны переносить метрики дважды
+	if _, err := dbStorage.runExclusive(ctx, metricsMigrationJob, true, dbStorage.migrateMetrics); err != nil {
+		return err
+	}
+
+	return dbStorage.createIndexes(ctx)
+}
+
+// migrateMetrics переносит метрики из общей таблицы с колонками delta и value для обоих типов в таблицы
+// gauges и counters и ставит на её место представление. Метрики, которые уже есть в новых таблицах, не меняются.
+func (dbStorage *databaseStorage) migrateMetrics(ctx context.Context) error {
+	var kind string
+	err := dbStorage.db.GetContext(ctx, &kind, `SELECT relkind::text FROM pg_class WHERE oid = to_regclass($1)`, dbStorage.tables.metrics())
+	if errors.Is(err, sql.ErrNoRows) {
+		kind = ""
+	} else if err != nil {
+		return err
+	}
+
+	// "r" - обычная таблица, после переноса на её месте представление ("v")
+	if kind != "r" {
+		_, err = dbStorage.db.ExecContext(ctx, dbStorage.tables.metricsViewQuery())
+		return err
+	}
+
+	txDB, err := dbStorage.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var gauges, counters int64
+	if gauges, counters, err = dbStorage.moveMetrics(ctx, txDB); err != nil {
+		return errors.Join(err, txDB.Rollback())
+	}
+
+	if err = txDB.Commit(); err != nil {
+		return err
+	}
+	dbStorage.log.Infof("Moved %d gauges and %d counters from the combined metrics table to separate tables.", gauges, counters)
+
+	return nil
+}
+
+func (dbStorage *databaseStorage) moveMetrics(ctx context.Context, txDB sqlx.ExecerContext) (gauges int64, counters int64, err error) {
+	metrics := dbStorage.tables.metrics()
+
+	// Колонка появилась позже таблицы, поэтому в старых базах её может не быть
+	if _, err = txDB.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMPTZ NOT NULL DEFAULT now()`, metrics)); err != nil {
+		return
+	}
+
+	result, err := txDB.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (name, value, updated_at)
+		SELECT name, value, updated_at FROM %s WHERE mtype = 'gauge' ON CONFLICT (name) DO NOTHING`, dbStorage.tables.gauges(), metrics))
+	if err != nil {
+		return
+	}
+	if gauges, err = result.RowsAffected(); err != nil {
+		return
+	}
+
+	result, err = txDB.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (name, delta, updated_at)
+		SELECT name, delta, updated_at FROM %s WHERE mtype = 'counter' ON CONFLICT (name) DO NOTHING`, dbStorage.tables.counters(), metrics))
+	if err != nil {
+		return
+	}
+	if counters, err = result.RowsAffected(); err != nil {
+		return
+	}
+
+	if _, err = txDB.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", metrics)); err != nil {
+		return
+	}
+
+	_, err = txDB.ExecContext(ctx, dbStorage.tables.metricsViewQuery())
+	return
+}
@@ -0,0 +1,69 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// NormalizeNames переводит имена метрик в нижний регистр и возвращает число затронутых метрик. Дубликаты
+// счётчиков суммируются, у дубликатов gauge остаётся значение метрики, которая уже была в нижнем регистре,
+// а если такой не было - последнее записанное.
+func (dbStorage *databaseStorage) NormalizeNames() (int, error) {
+	gaugesQuery := fmt.Sprintf(`WITH mixed AS (
+			DELETE FROM %[1]s WHERE name <> lower(name) RETURNING name, value, updated_at
+		), merged AS (
+			SELECT lower(name) AS name, (array_agg(value ORDER BY updated_at DESC))[1] AS value, MAX(updated_at) AS updated_at
+			FROM mixed GROUP BY lower(name)
+		)
+		INSERT INTO %[1]s AS m (name, value, updated_at) SELECT name, value, updated_at FROM merged
+		ON CONFLICT (name) DO
+		    UPDATE SET value = m.value, updated_at = GREATEST(m.updated_at, excluded.updated_at)`, dbStorage.tables.gauges())
+
+	countersQuery := fmt.Sprintf(`WITH mixed AS (
+			DELETE FROM %[1]s WHERE name <> lower(name) RETURNING name, delta, updated_at
+		), merged AS (
+			SELECT lower(name) AS name, SUM(delta)::bigint AS delta, MAX(updated_at) AS updated_at
+			FROM mixed GROUP BY lower(name)
+		)
+		INSERT INTO %[1]s AS m (name, delta, updated_at) SELECT name, delta, updated_at FROM merged
+		ON CONFLICT (name) DO
+		    UPDATE SET delta = %[2]s, updated_at = GREATEST(m.updated_at, excluded.updated_at)`, dbStorage.tables.counters(), counterSumExpression())
+
+	// Статистика переезжает вместе с gauge, но только если у имени в нижнем регистре своей ещё нет
+	statsQuery := fmt.Sprintf(`WITH mixed AS (
+			DELETE FROM %[1]s WHERE name <> lower(name) RETURNING name, count, sum, min, max, since
+		)
+		INSERT INTO %[1]s (name, count, sum, min, max, since)
+		SELECT DISTINCT ON (lower(name)) lower(name), count, sum, min, max, since FROM mixed
+		ON CONFLICT (name) DO NOTHING`, dbStorage.tables.gaugeStats())
+
+	var count int64
+	err := dbStorage.withRetry("NormalizeNames", func(ctx context.Context) error {
+		count = 0
+		for _, query := range []string{gaugesQuery, countersQuery} {
+			result, err := dbStorage.db.ExecContext(ctx, query)
+			if err != nil {
+				return counterError(err)
+			}
+
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			count += affected
+		}
+
+		if _, err := dbStorage.db.ExecContext(ctx, statsQuery); err != nil || count == 0 {
+			return err
+		}
+
+		if err := notifyChanges(ctx, dbStorage.db, dbStorage.tables, models.MetricsChange{All: true}); err != nil {
+			dbStorage.log.Errorf("Failed to notify about normalized names: %s", err)
+		}
+		return nil
+	})
+
+	return int(count), err
+}
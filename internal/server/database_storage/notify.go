@@ -0,0 +1,157 @@
+package dbstorage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+const (
+	// notifyPayloadLimit - NOTIFY принимает не больше 8000 байт, длинные списки заменяются на all.
+	notifyPayloadLimit = 7900
+
+	listenRetryInterval = time.Second * 5
+)
+
+// instanceID отличает уведомления этого процесса от уведомлений других экземпляров сервера.
+var instanceID = newInstanceID()
+
+type changeNotification struct {
+	Instance string `json:"instance"`
+	models.MetricsChange
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func notifyEnabled() bool {
+	return config.Config.DatabaseNotify
+}
+
+// notifyChanges сообщает другим экземплярам о записанных метриках. Внутри транзакции уведомление уходит
+// только после Commit.
+func notifyChanges(ctx context.Context, db sqlx.ExecerContext, t tables, change models.MetricsChange) error {
+	if !notifyEnabled() || change.Empty() {
+		return nil
+	}
+
+	payload, err := json.Marshal(changeNotification{Instance: instanceID, MetricsChange: change})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > notifyPayloadLimit {
+		if payload, err = json.Marshal(changeNotification{Instance: instanceID, MetricsChange: models.MetricsChange{All: true}}); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, "SELECT pg_notify($1, $2)", t.changesChannel(), string(payload))
+	return err
+}
+
+// notify - notifyChanges для записей вне транзакции. Как и у истории, ошибка только пишется в журнал.
+func (dbStorage *databaseStorage) notify(ctx context.Context, name, mType string) {
+	var change models.MetricsChange
+	change.Add(name, mType)
+
+	if err := notifyChanges(ctx, dbStorage.db, dbStorage.tables, change); err != nil {
+		dbStorage.log.Errorf("Failed to notify about metric %s (%s): %s", name, mType, err)
+	}
+}
+
+// WatchChanges подписывает fn на метрики, записанные другими экземплярами сервера с этой же базой.
+func (dbStorage *databaseStorage) WatchChanges(fn func(models.MetricsChange)) {
+	dbStorage.watchersMx.Lock()
+	defer dbStorage.watchersMx.Unlock()
+
+	dbStorage.watchers = append(dbStorage.watchers, fn)
+}
+
+func (dbStorage *databaseStorage) publish(change models.MetricsChange) {
+	dbStorage.watchersMx.Lock()
+	defer dbStorage.watchersMx.Unlock()
+
+	for _, fn := range dbStorage.watchers {
+		fn(change)
+	}
+}
+
+// startListening слушает канал уведомлений на отдельном соединении из пула и переподключается при обрыве.
+func (dbStorage *databaseStorage) startListening() {
+	ctx, cancel := context.WithCancel(context.Background())
+	dbStorage.stopListening = cancel
+
+	go func() {
+		for {
+			err := dbStorage.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Пока соединения не было, уведомления терялись
+			dbStorage.publish(models.MetricsChange{All: true})
+
+			dbStorage.log.Errorf("Lost the connection listening for changes: %s. Reconnecting after %s...", err, listenRetryInterval)
+			if clock.Sleep(ctx, dbStorage.clock, listenRetryInterval) != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (dbStorage *databaseStorage) listen(ctx context.Context) error {
+	conn, err := dbStorage.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+dbStorage.tables.ident(dbStorage.tables.changesChannel())); err != nil {
+			return err
+		}
+
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			dbStorage.handleNotification(notification.Payload)
+		}
+	})
+
+	// Соединение с LISTEN нельзя возвращать в пул: driver.ErrBadConn заставляет database/sql его закрыть
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	return err
+}
+
+func (dbStorage *databaseStorage) handleNotification(payload string) {
+	var notification changeNotification
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		dbStorage.log.Errorf("Invalid change notification %q: %s", payload, err)
+		notification = changeNotification{MetricsChange: models.MetricsChange{All: true}}
+	}
+
+	if notification.Instance == instanceID || notification.Empty() {
+		return
+	}
+
+	dbStorage.publish(notification.MetricsChange)
+}
@@ -0,0 +1,53 @@
+package dbstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestHandleNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		wanted  []models.MetricsChange
+	}{
+		{
+			name:    "Other instance",
+			payload: `{"instance":"other","gauges":["Alloc"],"counters":["PollCount"]}`,
+			wanted:  []models.MetricsChange{{Gauges: []string{"Alloc"}, Counters: []string{"PollCount"}}},
+		},
+		{
+			name:    "Own instance",
+			payload: `{"instance":"` + instanceID + `","gauges":["Alloc"]}`,
+		},
+		{
+			name:    "Too many metrics",
+			payload: `{"instance":"other","all":true}`,
+			wanted:  []models.MetricsChange{{All: true}},
+		},
+		{
+			name:    "Invalid payload",
+			payload: `Alloc`,
+			wanted:  []models.MetricsChange{{All: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbStorage := &databaseStorage{log: logger.Wrap(zaptest.NewLogger(t).Sugar())}
+
+			var changes []models.MetricsChange
+			dbStorage.WatchChanges(func(change models.MetricsChange) {
+				changes = append(changes, change)
+			})
+
+			dbStorage.handleNotification(tt.payload)
+			assert.Equal(t, tt.wanted, changes)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package dbstorage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/database"
+)
+
+// withRetry выполняет fn по политике повторов из конфига. Каждая попытка получает свой таймаут запроса.
+// Пока проверка здоровья считает базу недоступной, повторы не делаются: восстановлением занимается она.
+func (dbStorage *databaseStorage) withRetry(method string, fn func(ctx context.Context) error) error {
+	return dbStorage.retry(method, retryable, fn)
+}
+
+// withSafeRetry - withRetry для неидемпотентных запросов вроде прибавления к счётчику. После таймаута или обрыва
+// соединения запрос мог выполниться, и повтор применил бы его дважды, поэтому повторяются только ошибки,
+// при которых он точно не выполнился.
+func (dbStorage *databaseStorage) withSafeRetry(method string, fn func(ctx context.Context) error) error {
+	return dbStorage.retry(method, notExecuted, fn)
+}
+
+func (dbStorage *databaseStorage) retry(method string, retryable func(err error) bool, fn func(ctx context.Context) error) error {
+	policy := config.StorageRetryPolicy()
+	if !dbStorage.healthy.Load() {
+		policy.MaxAttempts = 1
+	}
+	policy.Retryable = retryable
+	policy.Clock = dbStorage.clock
+	policy.OnRetry = func(attempt int, delay time.Duration, err error) {
+		dbStorage.log.Errorf("Failed to execute %s (attempt %d): %s. Retrying after %s...", method, attempt, err, delay)
+		selfmetrics.StorageRetries.Inc("database", method)
+	}
+
+	return policy.Do(context.Background(), func(ctx context.Context) error {
+		ctx, cancel := queryContext(database.WithQueryName(ctx, method))
+		defer cancel()
+
+		return fn(ctx)
+	})
+}
+
+// retryable пропускает только временные ошибки: обрыв соединения, сериализацию и взаимоблокировки.
+func retryable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || pgconn.Timeout(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || // connection_exception
+			pgErr.Code == "40001" || // serialization_failure
+			pgErr.Code == "40P01" || // deadlock_detected
+			pgErr.Code == "57P03" // cannot_connect_now
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// notExecuted пропускает ошибки, после которых запрос точно не выполнился: соединение не установлено или
+// сломалось до отправки запроса, а также сериализацию и взаимоблокировки, при которых сервер откатил запрос.
+func notExecuted(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "08001" || // sqlclient_unable_to_establish_sqlconnection
+			pgErr.Code == "08004" || // sqlserver_rejected_establishment_of_sqlconnection
+			pgErr.Code == "40001" || // serialization_failure
+			pgErr.Code == "40P01" || // deadlock_detected
+			pgErr.Code == "57P03" // cannot_connect_now
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,98 @@
+package dbstorage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		wanted bool
+	}{
+		{
+			name: "Not found",
+			err:  sql.ErrNoRows,
+		},
+		{
+			name: "Canceled",
+			err:  context.Canceled,
+		},
+		{
+			name: "Unique violation",
+			err:  &pgconn.PgError{Code: "23505"},
+		},
+		{
+			name:   "Bad connection",
+			err:    fmt.Errorf("exec: %w", driver.ErrBadConn),
+			wanted: true,
+		},
+		{
+			name:   "Connection failure",
+			err:    &pgconn.PgError{Code: "08006"},
+			wanted: true,
+		},
+		{
+			name:   "Deadlock",
+			err:    &pgconn.PgError{Code: "40P01"},
+			wanted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wanted, retryable(tt.err))
+		})
+	}
+}
+
+func TestNotExecuted(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		wanted bool
+	}{
+		{
+			name: "Timeout",
+			err:  context.DeadlineExceeded,
+		},
+		{
+			name: "Connection failure",
+			err:  &pgconn.PgError{Code: "08006"},
+		},
+		{
+			name: "Read from a broken connection",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		},
+		{
+			name:   "Bad connection",
+			err:    fmt.Errorf("exec: %w", driver.ErrBadConn),
+			wanted: true,
+		},
+		{
+			name:   "Connection refused",
+			err:    fmt.Errorf("connect: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}),
+			wanted: true,
+		},
+		{
+			name:   "Serialization failure",
+			err:    &pgconn.PgError{Code: "40001"},
+			wanted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wanted, notExecuted(tt.err))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+// Search ищет метрики по имени через LIKE для glob и ~ для регулярных выражений.
+func (dbStorage *databaseStorage) Search(pattern string, regex bool) (metrics []models.MetricsValue, err error) {
+	// Шаблон проверяется заранее, чтобы ошибки в нём не отличались от хранилища в памяти
+	if _, err = policy.NameMatcher(pattern, regex); err != nil {
+		return nil, err
+	}
+
+	operator := "LIKE"
+	if regex {
+		operator = "~"
+	} else {
+		pattern = policy.GlobToLike(pattern)
+	}
+
+	if config.Config.CaseInsensitiveNames {
+		operator = map[string]string{"LIKE": "ILIKE", "~": "~*"}[operator]
+	}
+
+	query := fmt.Sprintf(`SELECT name, mtype, delta, value, updated_at
+		FROM %s WHERE name %s $1 ORDER BY name, mtype`, dbStorage.tables.metrics(), operator)
+
+	err = dbStorage.withRetry("Search", func(ctx context.Context) error {
+		metrics = nil
+		return dbStorage.db.SelectContext(ctx, &metrics, query, pattern)
+	})
+	return
+}
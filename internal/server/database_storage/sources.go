@@ -0,0 +1,71 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+func (dbStorage *databaseStorage) createSources(ctx context.Context) error {
+	_, err := dbStorage.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"agent_id" TEXT NOT NULL,
+		"name" TEXT NOT NULL,
+		"mtype" VARCHAR(12) NOT NULL,
+		"delta" BIGINT,
+		"value" DOUBLE PRECISION,
+		"updates" BIGINT NOT NULL DEFAULT 0,
+		"updated_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+		PRIMARY KEY (agent_id, name, mtype)
+	)`, dbStorage.tables.sources()))
+
+	return err
+}
+
+// RecordSources сохраняет последнее обновление каждой метрики от агента одной транзакцией.
+func (dbStorage *databaseStorage) RecordSources(agentID string, updates []models.MetricsUpdate) error {
+	query := fmt.Sprintf(`INSERT INTO %s AS s (agent_id, name, mtype, delta, value, updates, updated_at)
+			VALUES (:agent_id, :name, :mtype, :delta, :value, 1, now())
+		ON CONFLICT (agent_id, name, mtype) DO
+			UPDATE SET delta = excluded.delta, value = excluded.value, updates = s.updates + 1, updated_at = excluded.updated_at`,
+		dbStorage.tables.sources())
+
+	return dbStorage.withSafeRetry("RecordSources", func(ctx context.Context) error {
+		txDB, err := dbStorage.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = txDB.Rollback() // после Commit ничего не делает
+		}()
+
+		stmt, err := txDB.PrepareNamedContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, update := range updates {
+			if _, err = stmt.ExecContext(ctx, map[string]interface{}{
+				"agent_id": agentID,
+				"name":     update.ID,
+				"mtype":    update.MType,
+				"delta":    update.Delta,
+				"value":    update.Value,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return txDB.Commit()
+	})
+}
+
+func (dbStorage *databaseStorage) GetSources(mType, name string) (sources []models.MetricSource, err error) {
+	err = dbStorage.withRetry("GetSources", func(ctx context.Context) error {
+		sources = nil
+		return dbStorage.db.SelectContext(ctx, &sources, fmt.Sprintf(`SELECT agent_id, name, mtype, delta, value, updates, updated_at
+			FROM %s WHERE name = $1 AND mtype = $2 ORDER BY updated_at DESC, agent_id`, dbStorage.tables.sources()), name, mType)
+	})
+	return
+}
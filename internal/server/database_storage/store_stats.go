@@ -0,0 +1,37 @@
+package dbstorage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// EstimateSize возвращает место, которое занимают таблицы хранилища вместе с индексами и секциями истории.
+func (dbStorage *databaseStorage) EstimateSize(ctx context.Context) (size int64, err error) {
+	tables := []string{dbStorage.tables.gauges(), dbStorage.tables.counters(), dbStorage.tables.gaugeStats(), dbStorage.tables.sources(), dbStorage.tables.history()}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	err = dbStorage.db.GetContext(ctx, &size, `SELECT COALESCE(SUM(pg_total_relation_size(tree.relid)), 0)::bigint
+		FROM unnest($1::text[]) AS t(name), pg_partition_tree(to_regclass(t.name)) AS tree`, tables)
+	return
+}
+
+// CacheHitRates возвращает долю чтений базы данных, которые обошлись без диска.
+func (dbStorage *databaseStorage) CacheHitRates(ctx context.Context) (map[string]float64, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var rate sql.NullFloat64
+	err := dbStorage.db.GetContext(ctx, &rate, `SELECT blks_hit::float8 / NULLIF(blks_hit + blks_read, 0)
+		FROM pg_stat_database WHERE datname = current_database()`)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rate.Valid {
+		return map[string]float64{}, nil
+	}
+
+	return map[string]float64{"database_buffers": rate.Float64}, nil
+}
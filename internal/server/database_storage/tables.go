@@ -0,0 +1,172 @@
+package dbstorage
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+type tables struct {
+	schema string
+	prefix string
+}
+
+func newTables() tables {
+	return tables{
+		schema: config.Config.DatabaseSchema,
+		prefix: config.Config.DatabaseTablePrefix,
+	}
+}
+
+func (t tables) name(table string) string {
+	return t.prefix + table
+}
+
+func (t tables) ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+func (t tables) qualified(table string) string {
+	if t.schema == "" {
+		return pgx.Identifier{t.name(table)}.Sanitize()
+	}
+
+	return pgx.Identifier{t.schema, t.name(table)}.Sanitize()
+}
+
+// metrics - представление со всеми метриками в прежнем виде (name, mtype, delta, value, updated_at).
+// Пишутся метрики в gauges и counters, через представление только читаются.
+func (t tables) metrics() string {
+	return t.qualified("metrics")
+}
+
+func (t tables) gauges() string {
+	return t.qualified("gauges")
+}
+
+func (t tables) counters() string {
+	return t.qualified("counters")
+}
+
+func (t tables) gaugeStats() string {
+	return t.qualified("gauge_stats")
+}
+
+func (t tables) sources() string {
+	return t.qualified("metric_sources")
+}
+
+func (t tables) apiKeys() string {
+	return t.qualified("api_keys")
+}
+
+func (t tables) history() string {
+	return t.qualified("metrics_history")
+}
+
+func (t tables) historyDefault() string {
+	return t.qualified("metrics_history_default")
+}
+
+// changesChannel - канал LISTEN/NOTIFY, общий для всех экземпляров сервера с этими таблицами.
+func (t tables) changesChannel() string {
+	return t.name("metrics_changes")
+}
+
+func (t tables) metricsViewQuery() string {
+	return fmt.Sprintf(`CREATE OR REPLACE VIEW %s AS
+		SELECT name, 'gauge'::text AS mtype, NULL::bigint AS delta, value, updated_at FROM %s
+		UNION ALL
+		SELECT name, 'counter'::text AS mtype, delta, NULL::double precision AS value, updated_at FROM %s`, t.metrics(), t.gauges(), t.counters())
+}
+
+func (t tables) setGaugeMetricQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s AS m (name, value)
+				VALUES (:name, :value)
+			ON CONFLICT (name) DO
+			    UPDATE SET value = excluded.value, updated_at = now()`, t.gauges())
+}
+
+func (t tables) addCounterMetricQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s AS m (name, delta)
+				VALUES (:name, :delta)
+			ON CONFLICT (name) DO
+			    UPDATE SET delta = %s, updated_at = now()`, t.counters(), counterSumExpression())
+}
+
+// upsertBatchQuery записывает gauge ($1, $2) и счётчики ($3, $4) пакета из массивов и возвращает итоговые строки.
+func (t tables) upsertBatchQuery() string {
+	return fmt.Sprintf(`WITH g AS (
+			INSERT INTO %s AS m (name, value)
+				SELECT * FROM unnest($1::text[], $2::double precision[])
+			ON CONFLICT (name) DO
+			    UPDATE SET value = excluded.value, updated_at = now()
+			RETURNING name, 'gauge'::text AS mtype, NULL::bigint AS delta, value, updated_at
+		), c AS (
+			INSERT INTO %s AS m (name, delta)
+				SELECT * FROM unnest($3::text[], $4::bigint[])
+			ON CONFLICT (name) DO
+			    UPDATE SET delta = %s, updated_at = now()
+			RETURNING name, 'counter'::text AS mtype, delta, NULL::double precision AS value, updated_at
+		)
+		SELECT * FROM g UNION ALL SELECT * FROM c`, t.gauges(), t.counters(), counterSumExpression())
+}
+
+// lockGaugeMetricQuery блокирует строку gauge до конца транзакции и возвращает её значение. Отсутствующий
+// gauge создаётся с нулём, чтобы одновременные запросы к новому имени тоже ждали друг друга.
+func (t tables) lockGaugeMetricQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s AS m (name, value)
+				VALUES (:name, 0)
+			ON CONFLICT (name) DO
+			    UPDATE SET name = m.name
+			RETURNING value`, t.gauges())
+}
+
+// updateGaugeStatsQuery учитывает текущее значение gauge в статистике. Как и история, значение берётся
+// из таблицы gauge, поэтому запрос одинаково работает после установки и после изменения на величину.
+func (t tables) updateGaugeStatsQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s AS s (name, count, sum, min, max)
+	SELECT name, 1, value, value, value FROM %s
+		WHERE name = :name AND value NOT IN ('NaN', 'Infinity', '-Infinity')
+	ON CONFLICT (name) DO
+		UPDATE SET count = s.count + 1, sum = s.sum + excluded.sum,
+			min = CASE WHEN s.count = 0 THEN excluded.min ELSE LEAST(s.min, excluded.min) END,
+			max = CASE WHEN s.count = 0 THEN excluded.max ELSE GREATEST(s.max, excluded.max) END`, t.gaugeStats(), t.gauges())
+}
+
+// updateGaugeStatsBatchQuery - updateGaugeStatsQuery для всех gauge пакета из массива имён.
+func (t tables) updateGaugeStatsBatchQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s AS s (name, count, sum, min, max)
+	SELECT name, 1, value, value, value FROM %s
+		WHERE name = ANY($1::text[]) AND value NOT IN ('NaN', 'Infinity', '-Infinity')
+	ON CONFLICT (name) DO
+		UPDATE SET count = s.count + 1, sum = s.sum + excluded.sum,
+			min = CASE WHEN s.count = 0 THEN excluded.min ELSE LEAST(s.min, excluded.min) END,
+			max = CASE WHEN s.count = 0 THEN excluded.max ELSE GREATEST(s.max, excluded.max) END`, t.gaugeStats(), t.gauges())
+}
+
+// counterSumExpression повторяет policy.AddCounter на стороне базы. В режиме reject переполнение bigint
+// приводит к ошибке numeric_value_out_of_range, которую counterError превращает в errs.ErrCounterOverflow.
+func counterSumExpression() string {
+	switch policy.CounterMode() {
+	case policy.CounterClamp:
+		return "GREATEST(LEAST(m.delta::numeric + excluded.delta, 9223372036854775807), -9223372036854775808)::bigint"
+	case policy.CounterReject:
+		return "m.delta + excluded.delta"
+	default:
+		return "(((m.delta::numeric + excluded.delta + 9223372036854775808) % 18446744073709551616 + 18446744073709551616) % 18446744073709551616 - 9223372036854775808)::bigint"
+	}
+}
+
+func (t tables) insertHistoryQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s (name, mtype, delta, value)
+	SELECT name, mtype, COALESCE(delta, 0), COALESCE(value, 0) FROM %s WHERE name = :name AND mtype = :mtype`, t.history(), t.metrics())
+}
+
+func (t tables) insertHistoryBatchQuery() string {
+	return fmt.Sprintf(`INSERT INTO %s (name, mtype, delta, value)
+	SELECT name, mtype, COALESCE(delta, 0), COALESCE(value, 0) FROM %s WHERE (name, mtype) IN (SELECT * FROM unnest($1::text[], $2::text[]))`, t.history(), t.metrics())
+}
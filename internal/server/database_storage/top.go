@@ -0,0 +1,33 @@
+package dbstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// GetTop отбирает n метрик с наибольшими или наименьшими значениями через ORDER BY ... LIMIT.
+func (dbStorage *databaseStorage) GetTop(mType string, n int, desc bool) (metrics []models.MetricsValue, err error) {
+	query := dbStorage.tables.topQuery(mType, desc)
+
+	err = dbStorage.withRetry("GetTop", func(ctx context.Context) error {
+		metrics = nil
+		return dbStorage.db.SelectContext(ctx, &metrics, query, mType, n)
+	})
+	return
+}
+
+// topQuery сортирует по значению с тем же порядком NaN, что и storage.Top: в Postgres NaN больше любого числа.
+func (t tables) topQuery(mType string, desc bool) string {
+	column, direction := "value", "ASC"
+	if mType == string(models.CounterType) {
+		column = "delta"
+	}
+	if desc {
+		direction = "DESC"
+	}
+
+	return fmt.Sprintf(`SELECT name, mtype, delta, value, updated_at
+		FROM %s WHERE mtype = $1 ORDER BY %s %s, name LIMIT $2`, t.metrics(), column, direction)
+}
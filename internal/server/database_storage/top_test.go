@@ -0,0 +1,30 @@
+package dbstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopQuery(t *testing.T) {
+	tables := tables{}
+
+	tests := []struct {
+		name        string
+		mType       string
+		desc        bool
+		wantedOrder string
+	}{
+		{name: "Gauges desc puts NaN first", mType: "gauge", desc: true, wantedOrder: `ORDER BY value DESC, name LIMIT $2`},
+		{name: "Gauges asc puts NaN last", mType: "gauge", wantedOrder: `ORDER BY value ASC, name LIMIT $2`},
+		{name: "Counters", mType: "counter", desc: true, wantedOrder: `ORDER BY delta DESC, name LIMIT $2`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tables.topQuery(tt.mType, tt.desc)
+			assert.Contains(t, query, `FROM "metrics" WHERE mtype = $1`)
+			assert.Contains(t, query, tt.wantedOrder)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package dbstorage
+
+import (
+	"context"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/database"
+)
+
+type batchKey struct {
+	name  string
+	mType string
+}
+
+// UpsertBatch записывает пакет одним запросом INSERT ... ON CONFLICT ... RETURNING в обе таблицы и возвращает итоговые значения
+// метрик в порядке их первого появления. Повторы метрики сводятся заранее, потому что одну строку запрос дважды
+// обновить не может: дельты счётчика складываются, у gauge остаётся последнее значение. Статистика gauge
+// учитывает такое значение один раз на пакет.
+func (t *tx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	var (
+		names, mTypes []string
+		deltas        []int64
+		values        []float64
+		gauges        []string
+	)
+
+	index := make(map[batchKey]int)
+	for _, obj := range objects {
+		key := batchKey{name: obj.ID, mType: obj.MType}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(names)
+			index[key] = i
+
+			names, mTypes = append(names, obj.ID), append(mTypes, obj.MType)
+			deltas, values = append(deltas, 0), append(values, 0)
+			if obj.MType == string(models.GaugeType) {
+				gauges = append(gauges, obj.ID)
+			}
+		}
+
+		if obj.MType == string(models.CounterType) {
+			var err error
+			if deltas[i], err = policy.AddCounter(deltas[i], *obj.Delta); err != nil {
+				return nil, err
+			}
+		} else {
+			values[i] = *obj.Value
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var (
+		gaugeValues   []float64
+		counters      []string
+		counterDeltas []int64
+	)
+	for i, name := range names {
+		if mTypes[i] == string(models.GaugeType) {
+			gaugeValues = append(gaugeValues, values[i])
+		} else {
+			counters, counterDeltas = append(counters, name), append(counterDeltas, deltas[i])
+		}
+	}
+
+	ctx, cancel := queryContext(database.WithQueryName(context.Background(), "Tx.UpsertBatch"))
+	defer cancel()
+
+	var rows []models.MetricsValue
+	if err := t.txDB.SelectContext(ctx, &rows, t.tables.upsertBatchQuery(), gauges, gaugeValues, counters, counterDeltas); err != nil {
+		return nil, counterError(err)
+	}
+
+	if len(gauges) > 0 {
+		if _, err := t.txDB.ExecContext(ctx, t.tables.updateGaugeStatsBatchQuery(), gauges); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.history {
+		err := t.withHistorySavepoint(ctx, func() error {
+			_, err := t.txDB.ExecContext(ctx, t.tables.insertHistoryBatchQuery(), names, mTypes)
+			if err != nil {
+				t.log.Errorf("Failed to write history of %d metrics: %s", len(names), err)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, name := range names {
+		t.changed(name, mTypes[i])
+	}
+
+	// Порядок строк RETURNING не гарантирован
+	result := make([]models.MetricsValue, len(names))
+	for _, row := range rows {
+		result[index[batchKey{name: row.ID, mType: row.MType}]] = row
+	}
+
+	return result, nil
+}
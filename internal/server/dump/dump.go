@@ -0,0 +1,106 @@
+package dump
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+var (
+	ErrUnknownMetricType = errors.New("unknown metric type")
+	ErrCountersExist     = errors.New("storage already has counters")
+)
+
+func Dump(store models.Storage, w io.Writer) (int, error) {
+	metrics, err := store.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].MType != metrics[j].MType {
+			return metrics[i].MType < metrics[j].MType
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+
+	if metrics == nil {
+		metrics = []models.MetricsValue{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err = encoder.Encode(metrics); err != nil {
+		return 0, err
+	}
+
+	return len(metrics), nil
+}
+
+// Load загружает метрики, выгруженные Dump, одной транзакцией. Счётчики можно только прибавить, поэтому
+// в хранилище не должно быть счётчиков, иначе после загрузки их значения не совпадут с выгрузкой.
+func Load(store models.Storage, r io.Reader) (int, error) {
+	var metrics []models.MetricsValue
+	if err := json.NewDecoder(r).Decode(&metrics); err != nil {
+		return 0, err
+	}
+
+	if err := checkNoCounters(store); err != nil {
+		return 0, err
+	}
+
+	tx, err := store.NewTx()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, metric := range metrics {
+		switch metric.MType {
+		case string(models.GaugeType):
+			if metric.Value == nil {
+				err = fmt.Errorf("metric #%d (%s): gauge without value", i, metric.ID)
+			} else {
+				err = tx.SetGauge(metric.ID, metric.Value)
+			}
+		case string(models.CounterType):
+			if metric.Delta == nil {
+				err = fmt.Errorf("metric #%d (%s): counter without delta", i, metric.ID)
+			} else {
+				err = tx.AddCounter(metric.ID, metric.Delta)
+			}
+		default:
+			err = fmt.Errorf("metric #%d (%s): %w %q", i, metric.ID, ErrUnknownMetricType, metric.MType)
+		}
+
+		if err != nil {
+			return 0, errors.Join(err, tx.RollBack())
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(metrics), nil
+}
+
+// checkNoCounters возвращает ErrCountersExist, если в хранилище уже есть счётчики.
+func checkNoCounters(store models.Storage) error {
+	metrics, err := store.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metrics {
+		if metric.MType == string(models.CounterType) {
+			return fmt.Errorf("%w, for example %s: load them into a storage without counters", ErrCountersExist, metric.ID)
+		}
+	}
+
+	return nil
+}
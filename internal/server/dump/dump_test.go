@@ -0,0 +1,89 @@
+package dump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+)
+
+func getPointerFloat64(v float64) *float64 {
+	return &v
+}
+
+func getPointerInt64(v int64) *int64 {
+	return &v
+}
+
+func TestDumpAndLoad(t *testing.T) {
+	source := memstorage.NewMem()
+	require.NoError(t, source.SetGauge("Alloc", getPointerFloat64(123.5)))
+	require.NoError(t, source.AddCounter("PollCount", getPointerInt64(7)))
+
+	buf := new(bytes.Buffer)
+
+	count, err := Dump(source, buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	dumped := buf.Bytes()
+
+	destination := memstorage.NewMem()
+	require.NoError(t, destination.SetGauge("Alloc", getPointerFloat64(1)))
+
+	count, err = Load(destination, bytes.NewReader(dumped))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	gauge, err := destination.GetGauge("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 123.5, *gauge)
+
+	counter, err := destination.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *counter)
+
+	_, err = Load(destination, bytes.NewReader(dumped))
+	require.ErrorIs(t, err, ErrCountersExist, "a repeated load must not add the counters again")
+
+	counter, err = destination.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *counter)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "Invalid JSON",
+			data: `{"id": "test"`,
+		},
+		{
+			name: "Unknown type",
+			data: `[{"id": "test", "type": "histogram"}]`,
+		},
+		{
+			name: "Gauge without value",
+			data: `[{"id": "test", "type": "gauge"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstorage.NewMem()
+
+			_, err := Load(store, strings.NewReader(tt.data))
+			require.Error(t, err)
+
+			metrics, err := store.GetAll()
+			require.NoError(t, err)
+			assert.Empty(t, metrics)
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package dump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// ErrUnsupportedPromType - семейства histogram и summary в хранилище не переводятся.
+var ErrUnsupportedPromType = errors.New("unsupported prometheus metric type")
+
+// DumpProm выгружает метрики в текстовом формате Prometheus, который понимает textfile collector node_exporter.
+// Имена приводятся к допустимым в Prometheus, метки из ID метрики (см. models.LabeledName) становятся метками.
+func DumpProm(store models.Storage, w io.Writer) (int, error) {
+	metrics, err := store.GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	type sample struct {
+		labels map[string]string
+		value  string
+	}
+	families := make(map[string][]sample)
+	types := make(map[string]string)
+
+	for _, metric := range metrics {
+		name, labels := models.ParseLabeledName(metric.ID)
+		name = promName(name)
+
+		var value string
+		if metric.MType == string(models.CounterType) {
+			value = strconv.FormatInt(*metric.Delta, 10)
+		} else {
+			value = promFloat(*metric.Value)
+		}
+
+		if mType, ok := types[name]; ok && mType != metric.MType {
+			return 0, fmt.Errorf("metric %s: %s and %s share the prometheus name %s", metric.ID, mType, metric.MType, name)
+		}
+		types[name] = metric.MType
+		families[name] = append(families[name], sample{labels: labels, value: value})
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		samples := families[name]
+		sort.Slice(samples, func(i, j int) bool {
+			return promLabels(samples[i].labels) < promLabels(samples[j].labels)
+		})
+
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, types[name])
+		for _, s := range samples {
+			fmt.Fprintf(bw, "%s%s %s\n", name, promLabels(s.labels), s.value)
+		}
+	}
+
+	if err = bw.Flush(); err != nil {
+		return 0, err
+	}
+
+	return len(metrics), nil
+}
+
+// LoadProm загружает метрики из текстового формата Prometheus одной транзакцией. Семейства counter
+// записываются в счётчики, поэтому, как и для Load, в хранилище не должно быть счётчиков; gauge и untyped
+// записываются как gauge. Метки сохраняются в ID метрики.
+func LoadProm(store models.Storage, r io.Reader) (int, error) {
+	if err := checkNoCounters(store); err != nil {
+		return 0, err
+	}
+
+	tx, err := store.NewTx()
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := loadProm(tx, r)
+	if err != nil {
+		return 0, errors.Join(err, tx.RollBack())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func loadProm(tx models.StorageTx, r io.Reader) (int, error) {
+	types := make(map[string]string)
+	count := 0
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		if comment, ok := strings.CutPrefix(text, "#"); ok {
+			if fields := strings.Fields(comment); len(fields) == 3 && fields[0] == "TYPE" {
+				types[fields[1]] = fields[2]
+			}
+			continue
+		}
+
+		name, labels, value, err := parsePromSample(text)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		id := models.LabeledName(name, labels)
+
+		switch mType := promType(types, name); mType {
+		case "counter":
+			var delta int64
+			if delta, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return 0, fmt.Errorf("line %d: counter %s must be an integer: %w", line, name, err)
+			}
+			err = tx.AddCounter(id, &delta)
+		case "gauge", "untyped", "":
+			var gauge float64
+			if gauge, err = strconv.ParseFloat(value, 64); err != nil {
+				return 0, fmt.Errorf("line %d: gauge %s: %w", line, name, err)
+			}
+			err = tx.SetGauge(id, &gauge)
+		default:
+			err = fmt.Errorf("%w %q of %s", ErrUnsupportedPromType, mType, name)
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// promType возвращает тип семейства сэмпла. Сэмплы histogram и summary называются name_bucket, name_sum и name_count.
+func promType(types map[string]string, name string) string {
+	if mType, ok := types[name]; ok {
+		return mType
+	}
+
+	for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if mType := types[base]; mType == "histogram" || mType == "summary" {
+				return mType
+			}
+		}
+	}
+
+	return ""
+}
+
+// parsePromSample разбирает строку вида name{a="1"} value [timestamp]. Время замера не сохраняется.
+func parsePromSample(text string) (string, map[string]string, string, error) {
+	end := strings.IndexAny(text, "{ \t")
+	if end <= 0 {
+		return "", nil, "", fmt.Errorf("invalid sample %q", text)
+	}
+	name, rest := text[:end], text[end:]
+
+	var labels map[string]string
+	if strings.HasPrefix(rest, "{") {
+		var err error
+		if labels, rest, err = parsePromLabels(rest[1:]); err != nil {
+			return "", nil, "", fmt.Errorf("sample %s: %w", name, err)
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", nil, "", fmt.Errorf("sample %s: expected a value and an optional timestamp", name)
+	}
+
+	return name, labels, fields[0], nil
+}
+
+// parsePromLabels разбирает метки после открывающей скобки и возвращает остаток строки после закрывающей.
+func parsePromLabels(s string) (map[string]string, string, error) {
+	labels := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if rest, ok := strings.CutPrefix(s, "}"); ok {
+			return labels, rest, nil
+		}
+
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(rest), `"`) {
+			return nil, "", fmt.Errorf("invalid labels %q", s)
+		}
+		key, rest = strings.TrimSpace(key), strings.TrimSpace(rest)[1:]
+
+		var value strings.Builder
+		for {
+			if rest == "" {
+				return nil, "", fmt.Errorf("unterminated value of label %s", key)
+			}
+
+			c := rest[0]
+			rest = rest[1:]
+			if c == '"' {
+				break
+			} else if c == '\\' && rest != "" {
+				switch rest[0] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(rest[0])
+				}
+				rest = rest[1:]
+			} else {
+				value.WriteByte(c)
+			}
+		}
+		labels[key] = value.String()
+
+		s = strings.TrimLeft(rest, " \t")
+		s, _ = strings.CutPrefix(s, ",")
+	}
+}
+
+// promName заменяет символы, недопустимые в имени метрики Prometheus, на подчёркивание.
+func promName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || c >= '0' && c <= '9' && i > 0) {
+			b[i] = '_'
+		}
+	}
+
+	return string(b)
+}
+
+func promLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, key, escaper.Replace(labels[key]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func promFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
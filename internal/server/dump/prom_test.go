@@ -0,0 +1,100 @@
+package dump
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+)
+
+func TestDumpProm(t *testing.T) {
+	source := memstorage.NewMem()
+	require.NoError(t, source.SetGauge("Alloc", getPointerFloat64(123.5)))
+	require.NoError(t, source.SetGauge(`disk.free{mount="/",dev="sda \"1\""}`, getPointerFloat64(math.Inf(1))))
+	require.NoError(t, source.AddCounter("PollCount", getPointerInt64(7)))
+
+	buf := new(bytes.Buffer)
+
+	count, err := DumpProm(source, buf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, `# TYPE Alloc gauge
+Alloc 123.5
+# TYPE PollCount counter
+PollCount 7
+# TYPE disk_free gauge
+disk_free{dev="sda \"1\"",mount="/"} +Inf
+`, buf.String())
+
+	destination := memstorage.NewMem()
+	count, err = LoadProm(destination, buf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	gauge, err := destination.GetGauge(`disk_free{dev="sda \"1\"",mount="/"}`)
+	require.NoError(t, err)
+	assert.True(t, math.IsInf(*gauge, 1))
+
+	counter, err := destination.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), *counter)
+}
+
+func TestLoadProm(t *testing.T) {
+	data := `# HELP node_textfile_backup_age_seconds Age of the last backup.
+# TYPE node_textfile_backup_age_seconds gauge
+node_textfile_backup_age_seconds{job="db", host="a"} 3600 1700000000000
+
+# TYPE backups_total counter
+backups_total 42
+legacy_untyped 1e3
+`
+
+	store := memstorage.NewMem()
+	count, err := LoadProm(store, strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	gauge, err := store.GetGauge(`node_textfile_backup_age_seconds{host="a",job="db"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 3600.0, *gauge)
+
+	gauge, err = store.GetGauge("legacy_untyped")
+	require.NoError(t, err)
+	assert.Equal(t, 1000.0, *gauge)
+
+	counter, err := store.GetCounter("backups_total")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), *counter)
+}
+
+func TestLoadPromInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Fractional counter", data: "# TYPE c counter\nc 1.5\n"},
+		{name: "Histogram", data: "# TYPE h histogram\nh_bucket{le=\"1\"} 1\n"},
+		{name: "Unterminated label", data: "g{a=\"1} 1\n"},
+		{name: "Without value", data: "g{a=\"1\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstorage.NewMem()
+			require.NoError(t, store.SetGauge("Alloc", getPointerFloat64(1)))
+
+			_, err := LoadProm(store, strings.NewReader("g 1\n"+tt.data))
+			require.Error(t, err)
+
+			values, err := store.GetAll()
+			require.NoError(t, err)
+			assert.Len(t, values, 1, "a failed import must not write anything")
+		})
+	}
+}
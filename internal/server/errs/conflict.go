@@ -0,0 +1,13 @@
+package errs
+
+import "fmt"
+
+type TypeConflictError struct {
+	Name          string
+	ExistingType  string
+	RequestedType string
+}
+
+func (e *TypeConflictError) Error() string {
+	return fmt.Sprintf("metric %s already exists with type %s, cannot write it as %s", e.Name, e.ExistingType, e.RequestedType)
+}
@@ -5,4 +5,17 @@ import "errors"
 var (
 	ErrStorageInvalidGaugeName   = errors.New("invalid gauge name")
 	ErrStorageInvalidCounterName = errors.New("invalid counter name")
+
+	ErrCounterMissingDelta  = errors.New("counter delta is missing")
+	ErrCounterNegativeDelta = errors.New("negative counter delta")
+	ErrCounterOverflow      = errors.New("counter overflow")
+	ErrGaugeNotFinite       = errors.New("gauge value is NaN or Inf")
+
+	ErrHistoryUnsupported = errors.New("storage does not keep metric history")
+	ErrInvalidPattern     = errors.New("invalid search pattern")
+
+	ErrIndexStatsUnsupported = errors.New("storage does not report index stats")
+	ErrGaugeStatsUnsupported = errors.New("storage does not keep gauge stats")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
 )
@@ -0,0 +1,103 @@
+package filestorage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+// Зашифрованный снапшот - строка с идентификатором ключа и строка base64 с nonce и шифротекстом AES-GCM:
+//
+//	#aes-gcm key=<id>
+//	<base64>
+//
+// Внутри лежит обычный снапшот с контрольной суммой. Строка с ключом входит в проверку подлинности.
+const encryptedPrefix = "#aes-gcm key="
+
+var (
+	ErrSnapshotEncrypted = errors.New("snapshot is encrypted, but encryption keys are not set")
+	ErrSnapshotKeyID     = errors.New("snapshot is encrypted with an unknown key")
+	ErrSnapshotDecrypt   = errors.New("snapshot decryption failed")
+)
+
+// snapshotCipher шифрует снапшоты основным ключом и расшифровывает любым из известных, поэтому при смене ключа
+// старый оставляют в списке, пока снапшот и path.prev не будут переписаны новым.
+type snapshotCipher struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+func newSnapshotCipher(keys []config.EncryptionKey) (*snapshotCipher, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	c := &snapshotCipher{primary: keys[0].ID, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", key.ID, err)
+		}
+
+		if c.aeads[key.ID], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", key.ID, err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *snapshotCipher) seal(plain []byte) ([]byte, error) {
+	aead := c.aeads[c.primary]
+	header := []byte(encryptedPrefix + c.primary)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plain, header)
+
+	return []byte(string(header) + "\n" + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// openSnapshot расшифровывает снапшот. Незашифрованные снапшоты возвращаются как есть, чтобы включить
+// шифрование на уже работающем сервере: следующая запись снапшота будет зашифрована.
+func openSnapshot(data []byte, c *snapshotCipher) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedPrefix)) {
+		return data, nil
+	}
+
+	header, body, _ := bytes.Cut(data, []byte("\n"))
+	if c == nil {
+		return nil, ErrSnapshotEncrypted
+	}
+
+	id := string(header[len(encryptedPrefix):])
+	aead, ok := c.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrSnapshotKeyID, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSnapshotDecrypt, err)
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: snapshot is too short", ErrSnapshotDecrypt)
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSnapshotDecrypt, err)
+	}
+
+	return plain, nil
+}
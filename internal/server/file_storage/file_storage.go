@@ -2,12 +2,10 @@ package filestorage
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,72 +13,76 @@ import (
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
-var retries = []int{1, 3, 5}
-
 type (
 	fileStorage struct {
 		*memstorage.MemStorage
 
-		file *os.File
-		log  logger.Logger
+		path   string
+		format string
+		cipher *snapshotCipher
+		log    logger.Logger
+		clock  clock.Clock
+		stop   chan struct{}
 
-		encoder *json.Encoder
-		decoder *json.Decoder
+		mx sync.Mutex
 	}
 )
 
-func New(log logger.Logger) (*fileStorage, error) {
-	file, err := os.OpenFile(config.Config.FileStoragePath, os.O_RDWR|os.O_CREATE, 0666)
+func New(path string, log logger.Logger) (*fileStorage, error) {
+	// Проверяем, что по пути можно писать: сами снапшоты пишутся через временный файл
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = file.Close(); err != nil {
+		return nil, err
+	}
+
+	keys, err := config.LoadEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotCipher, err := newSnapshotCipher(keys)
 	if err != nil {
 		return nil, err
 	}
-	store := memstorage.NewMem()
 
+	store := memstorage.NewMem()
 	return &fileStorage{
 		MemStorage: store,
 
-		file: file,
-		log:  log,
-
-		encoder: json.NewEncoder(file),
-		decoder: json.NewDecoder(file),
+		path:   path,
+		format: config.Config.SnapshotFormat,
+		cipher: snapshotCipher,
+		log:    log,
+		clock:  clock.Real(),
+		stop:   make(chan struct{}),
 	}, nil
 }
 
 func (fStorage *fileStorage) Close() error {
-	return fStorage.file.Close()
+	close(fStorage.stop)
+
+	_, err := fStorage.update()
+	return err
 }
 
 func (fStorage *fileStorage) Restore() error {
-	var (
-		errs    []error
-		metrics []models.MetricsValue
-	)
-
-	for _, timeSleep := range retries {
-		if err := fStorage.file.Sync(); err != nil {
-			errs = append(errs, err)
-		}
-
-		if err := fStorage.decoder.Decode(&metrics); err != nil {
-			if !errors.Is(err, io.EOF) {
-				errs = append(errs, err)
-			}
-		}
-
-		if len(errs) <= 0 {
-			break
-		}
-
-		fStorage.log.Errorf("Failed to restore metrics in file: %s. Retrying after %ds...", errors.Join(errs...), timeSleep)
-		time.Sleep(time.Duration(timeSleep) * time.Second)
-	}
+	var metrics []models.MetricsValue
 
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	err := fStorage.withRetry("Restore", "restore metrics from file", func() (err error) {
+		metrics, err = fStorage.restoreSnapshot()
+		return
+	})
+	if err != nil {
+		return err
 	}
 
 	var errorsCount int
@@ -93,6 +95,11 @@ func (fStorage *fileStorage) Restore() error {
 		default:
 			errorsCount++
 			fStorage.log.Errorf("The metric couldn't be restored, it has an unknown type: %+v", metrics)
+			continue
+		}
+
+		if metric.UpdatedAt != nil {
+			fStorage.SetUpdatedAt(metric.MType, metric.ID, *metric.UpdatedAt)
 		}
 	}
 
@@ -107,14 +114,20 @@ func (fStorage *fileStorage) Start() {
 	}
 
 	go func() {
-		ticker := time.NewTicker(time.Second * time.Duration(storeInterval))
+		ticker := fStorage.clock.NewTicker(time.Second * time.Duration(storeInterval))
+		defer ticker.Stop()
 		fStorage.log.Debugf("A ticker was created and launched to update the metrics in the file")
 
-		for range ticker.C {
-			if count, err := fStorage.update(); err != nil {
-				fStorage.log.Errorf("Failed to save metrics to file: %s", err)
-			} else {
-				fStorage.log.Infof("Metrics (%d) are successfully synchronized and written to file.", count)
+		for {
+			select {
+			case <-fStorage.stop:
+				return
+			case <-ticker.C():
+				if count, err := fStorage.update(); err != nil {
+					fStorage.log.Errorf("Failed to save metrics to file: %s", err)
+				} else {
+					fStorage.log.Infof("Metrics (%d) are successfully synchronized and written to file.", count)
+				}
 			}
 		}
 	}()
@@ -126,37 +139,54 @@ func (fStorage *fileStorage) update() (int, error) {
 		return 0, err
 	}
 
-	var errs []error
-	for _, timeSleep := range retries {
-		if err = fStorage.file.Truncate(0); err != nil {
-			errs = append(errs, err)
-		}
+	err = fStorage.withRetry("Update", "update metrics in file", func() error {
+		fStorage.mx.Lock()
+		defer fStorage.mx.Unlock()
 
-		if _, err = fStorage.file.Seek(0, 0); err != nil {
-			errs = append(errs, err)
-		}
+		return writeSnapshot(fStorage.path, fStorage.format, metrics, fStorage.cipher)
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		if err = fStorage.encoder.Encode(&metrics); err != nil {
-			errs = append(errs, err)
-		}
+	return len(metrics), nil
+}
+
+// restoreSnapshot читает основной снапшот, а если он повреждён или пропал посреди записи - предыдущий.
+func (fStorage *fileStorage) restoreSnapshot() ([]models.MetricsValue, error) {
+	metrics, err := readSnapshot(fStorage.path, fStorage.cipher)
+	if err == nil {
+		return metrics, nil
+	}
 
-		if len(errs) <= 0 {
-			break
+	previous, prevErr := readSnapshot(fStorage.path+previousSuffix, fStorage.cipher)
+	if prevErr != nil {
+		if os.IsNotExist(prevErr) {
+			return nil, err
 		}
 
-		fStorage.log.Errorf("Failed to update metrics in file: %s. Retrying after %ds...", errors.Join(errs...), timeSleep)
-		time.Sleep(time.Duration(timeSleep) * time.Second)
+		return nil, errors.Join(err, prevErr)
 	}
 
-	if len(errs) > 0 {
-		return 0, errors.Join(errs...)
+	fStorage.log.Errorf("Failed to read snapshot: %s. Metrics are restored from the previous snapshot.", err)
+	return previous, nil
+}
+
+func (fStorage *fileStorage) withRetry(method, action string, fn func() error) error {
+	policy := config.StorageRetryPolicy()
+	policy.Clock = fStorage.clock
+	policy.OnRetry = func(_ int, delay time.Duration, err error) {
+		fStorage.log.Errorf("Failed to %s: %s. Retrying after %s...", action, err, delay)
+		selfmetrics.StorageRetries.Inc("file", method)
 	}
 
-	return len(metrics), nil
+	return policy.Do(context.Background(), func(_ context.Context) error {
+		return fn()
+	})
 }
 
 func (fStorage *fileStorage) Ping(_ context.Context) error {
-	_, err := fStorage.file.Stat()
+	_, err := os.Stat(fStorage.path)
 	if os.IsNotExist(err) {
 		return err
 	}
@@ -170,7 +200,7 @@ func (fStorage *fileStorage) GetMiddleware() gin.HandlerFunc {
 		storeInterval := config.Config.StoreInterval
 		if storeInterval > 0 {
 			return
-		} else if !strings.Contains(ctx.FullPath(), "/update") {
+		} else if !models.IsUpdateRoute(ctx.FullPath()) {
 			return
 		}
 
@@ -185,5 +215,17 @@ func (fStorage *fileStorage) GetMiddleware() gin.HandlerFunc {
 }
 
 func (fStorage *fileStorage) String() string {
-	return fmt.Sprintf("FileStorage - %s", fStorage.file.Name())
+	return fmt.Sprintf("FileStorage - %s", fStorage.path)
+}
+
+// EstimateSize возвращает размер файла с метриками. До первой записи файла его размер нулевой.
+func (fStorage *fileStorage) EstimateSize(_ context.Context) (int64, error) {
+	info, err := os.Stat(fStorage.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
 }
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func getPointerFloat64(v float64) *float64 {
@@ -48,9 +51,9 @@ func TestSuccessFileStorage(t *testing.T) {
 
 	require.NoError(t, config.Parse())
 
-	log := zaptest.NewLogger(t).Sugar()
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
 
-	fStorage, err := New(log)
+	fStorage, err := New(config.Config.FileStoragePath, log)
 	require.NoError(t, err)
 
 	for _, metric := range metrics {
@@ -67,7 +70,7 @@ func TestSuccessFileStorage(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, count, len(metrics))
 
-	fStorage, err = New(log)
+	fStorage, err = New(config.Config.FileStoragePath, log)
 	require.NoError(t, err)
 
 	require.NoError(t, fStorage.Restore())
@@ -109,7 +112,7 @@ func TestNegativeFileStorage(t *testing.T) {
 		zap.AddCaller(),
 	)
 
-	fStorage, err := New(log.Sugar())
+	fStorage, err := New(config.Config.FileStoragePath, logger.Wrap(log.Sugar()))
 	require.NoError(t, err)
 
 	require.NoError(t, fStorage.Restore())
@@ -119,3 +122,136 @@ func TestNegativeFileStorage(t *testing.T) {
 		t.Logf("Не удалось удалить тестовый json-файл: %s", err)
 	}
 }
+
+func TestSnapshotFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	t.Setenv("FILE_STORAGE_PATH", path)
+	t.Setenv("STORAGE_RETRY_ATTEMPTS", "1")
+	require.NoError(t, config.Parse())
+
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+
+	fStorage, err := New(config.Config.FileStoragePath, log)
+	require.NoError(t, err)
+
+	require.NoError(t, fStorage.SetGauge("TestGauge", getPointerFloat64(1.5)))
+	_, err = fStorage.update()
+	require.NoError(t, err)
+
+	require.NoError(t, fStorage.SetGauge("TestGauge", getPointerFloat64(2.5)))
+	_, err = fStorage.update()
+	require.NoError(t, err)
+
+	metrics, err := readSnapshot(path, nil)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, 2.5, *metrics[0].Value)
+
+	// Портим значение в последнем снапшоте, не трогая контрольную сумму
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte("2.5"), []byte("9.5"), 1), 0666))
+
+	_, err = readSnapshot(path, nil)
+	require.ErrorIs(t, err, ErrSnapshotChecksum)
+
+	fStorage, err = New(config.Config.FileStoragePath, log)
+	require.NoError(t, err)
+	require.NoError(t, fStorage.Restore())
+
+	value, err := fStorage.GetGauge("TestGauge")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, *value, "previous snapshot must be restored")
+
+	require.NoError(t, os.Remove(path+previousSuffix))
+	fStorage, err = New(config.Config.FileStoragePath, log)
+	require.NoError(t, err)
+	require.ErrorIs(t, fStorage.Restore(), ErrSnapshotChecksum)
+}
+
+func TestSnapshotEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	metrics := []models.MetricsValue{{ID: "Alloc", MType: "gauge", Value: getPointerFloat64(1.5)}}
+
+	oldKey := config.EncryptionKey{ID: "v1", Key: bytes.Repeat([]byte{1}, 32)}
+	newKey := config.EncryptionKey{ID: "v2", Key: bytes.Repeat([]byte{2}, 16)}
+
+	oldCipher, err := newSnapshotCipher([]config.EncryptionKey{oldKey})
+	require.NoError(t, err)
+
+	// Открытый снапшот читается и после включения шифрования
+	require.NoError(t, writeSnapshot(path, config.SnapshotFormatJSON, metrics, nil))
+	restored, err := readSnapshot(path, oldCipher)
+	require.NoError(t, err)
+	require.Equal(t, metrics, restored)
+
+	require.NoError(t, writeSnapshot(path, config.SnapshotFormatJSON, metrics, oldCipher))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "Alloc")
+
+	_, err = readSnapshot(path, nil)
+	require.ErrorIs(t, err, ErrSnapshotEncrypted)
+
+	// Новый ключ шифрует, старый остаётся для чтения
+	rotated, err := newSnapshotCipher([]config.EncryptionKey{newKey, oldKey})
+	require.NoError(t, err)
+
+	restored, err = readSnapshot(path, rotated)
+	require.NoError(t, err)
+	require.Equal(t, metrics, restored)
+
+	require.NoError(t, writeSnapshot(path, config.SnapshotFormatJSON, metrics, rotated))
+
+	_, err = readSnapshot(path, oldCipher)
+	require.ErrorIs(t, err, ErrSnapshotKeyID)
+
+	newOnly, err := newSnapshotCipher([]config.EncryptionKey{newKey})
+	require.NoError(t, err)
+	restored, err = readSnapshot(path, newOnly)
+	require.NoError(t, err)
+	require.Equal(t, metrics, restored)
+
+	// Подмена идентификатора ключа ломает проверку подлинности
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte("key=v2"), []byte("key=v1"), 1), 0666))
+
+	_, err = readSnapshot(path, rotated)
+	require.ErrorIs(t, err, ErrSnapshotDecrypt)
+}
+
+func TestSnapshotFormats(t *testing.T) {
+	updatedAt := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	metrics := []models.MetricsValue{
+		{ID: "Alloc", MType: string(models.GaugeType), Value: getPointerFloat64(1.5), UpdatedAt: &updatedAt},
+		{ID: "PollCount", MType: string(models.CounterType), Delta: getPointerInt64(7)},
+	}
+
+	for _, format := range []string{config.SnapshotFormatJSON, config.SnapshotFormatGob, config.SnapshotFormatProtobuf} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.db")
+
+			require.NoError(t, writeSnapshot(path, format, metrics, nil))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, format != config.SnapshotFormatJSON, bytes.HasPrefix(data, []byte(formatPrefix+format+"\n")))
+
+			restored, err := readSnapshot(path, nil)
+			require.NoError(t, err)
+			require.Len(t, restored, 2)
+			require.Equal(t, metrics[0].ID, restored[0].ID)
+			require.Equal(t, *metrics[0].Value, *restored[0].Value)
+			require.True(t, updatedAt.Equal(*restored[0].UpdatedAt))
+			require.Equal(t, *metrics[1].Delta, *restored[1].Delta)
+			require.Nil(t, restored[1].Value)
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	require.NoError(t, os.WriteFile(path, []byte(formatPrefix+"xml\n<metrics/>"), 0644))
+
+	_, err := readSnapshot(path, nil)
+	require.ErrorContains(t, err, `unknown snapshot format "xml"`)
+}
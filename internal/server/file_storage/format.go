@@ -0,0 +1,73 @@
+package filestorage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+// Снапшоты в gob и protobuf начинаются со строки с форматом, по ней формат определяется при восстановлении:
+//
+//	#format=gob
+//	<данные>
+//
+// JSON пишется без этой строки, как и раньше, чтобы старые версии сервера могли его прочитать.
+const formatPrefix = "#format="
+
+func encodeSnapshot(format string, metrics []models.MetricsValue) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case config.SnapshotFormatGob:
+		buf.WriteString(formatPrefix + format + "\n")
+		if err := gob.NewEncoder(&buf).Encode(metrics); err != nil {
+			return nil, err
+		}
+	case config.SnapshotFormatProtobuf:
+		list := make([]metricspb.Metric, 0, len(metrics))
+		for _, metric := range metrics {
+			list = append(list, metricspb.Metric{ID: metric.ID, Type: metric.MType, Delta: metric.Delta, Value: metric.Value, UpdatedAt: metric.UpdatedAt})
+		}
+
+		buf.WriteString(formatPrefix + format + "\n")
+		buf.Write(metricspb.MarshalList(list))
+	default:
+		return json.Marshal(metrics)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeSnapshot(body []byte) ([]models.MetricsValue, error) {
+	rest, ok := bytes.CutPrefix(body, []byte(formatPrefix))
+	if !ok {
+		var metrics []models.MetricsValue
+		return metrics, json.Unmarshal(body, &metrics)
+	}
+
+	format, data, _ := bytes.Cut(rest, []byte("\n"))
+	switch string(format) {
+	case config.SnapshotFormatGob:
+		var metrics []models.MetricsValue
+		return metrics, gob.NewDecoder(bytes.NewReader(data)).Decode(&metrics)
+	case config.SnapshotFormatProtobuf:
+		list, err := metricspb.UnmarshalList(data)
+		if err != nil {
+			return nil, err
+		}
+
+		metrics := make([]models.MetricsValue, 0, len(list))
+		for _, metric := range list {
+			metrics = append(metrics, models.MetricsValue{ID: metric.ID, MType: metric.Type, Delta: metric.Delta, Value: metric.Value, UpdatedAt: metric.UpdatedAt})
+		}
+
+		return metrics, nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot format %q", format)
+	}
+}
@@ -0,0 +1,120 @@
+package filestorage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// Снапшот - метрики в одном из форматов (см. format.go), за которыми идёт строка с контрольной суммой:
+//
+//	[{"id":"Alloc","type":"gauge","value":1.5}]
+//	#sha256=<hex>
+//
+// Файлы без футера (старый формат) читаются без проверки.
+const (
+	checksumPrefix = "#sha256="
+	previousSuffix = ".prev"
+)
+
+var ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+
+// writeSnapshot пишет снапшот во временный файл рядом с path и атомарно подменяет им path.
+// Предыдущий снапшот остаётся в path.prev на случай, если новый окажется повреждён.
+func writeSnapshot(path, format string, metrics []models.MetricsValue, c *snapshotCipher) error {
+	body, err := encodeSnapshot(format, metrics)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	body = append(body, '\n')
+	body = append(body, checksumPrefix+hex.EncodeToString(sum[:])+"\n"...)
+
+	if c != nil {
+		if body, err = c.seal(body); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(body); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return errors.Join(err, tmp.Close(), os.Remove(tmp.Name()))
+	}
+
+	if err = tmp.Close(); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	if err = os.Rename(path, path+previousSuffix); err != nil && !os.IsNotExist(err) {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// readSnapshot расшифровывает снапшот, если он зашифрован, и проверяет контрольную сумму. Пустой файл означает, что метрик нет.
+func readSnapshot(path string, c *snapshotCipher) ([]models.MetricsValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = openSnapshot(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	body := bytes.TrimRight(data, "\n")
+	if idx := bytes.LastIndexByte(body, '\n'); idx >= 0 && bytes.HasPrefix(body[idx+1:], []byte(checksumPrefix)) {
+		expected := string(body[idx+1+len(checksumPrefix):])
+		body = body[:idx]
+
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != expected {
+			return nil, fmt.Errorf("%s: %w", path, ErrSnapshotChecksum)
+		}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	metrics, err := decodeSnapshot(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return metrics, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	// На Windows каталог нельзя синхронизировать, rename там и так надёжен
+	if err = d.Sync(); err != nil && !errors.Is(err, os.ErrPermission) && !errors.Is(err, os.ErrInvalid) {
+		return errors.Join(err, d.Close())
+	}
+
+	return d.Close()
+}
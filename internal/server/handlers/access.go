@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// Access пропускает запрос, только если списки подсетей разрешают адрес клиента для группы group.
+// Адрес из заголовков берётся, только если запрос пришёл через доверенный прокси (-trusted-proxies).
+func (bh baseHandler) Access(group string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if bh.access == nil {
+			return
+		}
+
+		ip := net.ParseIP(ctx.ClientIP())
+		if bh.access.Allowed(group, ip) {
+			return
+		}
+
+		bh.logger(ctx).Infof("Rejected request from %s to %s by the %s access list.", ctx.ClientIP(), ctx.Request.URL.Path, group)
+
+		ctx.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Access from your address is denied."})
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/access"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/middlewares"
+	serverRouter "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestAccess(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.AdminKey = "secret"
+
+	path := filepath.Join(t.TempDir(), "access.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"writes": {"allow": ["10.0.0.0/8"], "deny": ["10.0.0.5"]},
+		"admin": {"allow": ["127.0.0.1"]}
+	}`), 0600))
+
+	guard, err := access.Open(path)
+	require.NoError(t, err)
+
+	r := serverRouter.New(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	r.SetAccessGuard(guard)
+	middlewares.Setup(r)
+	Setup(r)
+
+	tests := []struct {
+		name             string
+		method           string
+		url              string
+		remoteAddr       string
+		wantedStatusCode int
+	}{
+		{name: "Write from allowed subnet", method: http.MethodPost, url: "/update/counter/test/1", remoteAddr: "10.1.1.1:5000", wantedStatusCode: http.StatusOK},
+		{name: "Write from denied address", method: http.MethodPost, url: "/update/counter/test/1", remoteAddr: "10.0.0.5:5000", wantedStatusCode: http.StatusForbidden},
+		{name: "Write from other network", method: http.MethodPost, url: "/update/counter/test/1", remoteAddr: "192.168.0.1:5000", wantedStatusCode: http.StatusForbidden},
+		{name: "Read without list", method: http.MethodGet, url: "/value/counter/test", remoteAddr: "192.168.0.1:5000", wantedStatusCode: http.StatusOK},
+		{name: "Admin from localhost", method: http.MethodGet, url: "/admin/read-only", remoteAddr: "127.0.0.1:5000", wantedStatusCode: http.StatusOK},
+		{name: "Admin from allowed writer", method: http.MethodGet, url: "/admin/read-only", remoteAddr: "10.1.1.1:5000", wantedStatusCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("X-Forwarded-For", "10.1.1.1")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
+)
+
+type adminResponse struct {
+	Status string `json:"status"`
+}
+
+// AdminAuth пропускает запросы с ADMIN_KEY или, если настроены ключи API, с ключом роли admin.
+func (bh baseHandler) AdminAuth(ctx *gin.Context) {
+	adminKey := config.Config.AdminKey
+	if adminKey == "" && bh.keys == nil {
+		ctx.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Admin API is disabled."})
+		ctx.Abort()
+
+		return
+	}
+
+	key, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if ok && adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+		return
+	}
+
+	if bh.keys != nil {
+		bh.RequireRole(auth.RoleAdmin)(ctx)
+		return
+	}
+
+	bh.logger(ctx).Infof("Rejected admin request from %s to %s.", ctx.ClientIP(), ctx.Request.URL.Path)
+	bh.authFailure(ctx, lockout.ReasonAdminKey)
+
+	ctx.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid admin key."})
+	ctx.Abort()
+}
+
+func (bh baseHandler) Shutdown() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		bh.logger(ctx).Infof("Shutdown was requested by %s.", ctx.ClientIP())
+		bh.stop(false)
+
+		ctx.JSON(http.StatusAccepted, adminResponse{Status: "shutting down"})
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) Reload() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		bh.logger(ctx).Infof("Reload was requested by %s.", ctx.ClientIP())
+		bh.stop(true)
+
+		ctx.JSON(http.StatusAccepted, adminResponse{Status: "reloading"})
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) Config() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, config.Redacted())
+		ctx.Abort()
+	}
+}
+
+// SigningKeys показывает, какими ключами подписи пользуются агенты: ключ без запросов можно убирать.
+func (bh baseHandler) SigningKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, signing.Usage())
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// RequireRole пропускает запрос только с ключом API, которому хватает роли role.
+// Если ключи API не настроены, пропускает все запросы.
+func (bh baseHandler) RequireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if bh.keys == nil {
+			return
+		}
+
+		key, ok := bh.authenticate(ctx)
+		if !ok {
+			return
+		}
+
+		if !auth.Allows(key.Role, role) {
+			bh.logger(ctx).Infof("API key %s (%s) is not allowed to access %s.", key.ID, key.Role, ctx.Request.URL.Path)
+
+			ctx.JSON(http.StatusForbidden, models.ErrorResponse{Error: fmt.Sprintf("The API key needs the %s role.", role)})
+			ctx.Abort()
+		}
+	}
+}
+
+// authenticate проверяет ключ из заголовка Authorization и сам отвечает клиенту, если ключ не подошёл.
+func (bh baseHandler) authenticate(ctx *gin.Context) (*models.APIKey, bool) {
+	token, _ := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+
+	key, err := bh.keys.Authenticate(token)
+	if errors.Is(err, auth.ErrInvalidKey) {
+		bh.logger(ctx).Infof("Rejected request with an invalid API key from %s to %s.", ctx.ClientIP(), ctx.Request.URL.Path)
+		bh.authFailure(ctx, lockout.ReasonAPIKey)
+
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid API key."})
+		ctx.Abort()
+
+		return nil, false
+	} else if err != nil {
+		bh.handleStorageError(ctx, "Failed to check API key", err)
+		return nil, false
+	}
+
+	return key, true
+}
+
+// authFailure учитывает неудачную аутентификацию для аудита и блокировки адреса.
+func (bh baseHandler) authFailure(ctx *gin.Context, reason string) {
+	if bh.lockout != nil {
+		bh.lockout.Failure(ctx.ClientIP(), reason, ctx.Request.URL.Path)
+	}
+}
+
+func (bh baseHandler) CreateAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !bh.apiKeysEnabled(ctx) {
+			return
+		}
+
+		var obj models.APIKeyRequest
+		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &obj); err != nil {
+			if response == nil {
+				ctx.Status(statusCode)
+			} else {
+				ctx.JSON(statusCode, response)
+			}
+
+			ctx.Abort()
+
+			return
+		}
+
+		key, token, err := bh.keys.Create(obj.Name, obj.Role)
+		if errors.Is(err, auth.ErrInvalidRole) {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Field \"role\" must be read, write or admin."})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to create API key", err)
+			return
+		}
+
+		bh.logger(ctx).Infof("API key %s (%s, %s) was created by %s.", key.ID, key.Name, key.Role, ctx.ClientIP())
+
+		ctx.JSON(http.StatusCreated, models.CreatedAPIKey{APIKey: key, Key: token})
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) ListAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !bh.apiKeysEnabled(ctx) {
+			return
+		}
+
+		keys, err := bh.keys.List()
+		if err != nil {
+			bh.handleStorageError(ctx, "Failed to list API keys", err)
+			return
+		}
+
+		if keys == nil {
+			keys = make([]models.APIKey, 0)
+		}
+
+		ctx.JSON(http.StatusOK, keys)
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) RevokeAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !bh.apiKeysEnabled(ctx) {
+			return
+		}
+
+		id := ctx.Param("id")
+		if err := bh.keys.Revoke(id); errors.Is(err, errs.ErrAPIKeyNotFound) {
+			ctx.Status(http.StatusNotFound)
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to revoke API key", err)
+			return
+		}
+
+		bh.logger(ctx).Infof("API key %s was revoked by %s.", id, ctx.ClientIP())
+
+		ctx.Status(http.StatusNoContent)
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) apiKeysEnabled(ctx *gin.Context) bool {
+	if bh.keys != nil {
+		return true
+	}
+
+	ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "API keys are disabled."})
+	ctx.Abort()
+
+	return false
+}
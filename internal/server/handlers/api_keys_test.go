@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/middlewares"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	serverRouter "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestAPIKeys(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.AdminKey = "admin-secret"
+
+	store, err := auth.OpenFile(filepath.Join(t.TempDir(), "keys.json"), nil)
+	require.NoError(t, err)
+
+	r := serverRouter.New(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	r.SetAPIKeys(auth.New(store, nil))
+	middlewares.Setup(r)
+	Setup(r)
+
+	send := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	createKey := func(token, role string) models.CreatedAPIKey {
+		w := send(http.MethodPost, "/admin/keys", token, `{"name": "test", "role": "`+role+`"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created models.CreatedAPIKey
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+		return created
+	}
+
+	// Первый ключ выпускается с ADMIN_KEY, дальше можно обходиться ключами роли admin
+	admin := createKey("admin-secret", auth.RoleAdmin)
+	writer := createKey(admin.Key, auth.RoleWrite)
+	reader := createKey(admin.Key, auth.RoleRead)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/keys", admin.Key, `{"name": "test", "role": "root"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/admin/keys", "", `{"name": "test", "role": "read"}`).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/admin/keys", writer.Key, `{"name": "test", "role": "read"}`).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/update/counter/PollCount/1", "", "").Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/update/counter/PollCount/1", reader.Key, "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/update/counter/PollCount/1", writer.Key, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/value/counter/PollCount", "", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/value/counter/PollCount", reader.Key, "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/ping", "", "").Code)
+
+	w := send(http.MethodGet, "/admin/keys", admin.Key, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), reader.Key)
+
+	var keys []models.APIKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	assert.Len(t, keys, 3)
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/admin/keys/"+reader.ID, admin.Key, "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/admin/keys/missing", admin.Key, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/value/counter/PollCount", reader.Key, "").Code)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// UpdatesV2 принимает пакет метрик API v2. Метки хранятся в ID метрики, поэтому одна метрика с разными
+// метками - это разные ряды и в старом API.
+func (bh baseHandler) UpdatesV2() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !bh.validateContentType(ctx, "application/json", false) {
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
+			bh.handleBadRequest(ctx)
+			return
+		}
+
+		var objects []models.MetricsV2
+		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &objects); err != nil {
+			if statusCode == http.StatusInternalServerError {
+				bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
+			}
+
+			if response == nil {
+				ctx.Status(statusCode)
+			} else {
+				ctx.JSON(statusCode, response)
+			}
+
+			ctx.Abort()
+
+			return
+		}
+
+		updates := make([]models.MetricsUpdate, 0, len(objects))
+		for i, obj := range objects {
+			if err := obj.ValidateLabels(); err != nil {
+				ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Element %d: %s.", i, err), Field: fmt.Sprintf("[%d].labels", i)})
+				ctx.Abort()
+
+				return
+			}
+
+			updates = append(updates, obj.ToUpdate())
+		}
+
+		if bh.applyUpdates(ctx, updates) {
+			ctx.Status(http.StatusOK)
+			ctx.Abort()
+		}
+	}
+}
+
+// MetricsV2 отдаёт все метрики в формате API v2.
+func (bh baseHandler) MetricsV2() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		values, err := bh.storage.GetAll()
+		if err != nil {
+			if bh.handleStorageUnavailable(ctx, err) {
+				return
+			}
+
+			bh.logger(ctx).Debugf("Error get all metrics: %s", err)
+
+			ctx.Status(http.StatusInternalServerError)
+			ctx.Abort()
+
+			return
+		}
+
+		result := make([]models.MetricsV2, 0, len(values))
+		for _, value := range values {
+			result = append(result, models.MetricsV2FromValue(value))
+		}
+
+		ctx.JSON(http.StatusOK, result)
+		ctx.Abort()
+	}
+}
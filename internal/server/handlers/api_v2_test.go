@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestAPIV2(t *testing.T) {
+	config.Config.Features = []string{config.FeatureLabels}
+	defer func() { config.Config.Features = nil }()
+
+	store := memstorage.NewMem()
+	r := setupRouter(store, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/updates", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	w := post(`[
+		{"name":"Alloc","type":"gauge","value":1.5,"labels":{"host":"a \"1\"","dc":"eu"},"unit":"bytes"},
+		{"name":"Alloc","type":"gauge","value":2.5,"labels":{"host":"b"}},
+		{"name":"PollCount","type":"counter","delta":3}
+	]`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	value, err := store.GetGauge(`Alloc{dc="eu",host="a \"1\""}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, *value)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var metrics []models.MetricsV2
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+
+	labels := make(map[string]map[string]string)
+	for _, metric := range metrics {
+		labels[models.LabeledName(metric.Name, metric.Labels)] = metric.Labels
+	}
+	assert.Equal(t, map[string]map[string]string{
+		`Alloc{dc="eu",host="a \"1\""}`: {"dc": "eu", "host": `a "1"`},
+		`Alloc{host="b"}`:               {"host": "b"},
+		"PollCount":                     nil,
+	}, labels)
+
+	w = post(`[{"name":"Alloc","type":"gauge","value":1,"labels":{"host-name":"a"}}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Element 0: invalid label name \"host-name\": must match ^[A-Za-z_][A-Za-z0-9_]*$.","field":"[0].labels"}`, w.Body.String())
+
+	w = post(`[{"name":"Alloc","type":"gauge"}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	config.Config.Features = nil
+	w = post(`[{"name":"PollCount","type":"counter","delta":1}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestParseLabeledName(t *testing.T) {
+	tests := []struct {
+		id           string
+		wantedName   string
+		wantedLabels map[string]string
+	}{
+		{id: "Alloc", wantedName: "Alloc"},
+		{id: `Alloc{host="a",dc="eu"}`, wantedName: "Alloc", wantedLabels: map[string]string{"host": "a", "dc": "eu"}},
+		{id: `Alloc{path="a,b}=c"}`, wantedName: "Alloc", wantedLabels: map[string]string{"path": "a,b}=c"}},
+		{id: "Alloc{}", wantedName: "Alloc{}"},
+		{id: `Alloc{host=a}`, wantedName: `Alloc{host=a}`},
+		{id: `Alloc{host="a",}`, wantedName: `Alloc{host="a",}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			name, labels := models.ParseLabeledName(tt.id)
+
+			assert.Equal(t, tt.wantedName, name)
+			assert.Equal(t, tt.wantedLabels, labels)
+			if labels != nil {
+				assert.Equal(t, models.LabeledName(name, labels), models.LabeledName(tt.wantedName, tt.wantedLabels))
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestBadRequest(t *testing.T) {
@@ -30,7 +31,7 @@ func TestBadRequest(t *testing.T) {
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
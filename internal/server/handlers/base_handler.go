@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"sync/atomic"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/access"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/stale"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
@@ -11,6 +18,13 @@ type (
 	baseHandler struct {
 		storage models.Storage
 		log     logger.Logger
+		stop    func(restart bool)
+		stale   *stale.Monitor
+		keys    *auth.Keys
+		access  *access.Guard
+		lockout *lockout.Tracker
+
+		readOnly *atomic.Bool
 	}
 	router interface {
 		gin.IRouter
@@ -18,32 +32,94 @@ type (
 
 		GetStorage() models.Storage
 		GetLogger() logger.Logger
+		GetStaleMonitor() *stale.Monitor
+		GetAPIKeys() *auth.Keys
+		GetAccessGuard() *access.Guard
+		GetLockout() *lockout.Tracker
+		GetAdmin() *gin.Engine
+		Stop(restart bool)
 	}
 )
 
 func Setup(r router) {
-	bh := &baseHandler{storage: r.GetStorage(), log: r.GetLogger()}
+	bh := &baseHandler{storage: r.GetStorage(), log: r.GetLogger().Named("handlers"), stop: r.Stop, stale: r.GetStaleMonitor(), keys: r.GetAPIKeys(), access: r.GetAccessGuard(), lockout: r.GetLockout(), readOnly: &atomic.Bool{}}
+	bh.readOnly.Store(config.Config.ReadOnly)
+
+	// Служебные маршруты могут слушать отдельный адрес, чтобы не открывать их вместе с метриками
+	var ops gin.IRouter = r
+	if admin := r.GetAdmin(); admin != nil {
+		ops = admin
+		setupPprof(admin.Group("/debug/pprof", bh.Access(access.Admin)))
+
+		admin.NoRoute(bh.BadRequest)
+	}
+
+	ops.GET("/ping", bh.Ping())
+	ops.GET("/readyz", bh.Readyz())
+	ops.GET("/metrics", bh.SelfMetrics())
+	ops.GET("/api/buildinfo", bh.BuildInfo())
+
+	reads := r.Group("", bh.Access(access.Reads), bh.RequireRole(auth.RoleRead))
+
+	reads.GET("/", bh.Values())
 
-	r.GET("/", bh.Values())
+	reads.GET("/api/stats", bh.StoreStats())
+	reads.GET("/api/stats/:name", bh.GaugeStats())
+	reads.GET("/api/sources/:type/:name", bh.Sources())
+	reads.GET("/api/metrics/stale", bh.StaleMetrics())
+	reads.GET("/api/quantile/:name", bh.Quantile())
+	reads.GET("/api/rate/:name", bh.CounterRate())
+	reads.GET("/api/top", bh.Top())
+	reads.GET("/api/search", bh.Search())
 
-	r.GET("/ping", bh.Ping())
+	reads.POST("/value", bh.ValueByBody())
+	reads.POST("/value/", bh.ValueByBody())
 
-	r.POST("/value", bh.ValueByBody())
-	r.POST("/value/", bh.ValueByBody())
+	reads.GET("/value/:type/:name", bh.ValueByURI())
+	reads.GET("/value/:type/:name/", bh.ValueByURI())
+
+	reads.GET("/api/v2/metrics", bh.Feature(config.FeatureLabels), bh.MetricsV2())
+
+	writes := r.Group("", bh.Access(access.Writes), bh.RequireRole(auth.RoleWrite), bh.Writable, bh.AgentID)
+
+	writes.POST("/updates", bh.Updates())
+	writes.POST("/updates/", bh.Updates())
+
+	writes.POST("/update", bh.UpdateByBody())
+	writes.POST("/update/", bh.UpdateByBody())
+
+	writes.POST("/update/:type", bh.UpdateByURI())
+	writes.POST("/update/:type/", bh.UpdateByURI())
+	writes.POST("/update/:type/:name/:value", bh.UpdateByURI())
+	writes.POST("/update/:type/:name/:value/", bh.UpdateByURI())
+
+	if config.Config.LegacyUpdates {
+		writes.GET("/update", bh.UpdateByQuery())
+		writes.GET("/update/", bh.UpdateByQuery())
+	}
 
-	r.GET("/value/:type/:name", bh.ValueByURI())
-	r.GET("/value/:type/:name/", bh.ValueByURI())
+	writes.POST("/api/v2/updates", bh.Feature(config.FeatureLabels), bh.UpdatesV2())
 
-	r.POST("/updates", bh.Updates())
-	r.POST("/updates/", bh.Updates())
+	writes.POST("/api/gauge/:name/add", bh.AdjustGauge(1))
+	writes.POST("/api/gauge/:name/sub", bh.AdjustGauge(-1))
+	writes.DELETE("/api/stats/:name", bh.ResetGaugeStats())
 
-	r.POST("/update", bh.UpdateByBody())
-	r.POST("/update/", bh.UpdateByBody())
+	// В expvar попадают внутренние счётчики и память процесса, поэтому они доступны только администратору
+	ops.GET("/debug/vars", bh.Access(access.Admin), bh.AdminAuth, bh.DebugVars())
 
-	r.POST("/update/:type", bh.UpdateByURI())
-	r.POST("/update/:type/", bh.UpdateByURI())
-	r.POST("/update/:type/:name/:value", bh.UpdateByURI())
-	r.POST("/update/:type/:name/:value/", bh.UpdateByURI())
+	admin := ops.Group("/admin", bh.Access(access.Admin), bh.AdminAuth)
+	admin.POST("/shutdown", bh.Shutdown())
+	admin.POST("/reload", bh.Reload())
+	admin.GET("/config", bh.Config())
+	admin.GET("/signing-keys", bh.SigningKeys())
+	admin.GET("/keys", bh.ListAPIKeys())
+	admin.POST("/keys", bh.CreateAPIKey())
+	admin.DELETE("/keys/:id", bh.RevokeAPIKey())
+	admin.GET("/features", bh.Features())
+	admin.GET("/indexes", bh.IndexStats())
+	admin.GET("/read-only", bh.ReadOnly())
+	admin.POST("/read-only", bh.SetReadOnly(true))
+	admin.DELETE("/read-only", bh.SetReadOnly(false))
 
 	r.NoRoute(bh.BadRequest)
 }
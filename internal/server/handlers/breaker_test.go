@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mocks"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestBreakerOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{
+			name:   "Update by URI",
+			method: http.MethodPost,
+			url:    "/update/gauge/Alloc/1.5",
+		},
+		{
+			name:   "Updates",
+			method: http.MethodPost,
+			url:    "/updates/",
+			body:   `[{"id":"Alloc","type":"gauge","value":1.5}]`,
+		},
+		{
+			name:   "Value by URI",
+			method: http.MethodGet,
+			url:    "/value/gauge/Alloc",
+		},
+		{
+			name:   "Values",
+			method: http.MethodGet,
+			url:    "/",
+		},
+	}
+
+	config.Config.StorageBreakerOpenTimeout = time.Second * 30
+	defer func() { config.Config.StorageBreakerOpenTimeout = 0 }()
+
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	m := mocks.NewMockStorage(c)
+	m.EXPECT().GetMiddleware().Return(func(_ *gin.Context) {})
+	m.EXPECT().SetGauge(gomock.Any(), gomock.Any()).Return(driver.ErrBadConn)
+
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+	store := storage.Break(m, "database", breaker.Settings{Threshold: 1, Window: 1, MinRequests: 1, OpenTimeout: time.Hour}, log)
+
+	r := setupRouter(store, log)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc/1.5", nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusServiceUnavailable, w.Code)
+			assert.Equal(t, "30", w.Header().Get("Retry-After"))
+		})
+	}
+}
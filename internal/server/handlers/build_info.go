@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+)
+
+func (bh baseHandler) BuildInfo() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, buildinfo.Get())
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestBuildInfo(t *testing.T) {
+	buildinfo.Set(buildinfo.New("v1.0.0", "2023-10-15", ""))
+	defer buildinfo.Set(buildinfo.New("", "", ""))
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/buildinfo", nil)
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"version":"v1.0.0","date":"2023-10-15","commit":"N/A"}`, w.Body.String())
+}
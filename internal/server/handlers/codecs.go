@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// bindWithHandle разбирает тело бинарным форматом ugorji (MessagePack, CBOR). Поля называются так же,
+// как в JSON, и проверяются теми же тегами binding.
+func bindWithHandle(handle codec.Handle, name string) func(bh baseHandler, ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
+	return func(bh baseHandler, ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
+		err := codec.NewDecoder(ctx.Request.Body, handle).Decode(obj)
+		if errors.Is(err, io.EOF) {
+			return &models.ErrorResponse{Error: "Request body not provided."}, http.StatusBadRequest, err
+		} else if err != nil {
+			return &models.ErrorResponse{Error: fmt.Sprintf("%s error: %s", name, err)}, http.StatusBadRequest, err
+		}
+
+		if err = binding.Validator.ValidateStruct(obj); err != nil {
+			if ok, errResponse := bh.parseBindingErrors(obj, err); ok {
+				return errResponse, http.StatusBadRequest, err
+			}
+
+			return nil, http.StatusInternalServerError, err
+		}
+
+		return nil, 0, nil
+	}
+}
+
+// renderWithHandle отдаёт тело, закодированное через ugorji, для форматов без своего render в gin.
+func renderWithHandle(handle codec.Handle, contentType string) func(ctx *gin.Context, statusCode int, obj any) {
+	return func(ctx *gin.Context, statusCode int, obj any) {
+		var body []byte
+		if err := codec.NewEncoderBytes(&body, handle).Encode(obj); err != nil {
+			_ = ctx.Error(err)
+			ctx.Status(http.StatusInternalServerError)
+
+			return
+		}
+
+		ctx.Data(statusCode, contentType, body)
+	}
+}
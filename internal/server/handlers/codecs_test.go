@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+	"go.uber.org/zap/zaptest"
+
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestCodecs(t *testing.T) {
+	tests := []struct {
+		name        string
+		handle      codec.Handle
+		contentType string
+		accept      string
+	}{
+		{name: "msgpack", handle: new(codec.MsgpackHandle), contentType: "application/msgpack", accept: "application/x-msgpack"},
+		{name: "cbor", handle: new(codec.CborHandle), contentType: "application/cbor", accept: "text/html;q=0.9, application/cbor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testCodec(t, tt.handle, tt.contentType, tt.accept)
+		})
+	}
+}
+
+func testCodec(t *testing.T, handle codec.Handle, contentType, accept string) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	encode := func(obj any) []byte {
+		var body []byte
+		require.NoError(t, codec.NewEncoderBytes(&body, handle).Encode(obj))
+		return body
+	}
+	do := func(method, path string, body []byte, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+	header := map[string]string{"Content-Type": contentType}
+
+	w := do(http.MethodPost, "/updates", encode([]models.MetricsUpdate{
+		{ID: "Alloc", MType: "gauge", Value: getPointerFloat64(1.5)},
+		{ID: "PollCount", MType: "counter", Delta: getPointerInt64(3)},
+	}), header)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = do(http.MethodPost, "/value", encode(models.MetricsValue{ID: "Alloc", MType: "gauge"}), header)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), contentType)
+
+	var value models.MetricsValue
+	require.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), handle).Decode(&value))
+	assert.Equal(t, "Alloc", value.ID)
+	assert.Equal(t, 1.5, *value.Value)
+
+	w = do(http.MethodGet, "/", nil, map[string]string{"Accept": accept})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var values []models.MetricsValue
+	require.NoError(t, codec.NewDecoderBytes(w.Body.Bytes(), handle).Decode(&values))
+	assert.Len(t, values, 2)
+
+	w = do(http.MethodPost, "/update", encode(models.MetricsUpdate{ID: "Alloc", MType: "gauge"}), header)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Field validation for \"Value\" failed on the 'required_if=MType gauge' tag.","field":"value","expected":"required_if=MType gauge"}`, w.Body.String())
+
+	w = do(http.MethodPost, "/update", []byte{0xff}, header)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, "/update", nil, header)
+	assert.JSONEq(t, `{"error":"Request body not provided."}`, w.Body.String())
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestTypeConflict(t *testing.T) {
+	tests := []struct {
+		name             string
+		url              string
+		body             string
+		wantedStatusCode int
+		wantedResponse   *models.ConflictResponse
+	}{
+		{
+			name:             "Same type",
+			url:              "/update/gauge/Alloc/2.5",
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Counter over gauge",
+			url:              "/update/counter/Alloc/1",
+			wantedStatusCode: http.StatusConflict,
+			wantedResponse: &models.ConflictResponse{
+				Error:         "Metric \"Alloc\" already exists with type gauge.",
+				ID:            "Alloc",
+				ExistingType:  "gauge",
+				RequestedType: "counter",
+			},
+		},
+		{
+			name:             "Gauge over counter (batch)",
+			url:              "/updates/",
+			body:             `[{"id":"PollCount","type":"gauge","value":1.5}]`,
+			wantedStatusCode: http.StatusConflict,
+			wantedResponse: &models.ConflictResponse{
+				Error:         "Metric \"PollCount\" already exists with type counter.",
+				ID:            "PollCount",
+				ExistingType:  "counter",
+				RequestedType: "gauge",
+			},
+		},
+	}
+
+	mem := memstorage.NewMem()
+	require.NoError(t, mem.SetGauge("Alloc", getPointerFloat64(1.5)))
+	require.NoError(t, mem.AddCounter("PollCount", getPointerInt64(1)))
+
+	r := setupRouter(storage.RejectTypeConflicts(mem), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.wantedStatusCode, w.Code)
+
+			if tt.wantedResponse != nil {
+				var response models.ConflictResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, *tt.wantedResponse, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestCounterPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             string
+		initial          int64
+		delta            string
+		wantedStatusCode int
+		wantedValue      int64
+	}{
+		{
+			name:             "Allow negative delta",
+			mode:             "allow",
+			initial:          10,
+			delta:            "-3",
+			wantedStatusCode: http.StatusOK,
+			wantedValue:      7,
+		},
+		{
+			name:             "Allow overflow",
+			mode:             "allow",
+			initial:          math.MaxInt64,
+			delta:            "1",
+			wantedStatusCode: http.StatusOK,
+			wantedValue:      math.MinInt64,
+		},
+		{
+			name:             "Clamp negative delta",
+			mode:             "clamp",
+			initial:          10,
+			delta:            "-3",
+			wantedStatusCode: http.StatusOK,
+			wantedValue:      10,
+		},
+		{
+			name:             "Clamp overflow",
+			mode:             "clamp",
+			initial:          math.MaxInt64,
+			delta:            "1",
+			wantedStatusCode: http.StatusOK,
+			wantedValue:      math.MaxInt64,
+		},
+		{
+			name:             "Reject negative delta",
+			mode:             "reject",
+			initial:          10,
+			delta:            "-3",
+			wantedStatusCode: http.StatusBadRequest,
+			wantedValue:      10,
+		},
+		{
+			name:             "Reject overflow",
+			mode:             "reject",
+			initial:          math.MaxInt64,
+			delta:            "1",
+			wantedStatusCode: http.StatusBadRequest,
+			wantedValue:      math.MaxInt64,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, endpoint := range []string{"uri", "updates"} {
+			t.Run(fmt.Sprintf("%s (%s)", tt.name, endpoint), func(t *testing.T) {
+				config.Config.CounterPolicy = tt.mode
+				defer func() { config.Config.CounterPolicy = "" }()
+
+				storage := memstorage.NewMem()
+				require.NoError(t, storage.AddCounter("Test", getPointerInt64(tt.initial)))
+
+				r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+				var req *http.Request
+				if endpoint == "uri" {
+					req = httptest.NewRequest(http.MethodPost, "/update/counter/Test/"+tt.delta, nil)
+				} else {
+					body := fmt.Sprintf(`[{"id":"Test","type":"counter","delta":%s}]`, tt.delta)
+					req = httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+					req.Header.Set("Content-Type", "application/json")
+				}
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				assert.Equal(t, tt.wantedStatusCode, w.Code)
+
+				value, err := storage.GetCounter("Test")
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantedValue, *value)
+			})
+		}
+	}
+}
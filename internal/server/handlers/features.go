@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+// Feature скрывает маршрут, пока экспериментальная возможность не включена: такой запрос обрабатывается как несуществующий маршрут.
+func (bh baseHandler) Feature(name string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !config.FeatureEnabled(name) {
+			bh.BadRequest(ctx)
+		}
+	}
+}
+
+func (bh baseHandler) Features() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, config.Features())
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestFeature(t *testing.T) {
+	tests := []struct {
+		name             string
+		features         []string
+		wantedStatusCode int
+	}{
+		{
+			name:             "Disabled feature",
+			wantedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:             "Enabled feature",
+			features:         []string{config.FeatureLabels},
+			wantedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.Features = tt.features
+			defer func() { config.Config.Features = nil }()
+
+			bh := baseHandler{storage: memstorage.NewMem(), log: logger.Wrap(zaptest.NewLogger(t).Sugar())}
+
+			r := gin.New()
+			r.GET("/experimental", bh.Feature(config.FeatureLabels), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/experimental", nil))
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// validateAndShouldBindForm разбирает тело application/x-www-form-urlencoded с полями id, type, delta и value.
+// В форме передаётся одна метрика, поэтому /updates её не принимает.
+func (bh baseHandler) validateAndShouldBindForm(ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
+	if _, ok := obj.(*[]models.MetricsUpdate); ok {
+		err := fmt.Errorf("form bodies are not supported for %T", obj)
+		return &models.ErrorResponse{Error: "A form body carries a single metric, use /update."}, http.StatusBadRequest, err
+	}
+
+	if err := ctx.ShouldBindWith(obj, binding.FormPost); err != nil {
+		if ok, errResponse := bh.parseBindingErrors(obj, err); ok {
+			return errResponse, http.StatusBadRequest, err
+		}
+
+		return &models.ErrorResponse{Error: fmt.Sprintf("Form error: %s", err)}, http.StatusBadRequest, err
+	}
+
+	return nil, 0, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"github.com/ugorji/go/codec"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+// Форматы тел запросов и ответов эндпоинтов с метриками. JSON - основной, остальные компактнее.
+const (
+	formatJSON     = "json"
+	formatProtobuf = "protobuf"
+	formatMsgPack  = "msgpack"
+	formatCBOR     = "cbor"
+	formatForm     = "form"
+)
+
+// MIMECBOR - Content-Type тел в формате CBOR (RFC 8949).
+const MIMECBOR = "application/cbor"
+
+// bodyCodec разбирает и отдаёт тела одного формата. Новый формат добавляется только в bodyCodecs,
+// обработчики про конкретные форматы не знают. Форматы без render принимаются только в запросах.
+type bodyCodec struct {
+	mimeTypes []string
+	bind      func(bh baseHandler, ctx *gin.Context, obj any) (*models.ErrorResponse, int, error)
+	render    func(ctx *gin.Context, statusCode int, obj any)
+}
+
+var bodyCodecs = map[string]bodyCodec{
+	formatJSON: {
+		mimeTypes: []string{binding.MIMEJSON},
+		bind:      baseHandler.validateAndShouldBindJSON,
+		render: func(ctx *gin.Context, statusCode int, obj any) {
+			ctx.JSON(statusCode, obj)
+		},
+	},
+	formatProtobuf: {
+		mimeTypes: []string{metricspb.ContentType},
+		bind:      baseHandler.validateAndShouldBindProtobuf,
+		render:    renderProtobuf,
+	},
+	formatMsgPack: {
+		mimeTypes: []string{binding.MIMEMSGPACK, binding.MIMEMSGPACK2},
+		bind:      bindWithHandle(new(codec.MsgpackHandle), "MessagePack"),
+		render: func(ctx *gin.Context, statusCode int, obj any) {
+			ctx.Render(statusCode, render.MsgPack{Data: obj})
+		},
+	},
+	formatCBOR: {
+		mimeTypes: []string{MIMECBOR},
+		bind:      bindWithHandle(new(codec.CborHandle), "CBOR"),
+		render:    renderWithHandle(new(codec.CborHandle), MIMECBOR),
+	},
+	formatForm: {
+		mimeTypes: []string{binding.MIMEPOSTForm},
+		bind:      baseHandler.validateAndShouldBindForm,
+	},
+}
+
+// requestFormat определяет формат тела запроса по Content-Type; пустая строка - формат не поддерживается.
+func (bh baseHandler) requestFormat(ctx *gin.Context) string {
+	return formatOf(ctx.GetHeader("Content-Type"))
+}
+
+// responseFormat выбирает формат ответа по Accept. Без Accept ответ приходит в формате запроса.
+func (bh baseHandler) responseFormat(ctx *gin.Context) string {
+	for _, accepted := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		if format := formatOf(accepted); bodyCodecs[format].render != nil {
+			return format
+		}
+	}
+
+	if format := bh.requestFormat(ctx); bodyCodecs[format].render != nil {
+		return format
+	}
+
+	return formatJSON
+}
+
+func formatOf(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	for format, c := range bodyCodecs {
+		for _, mimeType := range c.mimeTypes {
+			if strings.EqualFold(mediaType, mimeType) {
+				return format
+			}
+		}
+	}
+
+	return ""
+}
+
+// validateAndShouldBind разбирает тело в формате из Content-Type.
+func (bh baseHandler) validateAndShouldBind(ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
+	c, ok := bodyCodecs[bh.requestFormat(ctx)]
+	if !ok {
+		c = bodyCodecs[formatJSON]
+	}
+
+	return c.bind(bh, ctx, obj)
+}
+
+// render отвечает в формате, который просит клиент.
+func (bh baseHandler) render(ctx *gin.Context, statusCode int, obj any) {
+	bodyCodecs[bh.responseFormat(ctx)].render(ctx, statusCode, obj)
+}
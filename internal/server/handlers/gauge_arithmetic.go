@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+// AdjustGauge изменяет gauge на delta из тела запроса на стороне хранилища, поэтому одновременные
+// изменения от нескольких клиентов не затирают друг друга. sign равен 1 для add и -1 для sub.
+func (bh baseHandler) AdjustGauge(sign float64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !bh.validateContentType(ctx, "application/json", false) {
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
+			bh.handleBadRequest(ctx)
+			return
+		}
+
+		id := ctx.Param("name")
+
+		var obj models.GaugeDelta
+		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &obj); err != nil {
+			if statusCode == http.StatusInternalServerError {
+				bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
+			}
+
+			if response == nil {
+				ctx.Status(statusCode)
+			} else {
+				ctx.JSON(statusCode, response)
+			}
+
+			ctx.Abort()
+
+			return
+		}
+
+		delta := sign * *obj.Delta
+		if err := storage.AddGauge(bh.storage, id, &delta); err != nil {
+			bh.handleStorageError(ctx, "Failed adjust gauge value", err)
+			return
+		}
+
+		response := models.MetricsValue{ID: id, MType: string(models.GaugeType)}
+		if value, err := bh.storage.GetGauge(id); err != nil {
+			bh.logger(ctx).Errorf("Failed to get adjusted gauge value: %s", err)
+		} else {
+			response.Value = value
+		}
+
+		ctx.JSON(http.StatusOK, response)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestAdjustGauge(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	adjust := func(op, body, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/gauge/Occupancy/"+op, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	tests := []struct {
+		name             string
+		op               string
+		body             string
+		contentType      string
+		wantedStatusCode int
+		wantedValue      float64
+	}{
+		{name: "Positive add (new gauge)", op: "add", body: `{"delta": 5}`, contentType: "application/json", wantedStatusCode: http.StatusOK, wantedValue: 5},
+		{name: "Positive sub", op: "sub", body: `{"delta": 1.5}`, contentType: "application/json", wantedStatusCode: http.StatusOK, wantedValue: 3.5},
+		{name: "Positive add (negative delta)", op: "add", body: `{"delta": -0.5}`, contentType: "application/json", wantedStatusCode: http.StatusOK, wantedValue: 3},
+		{name: "Negative (without delta)", op: "add", body: `{}`, contentType: "application/json", wantedStatusCode: http.StatusBadRequest},
+		{name: "Negative (text delta)", op: "add", body: `{"delta": "1"}`, contentType: "application/json", wantedStatusCode: http.StatusBadRequest},
+		{name: "Negative (invalid content-type)", op: "add", body: `{"delta": 1}`, contentType: "text/plain", wantedStatusCode: http.StatusBadRequest},
+		{name: "Negative (unknown operation)", op: "mul", body: `{"delta": 2}`, contentType: "application/json", wantedStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := adjust(tt.op, tt.body, tt.contentType)
+			require.Equal(t, tt.wantedStatusCode, w.Code, w.Body.String())
+
+			if tt.wantedStatusCode != http.StatusOK {
+				return
+			}
+
+			var response models.MetricsValue
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "Occupancy", response.ID)
+			assert.Equal(t, string(models.GaugeType), response.MType)
+			require.NotNil(t, response.Value)
+			assert.Equal(t, tt.wantedValue, *response.Value)
+		})
+	}
+}
+
+func TestAdjustGaugeConcurrent(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		for _, op := range []string{"add", "add", "sub"} {
+			wg.Add(1)
+			go func(op string) {
+				defer wg.Done()
+
+				req := httptest.NewRequest(http.MethodPost, "/api/gauge/Workers/"+op, bytes.NewBufferString(`{"delta": 1}`))
+				req.Header.Set("Content-Type", "application/json")
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				assert.Equal(t, http.StatusOK, w.Code)
+			}(op)
+		}
+	}
+	wg.Wait()
+
+	value, err := storage.GetGauge("Workers")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, *value)
+}
+
+func TestAdjustGaugePolicy(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	adjust := func(name, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/gauge/"+name+"/add", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	t.Run("Rounding", func(t *testing.T) {
+		config.Config.GaugeRounding, config.Config.GaugePrecision = policy.RoundDecimal, 2
+		defer func() { config.Config.GaugeRounding, config.Config.GaugePrecision = "", 0 }()
+
+		for i := 0; i < 3; i++ {
+			require.Equal(t, http.StatusOK, adjust("Load", `{"delta": 0.1}`))
+		}
+
+		value, err := storage.GetGauge("Load")
+		require.NoError(t, err)
+		assert.Equal(t, 0.3, *value)
+	})
+
+	huge := math.MaxFloat64
+	for _, tt := range []struct {
+		mode             string
+		wantedStatusCode int
+		wantedInf        bool
+	}{
+		{mode: policy.GaugeStore, wantedStatusCode: http.StatusOK, wantedInf: true},
+		{mode: policy.GaugeDrop, wantedStatusCode: http.StatusOK},
+		{mode: policy.GaugeReject, wantedStatusCode: http.StatusBadRequest},
+	} {
+		t.Run("Infinite sum ("+tt.mode+")", func(t *testing.T) {
+			config.Config.GaugePolicy = tt.mode
+			defer func() { config.Config.GaugePolicy = "" }()
+
+			require.NoError(t, storage.SetGauge("Huge", &huge))
+			assert.Equal(t, tt.wantedStatusCode, adjust("Huge", `{"delta": 1.7976931348623157e308}`))
+
+			value, err := storage.GetGauge("Huge")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantedInf, math.IsInf(*value, 1))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestGaugePolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             string
+		wantedStatusCode int
+		wantedStored     bool
+	}{
+		{
+			name:             "Store",
+			mode:             "store",
+			wantedStatusCode: http.StatusOK,
+			wantedStored:     true,
+		},
+		{
+			name:             "Drop",
+			mode:             "drop",
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Reject",
+			mode:             "reject",
+			wantedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.GaugePolicy = tt.mode
+			defer func() { config.Config.GaugePolicy = "" }()
+
+			storage := memstorage.NewMem()
+			r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/update/gauge/Test/NaN", nil))
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+
+			_, err := storage.GetGauge("Test")
+			if tt.wantedStored {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errs.ErrStorageInvalidGaugeName)
+			}
+		})
+	}
+}
+
+func TestFilterGauges(t *testing.T) {
+	config.Config.GaugePolicy = "drop"
+	defer func() { config.Config.GaugePolicy = "" }()
+
+	bh := baseHandler{log: logger.Wrap(zaptest.NewLogger(t).Sugar())}
+
+	objects, err := bh.filterGauges([]models.MetricsUpdate{
+		{ID: "Finite", MType: "gauge", Value: getPointerFloat64(1.5)},
+		{ID: "Inf", MType: "gauge", Value: getPointerFloat64(math.Inf(1))},
+		{ID: "Counter", MType: "counter", Delta: getPointerInt64(1)},
+	})
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "Finite", objects[0].ID)
+	assert.Equal(t, "Counter", objects[1].ID)
+
+	config.Config.GaugePolicy = "reject"
+	_, err = bh.filterGauges([]models.MetricsUpdate{{ID: "NaN", MType: "gauge", Value: getPointerFloat64(math.NaN())}})
+	assert.ErrorIs(t, err, errs.ErrGaugeNotFinite)
+}
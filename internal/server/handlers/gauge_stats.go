@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+func (bh baseHandler) GaugeStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		stats, err := storage.GaugeStats(bh.storage, ctx.Param("name"))
+		if err != nil {
+			bh.handleStatsError(ctx, "Failed to get gauge stats", err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, stats)
+		ctx.Abort()
+	}
+}
+
+// ResetGaugeStats начинает статистику gauge заново, само значение gauge не меняется.
+func (bh baseHandler) ResetGaugeStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := storage.ResetGaugeStats(bh.storage, ctx.Param("name")); err != nil {
+			bh.handleStatsError(ctx, "Failed to reset gauge stats", err)
+			return
+		}
+
+		ctx.Status(http.StatusNoContent)
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) handleStatsError(ctx *gin.Context, message string, err error) {
+	if errors.Is(err, errs.ErrGaugeStatsUnsupported) {
+		ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "The storage does not keep gauge stats."})
+		ctx.Abort()
+
+		return
+	}
+
+	if errors.Is(err, errs.ErrStorageInvalidGaugeName) {
+		bh.logger(ctx).Debugf("%s: %s", message, err)
+
+		ctx.Status(http.StatusNotFound)
+		ctx.Abort()
+
+		return
+	}
+
+	bh.handleStorageError(ctx, message, err)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestGaugeStats(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, value := range []float64{4, -2, math.NaN(), 10} {
+		value := value
+		require.NoError(t, storage.SetGauge("Temperature", &value))
+	}
+
+	getStats := func(name string) (*httptest.ResponseRecorder, models.GaugeStats) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats/"+name, nil))
+
+		var stats models.GaugeStats
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		}
+
+		return w, stats
+	}
+
+	w, stats := getStats("Temperature")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Temperature", stats.ID)
+	assert.Equal(t, int64(3), stats.Count, "NaN must not be counted")
+	assert.Equal(t, -2.0, stats.Min)
+	assert.Equal(t, 10.0, stats.Max)
+	assert.Equal(t, 4.0, stats.Avg)
+	assert.False(t, stats.Since.IsZero())
+
+	w, _ = getStats("Unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/stats/Temperature", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w, stats = getStats("Temperature")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), stats.Count)
+
+	value := 7.0
+	require.NoError(t, storage.SetGauge("Temperature", &value))
+
+	_, stats = getStats("Temperature")
+	assert.Equal(t, models.GaugeStats{ID: "Temperature", Count: 1, Min: 7, Max: 7, Avg: 7, Since: stats.Since}, stats)
+
+	gauge, err := storage.GetGauge("Temperature")
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, *gauge)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/stats/Unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGaugeStatsUnsupported(t *testing.T) {
+	r := setupRouter(struct{ models.Storage }{memstorage.NewMem()}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/api/stats/Temperature", nil))
+		assert.Equal(t, http.StatusNotImplemented, w.Code, method)
+	}
+}
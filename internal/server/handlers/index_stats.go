@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+// IndexStats отдаёт статистику использования индексов хранилища, чтобы находить лишние и недостающие индексы.
+func (bh baseHandler) IndexStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		stats, err := storage.IndexStats(ctx.Request.Context(), bh.storage)
+		if errors.Is(err, errs.ErrIndexStatsUnsupported) {
+			ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "The storage does not report index stats."})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to get index stats", err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, stats)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// jsonPath возвращает путь к значению, разбор которого закончился на offset байте body,
+// например "[37].value". Если body сломан раньше, возвращается путь к месту поломки.
+func jsonPath(body []byte, offset int64) string {
+	type frame struct {
+		array   bool
+		index   int
+		key     string
+		needKey bool
+	}
+
+	var stack []*frame
+	path := func() string {
+		var b strings.Builder
+		for _, f := range stack {
+			if f.array {
+				fmt.Fprintf(&b, "[%d]", f.index)
+			} else if f.key != "" {
+				if b.Len() > 0 {
+					b.WriteByte('.')
+				}
+				b.WriteString(f.key)
+			}
+		}
+
+		return b.String()
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return path()
+		}
+
+		switch tok {
+		case json.Delim('['), json.Delim('{'):
+			stack = append(stack, &frame{array: tok == json.Delim('['), needKey: tok == json.Delim('{')})
+			continue
+		case json.Delim(']'), json.Delim('}'):
+			stack = stack[:len(stack)-1]
+		default:
+			if top := len(stack) - 1; top >= 0 && stack[top].needKey {
+				stack[top].key, stack[top].needKey = tok.(string), false
+				continue
+			}
+		}
+
+		if dec.InputOffset() >= offset {
+			return path()
+		}
+
+		// Значение разобрано целиком, следующее будет у другого индекса или ключа
+		if top := len(stack) - 1; top >= 0 {
+			if stack[top].array {
+				stack[top].index++
+			} else {
+				stack[top].key, stack[top].needKey = "", true
+			}
+		}
+	}
+}
+
+// leadingSpace - число пробельных байт в начале body. Смещения в json.UnmarshalTypeError
+// отсчитываются от начала значения, а не тела.
+func leadingSpace(body []byte) int64 {
+	return int64(len(body) - len(bytes.TrimLeft(body, " \t\r\n")))
+}
+
+// jsonName возвращает имя поля structField в JSON. obj может быть структурой, слайсом структур или указателем на них.
+func jsonName(obj any, structField string) string {
+	t := reflect.TypeOf(obj)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+
+	if t != nil && t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName(structField); ok {
+			if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+	}
+
+	return structField
+}
+
+// invalidElement находит первый элемент слайса obj, который не проходит проверку тегов binding.
+func invalidElement(obj any) (int, any) {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Kind() != reflect.Slice {
+		return -1, obj
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := binding.Validator.ValidateStruct(v.Index(i).Interface()); err != nil {
+			return i, v.Index(i).Interface()
+		}
+	}
+
+	return -1, obj
+}
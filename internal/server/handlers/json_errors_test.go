@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestJSONPath(t *testing.T) {
+	body := []byte(`[{"id":"a","value":1},{"id":"b","tags":[1,2],"value":"x"}]`)
+
+	assert.Equal(t, "[0].id", jsonPath(body, int64(bytes.Index(body, []byte(`"a"`))+3)))
+	assert.Equal(t, "[1].tags[1]", jsonPath(body, int64(bytes.Index(body, []byte(`2]`))+1)))
+	assert.Equal(t, "[1].value", jsonPath(body, int64(bytes.Index(body, []byte(`"x"`))+3)))
+
+	broken := []byte(`[{"id":"a"},{"id":"b","value":}]`)
+	assert.Equal(t, "[1].value", jsonPath(broken, int64(len(broken))))
+}
+
+func TestUpdatesErrorDetails(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantedBody string
+	}{
+		{
+			name:       "Type error",
+			body:       `[{"id":"a","type":"gauge","value":1},{"id":"b","type":"counter","delta":1},{"id":"c","type":"gauge","value":"1"}]`,
+			wantedBody: `{"error":"Field value \"[2].value\" must be float64.","field":"[2].value","expected":"float64","offset":111}`,
+		},
+		{
+			name:       "Syntax error",
+			body:       `[{"id":"a","type":"gauge","value":1},{"id":"b","type":"counter","delta":}]`,
+			wantedBody: `{"error":"JSON error: invalid character '}' after array element","field":"[1]","offset":73}`,
+		},
+		{
+			name:       "Not an array",
+			body:       `{"id":"a","type":"gauge","value":1}`,
+			wantedBody: `{"error":"JSON error: expected an array of metrics, got {","offset":1}`,
+		},
+		{
+			name:       "Validation error",
+			body:       `[{"id":"a","type":"gauge","value":1},{"id":"b","type":"counter"}]`,
+			wantedBody: `{"error":"Element 1: Field validation for \"Delta\" failed on the 'required_if=MType counter' tag.","field":"[1].delta","expected":"required_if=MType counter"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, tt.wantedBody, w.Body.String())
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mocks"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestPing(t *testing.T) {
@@ -45,7 +46,7 @@ func TestPing(t *testing.T) {
 			m.EXPECT().GetMiddleware().Return(func(_ *gin.Context) {})
 			m.EXPECT().Ping(gomock.Any()).Return(tt.err)
 
-			r := setupRouter(m, zaptest.NewLogger(t).Sugar())
+			r := setupRouter(m, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
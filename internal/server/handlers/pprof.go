@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupPprof подключает профилировщик. Он есть только на отдельном служебном адресе.
+func setupPprof(r gin.IRouter) {
+	r.GET("/", gin.WrapF(pprof.Index))
+	r.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	r.GET("/profile", gin.WrapF(pprof.Profile))
+	r.POST("/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/trace", gin.WrapF(pprof.Trace))
+	r.GET("/:profile", func(ctx *gin.Context) {
+		pprof.Handler(ctx.Param("profile")).ServeHTTP(ctx.Writer, ctx.Request)
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+// validateAndShouldBindProtobuf разбирает тело в protobuf и проверяет его теми же тегами binding, что и JSON.
+func (bh baseHandler) validateAndShouldBindProtobuf(ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if len(body) == 0 {
+		return &models.ErrorResponse{Error: "Request body not provided."}, http.StatusBadRequest, io.EOF
+	}
+
+	switch obj := obj.(type) {
+	case *[]models.MetricsUpdate:
+		var metrics []metricspb.Metric
+		if metrics, err = metricspb.UnmarshalList(body); err == nil {
+			*obj = make([]models.MetricsUpdate, 0, len(metrics))
+			for _, metric := range metrics {
+				*obj = append(*obj, models.MetricsUpdate{ID: metric.ID, MType: metric.Type, Delta: metric.Delta, Value: metric.Value})
+			}
+		}
+	case *models.MetricsUpdate:
+		var metric metricspb.Metric
+		if metric, err = metricspb.Unmarshal(body); err == nil {
+			*obj = models.MetricsUpdate{ID: metric.ID, MType: metric.Type, Delta: metric.Delta, Value: metric.Value}
+		}
+	case *models.MetricsValue:
+		var metric metricspb.Metric
+		if metric, err = metricspb.Unmarshal(body); err == nil {
+			*obj = models.MetricsValue{ID: metric.ID, MType: metric.Type, Delta: metric.Delta, Value: metric.Value}
+		}
+	default:
+		return nil, http.StatusInternalServerError, fmt.Errorf("protobuf is not supported for %T", obj)
+	}
+
+	if err != nil {
+		return &models.ErrorResponse{Error: fmt.Sprintf("Protobuf error: %s", err)}, http.StatusBadRequest, err
+	}
+
+	if err = binding.Validator.ValidateStruct(obj); err != nil {
+		if ok, errResponse := bh.parseBindingErrors(obj, err); ok {
+			return errResponse, http.StatusBadRequest, err
+		}
+
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return nil, 0, nil
+}
+
+// renderProtobuf отдаёт метрику или список метрик в protobuf.
+func renderProtobuf(ctx *gin.Context, statusCode int, obj any) {
+	switch obj := obj.(type) {
+	case models.MetricsValue:
+		ctx.Data(statusCode, metricspb.ContentType, metricspb.Marshal(toProtobuf(obj)))
+	case []models.MetricsValue:
+		list := make([]metricspb.Metric, 0, len(obj))
+		for _, value := range obj {
+			list = append(list, toProtobuf(value))
+		}
+
+		ctx.Data(statusCode, metricspb.ContentType, metricspb.MarshalList(list))
+	default:
+		_ = ctx.Error(fmt.Errorf("protobuf is not supported for %T", obj))
+		ctx.Status(http.StatusInternalServerError)
+	}
+}
+
+func toProtobuf(obj models.MetricsValue) metricspb.Metric {
+	return metricspb.Metric{ID: obj.ID, Type: obj.MType, Delta: obj.Delta, Value: obj.Value, UpdatedAt: obj.UpdatedAt}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/metricspb"
+)
+
+func TestProtobuf(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	do := func(method, path string, body []byte, header map[string]string) *http.Response {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Result()
+	}
+	protobuf := map[string]string{"Content-Type": metricspb.ContentType}
+
+	value, delta := 1.5, int64(3)
+	res := do(http.MethodPost, "/updates", metricspb.MarshalList([]metricspb.Metric{
+		{ID: "Alloc", Type: "gauge", Value: &value},
+		{ID: "PollCount", Type: "counter", Delta: &delta},
+	}), protobuf)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Без Accept ответ приходит в том же формате, что и запрос
+	res = do(http.MethodPost, "/update", metricspb.Marshal(metricspb.Metric{ID: "PollCount", Type: "counter", Delta: &delta}), protobuf)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, metricspb.ContentType, res.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	updated, err := metricspb.Unmarshal(body)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *updated.Delta)
+
+	// Accept важнее формата запроса
+	res = do(http.MethodPost, "/value", metricspb.Marshal(metricspb.Metric{ID: "Alloc", Type: "gauge"}), map[string]string{
+		"Content-Type": metricspb.ContentType,
+		"Accept":       "application/json",
+	})
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Contains(t, res.Header.Get("Content-Type"), "application/json")
+	assert.Contains(t, string(body), `"value":1.5`)
+
+	res = do(http.MethodGet, "/", nil, map[string]string{"Accept": metricspb.ContentType})
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	list, err := metricspb.UnmarshalList(body)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	res = do(http.MethodPost, "/updates", []byte{0x0a, 0x05}, protobuf)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = do(http.MethodPost, "/update", metricspb.Marshal(metricspb.Metric{ID: "Alloc", Type: "gauge"}), protobuf)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.JSONEq(t, `{"error":"Field validation for \"Value\" failed on the 'required_if=MType gauge' tag.","field":"value","expected":"required_if=MType gauge"}`, string(body))
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+// Quantile считает квантиль гистограммы по её корзинам: ?q=0.99, остальные параметры запроса
+// отбирают ряды по меткам, например ?q=0.5&method=GetAll.
+func (bh baseHandler) Quantile() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := ctx.Param("name")
+
+		histogram, ok := selfmetrics.Default.Histogram(name)
+		if !ok {
+			ctx.JSON(http.StatusNotFound, models.ErrorResponse{Error: fmt.Sprintf("Histogram %s not found.", name)})
+			ctx.Abort()
+			return
+		}
+
+		q, err := strconv.ParseFloat(ctx.Query("q"), 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter q must be a number between 0 and 1."})
+			ctx.Abort()
+			return
+		}
+
+		filter := make(map[string]string)
+		for key, values := range ctx.Request.URL.Query() {
+			if key != "q" && len(values) > 0 {
+				filter[key] = values[0]
+			}
+		}
+
+		value, count, err := histogram.Quantile(q, filter)
+		if errors.Is(err, selfmetrics.ErrInvalidQuantile) || errors.Is(err, selfmetrics.ErrUnknownLabel) {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Invalid quantile query: %s.", err)})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.logger(ctx).Errorf("Failed to compute quantile of %s: %s", name, err)
+			ctx.Status(http.StatusInternalServerError)
+			ctx.Abort()
+			return
+		}
+
+		result := models.Quantile{ID: name, Quantile: q, Count: count}
+		if !math.IsNaN(value) {
+			result.Value = &value
+		}
+		if len(filter) > 0 {
+			result.Labels = filter
+		}
+
+		ctx.JSON(http.StatusOK, result)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestQuantile(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	selfmetrics.StorageQueryDuration.Observe(0.003, "quantile-test", "GetAll")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantedCode int
+	}{
+		{name: "Positive", path: "/api/quantile/metrics_storage_query_duration_seconds?q=0.5&backend=quantile-test", wantedCode: http.StatusOK},
+		{name: "Negative (unknown histogram)", path: "/api/quantile/Alloc?q=0.5", wantedCode: http.StatusNotFound},
+		{name: "Negative (without q)", path: "/api/quantile/metrics_storage_query_duration_seconds", wantedCode: http.StatusBadRequest},
+		{name: "Negative (q out of range)", path: "/api/quantile/metrics_storage_query_duration_seconds?q=99", wantedCode: http.StatusBadRequest},
+		{name: "Negative (unknown label)", path: "/api/quantile/metrics_storage_query_duration_seconds?q=0.5&host=a", wantedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.wantedCode, w.Code)
+
+			if tt.wantedCode != http.StatusOK {
+				return
+			}
+
+			var result models.Quantile
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, uint64(1), result.Count)
+			require.NotNil(t, result.Value)
+			assert.InDelta(t, 0.00375, *result.Value, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+const defaultRateWindow = 5 * time.Minute
+
+// CounterRate считает скорость роста counter в секунду по истории за окно ?window=5m.
+func (bh baseHandler) CounterRate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		window := defaultRateWindow
+		if raw := ctx.Query("window"); raw != "" {
+			var err error
+			if window, err = time.ParseDuration(raw); err != nil || window <= 0 {
+				ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter window must be a positive duration like 5m."})
+				ctx.Abort()
+				return
+			}
+		}
+
+		name := ctx.Param("name")
+
+		samples, err := storage.CounterHistory(bh.storage, name, time.Now().Add(-window))
+		if errors.Is(err, errs.ErrHistoryUnsupported) {
+			ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "The storage does not keep metric history."})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to get counter history", err)
+			return
+		}
+
+		if len(samples) == 0 {
+			ctx.Status(http.StatusNotFound)
+			ctx.Abort()
+			return
+		}
+
+		ctx.JSON(http.StatusOK, storage.CounterRate(name, window, samples))
+		ctx.Abort()
+	}
+}
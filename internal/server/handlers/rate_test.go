@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// historyStorage отдаёт заранее заданную историю counter, как хранилище в базе данных.
+type historyStorage struct {
+	*memstorage.MemStorage
+	samples map[string][]models.CounterSample
+}
+
+func (s historyStorage) GetCounterHistory(name string, _ time.Time) ([]models.CounterSample, error) {
+	return s.samples[name], nil
+}
+
+func TestCounterRate(t *testing.T) {
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+
+	now := time.Now()
+	store := historyStorage{
+		MemStorage: memstorage.NewMem(),
+		samples: map[string][]models.CounterSample{
+			"PollCount": {
+				{Delta: 5, CreatedAt: now.Add(-time.Minute)},
+				{Delta: 65, CreatedAt: now},
+			},
+		},
+	}
+	r := setupRouter(store, log)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantedCode int
+	}{
+		{name: "Positive", path: "/api/rate/PollCount?window=10m", wantedCode: http.StatusOK},
+		{name: "Positive (default window)", path: "/api/rate/PollCount", wantedCode: http.StatusOK},
+		{name: "Negative (without history)", path: "/api/rate/Unknown", wantedCode: http.StatusNotFound},
+		{name: "Negative (invalid window)", path: "/api/rate/PollCount?window=-1m", wantedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.wantedCode, w.Code)
+
+			if tt.wantedCode != http.StatusOK {
+				return
+			}
+
+			var rate models.CounterRate
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rate))
+			require.NotNil(t, rate.Rate)
+			assert.InDelta(t, 1.0, *rate.Rate, 1e-6)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	setupRouter(memstorage.NewMem(), log).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/rate/PollCount", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+const readOnlyRetryAfter = 60 // секунды
+
+func (bh baseHandler) Writable(ctx *gin.Context) {
+	if !bh.readOnly.Load() {
+		return
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(readOnlyRetryAfter))
+	ctx.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "The server is in read-only maintenance mode."})
+	ctx.Abort()
+}
+
+func (bh baseHandler) ReadOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, readOnlyResponse(bh.readOnly.Load()))
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) SetReadOnly(enabled bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if bh.readOnly.Swap(enabled) != enabled {
+			bh.logger(ctx).Infof("Read-only mode was switched to %t by %s.", enabled, ctx.ClientIP())
+		}
+
+		ctx.JSON(http.StatusOK, readOnlyResponse(enabled))
+		ctx.Abort()
+	}
+}
+
+func readOnlyResponse(enabled bool) adminResponse {
+	if enabled {
+		return adminResponse{Status: "read-only"}
+	}
+
+	return adminResponse{Status: "read-write"}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestReadOnly(t *testing.T) {
+	config.Config.AdminKey = "secret"
+	config.Config.ReadOnly = true
+	defer func() {
+		config.Config.AdminKey = ""
+		config.Config.ReadOnly = false
+	}()
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name             string
+		method           string
+		url              string
+		admin            bool
+		wantedStatusCode int
+		wantedRetryAfter string
+	}{
+		{
+			name:             "Update in read-only mode",
+			method:           http.MethodPost,
+			url:              "/update/gauge/test/1.5",
+			wantedStatusCode: http.StatusServiceUnavailable,
+			wantedRetryAfter: "60",
+		},
+		{
+			name:             "Read in read-only mode",
+			method:           http.MethodGet,
+			url:              "/",
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Disable read-only mode",
+			method:           http.MethodDelete,
+			url:              "/admin/read-only",
+			admin:            true,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Update after read-only mode",
+			method:           http.MethodPost,
+			url:              "/update/gauge/test/1.5",
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Enable read-only mode",
+			method:           http.MethodPost,
+			url:              "/admin/read-only",
+			admin:            true,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Batch update in read-only mode",
+			method:           http.MethodPost,
+			url:              "/updates/",
+			wantedStatusCode: http.StatusServiceUnavailable,
+			wantedRetryAfter: "60",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.admin {
+				req.Header.Set("Authorization", "Bearer secret")
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+			assert.Equal(t, tt.wantedRetryAfter, w.Header().Get("Retry-After"))
+		})
+	}
+}
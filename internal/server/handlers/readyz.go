@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/health"
+)
+
+type readyzResponse struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components"`
+}
+
+func (bh baseHandler) Readyz() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ready, components := health.Ready()
+
+		if ready {
+			ctx.JSON(http.StatusOK, readyzResponse{Status: "ready", Components: components})
+		} else {
+			ctx.JSON(http.StatusServiceUnavailable, readyzResponse{Status: "not ready", Components: components})
+		}
+
+		ctx.Abort()
+	}
+}
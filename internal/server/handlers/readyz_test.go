@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/health"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name             string
+		err              error
+		wantedStatusCode int
+		wantedResponse   readyzResponse
+	}{
+		{
+			name:             "Ready",
+			wantedStatusCode: http.StatusOK,
+			wantedResponse:   readyzResponse{Status: "ready", Components: map[string]string{"database": "ok"}},
+		},
+		{
+			name:             "Database unavailable",
+			err:              errors.New("connection refused"),
+			wantedStatusCode: http.StatusServiceUnavailable,
+			wantedResponse:   readyzResponse{Status: "not ready", Components: map[string]string{"database": "connection refused"}},
+		},
+	}
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	defer health.Remove("database")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health.Set("database", tt.err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, tt.wantedStatusCode, w.Code)
+
+			var response readyzResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantedResponse, response)
+		})
+	}
+}
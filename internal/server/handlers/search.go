@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+// Search ищет метрики по имени: ?q=Alloc* для glob или ?q=^Heap&regex=true для регулярного выражения.
+func (bh baseHandler) Search() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		pattern := ctx.Query("q")
+		if pattern == "" {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter q is required."})
+			ctx.Abort()
+			return
+		}
+
+		regex, err := strconv.ParseBool(ctx.DefaultQuery("regex", "false"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter regex must be true or false."})
+			ctx.Abort()
+			return
+		}
+
+		metrics, err := storage.Search(bh.storage, pattern, regex)
+		if errors.Is(err, errs.ErrInvalidPattern) {
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Invalid search pattern: %s.", err)})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to search metrics", err)
+			return
+		}
+
+		if metrics == nil {
+			metrics = make([]models.MetricsValue, 0)
+		}
+
+		ctx.JSON(http.StatusOK, metrics)
+		ctx.Abort()
+	}
+}
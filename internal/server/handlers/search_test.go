@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestSearch(t *testing.T) {
+	storage := memstorage.NewMem()
+	for _, name := range []string{"Alloc", "HeapAlloc", "HeapIdle", "Frees"} {
+		require.NoError(t, storage.SetGauge(name, getPointerFloat64(1)))
+	}
+	require.NoError(t, storage.AddCounter("Alloc", getPointerInt64(5)))
+
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantedCode int
+		wantedIDs  []string
+	}{
+		{name: "Positive (glob)", query: "q=Heap*", wantedCode: http.StatusOK, wantedIDs: []string{"HeapAlloc", "HeapIdle"}},
+		{name: "Positive (glob, both types)", query: "q=Alloc", wantedCode: http.StatusOK, wantedIDs: []string{"Alloc/counter", "Alloc/gauge"}},
+		{name: "Positive (regex)", query: "q=Alloc$&regex=true", wantedCode: http.StatusOK, wantedIDs: []string{"Alloc/counter", "Alloc/gauge", "HeapAlloc"}},
+		{name: "Positive (nothing found)", query: "q=Mallocs", wantedCode: http.StatusOK, wantedIDs: []string{}},
+		{name: "Negative (without q)", query: "", wantedCode: http.StatusBadRequest},
+		{name: "Negative (invalid regex)", query: "q=(&regex=true", wantedCode: http.StatusBadRequest},
+		{name: "Negative (invalid regex flag)", query: "q=Alloc&regex=maybe", wantedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/search?"+tt.query, nil))
+			require.Equal(t, tt.wantedCode, w.Code)
+
+			if tt.wantedCode != http.StatusOK {
+				return
+			}
+
+			var metrics []models.MetricsValue
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+
+			ids := make([]string, 0, len(metrics))
+			for _, metric := range metrics {
+				if metric.ID == "Alloc" {
+					ids = append(ids, metric.ID+"/"+metric.MType)
+				} else {
+					ids = append(ids, metric.ID)
+				}
+			}
+			assert.Equal(t, tt.wantedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+func (bh baseHandler) SelfMetrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+		ctx.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		if err := selfmetrics.Default.WritePrometheus(ctx.Writer); err != nil {
+			bh.logger(ctx).Errorf("Failed to write self-metrics: %s", err)
+		}
+
+		ctx.Abort()
+	}
+}
+
+// hiddenVars - переменные expvar, которые не отдаются: cmdline содержит флаги запуска вместе с ключами и DSN.
+var hiddenVars = map[string]bool{"cmdline": true}
+
+// DebugVars отдаёт переменные expvar в том же формате, что и expvar.Handler, но без hiddenVars.
+func (bh baseHandler) DebugVars() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+		ctx.Header("Content-Type", "application/json; charset=utf-8")
+
+		var b strings.Builder
+		b.WriteString("{\n")
+
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if hiddenVars[kv.Key] {
+				return
+			}
+
+			if !first {
+				b.WriteString(",\n")
+			}
+			first = false
+
+			fmt.Fprintf(&b, "%q: %s", kv.Key, kv.Value)
+		})
+
+		b.WriteString("\n}\n")
+
+		if _, err := io.WriteString(ctx.Writer, b.String()); err != nil {
+			bh.logger(ctx).Errorf("Failed to write expvar: %s", err)
+		}
+
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestSelfMetrics(t *testing.T) {
+	r := setupRouter(storage.Instrument(memstorage.NewMem(), "memory"), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/update/gauge/test/1.5", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.ServeHTTP(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Contains(t, res.Header.Get("Content-Type"), "text/plain")
+	require.Contains(t, string(body), `metrics_storage_query_duration_seconds_count{backend="memory",method="SetGauge"} 1`)
+}
+
+func TestDebugVars(t *testing.T) {
+	config.Config.AdminKey = "secret"
+	defer func() { config.Config.AdminKey = "" }()
+
+	r := setupRouter(storage.Instrument(memstorage.NewMem(), "memory"), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	r.ServeHTTP(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&vars))
+
+	require.Contains(t, vars, "memstats")
+	require.Contains(t, vars, "goroutines")
+	require.Contains(t, vars, "selfmetrics")
+	require.NotContains(t, vars, "cmdline")
+}
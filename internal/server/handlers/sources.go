@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+var agentIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:@-]{1,128}$`)
+
+// AgentID отклоняет обновления с некорректным заголовком X-Agent-ID. Без заголовка обновления принимаются,
+// но источник у них не запоминается.
+func (bh baseHandler) AgentID(ctx *gin.Context) {
+	agentID := ctx.GetHeader(models.AgentIDHeader)
+	if agentID == "" || agentIDPattern.MatchString(agentID) {
+		return
+	}
+
+	bh.logger(ctx).Debugf("Request with invalid agent id %q.", agentID)
+	ctx.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error: fmt.Sprintf("Header %s must be 1-128 characters of letters, digits and ._:@-", models.AgentIDHeader),
+	})
+	ctx.Abort()
+}
+
+// recordSources запоминает агента, приславшего уже сохранённые обновления. Ошибка только логируется:
+// значения метрик к этому моменту записаны.
+func (bh baseHandler) recordSources(ctx *gin.Context, updates ...models.MetricsUpdate) {
+	agentID := ctx.GetHeader(models.AgentIDHeader)
+	if agentID == "" || len(updates) == 0 {
+		return
+	}
+
+	if err := storage.RecordSources(bh.storage, agentID, updates); err != nil && !errors.Is(err, storage.ErrSourcesUnsupported) {
+		bh.logger(ctx).Errorf("Failed to record sources of %d metrics from agent %s: %s", len(updates), agentID, err)
+	}
+}
+
+// Sources показывает, какие агенты присылали метрику и что прислали последним.
+func (bh baseHandler) Sources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mType := ctx.Param("type")
+		if mType != string(models.GaugeType) && mType != string(models.CounterType) {
+			bh.logger(ctx).Debugf("An invalid metric type was passed.")
+			bh.handleBadRequest(ctx)
+			return
+		}
+
+		sources, err := storage.GetSources(bh.storage, mType, ctx.Param("name"))
+		if errors.Is(err, storage.ErrSourcesUnsupported) {
+			ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "The storage does not track metric sources."})
+			ctx.Abort()
+			return
+		} else if err != nil {
+			bh.handleStorageError(ctx, "Failed to get metric sources", err)
+			return
+		}
+
+		if len(sources) == 0 {
+			ctx.Status(http.StatusNotFound)
+			ctx.Abort()
+			return
+		}
+
+		ctx.JSON(http.StatusOK, sources)
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestSources(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	send := func(agentID, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if agentID != "" {
+			req.Header.Set(models.AgentIDHeader, agentID)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, send("host-a", `[{"id": "Alloc", "type": "gauge", "value": 10}, {"id": "PollCount", "type": "counter", "delta": 2}]`))
+	require.Equal(t, http.StatusOK, send("host-b", `[{"id": "Alloc", "type": "gauge", "value": -1e9}]`))
+	require.Equal(t, http.StatusOK, send("host-a", `[{"id": "Alloc", "type": "gauge", "value": 12}]`))
+	require.Equal(t, http.StatusOK, send("", `[{"id": "Alloc", "type": "gauge", "value": 13}]`))
+	require.Equal(t, http.StatusBadRequest, send("bad agent id", `[{"id": "Alloc", "type": "gauge", "value": 14}]`))
+
+	req := httptest.NewRequest(http.MethodPost, "/update/counter/PollCount/3", nil)
+	req.Header.Set(models.AgentIDHeader, "host-b")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	getSources := func(path string) (int, []models.MetricSource) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var sources []models.MetricSource
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sources))
+		}
+
+		return w.Code, sources
+	}
+
+	code, sources := getSources("/api/sources/gauge/Alloc")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, sources, 2)
+
+	byAgent := make(map[string]models.MetricSource)
+	for _, source := range sources {
+		byAgent[source.AgentID] = source
+	}
+	assert.Equal(t, 12.0, *byAgent["host-a"].Value)
+	assert.Equal(t, int64(2), byAgent["host-a"].Updates)
+	assert.Equal(t, -1e9, *byAgent["host-b"].Value)
+	assert.Equal(t, int64(1), byAgent["host-b"].Updates)
+
+	code, sources = getSources("/api/sources/counter/PollCount")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, sources, 2)
+
+	code, _ = getSources("/api/sources/gauge/Unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = getSources("/api/sources/histogram/Alloc")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// StaleMetrics отдаёт метрики, которые не обновлялись дольше STALE_FACTOR своих обычных интервалов.
+func (bh baseHandler) StaleMetrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if bh.stale == nil {
+			ctx.JSON(http.StatusNotImplemented, models.ErrorResponse{Error: "The staleness monitor is disabled."})
+			ctx.Abort()
+			return
+		}
+
+		ctx.JSON(http.StatusOK, bh.stale.Stale())
+		ctx.Abort()
+	}
+}
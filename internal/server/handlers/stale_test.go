@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/middlewares"
+	serverRouter "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/stale"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestStaleMetrics(t *testing.T) {
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+
+	w := httptest.NewRecorder()
+	setupRouter(memstorage.NewMem(), log).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/stale", nil))
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := memstorage.NewMem()
+	monitor := stale.New(store, stale.Options{CheckInterval: time.Second, Factor: 3}, log)
+	require.NoError(t, monitor.Check(context.Background()))
+
+	r := serverRouter.New(store, log)
+	r.SetStaleMonitor(monitor)
+	middlewares.Setup(r)
+	Setup(r)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/stale", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+// StoreStats отдаёт сводку по хранилищу: число метрик, размер, время обновлений и попадания в кэш.
+func (bh baseHandler) StoreStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		stats, err := storage.Stats(ctx.Request.Context(), bh.storage)
+		if err != nil {
+			bh.handleStorageError(ctx, "Failed to get storage stats", err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, stats)
+		ctx.Abort()
+	}
+}
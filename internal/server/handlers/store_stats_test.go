@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestStoreStats(t *testing.T) {
+	storage := memstorage.NewMem()
+	require.NoError(t, storage.SetGauge("Alloc", getPointerFloat64(1)))
+	require.NoError(t, storage.SetGauge("Frees", getPointerFloat64(2)))
+	require.NoError(t, storage.AddCounter("PollCount", getPointerInt64(5)))
+
+	oldest := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	newest := oldest.Add(time.Hour)
+	storage.SetUpdatedAt(string(models.GaugeType), "Alloc", oldest)
+	storage.SetUpdatedAt(string(models.GaugeType), "Frees", oldest.Add(time.Minute))
+	storage.SetUpdatedAt(string(models.CounterType), "PollCount", newest)
+
+	w := httptest.NewRecorder()
+	setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar())).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats models.StoreStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+
+	assert.Contains(t, stats.Backend, "MemStorage")
+	assert.Equal(t, map[string]int{"gauge": 2, "counter": 1}, stats.Metrics)
+	assert.Equal(t, 3, stats.Total)
+	assert.Nil(t, stats.SizeBytes)
+	require.NotNil(t, stats.OldestUpdate)
+	require.NotNil(t, stats.NewestUpdate)
+	assert.True(t, oldest.Equal(*stats.OldestUpdate))
+	assert.True(t, newest.Equal(*stats.NewestUpdate))
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+)
+
+const (
+	defaultTopN = 10
+	maxTopN     = 1000
+)
+
+// Top отдаёт n метрик одного типа с наибольшими или наименьшими значениями: ?type=gauge&n=20&order=desc.
+func (bh baseHandler) Top() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		mType := ctx.Query("type")
+		if mType != string(models.GaugeType) && mType != string(models.CounterType) {
+			bh.badTopQuery(ctx, "Query parameter type must be gauge or counter.")
+			return
+		}
+
+		n := defaultTopN
+		if raw := ctx.Query("n"); raw != "" {
+			var err error
+			if n, err = strconv.Atoi(raw); err != nil || n <= 0 || n > maxTopN {
+				bh.badTopQuery(ctx, "Query parameter n must be a number from 1 to "+strconv.Itoa(maxTopN)+".")
+				return
+			}
+		}
+
+		var desc bool
+		switch ctx.DefaultQuery("order", "desc") {
+		case "desc":
+			desc = true
+		case "asc":
+		default:
+			bh.badTopQuery(ctx, "Query parameter order must be asc or desc.")
+			return
+		}
+
+		metrics, err := storage.Top(bh.storage, mType, n, desc)
+		if err != nil {
+			bh.handleStorageError(ctx, "Failed to get top metrics", err)
+			return
+		}
+
+		if metrics == nil {
+			metrics = make([]models.MetricsValue, 0)
+		}
+
+		ctx.JSON(http.StatusOK, metrics)
+		ctx.Abort()
+	}
+}
+
+func (bh baseHandler) badTopQuery(ctx *gin.Context, message string) {
+	bh.logger(ctx).Debugf("Invalid top query: %s", ctx.Request.URL.RawQuery)
+
+	ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: message})
+	ctx.Abort()
+}
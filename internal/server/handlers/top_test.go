@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestTop(t *testing.T) {
+	storage := memstorage.NewMem()
+	for name, value := range map[string]float64{"Alloc": 30, "Frees": 10, "HeapIdle": 20, "HeapInuse": 20} {
+		require.NoError(t, storage.SetGauge(name, getPointerFloat64(value)))
+	}
+	require.NoError(t, storage.AddCounter("PollCount", getPointerInt64(5)))
+
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name       string
+		query      string
+		wantedCode int
+		wantedIDs  []string
+	}{
+		{name: "Positive (desc)", query: "type=gauge&n=3", wantedCode: http.StatusOK, wantedIDs: []string{"Alloc", "HeapIdle", "HeapInuse"}},
+		{name: "Positive (asc)", query: "type=gauge&n=2&order=asc", wantedCode: http.StatusOK, wantedIDs: []string{"Frees", "HeapIdle"}},
+		{name: "Positive (counter)", query: "type=counter", wantedCode: http.StatusOK, wantedIDs: []string{"PollCount"}},
+		{name: "Negative (without type)", query: "n=3", wantedCode: http.StatusBadRequest},
+		{name: "Negative (invalid n)", query: "type=gauge&n=0", wantedCode: http.StatusBadRequest},
+		{name: "Negative (invalid order)", query: "type=gauge&order=random", wantedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/top?"+tt.query, nil))
+			require.Equal(t, tt.wantedCode, w.Code)
+
+			if tt.wantedCode != http.StatusOK {
+				return
+			}
+
+			var metrics []models.MetricsValue
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+
+			ids := make([]string, 0, len(metrics))
+			for _, metric := range metrics {
+				ids = append(ids, metric.ID)
+			}
+			assert.Equal(t, tt.wantedIDs, ids)
+		})
+	}
+}
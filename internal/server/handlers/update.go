@@ -7,19 +7,20 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
 )
 
 func (bh baseHandler) UpdateByURI() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if !bh.validateContentType(ctx, "text/plain", true) {
-			bh.log.Debugf("Request with invalid content-type.")
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
 			bh.handleBadRequest(ctx)
 			return
 		}
 
 		id := ctx.Param("name")
 		if id == "" {
-			bh.log.Debugf("The required name parameter is not specified.")
+			bh.logger(ctx).Debugf("The required name parameter is not specified.")
 
 			ctx.Status(http.StatusNotFound)
 			ctx.Abort()
@@ -27,62 +28,92 @@ func (bh baseHandler) UpdateByURI() gin.HandlerFunc {
 			return
 		}
 
-		storageType := ctx.Param("type")
-		if storageType == string(models.GaugeType) {
-			value, err := strconv.ParseFloat(ctx.Param("value"), 64)
-			if err != nil {
-				bh.log.Debugf("The value parameter is not parsed as a float64 value.")
-				bh.handleBadRequest(ctx)
-				return
-			}
+		if bh.updateByParams(ctx, ctx.Param("type"), id, ctx.Param("value")) {
+			ctx.Status(http.StatusOK)
+			ctx.Abort()
+		}
+	}
+}
 
-			if err = bh.storage.SetGauge(id, &value); err != nil {
-				bh.log.Errorf("Failed set/update counter value: %s", err)
+// UpdateByQuery - совместимый маршрут GET /update?type=&name=&value= для скриптов и сетевых устройств,
+// которые умеют только простые GET-запросы. Включается флагом -legacy-updates.
+func (bh baseHandler) UpdateByQuery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.Query("name")
+		if id == "" {
+			bh.logger(ctx).Debugf("The required name query parameter is not specified.")
+			bh.handleBadRequest(ctx)
+			return
+		}
 
-				ctx.Status(http.StatusInternalServerError)
-				ctx.Abort()
+		if bh.updateByParams(ctx, ctx.Query("type"), id, ctx.Query("value")) {
+			ctx.Status(http.StatusOK)
+			ctx.Abort()
+		}
+	}
+}
 
-				return
-			}
-		} else if storageType == string(models.CounterType) {
-			value, err := strconv.ParseInt(ctx.Param("value"), 0, 64)
-			if err != nil {
-				bh.log.Debugf("The value parameter is not parsed as a int64 value.")
-				bh.handleBadRequest(ctx)
-				return
-			}
+// updateByParams записывает метрику, заданную строками из пути или query. Если запись не удалась,
+// ответ уже отправлен и возвращается false.
+func (bh baseHandler) updateByParams(ctx *gin.Context, storageType, id, rawValue string) bool {
+	if storageType == string(models.GaugeType) {
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			bh.logger(ctx).Debugf("The value parameter is not parsed as a float64 value.")
+			bh.handleBadRequest(ctx)
+			return false
+		}
 
-			if err = bh.storage.AddCounter(id, &value); err != nil {
-				bh.log.Errorf("Failed set/update counter value: %s", err)
+		value = policy.RoundGauge(value)
 
-				ctx.Status(http.StatusInternalServerError)
-				ctx.Abort()
+		keep, err := policy.GaugeValue(value)
+		if err != nil {
+			bh.handleStorageError(ctx, "Rejected gauge value", err)
+			return false
+		}
 
-				return
-			}
+		if !keep {
+			bh.logger(ctx).Debugf("The gauge value %v of %s was dropped by policy.", value, id)
+		} else if err = bh.storage.SetGauge(id, &value); err != nil {
+			bh.handleStorageError(ctx, "Failed set/update gauge value", err)
+			return false
 		} else {
-			bh.log.Debugf("An invalid metric type was passed.")
+			bh.recordSources(ctx, models.MetricsUpdate{ID: id, MType: storageType, Value: &value})
+		}
+	} else if storageType == string(models.CounterType) {
+		value, err := strconv.ParseInt(rawValue, 0, 64)
+		if err != nil {
+			bh.logger(ctx).Debugf("The value parameter is not parsed as a int64 value.")
 			bh.handleBadRequest(ctx)
-			return
+			return false
 		}
 
-		ctx.Status(http.StatusOK)
-		ctx.Abort()
+		if err = bh.storage.AddCounter(id, &value); err != nil {
+			bh.handleStorageError(ctx, "Failed set/update counter value", err)
+			return false
+		}
+		bh.recordSources(ctx, models.MetricsUpdate{ID: id, MType: storageType, Delta: &value})
+	} else {
+		bh.logger(ctx).Debugf("An invalid metric type was passed.")
+		bh.handleBadRequest(ctx)
+		return false
 	}
+
+	return true
 }
 
 func (bh baseHandler) UpdateByBody() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if !bh.validateContentType(ctx, "application/json", false) {
-			bh.log.Debugf("Request with invalid content-type.")
+		if bh.requestFormat(ctx) == "" {
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
 			bh.handleBadRequest(ctx)
 			return
 		}
 
 		var obj models.MetricsUpdate
-		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &obj); err != nil {
+		if response, statusCode, err := bh.validateAndShouldBind(ctx, &obj); err != nil {
 			if statusCode == http.StatusInternalServerError {
-				bh.log.Errorf("Error decoding object request: %s (%T)", err, err)
+				bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
 			}
 
 			if response == nil {
@@ -97,33 +128,38 @@ func (bh baseHandler) UpdateByBody() gin.HandlerFunc {
 		}
 
 		if obj.MType == string(models.GaugeType) {
-			if err := bh.storage.SetGauge(obj.ID, obj.Value); err != nil {
-				bh.log.Errorf("Failed set/update counter value: %s", err)
+			*obj.Value = policy.RoundGauge(*obj.Value)
 
-				ctx.Status(http.StatusInternalServerError)
-				ctx.Abort()
+			keep, err := policy.GaugeValue(*obj.Value)
+			if err != nil {
+				bh.handleStorageError(ctx, "Rejected gauge value", err)
+				return
+			}
 
+			if !keep {
+				bh.logger(ctx).Debugf("The gauge value %v of %s was dropped by policy.", *obj.Value, obj.ID)
+			} else if err = bh.storage.SetGauge(obj.ID, obj.Value); err != nil {
+				bh.handleStorageError(ctx, "Failed set/update gauge value", err)
 				return
+			} else {
+				bh.recordSources(ctx, obj)
 			}
 		} else if obj.MType == string(models.CounterType) {
 			if err := bh.storage.AddCounter(obj.ID, obj.Delta); err != nil {
-				bh.log.Errorf("Failed set/update counter value: %s", err)
-
-				ctx.Status(http.StatusInternalServerError)
-				ctx.Abort()
-
+				bh.handleStorageError(ctx, "Failed set/update counter value", err)
 				return
 			}
+			bh.recordSources(ctx, obj)
 
 			counter, err := bh.storage.GetCounter(obj.ID)
 			if err != nil {
-				bh.log.Errorf("Failed to get updated counter value: %s", err)
+				bh.logger(ctx).Errorf("Failed to get updated counter value: %s", err)
 			} else {
 				obj.Delta = counter
 			}
 		}
 
-		ctx.JSON(http.StatusOK, obj)
+		bh.render(ctx, http.StatusOK, models.MetricsValue{ID: obj.ID, MType: obj.MType, Delta: obj.Delta, Value: obj.Value})
 		ctx.Abort()
 	}
 }
@@ -7,14 +7,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestUpdate(t *testing.T) {
@@ -106,7 +109,7 @@ func TestUpdate(t *testing.T) {
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -172,7 +175,7 @@ func TestUpdateByBody(t *testing.T) {
 				"type":  "gauge",
 				"value": "invalid_value",
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"value\\\" must be float64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"value\\\" must be float64.\",\"field\":\"value\",\"expected\":\"float64\",\"offset\":51}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -205,7 +208,7 @@ func TestUpdateByBody(t *testing.T) {
 				"type":  "counter",
 				"delta": "invalid_value",
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\",\"field\":\"delta\",\"expected\":\"int64\",\"offset\":24}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -216,20 +219,20 @@ func TestUpdateByBody(t *testing.T) {
 				"type":  "counter",
 				"delta": 12345.6789,
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\",\"field\":\"delta\",\"expected\":\"int64\",\"offset\":19}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
 			name:             "Negative (without params)",
 			method:           http.MethodPost,
 			body:             models.MetricsUpdate{},
-			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\"}",
+			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\",\"field\":\"id\",\"expected\":\"required\"}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -254,3 +257,111 @@ func TestUpdateByBody(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateByQuery(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	store := memstorage.NewMem()
+
+	config.Config.LegacyUpdates = false
+	r := setupRouter(store, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/update?type=gauge&name=Alloc&value=1.5", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	config.Config.LegacyUpdates = true
+	r = setupRouter(store, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name             string
+		query            string
+		wantedStatusCode int
+	}{
+		{name: "Positive gauge", query: "type=gauge&name=Alloc&value=1.5", wantedStatusCode: http.StatusOK},
+		{name: "Positive counter", query: "type=counter&name=PollCount&value=3", wantedStatusCode: http.StatusOK},
+		{name: "Negative (without name)", query: "type=gauge&value=1.5", wantedStatusCode: http.StatusBadRequest},
+		{name: "Negative (invalid value)", query: "type=counter&name=PollCount&value=1.5", wantedStatusCode: http.StatusBadRequest},
+		{name: "Negative (invalid type)", query: "type=histogram&name=Alloc&value=1", wantedStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/update?"+tt.query, nil))
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+		})
+	}
+
+	value, err := store.GetGauge("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, *value)
+
+	delta, err := store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *delta)
+}
+
+func TestUpdateByForm(t *testing.T) {
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	tests := []struct {
+		name             string
+		url              string
+		body             string
+		wantedStatusCode int
+		wantedBody       string
+	}{
+		{
+			name:             "Positive gauge",
+			url:              "/update",
+			body:             "id=Alloc&type=gauge&value=1.5",
+			wantedStatusCode: http.StatusOK,
+			wantedBody:       `{"id":"Alloc","type":"gauge","value":1.5}`,
+		},
+		{
+			name:             "Positive counter",
+			url:              "/update",
+			body:             "id=PollCount&type=counter&delta=3",
+			wantedStatusCode: http.StatusOK,
+			wantedBody:       `{"id":"PollCount","type":"counter","delta":3}`,
+		},
+		{
+			name:             "Negative (without value)",
+			url:              "/update",
+			body:             "id=Alloc&type=gauge",
+			wantedStatusCode: http.StatusBadRequest,
+			wantedBody:       `{"error":"Field validation for \"Value\" failed on the 'required_if=MType gauge' tag.","field":"value","expected":"required_if=MType gauge"}`,
+		},
+		{
+			name:             "Negative (invalid delta)",
+			url:              "/update",
+			body:             "id=PollCount&type=counter&delta=abc",
+			wantedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:             "Negative (batch)",
+			url:              "/updates",
+			body:             "id=Alloc&type=gauge&value=1.5",
+			wantedStatusCode: http.StatusBadRequest,
+			wantedBody:       `{"error":"A form body carries a single metric, use /update."}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+			if tt.wantedBody != "" {
+				assert.JSONEq(t, tt.wantedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -6,20 +6,47 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
 )
 
 func (bh baseHandler) Updates() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if !bh.validateContentType(ctx, "application/json", false) {
-			bh.log.Debugf("Request with invalid content-type.")
+		if bh.requestFormat(ctx) == "" {
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
 			bh.handleBadRequest(ctx)
 			return
 		}
 
+		// С ?return=values в ответе итоговые значения всех метрик пакета
+		returnValues := false
+		switch ctx.Query("return") {
+		case "":
+		case "values":
+			returnValues = true
+		default:
+			bh.logger(ctx).Debugf("Invalid updates query: %s", ctx.Request.URL.RawQuery)
+
+			ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter return must be values."})
+			ctx.Abort()
+
+			return
+		}
+
+		// JSON разбирается потоком, чтобы пакеты из сотен тысяч метрик не держать в памяти целиком
+		if models.IsStreamedUpdate(ctx.Request, ctx.FullPath()) {
+			if bh.streamUpdates(ctx) {
+				ctx.Status(http.StatusOK)
+				ctx.Abort()
+			}
+
+			return
+		}
+
 		var objects []models.MetricsUpdate
-		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &objects); err != nil {
+		if response, statusCode, err := bh.validateAndShouldBind(ctx, &objects); err != nil {
 			if statusCode == http.StatusInternalServerError {
-				bh.log.Errorf("Error decoding object request: %s (%T)", err, err)
+				bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
 			}
 
 			if response == nil {
@@ -33,54 +60,121 @@ func (bh baseHandler) Updates() gin.HandlerFunc {
 			return
 		}
 
-		tx, err := bh.storage.NewTx()
-		if err != nil {
-			bh.log.Debugf("Failed to create transaction: %s (%T)", err, err)
-
-			ctx.Status(http.StatusInternalServerError)
+		if returnValues {
+			bh.upsertUpdates(ctx, objects)
+		} else if bh.applyUpdates(ctx, objects) {
+			ctx.Status(http.StatusOK)
 			ctx.Abort()
-
-			return
 		}
+	}
+}
+
+// upsertUpdates записывает пакет и отвечает итоговыми значениями его метрик (см. storage.UpsertBatch).
+func (bh baseHandler) upsertUpdates(ctx *gin.Context, objects []models.MetricsUpdate) {
+	objects, err := bh.filterGauges(objects)
+	if err != nil {
+		bh.handleStorageError(ctx, "Rejected gauge value", err)
+		return
+	}
 
-		for _, obj := range objects {
-			if obj.MType == string(models.GaugeType) {
-				if err = tx.SetGauge(obj.ID, obj.Value); err != nil {
-					bh.log.Errorf("Error set gauge (tx): %s (%T)", err, err)
-					if err = tx.RollBack(); err != nil {
-						bh.log.Errorf("Failed to rollback transaction [gauge]: %s (%T)", err, err)
-					}
+	values, err := storage.UpsertBatch(bh.storage, objects)
+	if err != nil {
+		bh.handleStorageError(ctx, "Failed to upsert updates", err)
+		return
+	}
+	bh.recordSources(ctx, objects...)
 
-					ctx.Status(http.StatusInternalServerError)
-					ctx.Abort()
+	if values == nil {
+		values = make([]models.MetricsValue, 0)
+	}
 
-					return
-				}
-			} else if obj.MType == string(models.CounterType) {
-				if err = tx.AddCounter(obj.ID, obj.Delta); err != nil {
-					bh.log.Errorf("Error add counter (tx): %s (%T)", err, err)
-					if err = tx.RollBack(); err != nil {
-						bh.log.Errorf("Failed to rollback transaction [counter]: %s (%T)", err, err)
-					}
+	bh.render(ctx, http.StatusOK, values)
+	ctx.Abort()
+}
 
-					ctx.Status(http.StatusInternalServerError)
-					ctx.Abort()
+// applyUpdates записывает пакет метрик одной транзакцией. Если запись не удалась, ответ уже отправлен и возвращается false.
+func (bh baseHandler) applyUpdates(ctx *gin.Context, objects []models.MetricsUpdate) bool {
+	objects, err := bh.filterGauges(objects)
+	if err != nil {
+		bh.handleStorageError(ctx, "Rejected gauge value", err)
+		return false
+	}
 
-					return
+	tx, ok := bh.beginTx(ctx)
+	if !ok {
+		return false
+	}
+
+	for _, obj := range objects {
+		if obj.MType == string(models.GaugeType) {
+			if err = tx.SetGauge(obj.ID, obj.Value); err != nil {
+				if rollbackErr := tx.RollBack(); rollbackErr != nil {
+					bh.logger(ctx).Errorf("Failed to rollback transaction [gauge]: %s (%T)", rollbackErr, rollbackErr)
+				}
+
+				bh.handleStorageError(ctx, "Error set gauge (tx)", err)
+				return false
+			}
+		} else if obj.MType == string(models.CounterType) {
+			if err = tx.AddCounter(obj.ID, obj.Delta); err != nil {
+				if rollbackErr := tx.RollBack(); rollbackErr != nil {
+					bh.logger(ctx).Errorf("Failed to rollback transaction [counter]: %s (%T)", rollbackErr, rollbackErr)
 				}
+
+				bh.handleStorageError(ctx, "Error add counter (tx)", err)
+				return false
 			}
 		}
+	}
 
-		if err = tx.Commit(); err != nil {
-			bh.log.Errorf("Failed to save changes from transaction: %s (%T)", err, err)
+	if err = tx.Commit(); err != nil {
+		bh.handleStorageError(ctx, "Failed to save changes from transaction", err)
+		return false
+	}
+	bh.recordSources(ctx, objects...)
 
-			ctx.Status(http.StatusInternalServerError)
-			ctx.Abort()
+	return true
+}
 
-			return
+// beginTx открывает транзакцию хранилища. Если это не удалось, ответ уже отправлен и возвращается false.
+func (bh baseHandler) beginTx(ctx *gin.Context) (models.StorageTx, bool) {
+	tx, err := bh.storage.NewTx()
+	if err != nil {
+		if bh.handleStorageUnavailable(ctx, err) {
+			return nil, false
 		}
 
-		ctx.Status(http.StatusOK)
+		bh.logger(ctx).Debugf("Failed to create transaction: %s (%T)", err, err)
+
+		ctx.Status(http.StatusInternalServerError)
 		ctx.Abort()
+
+		return nil, false
 	}
+
+	return tx, true
+}
+
+// filterGauges округляет gauge и применяет к ним политику NaN/Inf до начала транзакции.
+func (bh baseHandler) filterGauges(objects []models.MetricsUpdate) ([]models.MetricsUpdate, error) {
+	filtered := objects[:0]
+	for _, obj := range objects {
+		if obj.MType == string(models.GaugeType) {
+			*obj.Value = policy.RoundGauge(*obj.Value)
+
+			keep, err := policy.GaugeValue(*obj.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if !keep {
+				bh.log.Debugf("The gauge value %v of %s was dropped by policy.", *obj.Value, obj.ID)
+				continue
+			}
+		}
+
+		filtered = append(filtered, obj)
+	}
+
+	return filtered, nil
 }
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
+)
+
+// decodeError - тело запроса не удалось разобрать; response уходит клиенту как есть.
+type decodeError struct {
+	response   *models.ErrorResponse
+	statusCode int
+	err        error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// streamChunkSize - сколько метрик пакета разбирается и проверяется, прежде чем они пишутся в транзакцию.
+// Пакет не больше этого размера разбирается целиком до начала транзакции.
+const streamChunkSize = 1000
+
+// errResponded - ответ клиенту уже отправлен, пакет дальше не обрабатывается.
+var errResponded = errors.New("response is already sent")
+
+// deadlineBody ограничивает ожидание каждой части тела, пока открыта транзакция пакета, иначе медленный
+// клиент держал бы её сколько угодно. Если соединение не даёт задать дедлайн (HTTP/3, -handler-timeout),
+// остаётся общий -read-timeout.
+type deadlineBody struct {
+	io.Reader
+	rc      *http.ResponseController
+	timeout time.Duration
+	active  bool
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if b.active && b.timeout > 0 {
+		_ = b.rc.SetReadDeadline(time.Now().Add(b.timeout)) // ошибка значит, что дедлайн не поддерживается
+	}
+
+	return b.Reader.Read(p)
+}
+
+// streamUpdates читает JSON-массив метрик потоком: метрики разбираются и проверяются частями вне транзакции,
+// а каждая часть сразу пишется в транзакцию. Ошибка в любом элементе откатывает весь пакет.
+// Если запись не удалась, ответ уже отправлен и возвращается false.
+func (bh baseHandler) streamUpdates(ctx *gin.Context) bool {
+	var (
+		tx    models.StorageTx
+		chunk []models.MetricsUpdate
+	)
+
+	// Источники нужны только запросам с X-Agent-ID, остальные пакеты в памяти не копятся
+	var sources []models.MetricsUpdate
+	trackSources := ctx.GetHeader(models.AgentIDHeader) != ""
+
+	body := &deadlineBody{Reader: ctx.Request.Body, rc: http.NewResponseController(ctx.Writer), timeout: config.Config.StreamReadTimeout}
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		if tx == nil {
+			var ok bool
+			if tx, ok = bh.beginTx(ctx); !ok {
+				return errResponded
+			}
+			body.active = true
+		}
+
+		for _, obj := range chunk {
+			if obj.MType == string(models.GaugeType) {
+				if err := tx.SetGauge(obj.ID, obj.Value); err != nil {
+					return err
+				}
+			} else if err := tx.AddCounter(obj.ID, obj.Delta); err != nil {
+				return err
+			}
+		}
+		chunk = chunk[:0]
+
+		return nil
+	}
+
+	err := bh.decodeUpdates(body, func(obj models.MetricsUpdate) error {
+		if obj.MType == string(models.GaugeType) {
+			*obj.Value = policy.RoundGauge(*obj.Value)
+
+			keep, err := policy.GaugeValue(*obj.Value)
+			if err != nil {
+				return err
+			}
+
+			if !keep {
+				bh.logger(ctx).Debugf("The gauge value %v of %s was dropped by policy.", *obj.Value, obj.ID)
+				return nil
+			}
+		}
+
+		chunk = append(chunk, obj)
+		if trackSources {
+			sources = append(sources, obj)
+		}
+
+		if len(chunk) < streamChunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		// Подпись потокового тела проверяется в его конце, поэтому тело дочитывается до фиксации пакета
+		if _, err = io.Copy(io.Discard, body); err != nil {
+			err = streamDecodeError(-1, 0, err)
+		}
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	if tx != nil {
+		if err == nil {
+			err = tx.Commit()
+		} else if rollbackErr := tx.RollBack(); rollbackErr != nil {
+			bh.logger(ctx).Errorf("Failed to rollback transaction: %s (%T)", rollbackErr, rollbackErr)
+		}
+	}
+
+	var decodeErr *decodeError
+	if errors.Is(err, errResponded) {
+		return false
+	} else if errors.As(err, &decodeErr) {
+		if decodeErr.statusCode == http.StatusInternalServerError {
+			bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
+		}
+
+		if decodeErr.response == nil {
+			ctx.Status(decodeErr.statusCode)
+		} else {
+			ctx.JSON(decodeErr.statusCode, decodeErr.response)
+		}
+		ctx.Abort()
+
+		return false
+	} else if err != nil {
+		bh.handleStorageError(ctx, "Failed to apply updates (tx)", err)
+		return false
+	}
+	bh.recordSources(ctx, sources...)
+
+	return true
+}
+
+// decodeUpdates разбирает JSON-массив метрик и передаёт apply каждую проверенную метрику. Ошибки разбора
+// возвращаются как *decodeError с индексом сломанного элемента, ошибки apply - как есть.
+func (bh baseHandler) decodeUpdates(r io.Reader, apply func(obj models.MetricsUpdate) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return &decodeError{response: &models.ErrorResponse{Error: "Request body not provided."}, statusCode: http.StatusBadRequest, err: err}
+	} else if err != nil {
+		return streamDecodeError(-1, 0, err)
+	} else if tok == nil {
+		// null - пустой пакет, как и при разборе в слайс
+		return nil
+	} else if tok != json.Delim('[') {
+		err = fmt.Errorf("expected an array of metrics, got %v", tok)
+		return &decodeError{response: &models.ErrorResponse{Error: fmt.Sprintf("JSON error: %s", err), Offset: dec.InputOffset()}, statusCode: http.StatusBadRequest, err: err}
+	}
+
+	for index := 0; dec.More(); index++ {
+		// Decode сам пропускает запятую перед элементом, смещения ошибок типа считаются после неё
+		start := dec.InputOffset()
+		if index > 0 {
+			start++
+		}
+
+		var obj models.MetricsUpdate
+		if err = dec.Decode(&obj); err != nil {
+			return streamDecodeError(index, start, err)
+		}
+
+		if err = binding.Validator.ValidateStruct(&obj); err != nil {
+			if ok, response := bh.parseBindingErrors(&obj, err); ok {
+				response.Error = fmt.Sprintf("Element %d: %s", index, response.Error)
+				response.Field = fmt.Sprintf("[%d].%s", index, response.Field)
+
+				return &decodeError{response: response, statusCode: http.StatusBadRequest, err: err}
+			}
+
+			return &decodeError{statusCode: http.StatusInternalServerError, err: err}
+		}
+
+		if err = apply(obj); err != nil {
+			return err
+		}
+	}
+
+	if _, err = dec.Token(); err != nil {
+		return streamDecodeError(-1, 0, err)
+	}
+
+	return nil
+}
+
+// streamDecodeError описывает ошибку json.Decoder в элементе index (-1 - вне элементов), который начинается с байта start.
+func streamDecodeError(index int, start int64, err error) error {
+	if errors.Is(err, signing.ErrNonceCacheFull) {
+		return &decodeError{statusCode: http.StatusServiceUnavailable, err: err}
+	} else if errors.Is(err, signing.ErrBodyRejected) {
+		return &decodeError{statusCode: http.StatusBadRequest, err: err}
+	} else if errors.Is(err, os.ErrDeadlineExceeded) {
+		return &decodeError{response: &models.ErrorResponse{Error: "Request body was not received in time."}, statusCode: http.StatusRequestTimeout, err: err}
+	}
+
+	path := ""
+	if index >= 0 {
+		path = fmt.Sprintf("[%d]", index)
+	}
+
+	var jsonTypeError *json.UnmarshalTypeError
+	if errors.As(err, &jsonTypeError) {
+		field := path
+		if jsonTypeError.Field != "" {
+			field = fmt.Sprintf("%s.%s", path, jsonTypeError.Field)
+		}
+
+		return &decodeError{response: &models.ErrorResponse{
+			Error:    fmt.Sprintf("Field value \"%s\" must be %s.", field, jsonTypeError.Type),
+			Field:    field,
+			Expected: jsonTypeError.Type.String(),
+			Offset:   start + jsonTypeError.Offset,
+		}, statusCode: http.StatusBadRequest, err: err}
+	}
+
+	var jsonError *json.SyntaxError
+	if errors.As(err, &jsonError) {
+		return &decodeError{response: &models.ErrorResponse{
+			Error:  fmt.Sprintf("JSON error: %s", jsonError.Error()),
+			Field:  path,
+			Offset: jsonError.Offset,
+		}, statusCode: http.StatusBadRequest, err: err}
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return &decodeError{response: &models.ErrorResponse{Error: "JSON error: unexpected end of JSON input", Field: path}, statusCode: http.StatusBadRequest, err: err}
+	}
+
+	return &decodeError{statusCode: http.StatusInternalServerError, err: err}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/testutil"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestUpdatesStreamRollback(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	var body bytes.Buffer
+	body.WriteString("[")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&body, `{"id":"Counter%d","type":"counter","delta":1},`, i)
+	}
+	body.WriteString(`{"id":"Broken","type":"counter","delta":"1"}]`)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates", &body)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"[1000].delta"`)
+
+	values, err := storage.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, values, "a broken element must roll back the whole batch")
+}
+
+func TestUpdatesStreamValidatesBeforeTx(t *testing.T) {
+	storage := testutil.NewFakeStorage()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(`[{"id":"PollCount","type":"counter","delta":1},{"id":"Broken","type":"counter"}]`))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, storage.CallsOf(testutil.MethodNewTx), "a small batch must be validated before the transaction begins")
+}
+
+func TestUpdatesStreamReadTimeout(t *testing.T) {
+	saved := config.Config.StreamReadTimeout
+	defer func() { config.Config.StreamReadTimeout = saved }()
+	config.Config.StreamReadTimeout = 100 * time.Millisecond
+
+	storage := memstorage.NewMem()
+	srv := httptest.NewServer(setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar())))
+	defer srv.Close()
+
+	// Клиент отправляет больше одной части пакета и замолкает, пока транзакция открыта
+	body, writer := io.Pipe()
+	defer writer.Close()
+
+	go func() {
+		fmt.Fprint(writer, "[")
+		for i := 0; i <= streamChunkSize; i++ {
+			fmt.Fprintf(writer, `{"id":"Counter%d","type":"counter","delta":1},`, i)
+		}
+	}()
+
+	resp, err := http.Post(srv.URL+"/updates/", "application/json", body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+
+	values, err := storage.GetAll()
+	require.NoError(t, err)
+	assert.Empty(t, values, "a timed out batch must be rolled back")
+}
@@ -14,6 +14,7 @@ import (
 
 	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestUpdates(t *testing.T) {
@@ -58,7 +59,7 @@ func TestUpdates(t *testing.T) {
 					"value": "invalid_value",
 				},
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"value\\\" must be float64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"[0].value\\\" must be float64.\",\"field\":\"[0].value\",\"expected\":\"float64\",\"offset\":52}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -105,7 +106,7 @@ func TestUpdates(t *testing.T) {
 					"delta": "invalid_value",
 				},
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"[0].delta\\\" must be int64.\",\"field\":\"[0].delta\",\"expected\":\"int64\",\"offset\":25}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -118,7 +119,7 @@ func TestUpdates(t *testing.T) {
 					"delta": 12345.6789,
 				},
 			},
-			wantedBody:       "{\"error\":\"Field value \\\"delta\\\" must be int64.\"}",
+			wantedBody:       "{\"error\":\"Field value \\\"[0].delta\\\" must be int64.\",\"field\":\"[0].delta\",\"expected\":\"int64\",\"offset\":20}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -130,7 +131,7 @@ func TestUpdates(t *testing.T) {
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -155,3 +156,55 @@ func TestUpdates(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatesReturnValues(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	delta := int64(5)
+	require.NoError(t, storage.AddCounter("PollCount", &delta))
+
+	tests := []struct {
+		name             string
+		query            string
+		body             string
+		wantedBody       string
+		wantedStatusCode int
+	}{
+		{
+			name:             "Final values",
+			query:            "?return=values",
+			body:             `[{"id":"PollCount","type":"counter","delta":1},{"id":"Alloc","type":"gauge","value":1.5},{"id":"PollCount","type":"counter","delta":2}]`,
+			wantedBody:       `[{"id":"PollCount","type":"counter","delta":8},{"id":"Alloc","type":"gauge","value":1.5}]`,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Empty batch",
+			query:            "?return=values",
+			body:             `[]`,
+			wantedBody:       `[]`,
+			wantedStatusCode: http.StatusOK,
+		},
+		{
+			name:             "Invalid return",
+			query:            "?return=ids",
+			body:             `[]`,
+			wantedBody:       `{"error":"Query parameter return must be values."}`,
+			wantedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/"+tt.query, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantedStatusCode, w.Code)
+			assert.Equal(t, tt.wantedBody, w.Body.String())
+		})
+	}
+}
@@ -5,13 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
 )
 
 func (bh baseHandler) validateContentType(ctx *gin.Context, contentType string, withoutContentType bool) bool {
@@ -23,44 +30,78 @@ func (bh baseHandler) validateContentType(ctx *gin.Context, contentType string,
 	return requestContentType == contentType
 }
 
+// validateAndShouldBindJSON разбирает тело в JSON. В ошибках разбора указываются путь к полю, ожидаемый тип
+// и смещение в теле, чтобы в большом пакете было видно, какой элемент сломан.
 func (bh baseHandler) validateAndShouldBindJSON(ctx *gin.Context, obj any) (*models.ErrorResponse, int, error) {
-	if err := ctx.ShouldBindJSON(obj); err != nil {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	if err = binding.JSON.BindBody(body, obj); err != nil {
 		if errors.Is(err, io.EOF) {
 			return &models.ErrorResponse{Error: "Request body not provided."}, http.StatusBadRequest, err
 		}
 
 		var jsonTypeError *json.UnmarshalTypeError
 		if ok := errors.As(err, &jsonTypeError); ok {
+			field := jsonPath(body, jsonTypeError.Offset+leadingSpace(body))
+			if field == "" {
+				field = jsonTypeError.Field
+			}
+
 			return &models.ErrorResponse{
-				Error: fmt.Sprintf("Field value \"%s\" must be %s.", jsonTypeError.Field, jsonTypeError.Type),
+				Error:    fmt.Sprintf("Field value \"%s\" must be %s.", field, jsonTypeError.Type),
+				Field:    field,
+				Expected: jsonTypeError.Type.String(),
+				Offset:   jsonTypeError.Offset + leadingSpace(body),
 			}, http.StatusBadRequest, err
 		}
 
 		var jsonError *json.SyntaxError
 		if ok := errors.As(err, &jsonError); ok {
 			return &models.ErrorResponse{
-				Error: fmt.Sprintf("JSON error: %s", jsonError.Error()),
+				Error:  fmt.Sprintf("JSON error: %s", jsonError.Error()),
+				Field:  jsonPath(body, jsonError.Offset),
+				Offset: jsonError.Offset,
 			}, http.StatusBadRequest, err
 		}
 
-		if ok, errResponse := bh.parseValidationErrors(err); ok {
+		if ok, errResponse := bh.parseBindingErrors(obj, err); ok {
 			return errResponse, http.StatusBadRequest, err
 		}
 
-		var sliceValidationErrors binding.SliceValidationError
-		if ok := errors.As(err, &sliceValidationErrors); ok && len(sliceValidationErrors) > 0 {
-			if ok, errResponse := bh.parseValidationErrors(sliceValidationErrors[0]); ok {
-				return errResponse, http.StatusBadRequest, err
-			}
-		}
-
 		return nil, http.StatusInternalServerError, err
 	}
 
 	return nil, 0, nil
 }
 
-func (bh baseHandler) parseValidationErrors(err error) (bool, *models.ErrorResponse) {
+// parseBindingErrors переводит ошибки проверки тегов binding, в том числе у элементов слайса, в ответ клиенту.
+// У элемента слайса в Field добавляется его индекс.
+func (bh baseHandler) parseBindingErrors(obj any, err error) (bool, *models.ErrorResponse) {
+	if ok, errResponse := bh.parseValidationErrors(obj, err); ok {
+		return true, errResponse
+	}
+
+	var sliceValidationErrors binding.SliceValidationError
+	if ok := errors.As(err, &sliceValidationErrors); ok && len(sliceValidationErrors) > 0 {
+		// SliceValidationError не хранит индексы, поэтому сломанный элемент ищется повторной проверкой
+		index, elem := invalidElement(obj)
+
+		ok, errResponse := bh.parseValidationErrors(elem, sliceValidationErrors[0])
+		if ok && index >= 0 {
+			errResponse.Error = fmt.Sprintf("Element %d: %s", index, errResponse.Error)
+			errResponse.Field = fmt.Sprintf("[%d].%s", index, errResponse.Field)
+		}
+
+		return ok, errResponse
+	}
+
+	return false, nil
+}
+
+func (bh baseHandler) parseValidationErrors(obj any, err error) (bool, *models.ErrorResponse) {
 	var validationErrors validator.ValidationErrors
 	if ok := errors.As(err, &validationErrors); ok && len(validationErrors) > 0 {
 		fErr := validationErrors[0]
@@ -73,9 +114,62 @@ func (bh baseHandler) parseValidationErrors(err error) (bool, *models.ErrorRespo
 		}
 
 		return true, &models.ErrorResponse{
-			Error: fmt.Sprintf("Field validation for \"%s\" failed on the '%s' tag.", fErr.Field(), errResponse),
+			Error:    fmt.Sprintf("Field validation for \"%s\" failed on the '%s' tag.", fErr.Field(), errResponse),
+			Field:    jsonName(obj, fErr.StructField()),
+			Expected: errResponse,
 		}
 	}
 
 	return false, nil
 }
+
+// handleStorageError отвечает 400, если хранилище отвергло значение метрики по политике, 409 при конфликте типов,
+// 503 при разомкнутом circuit breaker и 500 при остальных ошибках.
+func (bh baseHandler) handleStorageError(ctx *gin.Context, message string, err error) {
+	var conflictErr *errs.TypeConflictError
+	if bh.handleStorageUnavailable(ctx, err) {
+		return
+	} else if errors.As(err, &conflictErr) {
+		bh.logger(ctx).Debugf("%s: %s", message, err)
+		ctx.JSON(http.StatusConflict, models.ConflictResponse{
+			Error:         fmt.Sprintf("Metric \"%s\" already exists with type %s.", conflictErr.Name, conflictErr.ExistingType),
+			ID:            conflictErr.Name,
+			ExistingType:  conflictErr.ExistingType,
+			RequestedType: conflictErr.RequestedType,
+		})
+	} else if errors.Is(err, errs.ErrCounterMissingDelta) || errors.Is(err, errs.ErrCounterNegativeDelta) || errors.Is(err, errs.ErrCounterOverflow) || errors.Is(err, errs.ErrGaugeNotFinite) {
+		bh.logger(ctx).Debugf("%s: %s", message, err)
+		ctx.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Invalid metric value: %s.", err)})
+	} else {
+		bh.logger(ctx).Errorf("%s: %s", message, err)
+		ctx.Status(http.StatusInternalServerError)
+	}
+
+	ctx.Abort()
+}
+
+func (bh baseHandler) handleStorageUnavailable(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, breaker.ErrOpen) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(config.Config.StorageBreakerOpenTimeout.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Storage is temporarily unavailable."})
+	ctx.Abort()
+
+	return true
+}
+
+// logger возвращает логгер с идентификатором запроса, чтобы записи хендлеров можно было сопоставить с логами агента.
+func (bh baseHandler) logger(ctx *gin.Context) logger.Logger {
+	if id := requestid.From(ctx.Request.Context()); id != "" {
+		return bh.log.With("request_id", id)
+	}
+
+	return bh.log
+}
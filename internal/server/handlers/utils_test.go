@@ -135,7 +135,7 @@ func TestValidateAndShouldBindJSON(t *testing.T) {
 			obj:  models.MetricsUpdate{},
 			body: models.MetricsUpdate{},
 
-			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"ID\" failed on the 'required' tag."},
+			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"ID\" failed on the 'required' tag.", Field: "id", Expected: "required"},
 			wantedStatusCode:    http.StatusBadRequest,
 			wantedErr:           true,
 		},
@@ -148,7 +148,7 @@ func TestValidateAndShouldBindJSON(t *testing.T) {
 				MType: "heh",
 			},
 
-			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"MType\" failed on the 'oneof=counter gauge' tag."},
+			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"MType\" failed on the 'oneof=counter gauge' tag.", Field: "type", Expected: "oneof=counter gauge"},
 			wantedStatusCode:    http.StatusBadRequest,
 			wantedErr:           true,
 		},
@@ -162,7 +162,7 @@ func TestValidateAndShouldBindJSON(t *testing.T) {
 				Delta: getRandomInt64(),
 			},
 
-			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"Value\" failed on the 'required_if=MType gauge' tag."},
+			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"Value\" failed on the 'required_if=MType gauge' tag.", Field: "value", Expected: "required_if=MType gauge"},
 			wantedStatusCode:    http.StatusBadRequest,
 			wantedErr:           true,
 		},
@@ -176,7 +176,7 @@ func TestValidateAndShouldBindJSON(t *testing.T) {
 				Value: getRandomFloat64(),
 			},
 
-			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"Delta\" failed on the 'required_if=MType counter' tag."},
+			wantedErrorResponse: &models.ErrorResponse{Error: "Field validation for \"Delta\" failed on the 'required_if=MType counter' tag.", Field: "delta", Expected: "required_if=MType counter"},
 			wantedStatusCode:    http.StatusBadRequest,
 			wantedErr:           true,
 		},
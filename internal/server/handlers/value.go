@@ -1,25 +1,28 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
 )
 
 func (bh baseHandler) ValueByURI() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if !bh.validateContentType(ctx, "text/plain", true) {
-			bh.log.Debugf("Request with invalid content-type.")
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
 			bh.handleBadRequest(ctx)
 			return
 		}
 
 		id := ctx.Param("name")
 		if id == "" {
-			bh.log.Debugf("The required name parameter is not specified.")
+			bh.logger(ctx).Debugf("The required name parameter is not specified.")
 
 			ctx.Status(http.StatusNotFound)
 			ctx.Abort()
@@ -33,6 +36,10 @@ func (bh baseHandler) ValueByURI() gin.HandlerFunc {
 		if storageType == string(models.GaugeType) {
 			value, err := bh.storage.GetGauge(id)
 			if err != nil {
+				if bh.handleStorageUnavailable(ctx, err) {
+					return
+				}
+
 				ctx.Status(http.StatusNotFound)
 				ctx.Abort()
 
@@ -40,9 +47,14 @@ func (bh baseHandler) ValueByURI() gin.HandlerFunc {
 			}
 
 			response = strconv.FormatFloat(*value, 'f', -1, 64)
+			bh.setLastUpdatedHeader(ctx, storageType, id)
 		} else if storageType == string(models.CounterType) {
 			value, err := bh.storage.GetCounter(id)
 			if err != nil {
+				if bh.handleStorageUnavailable(ctx, err) {
+					return
+				}
+
 				ctx.Status(http.StatusNotFound)
 				ctx.Abort()
 
@@ -50,8 +62,9 @@ func (bh baseHandler) ValueByURI() gin.HandlerFunc {
 			}
 
 			response = *value
+			bh.setLastUpdatedHeader(ctx, storageType, id)
 		} else {
-			bh.log.Debugf("An invalid metric type was passed.")
+			bh.logger(ctx).Debugf("An invalid metric type was passed.")
 			bh.handleBadRequest(ctx)
 			return
 		}
@@ -63,16 +76,16 @@ func (bh baseHandler) ValueByURI() gin.HandlerFunc {
 
 func (bh baseHandler) ValueByBody() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		if !bh.validateContentType(ctx, "application/json", false) {
-			bh.log.Debugf("Request with invalid content-type.")
+		if bh.requestFormat(ctx) == "" {
+			bh.logger(ctx).Debugf("Request with invalid content-type.")
 			bh.handleBadRequest(ctx)
 			return
 		}
 
 		var obj models.MetricsValue
-		if response, statusCode, err := bh.validateAndShouldBindJSON(ctx, &obj); err != nil {
+		if response, statusCode, err := bh.validateAndShouldBind(ctx, &obj); err != nil {
 			if statusCode == http.StatusInternalServerError {
-				bh.log.Errorf("Error decoding object request: %s (%T)", err, err)
+				bh.logger(ctx).Errorf("Error decoding object request: %s (%T)", err, err)
 			}
 
 			if response == nil {
@@ -89,6 +102,10 @@ func (bh baseHandler) ValueByBody() gin.HandlerFunc {
 		if obj.MType == string(models.GaugeType) {
 			value, err := bh.storage.GetGauge(obj.ID)
 			if err != nil {
+				if bh.handleStorageUnavailable(ctx, err) {
+					return
+				}
+
 				ctx.Status(http.StatusNotFound)
 				ctx.Abort()
 
@@ -99,6 +116,10 @@ func (bh baseHandler) ValueByBody() gin.HandlerFunc {
 		} else if obj.MType == string(models.CounterType) {
 			delta, err := bh.storage.GetCounter(obj.ID)
 			if err != nil {
+				if bh.handleStorageUnavailable(ctx, err) {
+					return
+				}
+
 				ctx.Status(http.StatusNotFound)
 				ctx.Abort()
 
@@ -107,8 +128,31 @@ func (bh baseHandler) ValueByBody() gin.HandlerFunc {
 
 			obj.Delta = delta
 		}
+		obj.UpdatedAt = bh.updatedAt(ctx, obj.MType, obj.ID)
 
-		ctx.JSON(http.StatusOK, obj)
+		bh.render(ctx, http.StatusOK, obj)
 		ctx.Abort()
 	}
 }
+
+// LastUpdatedHeader - время последней записи метрики в формате RFC 3339, по нему видно метрики умерших агентов.
+const LastUpdatedHeader = "X-Last-Updated"
+
+func (bh baseHandler) setLastUpdatedHeader(ctx *gin.Context, mType, name string) {
+	if updatedAt := bh.updatedAt(ctx, mType, name); updatedAt != nil {
+		ctx.Header(LastUpdatedHeader, updatedAt.UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// updatedAt возвращает время последней записи метрики или nil, если хранилище его не знает.
+func (bh baseHandler) updatedAt(ctx *gin.Context, mType, name string) *time.Time {
+	updatedAt, err := storage.UpdatedAt(bh.storage, mType, name)
+	if err != nil {
+		if !errors.Is(err, storage.ErrUpdatedAtUnsupported) {
+			bh.logger(ctx).Errorf("Failed to get update time of %s %s: %s", mType, name, err)
+		}
+		return nil
+	}
+
+	return &updatedAt
+}
@@ -7,7 +7,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestValue(t *testing.T) {
@@ -75,7 +78,7 @@ func TestValue(t *testing.T) {
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -143,7 +146,7 @@ func TestValueByBody(t *testing.T) {
 
 			metricType: models.GaugeType,
 
-			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\"}",
+			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\",\"field\":\"id\",\"expected\":\"required\"}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 		{
@@ -151,13 +154,13 @@ func TestValueByBody(t *testing.T) {
 
 			metricType: models.CounterType,
 
-			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\"}",
+			wantedBody:       "{\"error\":\"Field validation for \\\"ID\\\" failed on the 'required' tag.\",\"field\":\"id\",\"expected\":\"required\"}",
 			wantedStatusCode: http.StatusBadRequest,
 		},
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -191,8 +194,39 @@ func TestValueByBody(t *testing.T) {
 			body, err := io.ReadAll(res.Body)
 			require.NoError(t, err)
 
+			wantedBody := tt.wantedBody
+			if tt.wantedStatusCode == http.StatusOK {
+				updatedAt, err := storage.GetUpdatedAt(string(tt.metricType), tt.metricName)
+				require.NoError(t, err)
+
+				updatedAtJSON, err := json.Marshal(updatedAt)
+				require.NoError(t, err)
+				wantedBody = strings.TrimSuffix(wantedBody, "}") + ",\"updated_at\":" + string(updatedAtJSON) + "}"
+			}
+
 			assert.Equal(t, res.StatusCode, tt.wantedStatusCode)
-			assert.Equal(t, string(body), tt.wantedBody)
+			assert.Equal(t, string(body), wantedBody)
 		})
 	}
 }
+
+func TestValueLastUpdated(t *testing.T) {
+	storage := memstorage.NewMem()
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	value := 1.5
+	require.NoError(t, storage.SetGauge("Alloc", &value))
+
+	updatedAt := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	storage.SetUpdatedAt(string(models.GaugeType), "Alloc", updatedAt)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2023-10-15T10:30:00Z", w.Header().Get(LastUpdatedHeader))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/value/gauge/Unknown", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get(LastUpdatedHeader))
+}
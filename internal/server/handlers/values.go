@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"strings"
@@ -15,7 +16,11 @@ func (bh baseHandler) Values() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		values, err := bh.storage.GetAll()
 		if err != nil {
-			bh.log.Debugf("Error get all metrics: %s", err)
+			if bh.handleStorageUnavailable(ctx, err) {
+				return
+			}
+
+			bh.logger(ctx).Debugf("Error get all metrics: %s", err)
 
 			ctx.Status(http.StatusInternalServerError)
 			ctx.Abort()
@@ -23,12 +28,20 @@ func (bh baseHandler) Values() gin.HandlerFunc {
 			return
 		}
 
+		// HTML-страница остаётся ответом по умолчанию, остальные форматы отдаются только по запросу
+		if bh.responseFormat(ctx) != formatJSON {
+			bh.render(ctx, http.StatusOK, values)
+			ctx.Abort()
+
+			return
+		}
+
 		text := "<center><h1>Values</h1>"
 		for _, value := range values {
 			if value.MType == string(models.GaugeType) {
-				text += fmt.Sprintf("<p>%s: %s - %v</p>", value.MType, value.ID, *value.Value)
+				text += fmt.Sprintf("<p>%s: %s - %v</p>", value.MType, html.EscapeString(value.ID), *value.Value)
 			} else if value.MType == string(models.CounterType) {
-				text += fmt.Sprintf("<p>%s: %s - %d</p>", value.MType, value.ID, *value.Delta)
+				text += fmt.Sprintf("<p>%s: %s - %d</p>", value.MType, html.EscapeString(value.ID), *value.Delta)
 			}
 		}
 		text += "</center>"
@@ -37,7 +50,7 @@ func (bh baseHandler) Values() gin.HandlerFunc {
 		ctx.Header("Content-Type", "text/html; charset=utf-8")
 
 		if _, err := io.Copy(ctx.Writer, strings.NewReader(text)); err != nil {
-			bh.log.Debugf("io.Copy() error: %s", err)
+			bh.logger(ctx).Debugf("io.Copy() error: %s", err)
 			ctx.String(http.StatusInternalServerError, "%s", "Internal server error")
 		}
 
@@ -10,6 +10,7 @@ import (
 	"go.uber.org/zap/zaptest"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestValues(t *testing.T) {
@@ -36,7 +37,7 @@ func TestValues(t *testing.T) {
 	}
 
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,66 @@
+package health
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+var (
+	mx         sync.RWMutex
+	components = make(map[string]error)
+
+	componentUp = selfmetrics.Default.NewGauge(
+		"metrics_component_up",
+		"Whether a server dependency is healthy (1) or not (0).",
+		"component",
+	)
+)
+
+// Set запоминает состояние зависимости сервера: nil означает, что она работает.
+func Set(component string, err error) {
+	mx.Lock()
+	defer mx.Unlock()
+
+	components[component] = err
+
+	if err == nil {
+		componentUp.Set(1, component)
+	} else {
+		componentUp.Set(0, component)
+	}
+}
+
+func Remove(component string) {
+	mx.Lock()
+	defer mx.Unlock()
+
+	delete(components, component)
+}
+
+// Ready сообщает, что все зависимости работают, и возвращает состояние каждой из них.
+func Ready() (bool, map[string]string) {
+	mx.RLock()
+	defer mx.RUnlock()
+
+	ready := true
+	statuses := make(map[string]string, len(components))
+
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := components[name]; err != nil {
+			ready = false
+			statuses[name] = err.Error()
+		} else {
+			statuses[name] = "ok"
+		}
+	}
+
+	return ready, statuses
+}
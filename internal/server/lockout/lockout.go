@@ -0,0 +1,140 @@
+// Package lockout считает неудачные попытки аутентификации по адресам клиентов и временно
+// блокирует адреса, которые подбирают ключи.
+package lockout
+
+import (
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// Причины неудачной аутентификации, они же значения метки reason в самометриках.
+const (
+	ReasonSignature = "signature"
+	ReasonAPIKey    = "api_key"
+	ReasonAdminKey  = "admin_key"
+)
+
+// maxSources - сколько адресов хранится, прежде чем забытые записи начинают вычищаться.
+const maxSources = 10000
+
+type (
+	Options struct {
+		Threshold int           // неудач за Window, после которых адрес блокируется; 0 только считает неудачи
+		Window    time.Duration // окно подсчёта неудач
+		BlockFor  time.Duration // на сколько блокируется адрес
+
+		Clock clock.Clock
+	}
+
+	// Tracker пишет неудачи в журнал аудита и самометрики и решает, когда блокировать адрес.
+	Tracker struct {
+		opts Options
+		log  logger.Logger
+
+		mx      sync.Mutex
+		sources map[string]*source
+		blocked int
+	}
+
+	source struct {
+		failures     int
+		windowStart  time.Time
+		blockedUntil time.Time
+	}
+)
+
+// New создаёт Tracker. В log попадают события аудита, поэтому его стоит назвать отдельным модулем.
+func New(opts Options, log logger.Logger) *Tracker {
+	opts.Clock = clock.Or(opts.Clock)
+
+	return &Tracker{opts: opts, log: log, sources: make(map[string]*source)}
+}
+
+// Blocked сообщает, заблокирован ли адрес, и сколько ещё продлится блокировка.
+func (t *Tracker) Blocked(ip string) (time.Duration, bool) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	s, ok := t.sources[ip]
+	if !ok {
+		return 0, false
+	}
+
+	now := t.opts.Clock.Now()
+	t.expire(s, now)
+
+	left := s.blockedUntil.Sub(now)
+	return left, left > 0
+}
+
+// Failure учитывает неудачную попытку с адреса ip и возвращает true, если адрес после неё заблокирован.
+func (t *Tracker) Failure(ip, reason, path string) bool {
+	selfmetrics.AuthFailures.Inc(reason)
+	t.log.With("ip", ip, "reason", reason, "path", path).Infof("Authentication failure from %s (%s) on %s.", ip, reason, path)
+
+	if t.opts.Threshold <= 0 {
+		return false
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	now := t.opts.Clock.Now()
+	if len(t.sources) >= maxSources {
+		t.sweep(now)
+	}
+
+	s, ok := t.sources[ip]
+	if !ok {
+		s = &source{windowStart: now}
+		t.sources[ip] = s
+	}
+
+	if now.Before(s.blockedUntil) {
+		return true
+	}
+	t.expire(s, now)
+
+	if now.Sub(s.windowStart) > t.opts.Window {
+		s.failures, s.windowStart = 0, now
+	}
+
+	s.failures++
+	if s.failures < t.opts.Threshold {
+		return false
+	}
+
+	s.failures, s.blockedUntil = 0, now.Add(t.opts.BlockFor)
+	t.blocked++
+	selfmetrics.AuthBlocks.Inc()
+	selfmetrics.AuthBlockedSources.Set(float64(t.blocked))
+
+	t.log.With("ip", ip, "reason", reason).Infof("Blocked %s for %s after %d authentication failures in %s.", ip, t.opts.BlockFor, t.opts.Threshold, t.opts.Window)
+
+	return true
+}
+
+// expire снимает истёкшую блокировку, чтобы число заблокированных адресов не отставало.
+func (t *Tracker) expire(s *source, now time.Time) {
+	if s.blockedUntil.IsZero() || now.Before(s.blockedUntil) {
+		return
+	}
+
+	s.blockedUntil = time.Time{}
+	t.blocked--
+	selfmetrics.AuthBlockedSources.Set(float64(t.blocked))
+}
+
+// sweep забывает адреса, у которых истекли и окно подсчёта, и блокировка.
+func (t *Tracker) sweep(now time.Time) {
+	for ip, s := range t.sources {
+		t.expire(s, now)
+		if now.Sub(s.windowStart) > t.opts.Window && s.blockedUntil.IsZero() {
+			delete(t.sources, ip)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestTracker(t *testing.T) {
+	clk := clock.NewFake(time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC))
+	tracker := New(Options{Threshold: 3, Window: time.Minute, BlockFor: 10 * time.Minute, Clock: clk}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	failures := selfmetrics.AuthFailures.Value(ReasonAPIKey)
+	blocks := selfmetrics.AuthBlocks.Value()
+
+	assert.False(t, tracker.Failure("10.0.0.1", ReasonAPIKey, "/update/"))
+	assert.False(t, tracker.Failure("10.0.0.1", ReasonAPIKey, "/update/"))
+
+	// Окно подсчёта истекло, счёт начинается заново
+	clk.Advance(2 * time.Minute)
+	assert.False(t, tracker.Failure("10.0.0.1", ReasonAPIKey, "/update/"))
+	assert.False(t, tracker.Failure("10.0.0.1", ReasonAPIKey, "/update/"))
+	assert.True(t, tracker.Failure("10.0.0.1", ReasonAPIKey, "/update/"))
+
+	left, blocked := tracker.Blocked("10.0.0.1")
+	assert.True(t, blocked)
+	assert.Equal(t, 10*time.Minute, left)
+
+	_, blocked = tracker.Blocked("10.0.0.2")
+	assert.False(t, blocked)
+
+	assert.Equal(t, failures+5, selfmetrics.AuthFailures.Value(ReasonAPIKey))
+	assert.Equal(t, blocks+1, selfmetrics.AuthBlocks.Value())
+	assert.Equal(t, float64(1), selfmetrics.AuthBlockedSources.Value())
+
+	clk.Advance(10 * time.Minute)
+	_, blocked = tracker.Blocked("10.0.0.1")
+	assert.False(t, blocked)
+	assert.Equal(t, float64(0), selfmetrics.AuthBlockedSources.Value())
+}
+
+func TestTrackerWithoutThreshold(t *testing.T) {
+	tracker := New(Options{}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for i := 0; i < 100; i++ {
+		assert.False(t, tracker.Failure("10.0.0.1", ReasonSignature, "/updates/"))
+	}
+
+	_, blocked := tracker.Blocked("10.0.0.1")
+	assert.False(t, blocked)
+}
@@ -5,16 +5,21 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
 )
 
 type MemStorage struct {
 	gauge   map[string]*float64
 	counter map[string]*int64
+	stats   map[string]*gaugeStats
+	sources map[sourceKey]*models.MetricSource
+	updated map[metricKey]time.Time
 
 	mx sync.Mutex
 }
@@ -23,6 +28,9 @@ func NewMem() *MemStorage {
 	return &MemStorage{
 		gauge:   make(map[string]*float64),
 		counter: make(map[string]*int64),
+		stats:   make(map[string]*gaugeStats),
+		sources: make(map[sourceKey]*models.MetricSource),
+		updated: make(map[metricKey]time.Time),
 	}
 }
 
@@ -57,6 +65,32 @@ func (mStorage *MemStorage) SetGauge(name string, value *float64) error {
 
 	name = mStorage.normalizeName(name)
 	mStorage.gauge[name] = value
+	mStorage.observeGauge(name, *value)
+	mStorage.touch(models.GaugeType, name, time.Now())
+
+	return nil
+}
+
+// AddGauge прибавляет delta к gauge под блокировкой хранилища по правилам policy.AddGauge.
+// Отсутствующий gauge считается нулём.
+func (mStorage *MemStorage) AddGauge(name string, delta *float64) error {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	name = mStorage.normalizeName(name)
+
+	var current float64
+	if value, ok := mStorage.gauge[name]; ok {
+		current = *value
+	}
+
+	newValue, keep, err := policy.AddGauge(current, *delta)
+	if err != nil || !keep {
+		return err
+	}
+	mStorage.gauge[name] = &newValue
+	mStorage.observeGauge(name, newValue)
+	mStorage.touch(models.GaugeType, name, time.Now())
 
 	return nil
 }
@@ -76,22 +110,34 @@ func (mStorage *MemStorage) GetCounter(name string) (*int64, error) {
 }
 
 func (mStorage *MemStorage) AddCounter(name string, value *int64) error {
+	if value == nil {
+		return errs.ErrCounterMissingDelta
+	}
+
 	mStorage.mx.Lock()
 	defer mStorage.mx.Unlock()
 
 	name = mStorage.normalizeName(name)
-	currentValue, ok := mStorage.counter[name]
 
-	if !ok {
-		mStorage.counter[name] = value
-	} else {
-		newValue := (*currentValue) + (*value)
-		mStorage.counter[name] = &newValue
+	newValue, err := mStorage.nextCounter(name, *value)
+	if err != nil {
+		return err
 	}
+	mStorage.counter[name] = &newValue
+	mStorage.touch(models.CounterType, name, time.Now())
 
 	return nil
 }
 
+func (mStorage *MemStorage) nextCounter(name string, delta int64) (int64, error) {
+	currentValue, ok := mStorage.counter[name]
+	if !ok {
+		return policy.CounterDelta(delta)
+	}
+
+	return policy.AddCounter(*currentValue, delta)
+}
+
 func (mStorage *MemStorage) GetAll() ([]models.MetricsValue, error) {
 	mStorage.mx.Lock()
 	defer mStorage.mx.Unlock()
@@ -100,17 +146,19 @@ func (mStorage *MemStorage) GetAll() ([]models.MetricsValue, error) {
 
 	for k, value := range mStorage.gauge {
 		values = append(values, models.MetricsValue{
-			ID:    k,
-			MType: string(models.GaugeType),
-			Value: value,
+			ID:        k,
+			MType:     string(models.GaugeType),
+			Value:     value,
+			UpdatedAt: mStorage.updatedAt(models.GaugeType, k),
 		})
 	}
 
 	for k, delta := range mStorage.counter {
 		values = append(values, models.MetricsValue{
-			ID:    k,
-			MType: string(models.CounterType),
-			Delta: delta,
+			ID:        k,
+			MType:     string(models.CounterType),
+			Delta:     delta,
+			UpdatedAt: mStorage.updatedAt(models.CounterType, k),
 		})
 	}
 
@@ -129,6 +177,82 @@ func (mStorage *MemStorage) String() string {
 	return fmt.Sprintf("MemStorage - Pointer(%+v)", &mStorage)
 }
 
+// NormalizeNames переводит имена метрик в нижний регистр и возвращает число затронутых метрик. Дубликаты
+// счётчиков суммируются, у дубликатов gauge остаётся значение метрики, которая уже была в нижнем регистре.
+func (mStorage *MemStorage) NormalizeNames() (int, error) {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	counters := make(map[string]int64)
+	for name, value := range mStorage.counter {
+		lower := strings.ToLower(name)
+		if lower == name {
+			continue
+		}
+
+		current, ok := counters[lower]
+		if !ok {
+			if existing, exists := mStorage.counter[lower]; exists {
+				current, ok = *existing, true
+			}
+		}
+
+		sum := *value
+		if ok {
+			var err error
+			if sum, err = policy.AddCounter(current, *value); err != nil {
+				return 0, fmt.Errorf("counter %s: %w", name, err)
+			}
+		}
+		counters[lower] = sum
+	}
+
+	gauges := make(map[string]struct{})
+	for name, value := range mStorage.gauge {
+		lower := strings.ToLower(name)
+		if lower == name {
+			continue
+		}
+
+		if _, ok := mStorage.gauge[lower]; !ok {
+			mStorage.gauge[lower] = value
+			if stats, hasStats := mStorage.stats[name]; hasStats {
+				mStorage.stats[lower] = stats
+			}
+		}
+		delete(mStorage.gauge, name)
+		delete(mStorage.stats, name)
+		gauges[lower] = struct{}{}
+	}
+
+	for name := range mStorage.counter {
+		if strings.ToLower(name) != name {
+			delete(mStorage.counter, name)
+		}
+	}
+
+	for name, value := range counters {
+		value := value
+		mStorage.counter[name] = &value
+	}
+
+	// Объединённая метрика считается обновлённой тогда же, когда самый свежий из дубликатов
+	for key, at := range mStorage.updated {
+		lower := strings.ToLower(key.name)
+		if lower == key.name {
+			continue
+		}
+
+		lowerKey := metricKey{name: lower, mType: key.mType}
+		if current, ok := mStorage.updated[lowerKey]; !ok || at.After(current) {
+			mStorage.updated[lowerKey] = at
+		}
+		delete(mStorage.updated, key)
+	}
+
+	return len(gauges) + len(counters), nil
+}
+
 func (mStorage *MemStorage) normalizeName(name string) string {
 	return strings.TrimSpace(name)
 }
@@ -1,10 +1,14 @@
 package memstorage
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
 )
 
 func TestMemGetCounter(t *testing.T) {
@@ -125,3 +129,57 @@ func TestMem_normalizeName(t *testing.T) {
 		})
 	}
 }
+
+func TestMem_AddGauge(t *testing.T) {
+	storage := NewMem()
+
+	delta := 2.5
+	require.NoError(t, storage.AddGauge("Occupancy", &delta))
+	require.NoError(t, storage.AddGauge("Occupancy", &delta))
+
+	value, err := storage.GetGauge("Occupancy")
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, *value)
+
+	huge := math.MaxFloat64
+	require.NoError(t, storage.SetGauge("Huge", &huge))
+
+	config.Config.GaugePolicy = "reject"
+	defer func() { config.Config.GaugePolicy = "" }()
+
+	require.ErrorIs(t, storage.AddGauge("Huge", &huge), errs.ErrGaugeNotFinite)
+
+	value, err = storage.GetGauge("Huge")
+	require.NoError(t, err)
+	assert.Equal(t, math.MaxFloat64, *value, "a failed add must not change the gauge")
+
+	config.Config.GaugePolicy = "store"
+	require.NoError(t, storage.AddGauge("Huge", &huge))
+
+	value, err = storage.GetGauge("Huge")
+	require.NoError(t, err)
+	assert.True(t, math.IsInf(*value, 1), "the store mode must keep an infinite sum")
+}
+
+func TestMem_GaugeStats(t *testing.T) {
+	storage := NewMem()
+
+	value, delta := 1.0, 4.0
+	require.NoError(t, storage.SetGauge("Load", &value))
+	require.NoError(t, storage.AddGauge("Load", &delta))
+
+	tx, err := storage.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetGauge("Load", &value))
+	require.NoError(t, tx.Commit())
+
+	stats, err := storage.GetGaugeStats("Load")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Count)
+	assert.Equal(t, 1.0, stats.Min)
+	assert.Equal(t, 5.0, stats.Max)
+	assert.InDelta(t, 7.0/3, stats.Avg, 1e-9)
+
+	_, err = storage.GetGaugeStats("Unknown")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidGaugeName)
+}
@@ -2,8 +2,11 @@ package memstorage
 
 import (
 	"sync"
+	"time"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
 )
 
 type tx struct {
@@ -27,6 +30,13 @@ func (t *tx) SetGauge(name string, value *float64) error {
 }
 
 func (t *tx) AddCounter(name string, value *int64) error {
+	if value == nil {
+		return errs.ErrCounterMissingDelta
+	}
+	if _, err := policy.CounterDelta(*value); err != nil {
+		return err
+	}
+
 	t.mx.Lock()
 	defer t.mx.Unlock()
 
@@ -43,13 +53,48 @@ func (t *tx) Commit() error {
 	t.mx.Lock()
 	defer t.mx.Unlock()
 
+	t.storage.mx.Lock()
+	defer t.storage.mx.Unlock()
+
+	// Сначала считаем все счётчики, чтобы при переполнении не применить транзакцию частично
+	counters := make(map[string]int64)
 	for _, row := range t.rows {
-		switch row.MType {
-		case string(models.GaugeType):
-			_ = t.storage.SetGauge(row.ID, row.Value)
-		case string(models.CounterType):
-			_ = t.storage.AddCounter(row.ID, row.Delta)
+		if row.MType != string(models.CounterType) {
+			continue
+		}
+
+		name := t.storage.normalizeName(row.ID)
+
+		var (
+			value int64
+			err   error
+		)
+		if current, ok := counters[name]; ok {
+			value, err = policy.AddCounter(current, *row.Delta)
+		} else {
+			value, err = t.storage.nextCounter(name, *row.Delta)
+		}
+		if err != nil {
+			return err
 		}
+
+		counters[name] = value
+	}
+
+	now := time.Now()
+	for _, row := range t.rows {
+		if row.MType == string(models.GaugeType) {
+			name := t.storage.normalizeName(row.ID)
+			t.storage.gauge[name] = row.Value
+			t.storage.observeGauge(name, *row.Value)
+			t.storage.touch(models.GaugeType, name, now)
+		}
+	}
+
+	for name, value := range counters {
+		value := value
+		t.storage.counter[name] = &value
+		t.storage.touch(models.CounterType, name, now)
 	}
 
 	t.rows = []models.MetricsUpdate{}
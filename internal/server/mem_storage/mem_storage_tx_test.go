@@ -1,10 +1,13 @@
 package memstorage
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
 )
 
@@ -74,3 +77,41 @@ func TestMemStorageTx(t *testing.T) {
 		}
 	}
 }
+
+func TestMemStorageTxCounterOverflow(t *testing.T) {
+	config.Config.CounterPolicy = "reject"
+	defer func() { config.Config.CounterPolicy = "" }()
+
+	memStorage := NewMem()
+	require.NoError(t, memStorage.AddCounter("Test", getPointerInt64(math.MaxInt64-1)))
+
+	txx, err := memStorage.NewTx()
+	require.NoError(t, err)
+
+	require.NoError(t, txx.SetGauge("Wow", getPointerFloat64(13.5)))
+	require.NoError(t, txx.AddCounter("Test", getPointerInt64(1)))
+	require.NoError(t, txx.AddCounter("Test", getPointerInt64(1)))
+	require.ErrorIs(t, txx.AddCounter("Test", getPointerInt64(-1)), errs.ErrCounterNegativeDelta)
+
+	require.ErrorIs(t, txx.Commit(), errs.ErrCounterOverflow)
+
+	value, err := memStorage.GetCounter("Test")
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64-1), *value)
+
+	_, err = memStorage.GetGauge("Wow")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidGaugeName, "transaction must not be applied partially")
+}
+
+func TestMemStorageMissingCounterDelta(t *testing.T) {
+	memStorage := NewMem()
+	require.ErrorIs(t, memStorage.AddCounter("Test", nil), errs.ErrCounterMissingDelta)
+
+	txx, err := memStorage.NewTx()
+	require.NoError(t, err)
+	require.ErrorIs(t, txx.AddCounter("Test", nil), errs.ErrCounterMissingDelta)
+	require.NoError(t, txx.Commit())
+
+	_, err = memStorage.GetCounter("Test")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidCounterName)
+}
@@ -0,0 +1,43 @@
+package memstorage
+
+import (
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+// Search возвращает метрики, имена которых подходят под шаблон, см. policy.NameMatcher.
+func (mStorage *MemStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	matcher, err := policy.NameMatcher(pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	var values []models.MetricsValue
+
+	for k, value := range mStorage.gauge {
+		if matcher.MatchString(k) {
+			values = append(values, models.MetricsValue{
+				ID:        k,
+				MType:     string(models.GaugeType),
+				Value:     value,
+				UpdatedAt: mStorage.updatedAt(models.GaugeType, k),
+			})
+		}
+	}
+
+	for k, delta := range mStorage.counter {
+		if matcher.MatchString(k) {
+			values = append(values, models.MetricsValue{
+				ID:        k,
+				MType:     string(models.CounterType),
+				Delta:     delta,
+				UpdatedAt: mStorage.updatedAt(models.CounterType, k),
+			})
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,69 @@
+package memstorage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type sourceKey struct {
+	agentID string
+	name    string
+	mType   string
+}
+
+func (mStorage *MemStorage) RecordSources(agentID string, updates []models.MetricsUpdate) error {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	now := time.Now()
+	for _, update := range updates {
+		name := mStorage.normalizeName(update.ID)
+		key := sourceKey{agentID: agentID, name: name, mType: update.MType}
+
+		source, ok := mStorage.sources[key]
+		if !ok {
+			source = &models.MetricSource{AgentID: agentID, ID: name, MType: update.MType}
+			mStorage.sources[key] = source
+		}
+
+		source.Delta, source.Value = copyPointer(update.Delta), copyPointer(update.Value)
+		source.Updates++
+		source.UpdatedAt = now
+	}
+
+	return nil
+}
+
+func copyPointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+
+	copied := *value
+	return &copied
+}
+
+func (mStorage *MemStorage) GetSources(mType, name string) ([]models.MetricSource, error) {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	name = mStorage.normalizeName(name)
+
+	var sources []models.MetricSource
+	for key, source := range mStorage.sources {
+		if key.name == name && key.mType == mType {
+			sources = append(sources, *source)
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if !sources[i].UpdatedAt.Equal(sources[j].UpdatedAt) {
+			return sources[i].UpdatedAt.After(sources[j].UpdatedAt)
+		}
+		return sources[i].AgentID < sources[j].AgentID
+	})
+
+	return sources, nil
+}
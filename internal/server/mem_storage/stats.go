@@ -0,0 +1,71 @@
+package memstorage
+
+import (
+	"math"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type gaugeStats struct {
+	count    int64
+	sum      float64
+	min, max float64
+	since    time.Time
+}
+
+// observeGauge учитывает новое значение gauge в статистике. Вызывается под блокировкой хранилища.
+func (mStorage *MemStorage) observeGauge(name string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	stats, ok := mStorage.stats[name]
+	if !ok {
+		stats = &gaugeStats{since: time.Now()}
+		mStorage.stats[name] = stats
+	}
+
+	if stats.count == 0 || value < stats.min {
+		stats.min = value
+	}
+	if stats.count == 0 || value > stats.max {
+		stats.max = value
+	}
+	stats.count++
+	stats.sum += value
+}
+
+func (mStorage *MemStorage) GetGaugeStats(name string) (*models.GaugeStats, error) {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	name = mStorage.normalizeName(name)
+
+	stats, ok := mStorage.stats[name]
+	if !ok {
+		return nil, errs.ErrStorageInvalidGaugeName
+	}
+
+	result := &models.GaugeStats{ID: name, Count: stats.count, Min: stats.min, Max: stats.max, Since: stats.since}
+	if stats.count > 0 {
+		result.Avg = stats.sum / float64(stats.count)
+	}
+
+	return result, nil
+}
+
+// ResetGaugeStats начинает статистику gauge заново с текущего момента.
+func (mStorage *MemStorage) ResetGaugeStats(name string) error {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	name = mStorage.normalizeName(name)
+	if _, ok := mStorage.stats[name]; !ok {
+		return errs.ErrStorageInvalidGaugeName
+	}
+
+	mStorage.stats[name] = &gaugeStats{since: time.Now()}
+	return nil
+}
@@ -0,0 +1,54 @@
+package memstorage
+
+import (
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type metricKey struct {
+	name  string
+	mType string
+}
+
+// touch запоминает время записи метрики. Вызывается под блокировкой хранилища.
+func (mStorage *MemStorage) touch(mType models.MetricType, name string, at time.Time) {
+	mStorage.updated[metricKey{name: name, mType: string(mType)}] = at
+}
+
+// GetUpdatedAt возвращает время последней записи метрики.
+func (mStorage *MemStorage) GetUpdatedAt(mType, name string) (time.Time, error) {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	at, ok := mStorage.updated[metricKey{name: mStorage.normalizeName(name), mType: mType}]
+	if !ok {
+		if mType == string(models.CounterType) {
+			return time.Time{}, errs.ErrStorageInvalidCounterName
+		}
+		return time.Time{}, errs.ErrStorageInvalidGaugeName
+	}
+
+	return at, nil
+}
+
+// SetUpdatedAt переписывает время записи существующей метрики, например при восстановлении из снапшота.
+func (mStorage *MemStorage) SetUpdatedAt(mType, name string, at time.Time) {
+	mStorage.mx.Lock()
+	defer mStorage.mx.Unlock()
+
+	key := metricKey{name: mStorage.normalizeName(name), mType: mType}
+	if _, ok := mStorage.updated[key]; ok {
+		mStorage.updated[key] = at
+	}
+}
+
+func (mStorage *MemStorage) updatedAt(mType models.MetricType, name string) *time.Time {
+	at, ok := mStorage.updated[metricKey{name: name, mType: string(mType)}]
+	if !ok {
+		return nil
+	}
+
+	return &at
+}
@@ -3,29 +3,68 @@ package middlewares
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 type (
 	baseMiddleware struct {
-		log logger.Logger
+		log      logger.Logger
+		recorder *record.Writer
+		nonces   *signing.Nonces
+		lockout  *lockout.Tracker
 	}
 	router interface {
 		gin.IRouter
 
 		GetStorage() models.Storage
 		GetLogger() logger.Logger
+		GetRecorder() *record.Writer
+		GetLockout() *lockout.Tracker
 	}
 )
 
 func Setup(r router) {
 	bm := &baseMiddleware{
-		log: r.GetLogger(),
+		log:      r.GetLogger().Named("middlewares"),
+		recorder: r.GetRecorder(),
+		lockout:  r.GetLockout(),
 	}
 
+	if config.Config.ReplayWindow > 0 {
+		bm.nonces = signing.NewNonces(config.Config.ReplayWindow, config.Config.ReplayNonceCache)
+	}
+
+	r.Use(bm.ForwardedFor)
+	r.Use(bm.RequestID)
 	r.Use(bm.Logger)
+	r.Use(bm.Recovery)
+	r.Use(bm.SecurityHeaders)
+	r.Use(bm.Lockout)
 	r.Use(bm.Compress)
+	r.Use(bm.BodyLogger)
+	r.Use(bm.Record)
 	r.Use(bm.Hash)
 	r.Use(r.GetStorage().GetMiddleware())
 }
+
+// SetupAdmin настраивает отдельный роутер служебных маршрутов. Подпись, запись и сохранение
+// в хранилище нужны только запросам обновления, поэтому здесь их нет.
+func SetupAdmin(admin gin.IRouter, r router) {
+	bm := &baseMiddleware{
+		log:     r.GetLogger().Named("middlewares"),
+		lockout: r.GetLockout(),
+	}
+
+	admin.Use(bm.ForwardedFor)
+	admin.Use(bm.RequestID)
+	admin.Use(bm.Logger)
+	admin.Use(bm.Recovery)
+	admin.Use(bm.SecurityHeaders)
+	admin.Use(bm.Lockout)
+	admin.Use(bm.Compress)
+}
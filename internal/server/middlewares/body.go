@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// teeBody копирует тело запроса по мере того, как его читает обработчик.
+type teeBody struct {
+	io.ReadCloser
+	copied bytes.Buffer
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.copied.Write(p[:n])
+
+	return n, err
+}
+
+// requestBody возвращает функцию, которая отдаёт тело запроса. Обычное тело читается сразу, а потоковое
+// (см. models.IsStreamedUpdate) копируется по мере чтения обработчиком, поэтому целиком оно доступно
+// только после ctx.Next().
+func requestBody(ctx *gin.Context) (func() []byte, error) {
+	if models.IsStreamedUpdate(ctx.Request, ctx.FullPath()) {
+		body := &teeBody{ReadCloser: ctx.Request.Body}
+		ctx.Request.Body = body
+
+		return body.copied.Bytes, nil
+	}
+
+	body, err := ctx.GetRawData()
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return func() []byte { return body }, err
+}
@@ -0,0 +1,133 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+const redactedBody = "[redacted]"
+
+var (
+	redactedHeaders   = []string{"HashSHA256", "Authorization", "Cookie", "Set-Cookie"}
+	secretFieldRegexp = regexp.MustCompile(`(?i)(key|secret|token|password|hash|signature)`)
+)
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body  *bytes.Buffer
+	limit int
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	// Пишем на байт больше лимита, чтобы formatBody увидел, что ответ обрезан
+	if left := w.limit + 1 - w.body.Len(); w.limit <= 0 || len(b) <= left {
+		w.body.Write(b)
+	} else if left > 0 {
+		w.body.Write(b[:left])
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// BodyLogger пишет в debug тела запросов и ответов эндпоинтов обновления, чтобы разбирать кривые пакеты от агентов.
+// Подписи, ключи и похожие на них поля JSON заменяются на [redacted].
+func (bm baseMiddleware) BodyLogger(ctx *gin.Context) {
+	if !config.Config.DebugBodies || !models.IsUpdateRoute(ctx.FullPath()) {
+		return
+	}
+
+	limit := config.Config.DebugBodyLimit
+
+	body, err := requestBody(ctx)
+	if err != nil {
+		bm.log.Errorf("Error get body for debug logging: %s (%T)", err, err)
+	}
+
+	recorder := &bodyRecorder{ResponseWriter: ctx.Writer, body: new(bytes.Buffer), limit: limit}
+	ctx.Writer = recorder
+
+	ctx.Next()
+
+	bm.log.With(
+		"request_id", requestid.From(ctx.Request.Context()),
+		"method", ctx.Request.Method,
+		"uri", ctx.Request.URL.String(),
+		"status", ctx.Writer.Status(),
+		"request_headers", redactHeaders(ctx.Request.Header),
+		"response_headers", redactHeaders(ctx.Writer.Header()),
+	).Debugf(
+		"Request body: %s - Response body: %s",
+		formatBody(body(), limit), formatBody(recorder.body.Bytes(), limit),
+	)
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		result[name] = strings.Join(values, ", ")
+	}
+
+	for _, name := range redactedHeaders {
+		if _, ok := result[http.CanonicalHeaderKey(name)]; ok {
+			result[http.CanonicalHeaderKey(name)] = redactedBody
+		}
+	}
+
+	return result
+}
+
+func formatBody(body []byte, limit int) string {
+	if len(body) == 0 {
+		return "<empty>"
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err == nil {
+		if redacted, err := json.Marshal(redactJSON(value)); err == nil {
+			body = redacted
+		}
+	}
+
+	if limit > 0 && len(body) > limit {
+		return fmt.Sprintf("%s... (%d bytes truncated)", body[:limit], len(body)-limit)
+	}
+
+	return string(body)
+}
+
+func redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if secretFieldRegexp.MatchString(key) {
+				v[key] = redactedBody
+			} else {
+				v[key] = redactJSON(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareBodyLogger(t *testing.T) {
+	config.Config.DebugBodies = true
+	config.Config.DebugBodyLimit = 64
+	defer func() {
+		config.Config.DebugBodies = false
+		config.Config.DebugBodyLimit = 0
+	}()
+
+	core, logs := observer.New(zap.DebugLevel)
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zap.New(core).Sugar()))
+
+	body := `[{"id":"Alloc","type":"gauge","value":1.5,"api_key":"secret"}]`
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer admin-key")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	entries := logs.FilterMessageSnippet("Request body").AllUntimed()
+	require.Len(t, entries, 1)
+
+	message := entries[0].Message
+	assert.Contains(t, message, `"id":"Alloc"`)
+	assert.Contains(t, message, `"api_key":"[redacted]"`)
+	assert.NotContains(t, message, "secret")
+	assert.Contains(t, message, "bytes truncated")
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Equal(t, "[redacted]", fields["request_headers"].(map[string]string)["Authorization"])
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Len(t, logs.FilterMessageSnippet("Request body").AllUntimed(), 1, "only update endpoints are logged")
+}
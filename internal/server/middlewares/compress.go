@@ -2,39 +2,129 @@ package middlewares
 
 import (
 	"compress/gzip"
-	"io"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
 )
 
+// gzipWriter копит начало ответа, пока не наберётся minSize байт, и только тогда решает, сжимать ли его:
+// маленькие ответы вроде значения одной метрики от сжатия только растут.
 type gzipWriter struct {
 	gin.ResponseWriter
-	writer io.Writer
+	gz      *gzip.Writer
+	minSize int
+
+	buf        []byte
+	decided    bool
+	compressed bool
+}
+
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func (w *gzipWriter) Write(b []byte) (int, error) {
-	return w.writer.Write(b)
+	if w.decided {
+		if w.compressed {
+			return w.gz.Write(b)
+		}
+
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.minSize {
+		return len(b), nil
+	}
+
+	if err := w.decide(true); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
 }
 
 func (w *gzipWriter) WriteString(s string) (int, error) {
 	return w.Write([]byte(s))
 }
 
+// decide выбирает, сжимать ли ответ, и отправляет накопленное начало. Ответ, заголовки которого уже
+// отправлены или который сжат обработчиком, не трогаем.
+func (w *gzipWriter) decide(large bool) error {
+	w.decided = true
+
+	header := w.Header()
+	if large && !w.Written() && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		header.Add("Vary", "Accept-Encoding")
+		w.compressed = true
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// Close дописывает ответ: короткий отправляется как есть, сжатый завершается.
+func (w *gzipWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+
+	if w.compressed {
+		return w.gz.Close()
+	}
+
+	return nil
+}
+
+func compressible(contentType string) bool {
+	types := config.Config.CompressTypes
+	if len(types) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	for _, allowed := range types {
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") || allowed == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (bm baseMiddleware) Compress(ctx *gin.Context) {
 	if !strings.Contains(ctx.GetHeader("Accept-Encoding"), "gzip") {
 		return
 	}
 
-	gz, err := gzip.NewWriterLevel(ctx.Writer, gzip.BestSpeed)
+	gz, err := gzip.NewWriterLevel(ctx.Writer, config.CompressLevel())
 	if err != nil {
 		bm.log.Errorf("Failed to create writer with compression: %s (%T)", err, err)
 		return
 	}
-	defer gz.Close()
 
-	ctx.Header("Content-Encoding", "gzip")
-	ctx.Writer = &gzipWriter{ResponseWriter: ctx.Writer, writer: gz}
+	writer := &gzipWriter{ResponseWriter: ctx.Writer, gz: gz, minSize: config.Config.CompressMinSize}
+	defer func() {
+		if err := writer.Close(); err != nil {
+			bm.log.Errorf("Failed to finish the compressed response: %s", err)
+		}
+	}()
 
+	ctx.Writer = writer
 	ctx.Next()
 }
@@ -1,19 +1,26 @@
 package middlewares
 
 import (
+	"compress/gzip"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
 	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestMiddlewareCompress(t *testing.T) {
 	storage := memstorage.NewMem()
-	r := setupRouter(storage, zaptest.NewLogger(t).Sugar())
+	r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
 
 	w := httptest.NewRecorder()
 
@@ -28,3 +35,60 @@ func TestMiddlewareCompress(t *testing.T) {
 
 	require.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
 }
+
+func TestMiddlewareCompressThresholds(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	config.Config.CompressLevel = gzip.BestCompression
+	config.Config.CompressMinSize = 64
+	config.Config.CompressTypes = []string{"application/json", "text/*"}
+
+	large := strings.Repeat("a", 100)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		compressed  bool
+	}{
+		{name: "Small JSON", contentType: "application/json; charset=utf-8", body: `{"value":1}`},
+		{name: "Large JSON", contentType: "application/json; charset=utf-8", body: `"` + large + `"`, compressed: true},
+		{name: "Large text", contentType: "text/plain; charset=utf-8", body: large, compressed: true},
+		{name: "Large binary", contentType: "application/octet-stream", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bm := baseMiddleware{log: logger.Wrap(zaptest.NewLogger(t).Sugar())}
+
+			engine := gin.New()
+			engine.Use(bm.Compress)
+			engine.GET("/", func(ctx *gin.Context) {
+				ctx.Data(http.StatusOK, tt.contentType, []byte(tt.body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			body := io.Reader(w.Body)
+			if tt.compressed {
+				require.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+				gz, err := gzip.NewReader(w.Body)
+				require.NoError(t, err)
+				body = gz
+			} else {
+				require.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+// ForwardedFor оставляет в X-Forwarded-For только первый адрес, если клиентом считается самый левый.
+// Gin читает заголовок, только когда соединение пришло от доверенного прокси, так что подделка
+// заголовка напрямую ничего не даёт.
+func (bm baseMiddleware) ForwardedFor(ctx *gin.Context) {
+	if config.Config.ForwardedForPosition != config.ForwardedForLeftmost || config.ClientIPHeader() != config.ForwardedForHeader {
+		return
+	}
+
+	values := ctx.Request.Header.Values(config.ForwardedForHeader)
+	if len(values) == 0 {
+		return
+	}
+
+	first, _, _ := strings.Cut(values[0], ",")
+	ctx.Request.Header.Set(config.ForwardedForHeader, strings.TrimSpace(first))
+}
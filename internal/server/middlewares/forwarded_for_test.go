@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+func TestMiddlewareForwardedFor(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	tests := []struct {
+		name       string
+		header     string
+		position   string
+		remoteAddr string
+		headers    map[string]string
+		wantedIP   string
+	}{
+		{
+			name:       "Rightmost not trusted address",
+			header:     config.ForwardedForHeader,
+			position:   config.ForwardedForRightmost,
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 10.0.0.2"},
+			wantedIP:   "2.2.2.2",
+		},
+		{
+			name:       "Leftmost address",
+			header:     config.ForwardedForHeader,
+			position:   config.ForwardedForLeftmost,
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 2.2.2.2, 10.0.0.2"},
+			wantedIP:   "1.1.1.1",
+		},
+		{
+			name:       "X-Real-IP",
+			header:     config.RealIPHeader,
+			position:   config.ForwardedForRightmost,
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "3.3.3.3", "X-Forwarded-For": "1.1.1.1"},
+			wantedIP:   "3.3.3.3",
+		},
+		{
+			name:       "Not trusted proxy",
+			header:     config.ForwardedForHeader,
+			position:   config.ForwardedForLeftmost,
+			remoteAddr: "192.168.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1"},
+			wantedIP:   "192.168.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.ClientIPHeader = tt.header
+			config.Config.ForwardedForPosition = tt.position
+
+			engine := gin.New()
+			engine.RemoteIPHeaders = []string{config.ClientIPHeader()}
+			require.NoError(t, engine.SetTrustedProxies([]string{"10.0.0.0/8"}))
+
+			var clientIP string
+			engine.Use(baseMiddleware{}.ForwardedFor)
+			engine.GET("/", func(ctx *gin.Context) { clientIP = ctx.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.wantedIP, clientIP)
+		})
+	}
+}
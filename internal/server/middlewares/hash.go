@@ -5,48 +5,167 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
 )
 
+var errSignatureMismatch = errors.New("signature does not match the request body")
+
+// Hash проверяет подпись тела запроса ключом из HashKeyID или, если заголовка нет, ключом KEY.
+// Несколько ключей позволяют переводить агентов на новый ключ постепенно.
+// Если запрос несёт HashTimestamp и HashNonce, подписаны и они, а повтор такого запроса отклоняется.
+// С REQUIRE_REPLAY_NONCE обновления без подписи или без HashTimestamp и HashNonce не принимаются.
 func (bm baseMiddleware) Hash(ctx *gin.Context) {
-	secureKey := config.Config.Key
-	if secureKey == "" {
+	keys := config.SigningKeys()
+	if len(keys) == 0 {
 		return
 	}
 
-	hexHashByClient := ctx.GetHeader("HashSHA256")
+	hexHashByClient := ctx.GetHeader(signing.HashHeader)
 	if hexHashByClient == "" {
+		// Без подписи время и nonce проверить нельзя, поэтому при обязательном nonce обновления без неё отклоняются
+		if config.Config.RequireReplayNonce && models.IsUpdateRoute(ctx.FullPath()) {
+			bm.log.Debugf("Unsigned update request is rejected: %s and %s are required.", signing.TimestampHeader, signing.NonceHeader)
+
+			ctx.Status(http.StatusBadRequest)
+			ctx.Abort()
+		}
+
+		return
+	}
+
+	keyID := ctx.GetHeader(signing.KeyIDHeader)
+	if keyID == "" {
+		keyID = config.DefaultSigningKeyID
+	}
+
+	secureKey, ok := keys[keyID]
+	if !ok {
+		bm.log.Debugf("Request is signed with an unknown key %q.", keyID)
+		bm.authFailure(ctx, lockout.ReasonSignature)
+
+		ctx.Status(http.StatusBadRequest)
+		ctx.Abort()
+
 		return
 	}
 
 	hashByClient, err := hex.DecodeString(hexHashByClient)
 	if err != nil {
+		bm.authFailure(ctx, lockout.ReasonSignature)
+
+		ctx.Status(http.StatusBadRequest)
+		ctx.Abort()
+
+		return
+	}
+
+	timestamp, nonce := ctx.GetHeader(signing.TimestampHeader), ctx.GetHeader(signing.NonceHeader)
+	withNonce := timestamp != "" || nonce != ""
+	if !withNonce && config.Config.RequireReplayNonce {
+		bm.log.Debugf("Signed request without %s and %s is rejected.", signing.TimestampHeader, signing.NonceHeader)
+
 		ctx.Status(http.StatusBadRequest)
 		ctx.Abort()
 
 		return
 	}
 
+	mac := hmac.New(sha256.New, []byte(secureKey))
+	if withNonce {
+		mac.Write(signing.Material(timestamp, nonce, nil))
+	}
+
+	verify := func(hashByServer []byte) error {
+		return bm.verifySignature(ctx, keyID, hashByServer, hashByClient, timestamp, nonce)
+	}
+
+	// Потоковое тело не читается заранее: подпись считается по мере чтения и проверяется в конце тела
+	if models.IsStreamedUpdate(ctx.Request, ctx.FullPath()) {
+		ctx.Request.Body = &signedBody{ReadCloser: ctx.Request.Body, hash: mac, verify: verify}
+		return
+	}
+
 	body, err := ctx.GetRawData()
 	if err != nil {
 		bm.log.Errorf("Error get body for hash check: %s (%T)", err, err)
 	}
 	ctx.Request.Body = io.NopCloser(bytes.NewReader(body)) // Необходимо вернуть body, тк handler-ы потом не смогут прочитать body...
 
-	hash := hmac.New(sha256.New, []byte(secureKey))
-	hash.Write(body)
-
-	hashByServer := hash.Sum(nil)
-	hexHashByServer := hex.EncodeToString(hashByServer)
-	ctx.Header("HashSHA256", hexHashByServer)
-
-	if !bytes.Equal(hashByServer, hashByClient) {
+	mac.Write(body)
+	if err = verify(mac.Sum(nil)); errors.Is(err, signing.ErrNonceCacheFull) {
+		ctx.Status(http.StatusServiceUnavailable)
+		ctx.Abort()
+	} else if err != nil {
 		ctx.Status(http.StatusBadRequest)
 		ctx.Abort()
 	}
 }
+
+// verifySignature сверяет подпись, проверяет nonce и отмечает использование ключа. Ответ подписывается,
+// только если проверка прошла: иначе сервер подписывал бы любое тело по запросу клиента.
+func (bm baseMiddleware) verifySignature(ctx *gin.Context, keyID string, hashByServer, hashByClient []byte, timestamp, nonce string) error {
+	if !hmac.Equal(hashByServer, hashByClient) {
+		bm.authFailure(ctx, lockout.ReasonSignature)
+		return errSignatureMismatch
+	}
+
+	if (timestamp != "" || nonce != "") && bm.nonces != nil {
+		if err := bm.nonces.Check(timestamp, nonce, time.Now()); errors.Is(err, signing.ErrNonceCacheFull) {
+			// Клиент тут не виноват, поэтому это не ошибка аутентификации; запрос можно повторить позже
+			bm.log.Errorf("Rejected signed request from %s: %s, raise -replay-nonce-cache.", ctx.ClientIP(), err)
+			return err
+		} else if err != nil {
+			bm.log.Infof("Rejected signed request from %s: %s", ctx.ClientIP(), err)
+			bm.authFailure(ctx, lockout.ReasonSignature)
+			return err
+		}
+	}
+
+	ctx.Header(signing.HashHeader, hex.EncodeToString(hashByServer))
+	if keyID != config.DefaultSigningKeyID {
+		ctx.Header(signing.KeyIDHeader, keyID)
+	}
+
+	signing.Record(keyID, time.Now())
+	return nil
+}
+
+// signedBody считает подпись тела по мере чтения и проверяет её, когда тело прочитано до конца. Если проверка
+// не прошла, вместо io.EOF возвращается ошибка с signing.ErrBodyRejected, поэтому обработчик должен дочитать
+// тело до конца, прежде чем фиксировать изменения.
+type signedBody struct {
+	io.ReadCloser
+	hash   hash.Hash
+	verify func(hashByServer []byte) error
+	err    error
+}
+
+func (b *signedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) {
+		if verifyErr := b.verify(b.hash.Sum(nil)); verifyErr != nil {
+			err = fmt.Errorf("%w: %w", signing.ErrBodyRejected, verifyErr)
+		}
+		b.err = err
+	}
+
+	return n, err
+}
@@ -0,0 +1,191 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareHash(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	config.Config.Key = "old-key"
+	config.Config.SigningKeys = "v2:new-key"
+
+	body := `{"id": "PollCount", "type": "counter", "delta": 1}`
+	sign := func(key string) string {
+		hash := hmac.New(sha256.New, []byte(key))
+		hash.Write([]byte(body))
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+
+	tests := []struct {
+		name       string
+		keyID      string
+		hash       string
+		wantedCode int
+	}{
+		{name: "Default key", hash: sign("old-key"), wantedCode: http.StatusOK},
+		{name: "Key by id", keyID: "v2", hash: sign("new-key"), wantedCode: http.StatusOK},
+		{name: "Default key by id", keyID: config.DefaultSigningKeyID, hash: sign("old-key"), wantedCode: http.StatusOK},
+		{name: "Wrong key for id", keyID: "v2", hash: sign("old-key"), wantedCode: http.StatusBadRequest},
+		{name: "Unknown key id", keyID: "v3", hash: sign("new-key"), wantedCode: http.StatusBadRequest},
+		{name: "Unsigned", wantedCode: http.StatusOK},
+	}
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.hash != "" {
+				req.Header.Set(signing.HashHeader, tt.hash)
+			}
+			if tt.keyID != "" {
+				req.Header.Set(signing.KeyIDHeader, tt.keyID)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantedCode, w.Code)
+
+			// Запрос с неверной подписью не должен получать верную
+			if tt.wantedCode == http.StatusOK {
+				assert.Equal(t, tt.hash, w.Header().Get(signing.HashHeader))
+			} else {
+				assert.Empty(t, w.Header().Get(signing.HashHeader))
+			}
+		})
+	}
+
+	usage := signing.Usage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, config.DefaultSigningKeyID, usage[0].ID)
+	assert.Equal(t, int64(2), usage[0].Requests)
+	assert.Equal(t, "v2", usage[1].ID)
+	assert.Equal(t, int64(1), usage[1].Requests)
+	assert.NotNil(t, usage[1].LastUsed)
+}
+
+func TestMiddlewareHashReplay(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	config.Config.Key = "secret"
+	config.Config.ReplayWindow = time.Minute
+	config.Config.ReplayNonceCache = 100
+
+	body := `{"id": "PollCount", "type": "counter", "delta": 1}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	sign := func(timestamp, nonce string) string {
+		hash := hmac.New(sha256.New, []byte("secret"))
+		hash.Write(signing.Material(timestamp, nonce, []byte(body)))
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+	signBody := func() string {
+		hash := hmac.New(sha256.New, []byte("secret"))
+		hash.Write([]byte(body))
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+
+	tests := []struct {
+		name       string
+		require    bool
+		timestamp  string
+		nonce      string
+		hash       string
+		wantedCode int
+	}{
+		{name: "With nonce", timestamp: now, nonce: "n1", hash: sign(now, "n1"), wantedCode: http.StatusOK},
+		{name: "Replayed nonce", timestamp: now, nonce: "n1", hash: sign(now, "n1"), wantedCode: http.StatusBadRequest},
+		{name: "Stale timestamp", timestamp: old, nonce: "n2", hash: sign(old, "n2"), wantedCode: http.StatusBadRequest},
+		{name: "Nonce not signed", timestamp: now, nonce: "n3", hash: signBody(), wantedCode: http.StatusBadRequest},
+		{name: "Tampered nonce", timestamp: now, nonce: "n4", hash: sign(now, "n5"), wantedCode: http.StatusBadRequest},
+		{name: "Without nonce", hash: signBody(), wantedCode: http.StatusOK},
+		{name: "Without nonce when required", require: true, hash: signBody(), wantedCode: http.StatusBadRequest},
+		{name: "With nonce when required", require: true, timestamp: now, nonce: "n6", hash: sign(now, "n6"), wantedCode: http.StatusOK},
+		{name: "Unsigned when required", require: true, wantedCode: http.StatusBadRequest},
+		{name: "Unsigned", wantedCode: http.StatusOK},
+	}
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.RequireReplayNonce = tt.require
+
+			req := httptest.NewRequest(http.MethodPost, "/update/", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(signing.HashHeader, tt.hash)
+			if tt.timestamp != "" {
+				req.Header.Set(signing.TimestampHeader, tt.timestamp)
+				req.Header.Set(signing.NonceHeader, tt.nonce)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantedCode, w.Code)
+		})
+	}
+}
+
+func TestMiddlewareHashStreamed(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	config.Config.Key = "secret"
+
+	body := `[{"id": "PollCount", "type": "counter", "delta": 1}]`
+	hash := hmac.New(sha256.New, []byte("secret"))
+	hash.Write([]byte(body))
+	signature := hex.EncodeToString(hash.Sum(nil))
+
+	tests := []struct {
+		name       string
+		hash       string
+		wantedCode int
+		wantedHash string
+	}{
+		{name: "Valid signature", hash: signature, wantedCode: http.StatusOK, wantedHash: signature},
+		{name: "Invalid signature", hash: hex.EncodeToString(make([]byte, sha256.Size)), wantedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := memstorage.NewMem()
+			r := setupRouter(storage, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(signing.HashHeader, tt.hash)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, tt.wantedCode, w.Code)
+			assert.Equal(t, tt.wantedHash, w.Header().Get(signing.HashHeader))
+
+			values, err := storage.GetAll()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantedCode == http.StatusOK, len(values) == 1, "the batch must be applied only with a valid signature")
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// Lockout отклоняет запросы с адресов, заблокированных за подбор ключей, до конца блокировки.
+func (bm baseMiddleware) Lockout(ctx *gin.Context) {
+	if bm.lockout == nil {
+		return
+	}
+
+	left, blocked := bm.lockout.Blocked(ctx.ClientIP())
+	if !blocked {
+		return
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(left.Seconds()))))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{Error: "Too many authentication failures, try again later."})
+}
+
+// authFailure учитывает неудачную проверку подписи; сам запрос отклоняет вызывающий.
+func (bm baseMiddleware) authFailure(ctx *gin.Context, reason string) {
+	if bm.lockout != nil {
+		bm.lockout.Failure(ctx.ClientIP(), reason, ctx.Request.URL.Path)
+	}
+}
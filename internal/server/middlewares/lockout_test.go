@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/handlers"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	serverRouter "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/signing"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareLockout(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+	config.Config.Key = "secret"
+
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+
+	r := serverRouter.New(memstorage.NewMem(), log)
+	r.SetLockout(lockout.New(lockout.Options{Threshold: 2, Window: time.Minute, BlockFor: time.Minute}, log))
+	Setup(r)
+	handlers.Setup(r)
+
+	send := func(remoteAddr, hash string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/update/counter/test/1", bytes.NewBufferString(""))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(signing.HashHeader, hash)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("10.0.0.1:1000", "00").Code)
+	assert.Equal(t, http.StatusBadRequest, send("10.0.0.1:1000", "zz").Code)
+
+	w := send("10.0.0.1:1000", "00")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Другой адрес не заблокирован
+	assert.Equal(t, http.StatusBadRequest, send("10.0.0.2:1000", "00").Code)
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
 )
 
 func (bm baseMiddleware) Logger(ctx *gin.Context) {
@@ -18,5 +20,5 @@ func (bm baseMiddleware) Logger(ctx *gin.Context) {
 	statusCode := ctx.Writer.Status()
 	size := ctx.Writer.Size()
 
-	bm.log.Infof("%s Request - URI: \"%s\" - LeadTime: %v - StatusCode: %d (%s) - BodySize: %d", method, uri, duration, statusCode, http.StatusText(statusCode), size)
+	bm.log.With("request_id", requestid.From(ctx.Request.Context())).Infof("%s Request - URI: \"%s\" - LeadTime: %v - StatusCode: %d (%s) - BodySize: %d", method, uri, duration, statusCode, http.StatusText(statusCode), size)
 }
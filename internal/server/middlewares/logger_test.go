@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func TestMiddlewareLogger(t *testing.T) {
@@ -24,7 +25,7 @@ func TestMiddlewareLogger(t *testing.T) {
 		zap.AddCaller(),
 	)
 
-	r := setupRouter(storage, log.Sugar())
+	r := setupRouter(storage, logger.Wrap(log.Sugar()))
 
 	w := httptest.NewRecorder()
 
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+)
+
+// Record пишет принятые запросы обновления в файл записи, чтобы потом воспроизвести их командой replay.
+// Тело записывается уже распакованным, поэтому Content-Encoding не сохраняется.
+func (bm baseMiddleware) Record(ctx *gin.Context) {
+	if bm.recorder == nil || !models.IsUpdateRoute(ctx.FullPath()) {
+		return
+	}
+
+	received := time.Now()
+
+	body, err := requestBody(ctx)
+	if err != nil {
+		bm.log.Errorf("Error get body for recording: %s (%T)", err, err)
+	}
+
+	ctx.Next()
+
+	if status := ctx.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+		return
+	}
+
+	header := make(map[string]string, len(record.RecordedHeaders))
+	for _, key := range record.RecordedHeaders {
+		if value := ctx.GetHeader(key); value != "" {
+			header[key] = value
+		}
+	}
+
+	err = bm.recorder.Write(record.Entry{
+		Time:   received,
+		Method: ctx.Request.Method,
+		URI:    ctx.Request.URL.RequestURI(),
+		Header: header,
+		Body:   body(),
+	})
+	if err != nil {
+		bm.log.Errorf("Failed to record the request: %s", err)
+	}
+}
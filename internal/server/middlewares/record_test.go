@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/handlers"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+	serverRouter "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+
+	recorder, err := record.Open(path)
+	require.NoError(t, err)
+
+	r := serverRouter.New(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	r.SetRecorder(recorder)
+	Setup(r)
+	handlers.Setup(r)
+
+	requests := []struct {
+		method      string
+		uri         string
+		contentType string
+		body        string
+		status      int
+	}{
+		{method: http.MethodPost, uri: "/update/gauge/Alloc/1.5", contentType: "text/plain", status: http.StatusOK},
+		{method: http.MethodPost, uri: "/updates/", contentType: "application/json", body: `[{"id":"PollCount","type":"counter","delta":1}]`, status: http.StatusOK},
+		{method: http.MethodPost, uri: "/update/gauge/Alloc/bad", contentType: "text/plain", status: http.StatusBadRequest},
+		{method: http.MethodGet, uri: "/value/gauge/Alloc", status: http.StatusOK},
+	}
+
+	for _, tt := range requests {
+		req := httptest.NewRequest(tt.method, tt.uri, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, tt.status, w.Code, tt.uri)
+	}
+	require.NoError(t, recorder.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader := record.NewReader(file)
+
+	entry, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "/update/gauge/Alloc/1.5", entry.URI)
+	assert.Equal(t, "text/plain", entry.Header["Content-Type"])
+
+	entry, err = reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "/updates/", entry.URI)
+	assert.JSONEq(t, `[{"id":"PollCount","type":"counter","delta":1}]`, string(entry.Body))
+
+	_, err = reader.Next()
+	require.ErrorIs(t, err, io.EOF, "rejected and read-only requests must not be recorded")
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+func (bm baseMiddleware) Recovery(ctx *gin.Context) {
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+
+		// http.ErrAbortHandler - штатный способ прервать ответ, его обрабатывает net/http
+		if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+			panic(rec)
+		}
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unknown"
+		}
+		selfmetrics.HTTPPanics.Inc(route)
+
+		bm.log.With(
+			"method", ctx.Request.Method,
+			"route", route,
+			"uri", ctx.Request.URL.String(),
+			"request_id", requestid.From(ctx.Request.Context()),
+		).Errorf("Panic recovered: %v\n%s", rec, debug.Stack())
+
+		if ctx.Writer.Written() {
+			ctx.Abort()
+			return
+		}
+
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Internal server error."})
+	}()
+
+	ctx.Next()
+}
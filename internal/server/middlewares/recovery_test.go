@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareRecovery(t *testing.T) {
+	buf := new(bytes.Buffer)
+	log := zap.New(zapcore.NewCore(
+		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+		zapcore.AddSync(buf),
+		zapcore.DebugLevel),
+	)
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(log.Sugar()))
+	r.GET("/panic/:name", func(_ *gin.Context) {
+		panic("test panic")
+	})
+
+	w := httptest.NewRecorder()
+
+	req := httptest.NewRequest(http.MethodGet, "/panic/test", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error":"Internal server error."}`, w.Body.String())
+
+	assert.Contains(t, buf.String(), "Panic recovered: test panic")
+	assert.Contains(t, buf.String(), `"route": "/panic/:name"`)
+	assert.Contains(t, buf.String(), `"request_id": "req-42"`)
+	assert.Contains(t, buf.String(), "recovery_test.go")
+	assert.Contains(t, buf.String(), "StatusCode: 500", "request must still be logged")
+
+	assert.Equal(t, float64(1), selfmetrics.HTTPPanics.Value("/panic/:name"))
+}
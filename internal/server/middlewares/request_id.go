@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+// RequestID берёт идентификатор пакета от агента или создаёт новый, кладёт его в контекст запроса и возвращает в ответе.
+func (bm baseMiddleware) RequestID(ctx *gin.Context) {
+	id := ctx.GetHeader(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	ctx.Request = ctx.Request.WithContext(requestid.With(ctx.Request.Context(), id))
+	ctx.Header(requestid.Header, id)
+}
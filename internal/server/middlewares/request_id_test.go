@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantedNew bool
+	}{
+		{
+			name:   "From agent",
+			header: "batch-42",
+		},
+		{
+			name:      "Missing",
+			wantedNew: true,
+		},
+		{
+			name:      "Invalid",
+			header:    "bad id\twith tab",
+			wantedNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			r := setupRouter(memstorage.NewMem(), logger.Wrap(zap.New(core).Sugar()))
+
+			req := httptest.NewRequest(http.MethodPost, "/update/counter/PollCount/abc", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(requestid.Header)
+			if tt.wantedNew {
+				require.True(t, requestid.Valid(id))
+				assert.NotEqual(t, tt.header, id)
+			} else {
+				assert.Equal(t, tt.header, id)
+			}
+
+			// И лог хендлера, и лог запроса несут один и тот же идентификатор
+			entries := logs.FilterField(zap.String("request_id", id)).AllUntimed()
+			require.Len(t, entries, 2)
+			assert.Equal(t, "handlers", entries[0].LoggerName)
+			assert.Equal(t, "middlewares", entries[1].LoggerName)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+// SecurityHeaders добавляет заголовки, которые защищают HTML-страницы от XSS, подмены типа и встраивания во фреймы.
+// Strict-Transport-Security отправляется только в ответах по HTTPS.
+func (bm baseMiddleware) SecurityHeaders(ctx *gin.Context) {
+	if !config.Config.SecurityHeaders {
+		return
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if csp := config.Config.ContentSecurityPolicy; csp != "" {
+		header.Set("Content-Security-Policy", csp)
+	}
+
+	if frameOptions := config.Config.FrameOptions; frameOptions != "" {
+		header.Set("X-Frame-Options", frameOptions)
+	}
+
+	if maxAge := config.Config.HSTSMaxAge; maxAge > 0 && ctx.Request.TLS != nil {
+		header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int64(maxAge.Seconds())))
+	}
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	memstorage "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMiddlewareSecurityHeaders(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	tests := []struct {
+		name          string
+		enabled       bool
+		frameOptions  string
+		tls           bool
+		wantedHeaders map[string]string
+	}{
+		{
+			name:    "Disabled",
+			enabled: false,
+			wantedHeaders: map[string]string{
+				"X-Content-Type-Options":  "",
+				"Content-Security-Policy": "",
+			},
+		},
+		{
+			name:         "Over HTTP",
+			enabled:      true,
+			frameOptions: "DENY",
+			wantedHeaders: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"Content-Security-Policy":   config.DefaultContentSecurityPolicy,
+				"X-Frame-Options":           "DENY",
+				"Strict-Transport-Security": "",
+			},
+		},
+		{
+			name:         "Over HTTPS",
+			enabled:      true,
+			frameOptions: "SAMEORIGIN",
+			tls:          true,
+			wantedHeaders: map[string]string{
+				"X-Frame-Options":           "SAMEORIGIN",
+				"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+			},
+		},
+		{
+			name:    "Without frame options",
+			enabled: true,
+			wantedHeaders: map[string]string{
+				"X-Content-Type-Options": "nosniff",
+				"X-Frame-Options":        "",
+			},
+		},
+	}
+
+	r := setupRouter(memstorage.NewMem(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.SecurityHeaders = tt.enabled
+			config.Config.ContentSecurityPolicy = config.DefaultContentSecurityPolicy
+			config.Config.FrameOptions = tt.frameOptions
+			config.Config.HSTSMaxAge = time.Hour
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			for key, value := range tt.wantedHeaders {
+				assert.Equal(t, value, w.Header().Get(key), key)
+			}
+		})
+	}
+}
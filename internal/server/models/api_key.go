@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// APIKey - ключ доступа к API. Сам ключ не хранится, только его хеш SHA-256 в Hash.
+type APIKey struct {
+	ID        string     `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Role      string     `json:"role" db:"role"`
+	Hash      string     `json:"-" db:"hash"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+type (
+	APIKeyRequest struct {
+		Name string `json:"name" binding:"required"`
+		Role string `json:"role" binding:"required"`
+	}
+
+	// CreatedAPIKey - ответ на выпуск ключа, единственное место, где виден сам ключ.
+	CreatedAPIKey struct {
+		APIKey
+		Key string `json:"key"`
+	}
+)
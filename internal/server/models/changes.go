@@ -0,0 +1,22 @@
+package models
+
+// MetricsChange - метрики, записанные другим экземпляром сервера. All значит, что перечислить их нельзя
+// и сбросить нужно всё.
+type MetricsChange struct {
+	Gauges   []string `json:"gauges,omitempty"`
+	Counters []string `json:"counters,omitempty"`
+	All      bool     `json:"all,omitempty"`
+}
+
+// Add добавляет метрику типа mType к изменению.
+func (c *MetricsChange) Add(name, mType string) {
+	if mType == string(GaugeType) {
+		c.Gauges = append(c.Gauges, name)
+	} else {
+		c.Counters = append(c.Counters, name)
+	}
+}
+
+func (c MetricsChange) Empty() bool {
+	return !c.All && len(c.Gauges) == 0 && len(c.Counters) == 0
+}
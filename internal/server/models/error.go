@@ -1,5 +1,21 @@
 package models
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+type (
+	ErrorResponse struct {
+		Error string `json:"error"`
+
+		// Field - путь к полю с ошибкой: "value" или "[37].value" у элемента пакета.
+		Field string `json:"field,omitempty"`
+		// Expected - тип или правило проверки, которому поле не соответствует.
+		Expected string `json:"expected,omitempty"`
+		// Offset - смещение в байтах тела, на котором остановился разбор JSON.
+		Offset int64 `json:"offset,omitempty"`
+	}
+
+	ConflictResponse struct {
+		Error         string `json:"error"`
+		ID            string `json:"id"`
+		ExistingType  string `json:"existing_type"`
+		RequestedType string `json:"requested_type"`
+	}
+)
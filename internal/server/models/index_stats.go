@@ -0,0 +1,11 @@
+package models
+
+// IndexStats - статистика использования индекса таблицы хранилища с момента сброса статистики базы данных.
+type IndexStats struct {
+	Table         string `json:"table" db:"table_name"`
+	Index         string `json:"index" db:"index_name"`
+	Scans         int64  `json:"scans" db:"scans"`
+	TuplesRead    int64  `json:"tuples_read" db:"tuples_read"`
+	TuplesFetched int64  `json:"tuples_fetched" db:"tuples_fetched"`
+	SizeBytes     int64  `json:"size_bytes" db:"size_bytes"`
+}
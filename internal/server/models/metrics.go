@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type MetricType string
 
 const (
@@ -9,16 +11,34 @@ const (
 
 type (
 	MetricsUpdate struct {
-		ID    string   `json:"id" binding:"required"`
-		MType string   `json:"type" binding:"required,oneof=counter gauge"`
-		Delta *int64   `json:"delta,omitempty" binding:"required_if=MType counter"`
-		Value *float64 `json:"value,omitempty" binding:"required_if=MType gauge"`
+		ID    string   `json:"id" form:"id" binding:"required"`
+		MType string   `json:"type" form:"type" binding:"required,oneof=counter gauge"`
+		Delta *int64   `json:"delta,omitempty" form:"delta" binding:"required_if=MType counter"`
+		Value *float64 `json:"value,omitempty" form:"value" binding:"required_if=MType gauge"`
+	}
+
+	// GaugeDelta - тело запроса на изменение gauge на величину.
+	GaugeDelta struct {
+		Delta *float64 `json:"delta" binding:"required"`
+	}
+
+	// GaugeStats - статистика значений gauge с последнего сброса. NaN и ±Inf в неё не попадают.
+	GaugeStats struct {
+		ID    string    `json:"id" db:"name"`
+		Count int64     `json:"count" db:"count"`
+		Min   float64   `json:"min" db:"min"`
+		Max   float64   `json:"max" db:"max"`
+		Avg   float64   `json:"avg" db:"avg"`
+		Since time.Time `json:"since" db:"since"`
 	}
 
 	MetricsValue struct {
-		ID    string   `json:"id" db:"name" binding:"required"`
-		MType string   `json:"type" db:"mtype" binding:"required,oneof=counter gauge"`
+		ID    string   `json:"id" db:"name" form:"id" binding:"required"`
+		MType string   `json:"type" db:"mtype" form:"type" binding:"required,oneof=counter gauge"`
 		Delta *int64   `json:"delta,omitempty" db:"delta"`
 		Value *float64 `json:"value,omitempty" db:"value"`
+
+		// UpdatedAt - время последней записи метрики, если хранилище его знает.
+		UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
 	}
 )
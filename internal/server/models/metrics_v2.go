@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// labelNameRegexp - допустимые имена меток, как в Prometheus.
+var labelNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var errInvalidLabeledName = errors.New("invalid labeled name")
+
+// MetricsV2 - метрика API v2. Кроме значения несёт метки, единицу измерения и время замера.
+type MetricsV2 struct {
+	Name  string   `json:"name" binding:"required"`
+	MType string   `json:"type" binding:"required,oneof=counter gauge"`
+	Delta *int64   `json:"delta,omitempty" binding:"required_if=MType counter"`
+	Value *float64 `json:"value,omitempty" binding:"required_if=MType gauge"`
+
+	Labels    map[string]string `json:"labels,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+}
+
+// ValidateLabels проверяет имена меток.
+func (m MetricsV2) ValidateLabels() error {
+	for name := range m.Labels {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid label name %q: must match %s", name, labelNameRegexp)
+		}
+	}
+
+	return nil
+}
+
+// ToUpdate переводит метрику в старую форму. Метки входят в ID (см. LabeledName), а единица измерения
+// и время замера в старой форме не хранятся.
+func (m MetricsV2) ToUpdate() MetricsUpdate {
+	return MetricsUpdate{ID: LabeledName(m.Name, m.Labels), MType: m.MType, Delta: m.Delta, Value: m.Value}
+}
+
+// MetricsV2FromUpdate разбирает метрику старой формы, восстанавливая метки из ID.
+func MetricsV2FromUpdate(obj MetricsUpdate) MetricsV2 {
+	name, labels := ParseLabeledName(obj.ID)
+	return MetricsV2{Name: name, MType: obj.MType, Delta: obj.Delta, Value: obj.Value, Labels: labels}
+}
+
+// MetricsV2FromValue разбирает значение из хранилища. Временем замера считается время последней записи.
+func MetricsV2FromValue(obj MetricsValue) MetricsV2 {
+	name, labels := ParseLabeledName(obj.ID)
+	return MetricsV2{Name: name, MType: obj.MType, Delta: obj.Delta, Value: obj.Value, Labels: labels, Timestamp: obj.UpdatedAt}
+}
+
+// LabeledName собирает ID метрики с метками в виде name{a="1",b="2"}. Метки сортируются по имени,
+// чтобы один и тот же набор всегда давал один ID.
+func LabeledName(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[key]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// ParseLabeledName разбирает ID, собранный LabeledName. ID без меток или не в этом формате возвращается как имя целиком.
+func ParseLabeledName(id string) (string, map[string]string) {
+	name, rest, ok := strings.Cut(id, "{")
+	if !ok || !strings.HasSuffix(rest, "}") {
+		return id, nil
+	}
+
+	labels, err := parseLabels(strings.TrimSuffix(rest, "}"))
+	if err != nil || len(labels) == 0 {
+		return id, nil
+	}
+
+	return name, labels
+}
+
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok || !labelNameRegexp.MatchString(key) || !strings.HasPrefix(rest, `"`) {
+			return nil, errInvalidLabeledName
+		}
+
+		end := closingQuote(rest)
+		if end < 0 {
+			return nil, errInvalidLabeledName
+		}
+
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, errInvalidLabeledName
+		}
+		labels[key] = value
+
+		s = rest[end+1:]
+		if s != "" {
+			if s, ok = strings.CutPrefix(s, ","); !ok || s == "" {
+				return nil, errInvalidLabeledName
+			}
+		}
+	}
+
+	return labels, nil
+}
+
+// closingQuote возвращает индекс кавычки, закрывающей строку в начале s, с учётом экранирования.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
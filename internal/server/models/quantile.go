@@ -0,0 +1,10 @@
+package models
+
+// Quantile - квантиль гистограммы. Value равен nil, если наблюдений ещё не было.
+type Quantile struct {
+	ID       string            `json:"id"`
+	Quantile float64           `json:"quantile"`
+	Value    *float64          `json:"value"`
+	Count    uint64            `json:"count"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type (
+	// CounterSample - значение counter из истории на момент записи.
+	CounterSample struct {
+		Delta     int64     `db:"delta"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	// CounterRate - скорость роста counter в секунду за окно. Rate равен nil, если в окне меньше двух записей.
+	CounterRate struct {
+		ID       string    `json:"id"`
+		Window   float64   `json:"window_seconds"`
+		Rate     *float64  `json:"rate"`
+		Increase float64   `json:"increase"`
+		Resets   int       `json:"resets"`
+		Samples  int       `json:"samples"`
+		From     time.Time `json:"from"`
+		To       time.Time `json:"to"`
+	}
+)
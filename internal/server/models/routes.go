@@ -0,0 +1,25 @@
+package models
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// IsUpdateRoute сообщает, что маршрут меняет метрики. Такие запросы записываются для replay,
+// логируются с телом и сразу сохраняются в файл, если STORE_INTERVAL равен 0.
+func IsUpdateRoute(fullPath string) bool {
+	return strings.Contains(fullPath, "/update") || strings.HasPrefix(fullPath, "/api/gauge/")
+}
+
+// IsStreamedUpdate сообщает, что тело запроса разбирается потоком: это JSON-пакет /updates без ?return.
+// Middleware не должны читать такое тело заранее, иначе обработчик будет ждать его целиком.
+func IsStreamedUpdate(r *http.Request, fullPath string) bool {
+	if strings.TrimSuffix(fullPath, "/") != "/updates" || r.URL.Query().Get("return") != "" {
+		return false
+	}
+
+	mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	return strings.EqualFold(strings.TrimSpace(mediaType), binding.MIMEJSON)
+}
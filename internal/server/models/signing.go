@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SigningKeyUsage - сколько запросов подписано ключом и когда был последний. LastUsed равен nil, если ни одного.
+type SigningKeyUsage struct {
+	ID       string     `json:"id"`
+	Requests int64      `json:"requests"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// AgentIDHeader - заголовок, в котором агент представляется серверу.
+const AgentIDHeader = "X-Agent-ID"
+
+// MetricSource - последнее обновление метрики от одного агента.
+type MetricSource struct {
+	AgentID   string    `json:"agent_id" db:"agent_id"`
+	ID        string    `json:"id" db:"name"`
+	MType     string    `json:"type" db:"mtype"`
+	Delta     *int64    `json:"delta,omitempty" db:"delta"`
+	Value     *float64  `json:"value,omitempty" db:"value"`
+	Updates   int64     `json:"updates" db:"updates"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// StaleMetric - метрика, которая давно не обновлялась по сравнению со своим обычным интервалом.
+type StaleMetric struct {
+	ID        string    `json:"id"`
+	MType     string    `json:"type"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Interval  float64   `json:"interval_seconds"`
+	StaleFor  float64   `json:"stale_seconds"`
+}
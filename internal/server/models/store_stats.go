@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// StoreStats - сводка по хранилищу для дашбордов ёмкости. Поля, которые хранилище не умеет считать, опускаются.
+type StoreStats struct {
+	Backend       string             `json:"backend"`
+	Metrics       map[string]int     `json:"metrics"`
+	Total         int                `json:"total"`
+	SizeBytes     *int64             `json:"size_bytes,omitempty"`
+	OldestUpdate  *time.Time         `json:"oldest_update,omitempty"`
+	NewestUpdate  *time.Time         `json:"newest_update,omitempty"`
+	CacheHitRates map[string]float64 `json:"cache_hit_rates,omitempty"`
+}
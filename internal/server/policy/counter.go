@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"math"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+// Режимы обработки отрицательных дельт и переполнения счётчиков.
+const (
+	CounterAllow  = "allow"  // отрицательные дельты принимаются, сумма переполняется по модулю 2^64
+	CounterClamp  = "clamp"  // отрицательные дельты считаются нулевыми, сумма ограничивается math.MaxInt64
+	CounterReject = "reject" // отрицательные дельты и переполнение возвращают ошибку
+)
+
+func CounterMode() string {
+	if config.Config.CounterPolicy == "" {
+		return CounterAllow
+	}
+
+	return config.Config.CounterPolicy
+}
+
+// CounterDelta проверяет дельту счётчика до записи.
+func CounterDelta(delta int64) (int64, error) {
+	if delta >= 0 {
+		return delta, nil
+	}
+
+	switch CounterMode() {
+	case CounterClamp:
+		return 0, nil
+	case CounterReject:
+		return 0, errs.ErrCounterNegativeDelta
+	default:
+		return delta, nil
+	}
+}
+
+// AddCounter складывает текущее значение счётчика с дельтой по выбранному режиму.
+func AddCounter(current, delta int64) (int64, error) {
+	delta, err := CounterDelta(delta)
+	if err != nil {
+		return 0, err
+	}
+
+	sum := current + delta
+	overflow := (delta > 0 && sum < current) || (delta < 0 && sum > current)
+	if !overflow {
+		return sum, nil
+	}
+
+	switch CounterMode() {
+	case CounterClamp:
+		if delta > 0 {
+			return math.MaxInt64, nil
+		}
+		return math.MinInt64, nil
+	case CounterReject:
+		return 0, errs.ErrCounterOverflow
+	default:
+		return sum, nil
+	}
+}
@@ -0,0 +1,88 @@
+package policy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+func TestAddCounter(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		current     int64
+		delta       int64
+		wantedValue int64
+		wantedErr   error
+	}{
+		{
+			name:        "Allow (default mode)",
+			current:     10,
+			delta:       -3,
+			wantedValue: 7,
+		},
+		{
+			name:        "Allow overflow",
+			mode:        CounterAllow,
+			current:     math.MaxInt64,
+			delta:       1,
+			wantedValue: math.MinInt64,
+		},
+		{
+			name:        "Clamp negative delta",
+			mode:        CounterClamp,
+			current:     10,
+			delta:       -3,
+			wantedValue: 10,
+		},
+		{
+			name:        "Clamp overflow",
+			mode:        CounterClamp,
+			current:     math.MaxInt64 - 1,
+			delta:       5,
+			wantedValue: math.MaxInt64,
+		},
+		{
+			name:      "Reject negative delta",
+			mode:      CounterReject,
+			current:   10,
+			delta:     -3,
+			wantedErr: errs.ErrCounterNegativeDelta,
+		},
+		{
+			name:      "Reject overflow",
+			mode:      CounterReject,
+			current:   math.MaxInt64,
+			delta:     1,
+			wantedErr: errs.ErrCounterOverflow,
+		},
+		{
+			name:        "Reject without overflow",
+			mode:        CounterReject,
+			current:     math.MaxInt64 - 1,
+			delta:       1,
+			wantedValue: math.MaxInt64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.CounterPolicy = tt.mode
+			defer func() { config.Config.CounterPolicy = "" }()
+
+			value, err := AddCounter(tt.current, tt.delta)
+			if tt.wantedErr != nil {
+				require.ErrorIs(t, err, tt.wantedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantedValue, value)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+// Режимы обработки NaN и ±Inf в значениях gauge.
+const (
+	GaugeStore  = "store"  // значение сохраняется как есть
+	GaugeDrop   = "drop"   // запрос считается успешным, но значение не сохраняется
+	GaugeReject = "reject" // запрос отклоняется с ошибкой
+)
+
+// Режимы округления gauge при приёме. Точность задаёт -gauge-precision.
+const (
+	RoundNone        = "none"        // значение сохраняется как есть
+	RoundSignificant = "significant" // до заданного числа значащих цифр
+	RoundDecimal     = "decimal"     // до заданного числа знаков после запятой
+)
+
+func GaugeMode() string {
+	if config.Config.GaugePolicy == "" {
+		return GaugeStore
+	}
+
+	return config.Config.GaugePolicy
+}
+
+// GaugeValue сообщает, нужно ли сохранять значение gauge.
+func GaugeValue(value float64) (bool, error) {
+	if !math.IsNaN(value) && !math.IsInf(value, 0) {
+		return true, nil
+	}
+
+	switch GaugeMode() {
+	case GaugeDrop:
+		return false, nil
+	case GaugeReject:
+		return false, errs.ErrGaugeNotFinite
+	default:
+		return true, nil
+	}
+}
+
+// RoundGauge округляет значение gauge по -gauge-rounding, чтобы шум вроде 0.30000000000000004 не менял
+// сохранённое значение. NaN и ±Inf не меняются.
+func RoundGauge(value float64) float64 {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return value
+	}
+
+	var formatted string
+	switch config.Config.GaugeRounding {
+	case RoundSignificant:
+		formatted = strconv.FormatFloat(value, 'g', config.Config.GaugePrecision, 64)
+	case RoundDecimal:
+		formatted = strconv.FormatFloat(value, 'f', config.Config.GaugePrecision, 64)
+	default:
+		return value
+	}
+
+	rounded, err := strconv.ParseFloat(formatted, 64)
+	if err != nil {
+		return value
+	}
+
+	return rounded
+}
+
+// AddGauge прибавляет delta к current и применяет к сумме те же правила, что и к записываемому значению:
+// округление и режим обработки NaN и ±Inf. Если keep равен false, сумму сохранять не нужно.
+func AddGauge(current, delta float64) (value float64, keep bool, err error) {
+	value = RoundGauge(current + delta)
+
+	keep, err = GaugeValue(value)
+	return value, keep, err
+}
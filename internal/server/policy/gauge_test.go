@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+func TestGaugeValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		value      float64
+		wantedKeep bool
+		wantedErr  error
+	}{
+		{
+			name:       "Finite value",
+			mode:       GaugeReject,
+			value:      1.5,
+			wantedKeep: true,
+		},
+		{
+			name:       "Store NaN (default mode)",
+			value:      math.NaN(),
+			wantedKeep: true,
+		},
+		{
+			name:  "Drop Inf",
+			mode:  GaugeDrop,
+			value: math.Inf(1),
+		},
+		{
+			name:      "Reject -Inf",
+			mode:      GaugeReject,
+			value:     math.Inf(-1),
+			wantedErr: errs.ErrGaugeNotFinite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.GaugePolicy = tt.mode
+			defer func() { config.Config.GaugePolicy = "" }()
+
+			keep, err := GaugeValue(tt.value)
+			assert.ErrorIs(t, err, tt.wantedErr)
+			assert.Equal(t, tt.wantedKeep, keep)
+		})
+	}
+}
+
+func TestRoundGauge(t *testing.T) {
+	tests := []struct {
+		name      string
+		rounding  string
+		precision int
+		value     float64
+		wanted    float64
+	}{
+		{
+			name:   "No rounding (default)",
+			value:  0.1 + 0.2,
+			wanted: 0.1 + 0.2,
+		},
+		{
+			name:      "Significant digits",
+			rounding:  RoundSignificant,
+			precision: 15,
+			value:     0.1 + 0.2,
+			wanted:    0.3,
+		},
+		{
+			name:      "Significant digits of a large value",
+			rounding:  RoundSignificant,
+			precision: 3,
+			value:     123456,
+			wanted:    123000,
+		},
+		{
+			name:      "Decimal places",
+			rounding:  RoundDecimal,
+			precision: 2,
+			value:     1.23456,
+			wanted:    1.23,
+		},
+		{
+			name:      "Inf is left as is",
+			rounding:  RoundDecimal,
+			precision: 2,
+			value:     math.Inf(1),
+			wanted:    math.Inf(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.GaugeRounding, config.Config.GaugePrecision = tt.rounding, tt.precision
+			defer func() { config.Config.GaugeRounding, config.Config.GaugePrecision = "", 0 }()
+
+			assert.Equal(t, tt.wanted, RoundGauge(tt.value))
+		})
+	}
+
+	t.Run("NaN is left as is", func(t *testing.T) {
+		config.Config.GaugeRounding, config.Config.GaugePrecision = RoundSignificant, 3
+		defer func() { config.Config.GaugeRounding, config.Config.GaugePrecision = "", 0 }()
+
+		assert.True(t, math.IsNaN(RoundGauge(math.NaN())))
+	})
+}
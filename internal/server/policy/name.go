@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+// MetricName приводит имя метрики к нижнему регистру, если включены регистронезависимые имена.
+func MetricName(name string) string {
+	if !config.Config.CaseInsensitiveNames {
+		return name
+	}
+
+	return strings.ToLower(name)
+}
+
+// NameMatcher компилирует шаблон поиска по именам: glob с * и ? или, если regex, регулярное выражение.
+// Glob должен совпасть с именем целиком, регулярное выражение - с любой его частью.
+func NameMatcher(pattern string, regex bool) (*regexp.Regexp, error) {
+	if !regex {
+		pattern = "^" + globToRegexp(pattern) + "$"
+	}
+
+	if config.Config.CaseInsensitiveNames {
+		pattern = "(?i)" + pattern
+	}
+
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errs.ErrInvalidPattern, err)
+	}
+
+	return matcher, nil
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return b.String()
+}
+
+// GlobToLike переводит glob в шаблон LIKE, экранируя его спецсимволы обратной косой чертой.
+func GlobToLike(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+func TestNameMatcher(t *testing.T) {
+	tests := []struct {
+		name            string
+		pattern         string
+		regex           bool
+		caseInsensitive bool
+		matches         []string
+		mismatches      []string
+	}{
+		{
+			name:       "Glob",
+			pattern:    "Heap*",
+			matches:    []string{"Heap", "HeapAlloc", "HeapIdle"},
+			mismatches: []string{"heapAlloc", "TotalHeap"},
+		},
+		{
+			name:       "Glob with special characters",
+			pattern:    "cpu.?",
+			matches:    []string{"cpu.1", "cpu.x"},
+			mismatches: []string{"cpux1", "cpu.10"},
+		},
+		{
+			name:            "Glob (case-insensitive names)",
+			pattern:         "Heap*",
+			caseInsensitive: true,
+			matches:         []string{"heapalloc"},
+		},
+		{
+			name:       "Regex",
+			pattern:    "Alloc$",
+			regex:      true,
+			matches:    []string{"Alloc", "HeapAlloc"},
+			mismatches: []string{"Allocs"},
+		},
+	}
+
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.CaseInsensitiveNames = tt.caseInsensitive
+
+			matcher, err := NameMatcher(tt.pattern, tt.regex)
+			require.NoError(t, err)
+
+			for _, name := range tt.matches {
+				assert.True(t, matcher.MatchString(name), name)
+			}
+			for _, name := range tt.mismatches {
+				assert.False(t, matcher.MatchString(name), name)
+			}
+		})
+	}
+
+	_, err := NameMatcher("(", true)
+	assert.ErrorIs(t, err, errs.ErrInvalidPattern)
+}
+
+func TestGlobToLike(t *testing.T) {
+	assert.Equal(t, `Heap%`, GlobToLike("Heap*"))
+	assert.Equal(t, `cpu\_\%_`, GlobToLike("cpu_%?"))
+	assert.Equal(t, `a\\b`, GlobToLike(`a\b`))
+}
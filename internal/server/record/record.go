@@ -0,0 +1,108 @@
+// Package record пишет принятые запросы обновления в файл и воспроизводит их на другом сервере.
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry - один записанный запрос. Файл записи состоит из Entry в формате JSON, по одной на строку.
+type Entry struct {
+	Time   time.Time         `json:"time"`
+	Method string            `json:"method"`
+	URI    string            `json:"uri"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   []byte            `json:"body,omitempty"`
+}
+
+// RecordedHeaders - заголовки, без которых запрос нельзя воспроизвести.
+var RecordedHeaders = []string{"Content-Type", "HashSHA256", "HashKeyID", "HashTimestamp", "HashNonce", "X-Agent-ID"}
+
+type (
+	Writer struct {
+		mx   sync.Mutex
+		file *os.File
+		enc  *json.Encoder
+	}
+
+	Reader struct {
+		scanner *bufio.Scanner
+		line    int
+	}
+
+	// LineError - ошибка разбора строки файла записи.
+	LineError struct {
+		Line int
+		Err  error
+	}
+)
+
+// Open открывает файл записи на дозапись, чтобы перезапуск сервера не затирал уже записанный трафик.
+func Open(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (w *Writer) Write(entry Entry) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return w.enc.Encode(entry)
+}
+
+func (w *Writer) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	return errors.Join(w.file.Sync(), w.file.Close())
+}
+
+func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	return &Reader{scanner: scanner}
+}
+
+// Next возвращает следующую запись или io.EOF, если записи кончились.
+func (r *Reader) Next() (Entry, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return Entry{}, &LineError{Line: r.line, Err: err}
+		}
+
+		return entry, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{}, io.EOF
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
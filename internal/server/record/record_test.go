@@ -0,0 +1,102 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+func TestReaderLineError(t *testing.T) {
+	reader := NewReader(strings.NewReader("{\"method\":\"POST\",\"uri\":\"/updates/\"}\n\nnot json\n"))
+
+	entry, err := reader.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "/updates/", entry.URI)
+
+	_, err = reader.Next()
+	var lineErr *LineError
+	require.ErrorAs(t, err, &lineErr)
+	assert.Equal(t, 3, lineErr.Line)
+}
+
+func TestReplay(t *testing.T) {
+	var (
+		mx       sync.Mutex
+		received []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mx.Lock()
+		received = append(received, r.URL.RequestURI()+" "+string(body)+" "+r.Header.Get("HashSHA256"))
+		mx.Unlock()
+
+		if r.Header.Get(requestid.Header) == "" || strings.HasSuffix(r.URL.Path, "/bad") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	writer, err := Open(path)
+	require.NoError(t, err)
+
+	start := time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: start, Method: http.MethodPost, URI: "/updates/", Header: map[string]string{"HashSHA256": "abc"}, Body: []byte(`[]`)},
+		{Time: start.Add(10 * time.Second), Method: http.MethodPost, URI: "/update/gauge/Alloc/1"},
+		{Time: start.Add(30 * time.Second), Method: http.MethodPost, URI: "/update/gauge/Alloc/bad"},
+	}
+	for _, entry := range entries {
+		require.NoError(t, writer.Write(entry))
+	}
+	require.NoError(t, writer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	file := bytes.NewReader(data)
+
+	clk := clock.NewFake(time.Unix(0, 0))
+
+	type result struct {
+		stats ReplayStats
+		err   error
+	}
+	done := make(chan result)
+	go func() {
+		stats, err := Replay(context.Background(), NewReader(file), ReplayOptions{
+			Target: strings.TrimPrefix(server.URL, "http://"),
+			Speed:  2,
+			Clock:  clk,
+		}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+		done <- result{stats, err}
+	}()
+
+	// При скорости 2 паузы между запросами вдвое короче записанных
+	clk.BlockUntil(1)
+	clk.Advance(5 * time.Second)
+	clk.BlockUntil(1)
+	clk.Advance(10 * time.Second)
+
+	res := <-done
+	require.NoError(t, res.err)
+	assert.Equal(t, ReplayStats{Sent: 3, Failed: 1}, res.stats)
+	assert.Equal(t, time.Unix(15, 0), clk.Now())
+	assert.Equal(t, []string{"/updates/ [] abc", "/update/gauge/Alloc/1  ", "/update/gauge/Alloc/bad  "}, received)
+}
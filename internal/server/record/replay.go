@@ -0,0 +1,108 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/requestid"
+)
+
+type (
+	ReplayOptions struct {
+		// Target - адрес сервера вида host:port или URL.
+		Target string
+		// Speed ускоряет воспроизведение: 1 - исходная скорость, 2 - вдвое быстрее, 0 - без пауз.
+		Speed float64
+
+		Client *http.Client
+		Clock  clock.Clock
+	}
+
+	ReplayStats struct {
+		Sent   int
+		Failed int
+	}
+)
+
+// Replay отправляет записанные запросы на Target, сохраняя интервалы между ними с учётом Speed.
+// Ошибки отдельных запросов не прерывают воспроизведение, а учитываются в ReplayStats.Failed.
+func Replay(ctx context.Context, r *Reader, opts ReplayOptions, log logger.Logger) (ReplayStats, error) {
+	if opts.Speed < 0 {
+		return ReplayStats{}, fmt.Errorf("invalid replay speed %v: must not be negative", opts.Speed)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	clk := clock.Or(opts.Clock)
+
+	target := strings.TrimSuffix(opts.Target, "/")
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	var (
+		stats          ReplayStats
+		first, started time.Time
+	)
+
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return stats, nil
+		} else if err != nil {
+			return stats, err
+		}
+
+		if first.IsZero() {
+			first, started = entry.Time, clk.Now()
+		} else if opts.Speed > 0 {
+			due := time.Duration(float64(entry.Time.Sub(first)) / opts.Speed)
+			if wait := due - clk.Since(started); wait > 0 {
+				if err = clock.Sleep(ctx, clk, wait); err != nil {
+					return stats, err
+				}
+			}
+		}
+
+		stats.Sent++
+		if err = send(ctx, client, target, entry); err != nil {
+			stats.Failed++
+			log.Errorf("Failed to replay %s %s recorded at %s: %s", entry.Method, entry.URI, entry.Time.Format(time.RFC3339Nano), err)
+		}
+	}
+}
+
+func send(ctx context.Context, client *http.Client, target string, entry Entry) error {
+	req, err := http.NewRequestWithContext(ctx, entry.Method, target+entry.URI, bytes.NewReader(entry.Body))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range entry.Header {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set(requestid.Header, requestid.New())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -3,25 +3,45 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/access"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/stale"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 type Router struct {
 	*gin.Engine
 
-	storage models.Storage
-	log     logger.Logger
+	storage  models.Storage
+	log      logger.Logger
+	recorder *record.Writer
+	stale    *stale.Monitor
+	keys     *auth.Keys
+	access   *access.Guard
+	lockout  *lockout.Tracker
+	admin    *gin.Engine
+
+	stop chan bool
 }
 
 func New(storage models.Storage, log logger.Logger) *Router {
 	gin.SetMode(gin.ReleaseMode)
 
+	// gin по умолчанию доверяет заголовкам X-Forwarded-For от кого угодно. Пока доверенные прокси
+	// не заданы, адрес клиента берётся из соединения.
+	engine := gin.New()
+	_ = engine.SetTrustedProxies(nil)
+
 	return &Router{
-		Engine: gin.New(),
+		Engine: engine,
 
 		storage: storage,
 		log:     log,
+
+		stop: make(chan bool, 1),
 	}
 }
 
@@ -32,3 +52,70 @@ func (r *Router) GetStorage() models.Storage {
 func (r *Router) GetLogger() logger.Logger {
 	return r.log
 }
+
+// SetRecorder включает запись принятых запросов обновления. Вызывается до настройки middleware.
+func (r *Router) SetRecorder(recorder *record.Writer) {
+	r.recorder = recorder
+}
+
+func (r *Router) GetRecorder() *record.Writer {
+	return r.recorder
+}
+
+// SetStaleMonitor включает поиск устаревших метрик. Вызывается до настройки обработчиков.
+func (r *Router) SetStaleMonitor(monitor *stale.Monitor) {
+	r.stale = monitor
+}
+
+func (r *Router) GetStaleMonitor() *stale.Monitor {
+	return r.stale
+}
+
+// SetAPIKeys включает проверку ключей API. Вызывается до настройки обработчиков.
+func (r *Router) SetAPIKeys(keys *auth.Keys) {
+	r.keys = keys
+}
+
+func (r *Router) GetAPIKeys() *auth.Keys {
+	return r.keys
+}
+
+// SetAccessGuard включает проверку адресов клиентов. Вызывается до настройки обработчиков.
+func (r *Router) SetAccessGuard(guard *access.Guard) {
+	r.access = guard
+}
+
+func (r *Router) GetAccessGuard() *access.Guard {
+	return r.access
+}
+
+// SetLockout включает аудит неудачной аутентификации и блокировку подбирающих ключи адресов.
+// Вызывается до настройки middleware и обработчиков.
+func (r *Router) SetLockout(tracker *lockout.Tracker) {
+	r.lockout = tracker
+}
+
+func (r *Router) GetLockout() *lockout.Tracker {
+	return r.lockout
+}
+
+// SetAdmin переносит служебные маршруты на отдельный роутер, который слушает свой адрес.
+// Вызывается до настройки обработчиков.
+func (r *Router) SetAdmin(admin *gin.Engine) {
+	r.admin = admin
+}
+
+func (r *Router) GetAdmin() *gin.Engine {
+	return r.admin
+}
+
+func (r *Router) Stop(restart bool) {
+	select {
+	case r.stop <- restart:
+	default:
+	}
+}
+
+func (r *Router) Stopped() <-chan bool {
+	return r.stop
+}
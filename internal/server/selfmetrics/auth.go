@@ -0,0 +1,17 @@
+package selfmetrics
+
+var (
+	AuthFailures = Default.NewCounter(
+		"metrics_auth_failures_total",
+		"Rejected signatures, API keys and admin keys by reason.",
+		"reason",
+	)
+	AuthBlocks = Default.NewCounter(
+		"metrics_auth_blocks_total",
+		"Source addresses temporarily blocked after repeated authentication failures.",
+	)
+	AuthBlockedSources = Default.NewGauge(
+		"metrics_auth_blocked_sources",
+		"Source addresses that are blocked right now.",
+	)
+)
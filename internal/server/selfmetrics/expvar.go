@@ -0,0 +1,92 @@
+package selfmetrics
+
+import (
+	"expvar"
+	"runtime"
+	"strings"
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("selfmetrics", expvar.Func(func() any {
+		return Default.Snapshot()
+	}))
+}
+
+type snapshotter interface {
+	snapshot() (string, map[string]any)
+}
+
+func (r *Registry) Snapshot() map[string]any {
+	r.mx.Lock()
+	families := append([]family(nil), r.families...)
+	r.mx.Unlock()
+
+	result := make(map[string]any, len(families))
+	for _, f := range families {
+		if s, ok := f.(snapshotter); ok {
+			name, values := s.snapshot()
+			result[name] = values
+		}
+	}
+
+	return result
+}
+
+func (c *Counter) snapshot() (string, map[string]any) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	values := make(map[string]any, len(c.series))
+	for _, s := range c.series {
+		values[c.labelsKey(s.labels)] = s.value
+	}
+
+	return c.name, values
+}
+
+func (g *Gauge) snapshot() (string, map[string]any) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	values := make(map[string]any, len(g.series))
+	for _, s := range g.series {
+		values[g.labelsKey(s.labels)] = s.value
+	}
+
+	return g.name, values
+}
+
+func (h *Histogram) snapshot() (string, map[string]any) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	values := make(map[string]any, len(h.series))
+	for _, s := range h.series {
+		values[h.labelsKey(s.labels)] = map[string]any{
+			"count": s.count,
+			"sum":   s.sum,
+		}
+	}
+
+	return h.name, values
+}
+
+func (m meta) labelsKey(values []string) string {
+	if len(m.labels) == 0 {
+		return "value"
+	}
+
+	pairs := make([]string, 0, len(m.labels))
+	for i, name := range m.labels {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,7 @@
+package selfmetrics
+
+var HTTPPanics = Default.NewCounter(
+	"metrics_http_panics_total",
+	"Panics recovered while handling HTTP requests.",
+	"route",
+)
@@ -0,0 +1,103 @@
+package selfmetrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrInvalidQuantile = errors.New("quantile must be between 0 and 1")
+	ErrUnknownLabel    = errors.New("unknown label")
+)
+
+// Histogram возвращает гистограмму реестра по имени.
+func (r *Registry) Histogram(name string) (*Histogram, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, f := range r.families {
+		if h, ok := f.(*Histogram); ok && h.name == name {
+			return h, true
+		}
+	}
+
+	return nil, false
+}
+
+// Quantile считает квантиль q по корзинам всех рядов, у которых метки совпадают с filter,
+// и возвращает его вместе с числом наблюдений. Без наблюдений квантиль равен NaN.
+func (h *Histogram) Quantile(q float64, filter map[string]string) (float64, uint64, error) {
+	if math.IsNaN(q) || q < 0 || q > 1 {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidQuantile, q)
+	}
+
+	positions := make(map[int]string, len(filter))
+	for name, value := range filter {
+		i := h.labelIndex(name)
+		if i < 0 {
+			return 0, 0, fmt.Errorf("%w %q of %s", ErrUnknownLabel, name, h.name)
+		}
+		positions[i] = value
+	}
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	counts := make([]uint64, len(h.buckets))
+	var total uint64
+
+series:
+	for _, s := range h.series {
+		for i, value := range positions {
+			if i >= len(s.labels) || s.labels[i] != value {
+				continue series
+			}
+		}
+
+		for i, count := range s.counts {
+			counts[i] += count
+		}
+		total += s.count
+	}
+
+	return BucketQuantile(q, h.buckets, counts, total), total, nil
+}
+
+func (h *Histogram) labelIndex(name string) int {
+	for i, label := range h.labels {
+		if label == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// BucketQuantile считает квантиль по накопленным счётчикам корзин так же, как histogram_quantile в Prometheus:
+// внутри корзины значения считаются распределёнными равномерно, а квантиль из корзины +Inf равен
+// верхней границе последней конечной корзины. Без наблюдений возвращает NaN.
+func BucketQuantile(q float64, bounds []float64, counts []uint64, total uint64) float64 {
+	if total == 0 || len(bounds) == 0 {
+		return math.NaN()
+	}
+
+	rank := q * float64(total)
+
+	var prevBound float64
+	var prevCount uint64
+	for i, bound := range bounds {
+		if float64(counts[i]) >= rank && counts[i] > prevCount {
+			lower := prevBound
+			if i == 0 && bound <= 0 {
+				return bound
+			}
+
+			return lower + (bound-lower)*(rank-float64(prevCount))/float64(counts[i]-prevCount)
+		}
+
+		prevBound, prevCount = bound, counts[i]
+	}
+
+	return bounds[len(bounds)-1]
+}
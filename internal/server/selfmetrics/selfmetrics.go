@@ -0,0 +1,335 @@
+package selfmetrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type (
+	Registry struct {
+		mx       sync.Mutex
+		families []family
+	}
+
+	family interface {
+		write(w io.Writer) error
+	}
+
+	meta struct {
+		name   string
+		help   string
+		kind   string
+		labels []string
+	}
+
+	Counter struct {
+		meta
+
+		mx     sync.Mutex
+		series map[string]*counterSeries
+	}
+
+	counterSeries struct {
+		labels []string
+		value  float64
+	}
+
+	Gauge struct {
+		meta
+
+		mx     sync.Mutex
+		series map[string]*counterSeries
+	}
+
+	Histogram struct {
+		meta
+		buckets []float64
+
+		mx     sync.Mutex
+		series map[string]*histogramSeries
+	}
+
+	histogramSeries struct {
+		labels []string
+		counts []uint64
+		count  uint64
+		sum    float64
+	}
+)
+
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		meta:   meta{name: name, help: help, kind: "counter", labels: labels},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(c)
+
+	return c
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{
+		meta:   meta{name: name, help: help, kind: "gauge", labels: labels},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(g)
+
+	return g
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		meta:    meta{name: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+
+	return h
+}
+
+func (r *Registry) register(f family) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.families = append(r.families, f)
+}
+
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mx.Lock()
+	families := append([]family(nil), r.families...)
+	r.mx.Unlock()
+
+	for _, f := range families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *Counter) Add(value float64, labels ...string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.get(labels).value += value
+}
+
+func (c *Counter) Value(labels ...string) float64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.get(labels).value
+}
+
+func (c *Counter) get(labels []string) *counterSeries {
+	key := seriesKey(labels)
+
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labels: append([]string(nil), labels...)}
+		c.series[key] = s
+	}
+
+	return s
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return writeScalars(w, c.meta, c.series)
+}
+
+func (g *Gauge) Set(value float64, labels ...string) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.get(labels).value = value
+}
+
+func (g *Gauge) Add(value float64, labels ...string) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	g.get(labels).value += value
+}
+
+func (g *Gauge) Value(labels ...string) float64 {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return g.get(labels).value
+}
+
+func (g *Gauge) get(labels []string) *counterSeries {
+	key := seriesKey(labels)
+
+	s, ok := g.series[key]
+	if !ok {
+		s = &counterSeries{labels: append([]string(nil), labels...)}
+		g.series[key] = s
+	}
+
+	return s
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	return writeScalars(w, g.meta, g.series)
+}
+
+func (h *Histogram) Observe(value float64, labels ...string) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	key := seriesKey(labels)
+
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labels: append([]string(nil), labels...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *Histogram) Count(labels ...string) uint64 {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if s, ok := h.series[seriesKey(labels)]; ok {
+		return s.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		for i, bound := range h.buckets {
+			labels := h.formatLabels(s.labels, "le", formatFloat(bound))
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, labels, s.counts[i]); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(s.labels, "le", "+Inf"), s.count); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(s.labels), formatFloat(s.sum)); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(s.labels), s.count); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeScalars(w io.Writer, m meta, series map[string]*counterSeries) error {
+	if err := m.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, key := range sortedKeys(series) {
+		s := series[key]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", m.name, m.formatLabels(s.labels), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m meta) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+	return err
+}
+
+// labelEscaper экранирует значение метки по текстовому формату Prometheus: только обратную косую черту, кавычку и перевод строки.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (m meta) formatLabels(values []string, extra ...string) string {
+	var pairs []string
+	for i, name := range m.labels {
+		var value string
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(value)))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[i], labelEscaper.Replace(extra[i+1])))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func seriesKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
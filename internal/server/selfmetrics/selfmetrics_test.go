@@ -0,0 +1,110 @@
+package selfmetrics
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+
+	counter := r.NewCounter("test_errors_total", "Test errors.", "method", "class")
+	counter.Inc("GetGauge", "timeout")
+	counter.Add(2, "GetGauge", "timeout")
+	counter.Inc("AddCounter", "other")
+
+	gauge := r.NewGauge("test_queue_depth", "Test queue depth.")
+	gauge.Set(5)
+	gauge.Add(-2)
+
+	histogram := r.NewHistogram("test_duration_seconds", "Test duration.", []float64{0.1, 1}, "method")
+	histogram.Observe(0.05, "Ping")
+	histogram.Observe(0.5, "Ping")
+	histogram.Observe(3, "Ping")
+
+	assert.Equal(t, float64(3), counter.Value("GetGauge", "timeout"))
+	assert.Equal(t, float64(3), gauge.Value())
+	assert.Equal(t, uint64(3), histogram.Count("Ping"))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, r.WritePrometheus(buf))
+
+	wanted := `# HELP test_errors_total Test errors.
+# TYPE test_errors_total counter
+test_errors_total{method="AddCounter",class="other"} 1
+test_errors_total{method="GetGauge",class="timeout"} 3
+# HELP test_queue_depth Test queue depth.
+# TYPE test_queue_depth gauge
+test_queue_depth 3
+# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="Ping",le="0.1"} 1
+test_duration_seconds_bucket{method="Ping",le="1"} 2
+test_duration_seconds_bucket{method="Ping",le="+Inf"} 3
+test_duration_seconds_sum{method="Ping"} 3.55
+test_duration_seconds_count{method="Ping"} 3
+`
+	assert.Equal(t, wanted, buf.String())
+}
+
+func TestRegistryWritePrometheusEscapesLabels(t *testing.T) {
+	r := NewRegistry()
+
+	counter := r.NewCounter("test_requests_total", "Test requests.", "path")
+	counter.Inc("/метрики\\\"x\"\n")
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, r.WritePrometheus(buf))
+
+	assert.Contains(t, buf.String(), `test_requests_total{path="/метрики\\\"x\"\n"} 1`)
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	r := NewRegistry()
+
+	histogram := r.NewHistogram("test_duration_seconds", "Test duration.", []float64{0.1, 1, 10}, "method")
+	for i := 0; i < 50; i++ {
+		histogram.Observe(0.05, "Ping")
+	}
+	for i := 0; i < 50; i++ {
+		histogram.Observe(0.5, "GetAll")
+	}
+
+	found, ok := r.Histogram("test_duration_seconds")
+	require.True(t, ok)
+
+	_, ok = r.Histogram("missing")
+	assert.False(t, ok)
+
+	value, count, err := found.Quantile(0.5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), count)
+	assert.InDelta(t, 0.1, value, 1e-9)
+
+	value, _, err = found.Quantile(0.75, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.55, value, 1e-9)
+
+	value, count, err = found.Quantile(0.5, map[string]string{"method": "Ping"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(50), count)
+	assert.InDelta(t, 0.05, value, 1e-9)
+
+	value, count, err = found.Quantile(0.5, map[string]string{"method": "Missing"})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	assert.True(t, math.IsNaN(value))
+
+	_, _, err = found.Quantile(1.5, nil)
+	assert.ErrorIs(t, err, ErrInvalidQuantile)
+
+	_, _, err = found.Quantile(0.5, map[string]string{"backend": "mem"})
+	assert.ErrorIs(t, err, ErrUnknownLabel)
+
+	// Наблюдения выше последней границы дают её значение
+	assert.Equal(t, 10.0, BucketQuantile(0.99, []float64{0.1, 1, 10}, []uint64{0, 0, 1}, 10))
+}
@@ -0,0 +1,35 @@
+package selfmetrics
+
+var (
+	StorageQueryDuration = Default.NewHistogram(
+		"metrics_storage_query_duration_seconds",
+		"Latency of storage operations.",
+		DefaultBuckets,
+		"backend", "method",
+	)
+	StorageErrors = Default.NewCounter(
+		"metrics_storage_errors_total",
+		"Failed storage operations by error class.",
+		"backend", "method", "class",
+	)
+	StorageRetries = Default.NewCounter(
+		"metrics_storage_retries_total",
+		"Retries of storage operations.",
+		"backend", "method",
+	)
+	StorageSlowQueries = Default.NewCounter(
+		"metrics_storage_slow_queries_total",
+		"Database queries slower than the -db-slow-query threshold.",
+		"method",
+	)
+	StorageBreakerState = Default.NewGauge(
+		"metrics_storage_breaker_state",
+		"State of the storage circuit breaker: 0 closed, 1 half-open, 2 open.",
+		"backend",
+	)
+	StorageBreakerRejections = Default.NewCounter(
+		"metrics_storage_breaker_rejections_total",
+		"Storage operations rejected by an open circuit breaker.",
+		"backend", "method",
+	)
+)
@@ -0,0 +1,111 @@
+package signing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Заголовки защиты от повтора: время отправки в секундах Unix и случайная строка запроса.
+// Если они есть, подписывается Material, а не одно тело.
+const (
+	TimestampHeader = "HashTimestamp"
+	NonceHeader     = "HashNonce"
+)
+
+const maxNonceLength = 128
+
+var (
+	ErrInvalidTimestamp = errors.New("invalid signature timestamp")
+	ErrStaleTimestamp   = errors.New("signature timestamp is outside the allowed clock skew")
+	ErrInvalidNonce     = errors.New("invalid signature nonce")
+	ErrReplayedNonce    = errors.New("signature nonce was already used")
+	ErrNonceCacheFull   = errors.New("too many signature nonces in the replay window")
+)
+
+type (
+	// Nonces помнит nonce подписанных запросов за окно, в котором принимается их время.
+	// Кеш ограничен по размеру: при переполнении новые nonce отклоняются, пока старые не выйдут из окна.
+	// Забыть nonce раньше нельзя: запрос с ним можно было бы повторить.
+	Nonces struct {
+		window time.Duration
+		size   int
+
+		mx    sync.Mutex
+		seen  map[string]time.Time
+		order []seenNonce
+	}
+
+	seenNonce struct {
+		nonce string
+		at    time.Time
+	}
+)
+
+// Material возвращает подписываемые данные: время, nonce и тело через перевод строки.
+func Material(timestamp, nonce string, body []byte) []byte {
+	material := make([]byte, 0, len(timestamp)+len(nonce)+len(body)+2)
+	material = append(material, timestamp...)
+	material = append(material, '\n')
+	material = append(material, nonce...)
+	material = append(material, '\n')
+
+	return append(material, body...)
+}
+
+func NewNonces(window time.Duration, size int) *Nonces {
+	return &Nonces{window: window, size: size, seen: make(map[string]time.Time)}
+}
+
+// Check принимает запрос, если его время отличается от now не больше чем на окно и nonce ещё не встречался.
+// Вызывать нужно только после проверки подписи, иначе кеш можно забить чужими nonce.
+func (n *Nonces) Check(timestamp, nonce string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidTimestamp, timestamp)
+	}
+
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return ErrInvalidNonce
+	}
+
+	sentAt := time.Unix(seconds, 0)
+	if skew := now.Sub(sentAt); skew > n.window || skew < -n.window {
+		return fmt.Errorf("%w: %s", ErrStaleTimestamp, skew.Round(time.Second))
+	}
+
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	n.expire(now)
+
+	if _, ok := n.seen[nonce]; ok {
+		return ErrReplayedNonce
+	}
+
+	if n.size > 0 && len(n.order) >= n.size {
+		return ErrNonceCacheFull
+	}
+
+	n.seen[nonce] = now
+	n.order = append(n.order, seenNonce{nonce: nonce, at: now})
+
+	return nil
+}
+
+// expire забывает nonce старше двух окон: запрос с таким nonce уже не пройдёт проверку времени.
+func (n *Nonces) expire(now time.Time) {
+	for len(n.order) > 0 && now.Sub(n.order[0].at) > 2*n.window {
+		delete(n.seen, n.order[0].nonce)
+		n.order = n.order[1:]
+	}
+}
+
+func (n *Nonces) Len() int {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	return len(n.seen)
+}
@@ -0,0 +1,58 @@
+package signing
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoncesCheck(t *testing.T) {
+	now := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	at := func(offset time.Duration) string {
+		return strconv.FormatInt(now.Add(offset).Unix(), 10)
+	}
+
+	nonces := NewNonces(time.Minute, 0)
+
+	require.NoError(t, nonces.Check(at(0), "first", now))
+	require.ErrorIs(t, nonces.Check(at(0), "first", now), ErrReplayedNonce)
+	require.NoError(t, nonces.Check(at(-30*time.Second), "second", now))
+	require.NoError(t, nonces.Check(at(30*time.Second), "third", now))
+
+	require.ErrorIs(t, nonces.Check(at(-2*time.Minute), "old", now), ErrStaleTimestamp)
+	require.ErrorIs(t, nonces.Check(at(2*time.Minute), "future", now), ErrStaleTimestamp)
+	require.ErrorIs(t, nonces.Check("yesterday", "bad", now), ErrInvalidTimestamp)
+	require.ErrorIs(t, nonces.Check(at(0), "", now), ErrInvalidNonce)
+
+	// Через два окна nonce забываются: запрос с ними всё равно не пройдёт проверку времени
+	later := now.Add(3 * time.Minute)
+	require.NoError(t, nonces.Check(strconv.FormatInt(later.Unix(), 10), "later", later))
+	assert.Equal(t, 1, nonces.Len())
+}
+
+func TestNoncesBounded(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	nonces := NewNonces(time.Minute, 2)
+	for _, nonce := range []string{"a", "b"} {
+		require.NoError(t, nonces.Check(timestamp, nonce, now))
+	}
+
+	// Живые nonce не вытесняются: иначе запрос можно повторить, забив кеш
+	require.ErrorIs(t, nonces.Check(timestamp, "c", now), ErrNonceCacheFull)
+	require.ErrorIs(t, nonces.Check(timestamp, "a", now), ErrReplayedNonce)
+	assert.Equal(t, 2, nonces.Len())
+
+	// Когда старые nonce выходят из окна, место освобождается
+	later := now.Add(3 * time.Minute)
+	require.NoError(t, nonces.Check(strconv.FormatInt(later.Unix(), 10), "c", later))
+	assert.Equal(t, 1, nonces.Len())
+}
+
+func TestMaterial(t *testing.T) {
+	assert.Equal(t, "1700000000\nabc\n{}", string(Material("1700000000", "abc", []byte("{}"))))
+}
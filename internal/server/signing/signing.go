@@ -0,0 +1,78 @@
+// Package signing учитывает, какими ключами подписаны запросы, чтобы было видно, когда старый ключ можно убрать.
+package signing
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+// Заголовки подписи: HashSHA256 - HMAC-SHA256 тела, HashKeyID - каким ключом он посчитан.
+const (
+	HashHeader  = "HashSHA256"
+	KeyIDHeader = "HashKeyID"
+)
+
+// ErrBodyRejected возвращает тело потокового обновления вместо io.EOF, если подпись не прошла проверку.
+var ErrBodyRejected = errors.New("request body failed the signature check")
+
+type usage struct {
+	requests int64
+	lastUsed time.Time
+}
+
+var (
+	mx   sync.Mutex
+	used = make(map[string]*usage)
+
+	signedRequests = selfmetrics.Default.NewCounter(
+		"metrics_signed_requests_total",
+		"Requests with a valid signature by signing key.",
+		"key_id",
+	)
+)
+
+// Record отмечает запрос с верной подписью ключом id.
+func Record(id string, at time.Time) {
+	mx.Lock()
+	defer mx.Unlock()
+
+	u, ok := used[id]
+	if !ok {
+		u = &usage{}
+		used[id] = u
+	}
+
+	u.requests++
+	u.lastUsed = at
+	signedRequests.Inc(id)
+}
+
+// Usage возвращает использование каждого настроенного ключа с момента запуска сервера, секреты не раскрываются.
+func Usage() []models.SigningKeyUsage {
+	mx.Lock()
+	defer mx.Unlock()
+
+	keys := config.SigningKeys()
+
+	result := make([]models.SigningKeyUsage, 0, len(keys))
+	for id := range keys {
+		item := models.SigningKeyUsage{ID: id}
+		if u, ok := used[id]; ok {
+			lastUsed := u.lastUsed.UTC()
+			item.Requests, item.LastUsed = u.requests, &lastUsed
+		}
+		result = append(result, item)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
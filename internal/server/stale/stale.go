@@ -0,0 +1,263 @@
+// Package stale находит метрики, которые перестали обновляться: обычный интервал обновления
+// каждой метрики выучивается по времени её обновлений в хранилище.
+package stale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// WebhookEvent - событие в теле запроса на вебхук.
+const WebhookEvent = "metrics.stale"
+
+// intervalWeight - вес нового интервала в скользящем среднем.
+const intervalWeight = 0.3
+
+type (
+	Options struct {
+		// CheckInterval - как часто проверяются метрики.
+		CheckInterval time.Duration
+		// Factor - во сколько обычных интервалов метрика может не обновляться, прежде чем станет устаревшей.
+		Factor float64
+		// WebhookURL получает POST с метриками, которые стали устаревшими. Пустой - без уведомлений.
+		WebhookURL string
+
+		Client *http.Client
+		Clock  clock.Clock
+	}
+
+	Monitor struct {
+		store models.Storage
+		opts  Options
+		log   logger.Logger
+
+		mx      sync.Mutex
+		metrics map[metricKey]*metricState
+	}
+
+	// WebhookPayload - тело запроса на вебхук.
+	WebhookPayload struct {
+		Event   string               `json:"event"`
+		Metrics []models.StaleMetric `json:"metrics"`
+	}
+
+	metricKey struct {
+		name  string
+		mType string
+	}
+
+	metricState struct {
+		updatedAt time.Time
+		interval  time.Duration
+		stale     bool
+
+		// value нужен хранилищам без времени обновления: тогда обновлением считается смена значения.
+		value string
+	}
+)
+
+func New(store models.Storage, opts Options, log logger.Logger) *Monitor {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	opts.Clock = clock.Or(opts.Clock)
+
+	return &Monitor{
+		store:   store,
+		opts:    opts,
+		log:     log,
+		metrics: make(map[metricKey]*metricState),
+	}
+}
+
+// Run проверяет метрики каждые CheckInterval, пока не отменён ctx.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := m.opts.Clock.NewTicker(m.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := m.Check(ctx); err != nil {
+				m.log.Errorf("Failed to check metrics for staleness: %s", err)
+			}
+		}
+	}
+}
+
+// Check обновляет выученные интервалы и отправляет на вебхук метрики, которые только что стали устаревшими.
+func (m *Monitor) Check(ctx context.Context) error {
+	metrics, err := m.store.GetAll()
+	if err != nil {
+		return err
+	}
+
+	became := m.observe(metrics, m.opts.Clock.Now())
+	if len(became) == 0 {
+		return nil
+	}
+
+	m.log.Infof("%d metric(s) became stale, the first is %s (%s).", len(became), became[0].ID, became[0].MType)
+
+	if m.opts.WebhookURL == "" {
+		return nil
+	}
+
+	return m.notify(ctx, became)
+}
+
+// Stale возвращает метрики, которые были устаревшими при последней проверке.
+func (m *Monitor) Stale() []models.StaleMetric {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := m.opts.Clock.Now()
+
+	result := make([]models.StaleMetric, 0)
+	for key, state := range m.metrics {
+		if state.stale {
+			result = append(result, staleMetric(key, state, now))
+		}
+	}
+	sortMetrics(result)
+
+	return result
+}
+
+func (m *Monitor) observe(metrics []models.MetricsValue, now time.Time) []models.StaleMetric {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	seen := make(map[metricKey]struct{}, len(metrics))
+	var became []models.StaleMetric
+
+	for _, metric := range metrics {
+		key := metricKey{name: metric.ID, mType: metric.MType}
+		seen[key] = struct{}{}
+
+		value := metricValue(metric)
+		updatedAt := now
+		if metric.UpdatedAt != nil {
+			updatedAt = *metric.UpdatedAt
+		}
+
+		state, ok := m.metrics[key]
+		if !ok {
+			m.metrics[key] = &metricState{updatedAt: updatedAt, value: value}
+			continue
+		}
+
+		updated := updatedAt.After(state.updatedAt)
+		if metric.UpdatedAt == nil {
+			updated = value != state.value
+		}
+
+		if updated {
+			state.learn(updatedAt.Sub(state.updatedAt))
+			state.updatedAt, state.value, state.stale = updatedAt, value, false
+			continue
+		}
+
+		// Пока интервал не выучен, судить о метрике не по чему
+		if state.stale || state.interval <= 0 {
+			continue
+		}
+
+		if now.Sub(state.updatedAt) > time.Duration(m.opts.Factor*float64(state.interval)) {
+			state.stale = true
+			became = append(became, staleMetric(key, state, now))
+		}
+	}
+
+	for key := range m.metrics {
+		if _, ok := seen[key]; !ok {
+			delete(m.metrics, key)
+		}
+	}
+	sortMetrics(became)
+
+	return became
+}
+
+func (m *Monitor) notify(ctx context.Context, metrics []models.StaleMetric) error {
+	body, err := json.Marshal(WebhookPayload{Event: WebhookEvent, Metrics: metrics})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.opts.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stale webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("stale webhook: unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// learn добавляет интервал между обновлениями в скользящее среднее.
+func (s *metricState) learn(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if s.interval <= 0 {
+		s.interval = interval
+		return
+	}
+
+	s.interval = time.Duration(intervalWeight*float64(interval) + (1-intervalWeight)*float64(s.interval))
+}
+
+func staleMetric(key metricKey, state *metricState, now time.Time) models.StaleMetric {
+	return models.StaleMetric{
+		ID:        key.name,
+		MType:     key.mType,
+		UpdatedAt: state.updatedAt.UTC(),
+		Interval:  state.interval.Seconds(),
+		StaleFor:  now.Sub(state.updatedAt).Seconds(),
+	}
+}
+
+func metricValue(metric models.MetricsValue) string {
+	switch {
+	case metric.Delta != nil:
+		return fmt.Sprint(*metric.Delta)
+	case metric.Value != nil:
+		return fmt.Sprint(*metric.Value)
+	default:
+		return ""
+	}
+}
+
+func sortMetrics(metrics []models.StaleMetric) {
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].MType != metrics[j].MType {
+			return metrics[i].MType < metrics[j].MType
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+}
@@ -0,0 +1,82 @@
+package stale
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestMonitor(t *testing.T) {
+	payloads := make(chan WebhookPayload, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads <- payload
+	}))
+	defer webhook.Close()
+
+	start := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+
+	store := memstorage.NewMem()
+	monitor := New(store, Options{CheckInterval: time.Second, Factor: 3, WebhookURL: webhook.URL, Clock: clk}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	update := func(name string) {
+		value := 1.0
+		require.NoError(t, store.SetGauge(name, &value))
+		store.SetUpdatedAt(string(models.GaugeType), name, clk.Now())
+	}
+
+	// Обе метрики обновляются раз в 10 секунд
+	for i := 0; i < 3; i++ {
+		update("Alloc")
+		update("Frees")
+		require.NoError(t, monitor.Check(context.Background()))
+		clk.Advance(10 * time.Second)
+	}
+	assert.Empty(t, monitor.Stale())
+
+	// Frees перестаёт обновляться, устаревшей она становится после трёх интервалов
+	for i := 0; i < 3; i++ {
+		update("Alloc")
+		require.NoError(t, monitor.Check(context.Background()))
+		clk.Advance(10 * time.Second)
+	}
+	assert.Empty(t, monitor.Stale())
+
+	update("Alloc")
+	require.NoError(t, monitor.Check(context.Background()))
+
+	stale := monitor.Stale()
+	require.Len(t, stale, 1)
+	assert.Equal(t, "Frees", stale[0].ID)
+	assert.Equal(t, string(models.GaugeType), stale[0].MType)
+	assert.Equal(t, 10.0, stale[0].Interval)
+	assert.Equal(t, 40.0, stale[0].StaleFor)
+
+	payload := <-payloads
+	assert.Equal(t, WebhookEvent, payload.Event)
+	assert.Equal(t, stale, payload.Metrics)
+
+	// Повторная проверка не шлёт уведомление ещё раз, а обновление снимает флаг
+	clk.Advance(10 * time.Second)
+	update("Alloc")
+	require.NoError(t, monitor.Check(context.Background()))
+	assert.Len(t, payloads, 0)
+
+	update("Frees")
+	require.NoError(t, monitor.Check(context.Background()))
+	assert.Empty(t, monitor.Stale())
+}
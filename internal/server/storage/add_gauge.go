@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+type gaugeAdder interface {
+	AddGauge(name string, delta *float64) error
+}
+
+// AddGauge прибавляет delta к gauge по правилам policy.AddGauge. Если хранилище не умеет делать это атомарно, значение читается и
+// записывается заново, и одновременные изменения от разных клиентов могут потеряться.
+func AddGauge(store models.Storage, name string, delta *float64) error {
+	if adder, ok := store.(gaugeAdder); ok {
+		return adder.AddGauge(name, delta)
+	}
+
+	var current float64
+	if value, err := store.GetGauge(name); err == nil {
+		current = *value
+	} else if !isNotFound(err) {
+		return err
+	}
+
+	newValue, keep, err := policy.AddGauge(current, *delta)
+	if err != nil || !keep {
+		return err
+	}
+
+	return store.SetGauge(name, &newValue)
+}
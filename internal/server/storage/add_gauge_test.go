@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// plainStorage скрывает необязательные методы хранилища.
+type plainStorage struct {
+	models.Storage
+}
+
+func TestAddGauge(t *testing.T) {
+	delta := 1.5
+
+	t.Run("Fallback treats missing gauge as zero", func(t *testing.T) {
+		store := plainStorage{Storage: memstorage.NewMem()}
+
+		require.NoError(t, AddGauge(store, "Alloc", &delta))
+		require.NoError(t, AddGauge(store, "Alloc", &delta))
+
+		value, err := store.GetGauge("Alloc")
+		require.NoError(t, err)
+		assert.Equal(t, 3.0, *value)
+	})
+
+	t.Run("Fallback applies gauge policy", func(t *testing.T) {
+		config.Config.GaugePolicy = "reject"
+		defer func() { config.Config.GaugePolicy = "" }()
+
+		store := plainStorage{Storage: memstorage.NewMem()}
+		value, big := math.MaxFloat64, math.MaxFloat64
+		require.NoError(t, store.SetGauge("Alloc", &value))
+
+		assert.ErrorIs(t, AddGauge(store, "Alloc", &big), errs.ErrGaugeNotFinite)
+
+		current, err := store.GetGauge("Alloc")
+		require.NoError(t, err)
+		assert.Equal(t, math.MaxFloat64, *current)
+	})
+
+	t.Run("Wrappers keep the backend method", func(t *testing.T) {
+		store := Normalize(memstorage.NewMem())
+
+		require.NoError(t, AddGauge(store, "Alloc", &delta))
+
+		value, err := store.GetGauge("Alloc")
+		require.NoError(t, err)
+		assert.Equal(t, delta, *value)
+	})
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+var ErrAPIKeysUnsupported = errors.New("storage cannot keep api keys")
+
+// APIKeyStore возвращает хранилище под всеми обёртками, если оно умеет хранить ключи API.
+func APIKeyStore(store models.Storage) (auth.KeyStore, error) {
+	for {
+		if keys, ok := store.(auth.KeyStore); ok {
+			return keys, nil
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return nil, ErrAPIKeysUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
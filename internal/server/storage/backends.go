@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/database_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/file_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/database"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// Backend - хранилище, которое выбирается по схеме STORAGE_DSN.
+type Backend struct {
+	// Open открывает хранилище. dsn передаётся целиком, вместе со схемой.
+	Open func(dsn string, log logger.Logger) (models.Storage, error)
+	// Name - имя бэкенда в логах и метриках. Если не задано, используется схема.
+	Name string
+	// Remote - хранилище доступно по сети, его вызовы защищает breaker.
+	Remote bool
+}
+
+var (
+	backendsMx sync.RWMutex
+	backends   = make(map[string]Backend)
+)
+
+func init() {
+	postgres := Backend{Open: openDatabase, Name: "database", Remote: true}
+	RegisterBackend("postgres", postgres)
+	RegisterBackend("postgresql", postgres)
+
+	RegisterBackend("file", Backend{Open: func(dsn string, log logger.Logger) (models.Storage, error) {
+		return openFile(dsnPath(dsn), log)
+	}, Name: "file"})
+
+	memory := Backend{Open: func(string, logger.Logger) (models.Storage, error) {
+		return memstorage.NewMem(), nil
+	}, Name: "memory"}
+	RegisterBackend("mem", memory)
+	RegisterBackend("memory", memory)
+}
+
+// RegisterBackend добавляет хранилище для схемы scheme://. Обычно вызывается из init пакета с хранилищем.
+// Повторная регистрация схемы - ошибка программиста, поэтому паникует.
+func RegisterBackend(scheme string, backend Backend) {
+	backendsMx.Lock()
+	defer backendsMx.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if scheme == "" || strings.Contains(scheme, ":") {
+		panic(fmt.Sprintf("storage: invalid backend scheme %q", scheme))
+	}
+	if backend.Open == nil {
+		panic(fmt.Sprintf("storage: nil Open for backend %q", scheme))
+	}
+	if _, ok := backends[scheme]; ok {
+		panic(fmt.Sprintf("storage: backend %q is already registered", scheme))
+	}
+
+	if backend.Name == "" {
+		backend.Name = scheme
+	}
+	backends[scheme] = backend
+}
+
+// Backends возвращает отсортированные схемы всех зарегистрированных хранилищ.
+func Backends() []string {
+	backendsMx.RLock()
+	defer backendsMx.RUnlock()
+
+	schemes := make([]string, 0, len(backends))
+	for scheme := range backends {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// openBackend открывает хранилище по схеме dsn и оборачивает его метриками и breaker.
+// В ошибках dsn не выводится целиком: в нём может быть пароль.
+func openBackend(dsn string, log logger.Logger) (models.Storage, error) {
+	scheme, _, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return nil, errors.New("storage dsn has no scheme")
+	}
+
+	backend, ok := lookupBackend(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unknown storage scheme %q: expected one of %s", scheme, strings.Join(Backends(), ", "))
+	}
+
+	store, err := backend.Open(dsn, log)
+	if err != nil {
+		return nil, fmt.Errorf("open %s storage: %w", backend.Name, err)
+	}
+
+	return wrapBackend(store, backend, log), nil
+}
+
+func lookupBackend(scheme string) (Backend, bool) {
+	backendsMx.RLock()
+	defer backendsMx.RUnlock()
+
+	backend, ok := backends[strings.ToLower(scheme)]
+	return backend, ok
+}
+
+func wrapBackend(store models.Storage, backend Backend, log logger.Logger) models.Storage {
+	store = instrument(store, backend.Name, log)
+	if backend.Remote && config.StorageBreakerEnabled() {
+		store = Break(store, backend.Name, config.StorageBreakerSettings(), log.Named("storage").With("backend", backend.Name))
+	}
+
+	return store
+}
+
+func openDatabase(dsn string, log logger.Logger) (models.Storage, error) {
+	db, err := database.New(dsn, log.Named("database"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = database.Wait(context.Background(), db, log.Named("database")); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	store, err := dbstorage.New(db, log.Named("dbstorage").With("backend", "database"))
+	if err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+
+	return store, nil
+}
+
+func openFile(path string, log logger.Logger) (models.Storage, error) {
+	fs, err := filestorage.New(path, log.Named("filestorage").With("backend", "file"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = fs.Restore(); err != nil {
+		return nil, err
+	}
+	fs.Start()
+
+	return fs, nil
+}
+
+// dsnPath возвращает путь из file://path: file://tmp/m.json - относительный, file:///tmp/m.json - абсолютный.
+func dsnPath(dsn string) string {
+	_, path, _ := strings.Cut(dsn, "://")
+	return path
+}
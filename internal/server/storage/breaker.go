@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type (
+	// breakerStorage не пускает запросы в хранилище, пока оно недоступно: вместо ожидания повторов
+	// хендлеры сразу получают breaker.ErrOpen.
+	breakerStorage struct {
+		models.Storage
+		backend string
+		breaker *breaker.Breaker
+	}
+
+	breakerTx struct {
+		models.StorageTx
+		store *breakerStorage
+	}
+)
+
+func Break(store models.Storage, backend string, settings breaker.Settings, log logger.Logger) models.Storage {
+	settings.IsFailure = breakerFailure
+	settings.OnStateChange = func(from, to breaker.State) {
+		log.Errorf("Storage circuit breaker changed state: %s -> %s", from, to)
+		selfmetrics.StorageBreakerState.Set(float64(to), backend)
+	}
+	selfmetrics.StorageBreakerState.Set(float64(breaker.Closed), backend)
+
+	return &breakerStorage{Storage: store, backend: backend, breaker: breaker.New(settings)}
+}
+
+func (s *breakerStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *breakerStorage) NewTx() (models.StorageTx, error) {
+	var t models.StorageTx
+
+	err := s.do("NewTx", func() (err error) {
+		t, err = s.Storage.NewTx()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &breakerTx{StorageTx: t, store: s}, nil
+}
+
+func (s *breakerStorage) SetGauge(name string, value *float64) error {
+	return s.do("SetGauge", func() error {
+		return s.Storage.SetGauge(name, value)
+	})
+}
+
+func (s *breakerStorage) AddGauge(name string, delta *float64) error {
+	return s.do("AddGauge", func() error {
+		return AddGauge(s.Storage, name, delta)
+	})
+}
+
+func (s *breakerStorage) AddCounter(name string, value *int64) error {
+	return s.do("AddCounter", func() error {
+		return s.Storage.AddCounter(name, value)
+	})
+}
+
+func (s *breakerStorage) GetGauge(name string) (value *float64, err error) {
+	err = s.do("GetGauge", func() (err error) {
+		value, err = s.Storage.GetGauge(name)
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) GetCounter(name string) (value *int64, err error) {
+	err = s.do("GetCounter", func() (err error) {
+		value, err = s.Storage.GetCounter(name)
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) GetAll() (metrics []models.MetricsValue, err error) {
+	err = s.do("GetAll", func() (err error) {
+		metrics, err = s.Storage.GetAll()
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) Search(pattern string, regex bool) (metrics []models.MetricsValue, err error) {
+	err = s.do("Search", func() (err error) {
+		metrics, err = search(s.Storage, pattern, regex)
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) GetTop(mType string, n int, desc bool) (metrics []models.MetricsValue, err error) {
+	err = s.do("GetTop", func() (err error) {
+		metrics, err = Top(s.Storage, mType, n, desc)
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) GetGaugeStats(name string) (stats *models.GaugeStats, err error) {
+	err = s.do("GetGaugeStats", func() (err error) {
+		stats, err = GaugeStats(s.Storage, name)
+		return
+	})
+	return
+}
+
+func (s *breakerStorage) ResetGaugeStats(name string) error {
+	return s.do("ResetGaugeStats", func() error {
+		return ResetGaugeStats(s.Storage, name)
+	})
+}
+
+func (s *breakerStorage) do(method string, fn func() error) error {
+	err := s.breaker.Do(fn)
+	if errors.Is(err, breaker.ErrOpen) {
+		selfmetrics.StorageBreakerRejections.Inc(s.backend, method)
+	}
+
+	return err
+}
+
+func (t *breakerTx) SetGauge(name string, value *float64) error {
+	return t.store.do("Tx.SetGauge", func() error {
+		return t.StorageTx.SetGauge(name, value)
+	})
+}
+
+func (t *breakerTx) AddCounter(name string, value *int64) error {
+	return t.store.do("Tx.AddCounter", func() error {
+		return t.StorageTx.AddCounter(name, value)
+	})
+}
+
+func (t *breakerTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *breakerTx) UpsertBatch(objects []models.MetricsUpdate) (values []models.MetricsValue, err error) {
+	err = t.store.do("Tx.UpsertBatch", func() (err error) {
+		values, err = upsertBatch(t.StorageTx, objects)
+		return
+	})
+	return
+}
+
+func (t *breakerTx) Commit() error {
+	return t.store.do("Tx.Commit", t.StorageTx.Commit)
+}
+
+// breakerFailure считает отказом только недоступность базы. Отказы по политикам, конфликты типов
+// и отсутствие метрики говорят о запросе, а не о хранилище.
+func breakerFailure(err error) bool {
+	switch errorClass(err) {
+	case "timeout", "connection":
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || // connection_exception
+			strings.HasPrefix(pgErr.Code, "57P") // operator_intervention: admin_shutdown, cannot_connect_now...
+	}
+
+	return errors.Is(err, driver.ErrBadConn)
+}
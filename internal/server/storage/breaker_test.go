@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type failingStorage struct {
+	models.Storage
+	err error
+}
+
+func (s *failingStorage) SetGauge(name string, value *float64) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.Storage.SetGauge(name, value)
+}
+
+func TestBreakerStorage(t *testing.T) {
+	failing := &failingStorage{Storage: memstorage.NewMem()}
+	store := Break(failing, "breaker_test", breaker.Settings{
+		Threshold:   0.5,
+		Window:      4,
+		MinRequests: 2,
+		OpenTimeout: time.Hour,
+	}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	value := 1.5
+	require.NoError(t, store.SetGauge("Test", &value))
+
+	for i := 0; i < 3; i++ {
+		_, err := store.GetCounter("Missing")
+		require.ErrorIs(t, err, errs.ErrStorageInvalidCounterName, "not found must not open the breaker")
+	}
+
+	failing.err = driver.ErrBadConn
+	require.ErrorIs(t, store.SetGauge("Test", &value), driver.ErrBadConn)
+	require.ErrorIs(t, store.SetGauge("Test", &value), driver.ErrBadConn)
+
+	failing.err = nil
+	require.ErrorIs(t, store.SetGauge("Test", &value), breaker.ErrOpen)
+
+	_, err := store.NewTx()
+	require.ErrorIs(t, err, breaker.ErrOpen)
+
+	assert.Equal(t, float64(breaker.Open), selfmetrics.StorageBreakerState.Value("breaker_test"))
+	assert.Equal(t, float64(1), selfmetrics.StorageBreakerRejections.Value("breaker_test", "SetGauge"))
+	assert.Equal(t, float64(1), selfmetrics.StorageBreakerRejections.Value("breaker_test", "NewTx"))
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+type (
+	// cachedStorage отвечает на чтения значений из памяти в течение ttl, чтобы дашборды, которые обновляются
+	// каждую секунду, не делали запрос в базу на каждый виджет. Запись через обёртку сбрасывает затронутые
+	// значения сразу, а запись в обход неё (другой экземпляр сервера) видна не позже чем через ttl.
+	cachedStorage struct {
+		models.Storage
+		ttl   time.Duration
+		clock clock.Clock
+
+		mx sync.Mutex
+		// version меняется при каждой записи: чтение, которое началось до неё, не кладёт в кэш старое значение
+		version  uint64
+		gauges   map[string]cachedGauge
+		counters map[string]cachedCounter
+		all      []models.MetricsValue
+		allUntil time.Time
+	}
+
+	cachedGauge struct {
+		value float64
+		until time.Time
+	}
+
+	cachedCounter struct {
+		value int64
+		until time.Time
+	}
+
+	cachedTx struct {
+		models.StorageTx
+		store *cachedStorage
+
+		mx       sync.Mutex
+		gauges   []string
+		counters []string
+	}
+)
+
+// Cache кэширует чтения на ttl. Если хранилище сообщает о записях других экземпляров сервера (см. -db-notify),
+// они сбрасываются сразу, а не через ttl.
+func Cache(store models.Storage, ttl time.Duration) models.Storage {
+	s := cache(store, ttl, clock.Real())
+	watchChanges(store, s.applyChange)
+
+	return s
+}
+
+func cache(store models.Storage, ttl time.Duration, clk clock.Clock) *cachedStorage {
+	return &cachedStorage{
+		Storage:  store,
+		ttl:      ttl,
+		clock:    clk,
+		gauges:   make(map[string]cachedGauge),
+		counters: make(map[string]cachedCounter),
+	}
+}
+
+func (s *cachedStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *cachedStorage) NewTx() (models.StorageTx, error) {
+	t, err := s.Storage.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachedTx{StorageTx: t, store: s}, nil
+}
+
+func (s *cachedStorage) SetGauge(name string, value *float64) error {
+	defer s.forget([]string{name}, nil)
+	return s.Storage.SetGauge(name, value)
+}
+
+func (s *cachedStorage) AddGauge(name string, delta *float64) error {
+	defer s.forget([]string{name}, nil)
+	return AddGauge(s.Storage, name, delta)
+}
+
+func (s *cachedStorage) AddCounter(name string, value *int64) error {
+	defer s.forget(nil, []string{name})
+	return s.Storage.AddCounter(name, value)
+}
+
+func (s *cachedStorage) GetGauge(name string) (*float64, error) {
+	s.mx.Lock()
+	if cached, ok := s.gauges[name]; ok && s.clock.Now().Before(cached.until) {
+		s.mx.Unlock()
+
+		value := cached.value
+		return &value, nil
+	}
+	version := s.version
+	s.mx.Unlock()
+
+	value, err := s.Storage.GetGauge(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	if s.version == version {
+		s.gauges[name] = cachedGauge{value: *value, until: s.clock.Now().Add(s.ttl)}
+	}
+	s.mx.Unlock()
+
+	return value, nil
+}
+
+func (s *cachedStorage) GetCounter(name string) (*int64, error) {
+	s.mx.Lock()
+	if cached, ok := s.counters[name]; ok && s.clock.Now().Before(cached.until) {
+		s.mx.Unlock()
+
+		value := cached.value
+		return &value, nil
+	}
+	version := s.version
+	s.mx.Unlock()
+
+	value, err := s.Storage.GetCounter(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	if s.version == version {
+		s.counters[name] = cachedCounter{value: *value, until: s.clock.Now().Add(s.ttl)}
+	}
+	s.mx.Unlock()
+
+	return value, nil
+}
+
+func (s *cachedStorage) GetAll() ([]models.MetricsValue, error) {
+	s.mx.Lock()
+	if s.all != nil && s.clock.Now().Before(s.allUntil) {
+		all := append([]models.MetricsValue(nil), s.all...)
+		s.mx.Unlock()
+
+		return all, nil
+	}
+	version := s.version
+	s.mx.Unlock()
+
+	all, err := s.Storage.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	if s.version == version {
+		s.all = append(make([]models.MetricsValue, 0, len(all)), all...)
+		s.allUntil = s.clock.Now().Add(s.ttl)
+	}
+	s.mx.Unlock()
+
+	return all, nil
+}
+
+func (s *cachedStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	return search(s.Storage, pattern, regex)
+}
+
+func (s *cachedStorage) GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	return Top(s.Storage, mType, n, desc)
+}
+
+// forget сбрасывает записанные метрики и общий список. Вызывается после записи, даже неудачной:
+// по ошибке нельзя понять, изменилось ли что-то в хранилище.
+func (s *cachedStorage) forget(gauges, counters []string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.version++
+	for _, name := range gauges {
+		delete(s.gauges, name)
+	}
+	for _, name := range counters {
+		delete(s.counters, name)
+	}
+	s.all = nil
+}
+
+// applyChange сбрасывает метрики, записанные другим экземпляром сервера.
+func (s *cachedStorage) applyChange(change models.MetricsChange) {
+	if !change.All {
+		s.forget(change.Gauges, change.Counters)
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.version++
+	s.gauges = make(map[string]cachedGauge)
+	s.counters = make(map[string]cachedCounter)
+	s.all = nil
+}
+
+func (t *cachedTx) SetGauge(name string, value *float64) error {
+	t.mx.Lock()
+	t.gauges = append(t.gauges, name)
+	t.mx.Unlock()
+
+	return t.StorageTx.SetGauge(name, value)
+}
+
+func (t *cachedTx) AddCounter(name string, value *int64) error {
+	t.mx.Lock()
+	t.counters = append(t.counters, name)
+	t.mx.Unlock()
+
+	return t.StorageTx.AddCounter(name, value)
+}
+
+func (t *cachedTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *cachedTx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	t.mx.Lock()
+	for _, obj := range objects {
+		if obj.MType == string(models.GaugeType) {
+			t.gauges = append(t.gauges, obj.ID)
+		} else {
+			t.counters = append(t.counters, obj.ID)
+		}
+	}
+	t.mx.Unlock()
+
+	return upsertBatch(t.StorageTx, objects)
+}
+
+func (t *cachedTx) Commit() error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	defer t.store.forget(t.gauges, t.counters)
+	return t.StorageTx.Commit()
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+// countingReads считает чтения, которые дошли до хранилища.
+type countingReads struct {
+	*memstorage.MemStorage
+	reads int
+}
+
+func (s *countingReads) GetGauge(name string) (*float64, error) {
+	s.reads++
+	return s.MemStorage.GetGauge(name)
+}
+
+func (s *countingReads) GetCounter(name string) (*int64, error) {
+	s.reads++
+	return s.MemStorage.GetCounter(name)
+}
+
+func TestCachedStorage(t *testing.T) {
+	inner := &countingReads{MemStorage: memstorage.NewMem()}
+	clk := clock.NewFake(time.Unix(0, 0))
+	store := cache(inner, time.Second, clk)
+
+	value := 1.5
+	require.NoError(t, store.SetGauge("Alloc", &value))
+
+	for i := 0; i < 3; i++ {
+		got, err := store.GetGauge("Alloc")
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, *got)
+	}
+	assert.Equal(t, 1, inner.reads, "repeated reads within the ttl must be served from the cache")
+
+	// Запись через обёртку сразу сбрасывает кэш
+	value = 2.5
+	require.NoError(t, store.SetGauge("Alloc", &value))
+
+	got, err := store.GetGauge("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, *got)
+	assert.Equal(t, 2, inner.reads)
+
+	// Запись в обход обёртки видна после ttl
+	value = 3.5
+	require.NoError(t, inner.SetGauge("Alloc", &value))
+
+	got, err = store.GetGauge("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, *got)
+
+	clk.Advance(time.Second)
+	got, err = store.GetGauge("Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, 3.5, *got)
+}
+
+func TestCachedStorageTx(t *testing.T) {
+	inner := &countingReads{MemStorage: memstorage.NewMem()}
+	store := cache(inner, time.Hour, clock.NewFake(time.Unix(0, 0)))
+
+	delta := int64(1)
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+
+	got, err := store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *got)
+
+	all, err := store.GetAll()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	tx, err := store.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.AddCounter("PollCount", &delta))
+	value := 1.5
+	require.NoError(t, tx.SetGauge("Alloc", &value))
+	require.NoError(t, tx.Commit())
+
+	got, err = store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *got)
+
+	all, err = store.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+// watchedReads сообщает кэшу о записях "другого экземпляра" через WatchChanges.
+type watchedReads struct {
+	*countingReads
+	watchers []func(models.MetricsChange)
+}
+
+func (s *watchedReads) WatchChanges(fn func(models.MetricsChange)) {
+	s.watchers = append(s.watchers, fn)
+}
+
+func TestCachedStorageWatchesChanges(t *testing.T) {
+	inner := &watchedReads{countingReads: &countingReads{MemStorage: memstorage.NewMem()}}
+	store := Cache(Instrument(inner, "memory"), time.Hour)
+	require.Len(t, inner.watchers, 1, "the cache must subscribe through wrappers")
+
+	value, delta := 1.5, int64(1)
+	require.NoError(t, inner.SetGauge("Alloc", &value))
+	require.NoError(t, inner.AddCounter("PollCount", &delta))
+
+	read := func() {
+		_, err := store.GetGauge("Alloc")
+		require.NoError(t, err)
+		_, err = store.GetCounter("PollCount")
+		require.NoError(t, err)
+	}
+
+	read()
+	read()
+	assert.Equal(t, 2, inner.reads)
+
+	inner.watchers[0](models.MetricsChange{Gauges: []string{"Alloc"}})
+	read()
+	assert.Equal(t, 3, inner.reads, "only the changed gauge must be read again")
+
+	inner.watchers[0](models.MetricsChange{All: true})
+	read()
+	assert.Equal(t, 5, inner.reads)
+}
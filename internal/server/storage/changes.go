@@ -0,0 +1,23 @@
+package storage
+
+import "github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+
+type changeWatcher interface {
+	WatchChanges(fn func(models.MetricsChange))
+}
+
+// watchChanges подписывает fn на записи других экземпляров сервера, если хранилище под обёртками о них сообщает.
+func watchChanges(store models.Storage, fn func(models.MetricsChange)) {
+	for {
+		if watcher, ok := store.(changeWatcher); ok {
+			watcher.WatchChanges(fn)
+			return
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return
+		}
+		store = wrapped.Unwrap()
+	}
+}
@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// coalescingStorage копит AddCounter в памяти и раз в окно записывает сумму по каждому счётчику одним запросом.
+type coalescingStorage struct {
+	models.Storage
+	log logger.Logger
+
+	mx      sync.Mutex
+	pending map[string]int64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func Coalesce(store models.Storage, window time.Duration, log logger.Logger) models.Storage {
+	return coalesce(store, window, clock.Real(), log)
+}
+
+func coalesce(store models.Storage, window time.Duration, clk clock.Clock, log logger.Logger) *coalescingStorage {
+	s := &coalescingStorage{
+		Storage: store,
+		log:     log,
+		pending: make(map[string]int64),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go s.run(clk.NewTicker(window))
+	return s
+}
+
+func (s *coalescingStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *coalescingStorage) run(ticker clock.Ticker) {
+	defer close(s.done)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C():
+			if err := s.flush(); err != nil {
+				s.log.Errorf("Failed to flush coalesced counters: %s", err)
+			}
+		}
+	}
+}
+
+func (s *coalescingStorage) AddGauge(name string, delta *float64) error {
+	return AddGauge(s.Storage, name, delta)
+}
+
+func (s *coalescingStorage) AddCounter(name string, value *int64) error {
+	if value == nil {
+		return s.Storage.AddCounter(name, value)
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var (
+		sum int64
+		err error
+	)
+	if current, ok := s.pending[name]; ok {
+		sum, err = policy.AddCounter(current, *value)
+	} else {
+		sum, err = policy.CounterDelta(*value)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.pending[name] = sum
+	return nil
+}
+
+func (s *coalescingStorage) GetCounter(name string) (*int64, error) {
+	s.mx.Lock()
+	delta, ok := s.pending[name]
+	s.mx.Unlock()
+
+	value, err := s.Storage.GetCounter(name)
+	if err != nil {
+		if ok && isNotFound(err) {
+			return &delta, nil
+		}
+		return nil, err
+	}
+
+	sum := *value + delta
+	return &sum, nil
+}
+
+func (s *coalescingStorage) GetAll() ([]models.MetricsValue, error) {
+	metrics, err := s.Storage.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return mergePending(metrics, s.pending), nil
+}
+
+// Search добавляет к найденным счётчикам ещё не записанные дельты, как GetAll.
+func (s *coalescingStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	matcher, err := policy.NameMatcher(pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := search(s.Storage, pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	pending := make(map[string]int64)
+	for name, delta := range s.pending {
+		if matcher.MatchString(name) {
+			pending[name] = delta
+		}
+	}
+
+	return mergePending(metrics, pending), nil
+}
+
+// GetTop с незаписанными дельтами отбирает счётчики после GetAll: дельта может поднять в топ счётчик, которого нет в выборке хранилища.
+func (s *coalescingStorage) GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	s.mx.Lock()
+	pending := len(s.pending)
+	s.mx.Unlock()
+
+	if mType == string(models.CounterType) && pending > 0 {
+		return topAll(s, mType, n, desc)
+	}
+
+	return Top(s.Storage, mType, n, desc)
+}
+
+// mergePending прибавляет дельты pending к счётчикам из metrics и добавляет счётчики, которых там ещё нет.
+func mergePending(metrics []models.MetricsValue, pending map[string]int64) []models.MetricsValue {
+	merged := make(map[string]bool, len(pending))
+	for i, metric := range metrics {
+		delta, ok := pending[metric.ID]
+		if !ok || metric.MType != string(models.CounterType) {
+			continue
+		}
+
+		sum := delta
+		if metric.Delta != nil {
+			sum += *metric.Delta
+		}
+		metrics[i].Delta = &sum
+		merged[metric.ID] = true
+	}
+
+	for _, name := range sortedNames(pending) {
+		if merged[name] {
+			continue
+		}
+
+		delta := pending[name]
+		metrics = append(metrics, models.MetricsValue{ID: name, MType: string(models.CounterType), Delta: &delta})
+	}
+
+	return metrics
+}
+
+func (s *coalescingStorage) flush() error {
+	s.mx.Lock()
+	pending := s.pending
+	s.pending = make(map[string]int64, len(pending))
+	s.mx.Unlock()
+
+	count := len(pending)
+	if count == 0 {
+		return nil
+	}
+
+	err := s.write(pending)
+	if rejectedCounter(err) {
+		// Пакет отвергнут из-за какого-то счётчика: пишем их по одному, чтобы отбросить только его
+		err = s.writeEach(pending)
+	}
+
+	if err != nil {
+		s.requeue(pending) // Попробуем записать в следующем окне
+		return err
+	}
+
+	s.log.Debugf("Coalesced counters (%d) have been written to %s.", count, s.Storage)
+	return nil
+}
+
+func (s *coalescingStorage) write(pending map[string]int64) error {
+	tx, err := s.Storage.NewTx()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedNames(pending) {
+		delta := pending[name]
+		if err = tx.AddCounter(name, &delta); err != nil {
+			return errors.Join(fmt.Errorf("counter %s: %w", name, err), tx.RollBack())
+		}
+	}
+
+	return tx.Commit()
+}
+
+// writeEach записывает счётчики по одному и убирает из pending записанные и те, что хранилище отвергает
+// насовсем. На временной ошибке останавливается, незаписанные счётчики остаются в pending.
+func (s *coalescingStorage) writeEach(pending map[string]int64) error {
+	for _, name := range sortedNames(pending) {
+		delta := pending[name]
+		if err := s.Storage.AddCounter(name, &delta); rejectedCounter(err) {
+			s.log.Errorf("Coalesced counter %s with delta %d was dropped: %s", name, delta, err)
+		} else if err != nil {
+			return err
+		}
+
+		delete(pending, name)
+	}
+
+	return nil
+}
+
+// requeue возвращает незаписанные счётчики в очередь. С накопленными за это время дельтами они складываются
+// по политике счётчиков; если сумма недопустима, незаписанная дельта отбрасывается.
+func (s *coalescingStorage) requeue(pending map[string]int64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, name := range sortedNames(pending) {
+		current, ok := s.pending[name]
+		if !ok {
+			s.pending[name] = pending[name]
+			continue
+		}
+
+		sum, err := policy.AddCounter(pending[name], current)
+		if err != nil {
+			s.log.Errorf("Coalesced counter %s with delta %d was dropped: %s", name, pending[name], err)
+			continue
+		}
+		s.pending[name] = sum
+	}
+}
+
+func (s *coalescingStorage) Close() error {
+	close(s.stop)
+	<-s.done
+
+	return errors.Join(s.flush(), s.Storage.Close())
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, errs.ErrStorageInvalidGaugeName) ||
+		errors.Is(err, errs.ErrStorageInvalidCounterName)
+}
+
+// rejectedCounter сообщает, что хранилище не примет счётчик и при повторе: переполнение, запрещённая
+// отрицательная дельта, недопустимое имя или метрика другого типа.
+func rejectedCounter(err error) bool {
+	var conflictErr *errs.TypeConflictError
+	return errors.Is(err, errs.ErrCounterOverflow) ||
+		errors.Is(err, errs.ErrCounterNegativeDelta) ||
+		errors.Is(err, errs.ErrStorageInvalidCounterName) ||
+		errors.As(err, &conflictErr)
+}
+
+func sortedNames(m map[string]int64) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
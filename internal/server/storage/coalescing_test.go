@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/testutil"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// keepOnClose не даёт MemStorage очиститься при Close, чтобы проверить запись при закрытии.
+type keepOnClose struct {
+	*memstorage.MemStorage
+}
+
+func (keepOnClose) Close() error {
+	return nil
+}
+
+func TestCoalescingStorage(t *testing.T) {
+	inner := keepOnClose{memstorage.NewMem()}
+	clk := clock.NewFake(time.Unix(0, 0))
+	store := coalesce(inner, time.Hour, clk, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	for _, delta := range []int64{1, 2, 3} {
+		require.NoError(t, store.AddCounter("PollCount", &delta))
+	}
+
+	_, err := inner.GetCounter("PollCount")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidCounterName, "counter must not be written before flush")
+
+	value, err := store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *value)
+
+	metrics, err := store.GetAll()
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, models.MetricsValue{ID: "PollCount", MType: "counter", Delta: value}, metrics[0])
+
+	// Сумма записывается по тикеру окна
+	clk.Advance(time.Hour)
+	require.Eventually(t, func() bool {
+		value, err := inner.GetCounter("PollCount")
+		return err == nil && *value == 6
+	}, time.Second, time.Millisecond)
+
+	delta := int64(4)
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+
+	value, err = store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *value)
+
+	require.NoError(t, store.Close())
+
+	value, err = inner.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *value, "pending counters must be flushed on close")
+}
+
+func TestCoalescingStorageDropsRejectedCounters(t *testing.T) {
+	config.Config.CounterPolicy = "reject"
+	defer func() { config.Config.CounterPolicy = "" }()
+
+	inner := keepOnClose{memstorage.NewMem()}
+	big := int64(math.MaxInt64)
+	require.NoError(t, inner.AddCounter("Big", &big))
+
+	store := coalesce(inner, time.Hour, clock.NewFake(time.Unix(0, 0)), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	defer func() { require.NoError(t, store.Close()) }()
+
+	for name, delta := range map[string]int64{"Big": 1, "PollCount": 2} {
+		delta := delta
+		require.NoError(t, store.AddCounter(name, &delta))
+	}
+
+	// Переполненный счётчик отбрасывается, а остальные записываются и дальше
+	require.NoError(t, store.flush())
+
+	value, err := inner.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *value)
+
+	value, err = inner.GetCounter("Big")
+	require.NoError(t, err)
+	assert.Equal(t, big, *value)
+
+	delta := int64(3)
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+	require.NoError(t, store.flush())
+
+	value, err = inner.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), *value)
+}
+
+func TestCoalescingStorageRequeuesTransientErrors(t *testing.T) {
+	inner := testutil.NewFakeStorage()
+	errUnavailable := errors.New("connection refused")
+	inner.FailOnce(testutil.MethodNewTx, errUnavailable)
+
+	store := coalesce(inner, time.Hour, clock.NewFake(time.Unix(0, 0)), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	defer func() { require.NoError(t, store.Close()) }()
+
+	delta := int64(1)
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+	require.ErrorIs(t, store.flush(), errUnavailable)
+
+	// Незаписанная дельта складывается с новой и записывается в следующем окне
+	delta = 2
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+	require.NoError(t, store.flush())
+
+	inner.AssertSnapshot(t, testutil.Snapshot{Counters: map[string]int64{"PollCount": 3}})
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type (
+	// conflictingStorage запрещает записывать метрику, если она уже существует с другим типом.
+	conflictingStorage struct {
+		models.Storage
+	}
+
+	conflictingTx struct {
+		models.StorageTx
+		store *conflictingStorage
+
+		mx    sync.Mutex
+		types map[string]models.MetricType
+	}
+)
+
+func RejectTypeConflicts(store models.Storage) models.Storage {
+	return &conflictingStorage{Storage: store}
+}
+
+func (s *conflictingStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *conflictingStorage) NewTx() (models.StorageTx, error) {
+	t, err := s.Storage.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return &conflictingTx{StorageTx: t, store: s, types: make(map[string]models.MetricType)}, nil
+}
+
+func (s *conflictingStorage) SetGauge(name string, value *float64) error {
+	if err := s.check(name, models.GaugeType); err != nil {
+		return err
+	}
+
+	return s.Storage.SetGauge(name, value)
+}
+
+func (s *conflictingStorage) AddGauge(name string, delta *float64) error {
+	if err := s.check(name, models.GaugeType); err != nil {
+		return err
+	}
+
+	return AddGauge(s.Storage, name, delta)
+}
+
+func (s *conflictingStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	return search(s.Storage, pattern, regex)
+}
+
+func (s *conflictingStorage) GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	return Top(s.Storage, mType, n, desc)
+}
+
+func (s *conflictingStorage) AddCounter(name string, value *int64) error {
+	if err := s.check(name, models.CounterType); err != nil {
+		return err
+	}
+
+	return s.Storage.AddCounter(name, value)
+}
+
+func (s *conflictingStorage) check(name string, requested models.MetricType) error {
+	var err error
+	if requested == models.GaugeType {
+		_, err = s.Storage.GetCounter(name)
+	} else {
+		_, err = s.Storage.GetGauge(name)
+	}
+
+	switch {
+	case err == nil:
+		return conflict(name, requested)
+	case isNotFound(err):
+		return nil
+	default:
+		return err
+	}
+}
+
+func (t *conflictingTx) SetGauge(name string, value *float64) error {
+	if err := t.check(name, models.GaugeType); err != nil {
+		return err
+	}
+
+	return t.StorageTx.SetGauge(name, value)
+}
+
+func (t *conflictingTx) AddCounter(name string, value *int64) error {
+	if err := t.check(name, models.CounterType); err != nil {
+		return err
+	}
+
+	return t.StorageTx.AddCounter(name, value)
+}
+
+func (t *conflictingTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *conflictingTx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	for _, obj := range objects {
+		if err := t.check(obj.ID, models.MetricType(obj.MType)); err != nil {
+			return nil, err
+		}
+	}
+
+	return upsertBatch(t.StorageTx, objects)
+}
+
+// check учитывает и уже сохранённые метрики, и метрики, записанные ранее в этой же транзакции.
+func (t *conflictingTx) check(name string, requested models.MetricType) error {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if existing, ok := t.types[name]; ok {
+		if existing != requested {
+			return conflict(name, requested)
+		}
+		return nil
+	}
+
+	if err := t.store.check(name, requested); err != nil {
+		return err
+	}
+
+	t.types[name] = requested
+	return nil
+}
+
+func conflict(name string, requested models.MetricType) error {
+	existing := models.CounterType
+	if requested == models.CounterType {
+		existing = models.GaugeType
+	}
+
+	return &errs.TypeConflictError{Name: name, ExistingType: string(existing), RequestedType: string(requested)}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+)
+
+func TestRejectTypeConflicts(t *testing.T) {
+	store := RejectTypeConflicts(memstorage.NewMem())
+
+	value, delta := 1.5, int64(1)
+	require.NoError(t, store.SetGauge("Alloc", &value))
+	require.NoError(t, store.SetGauge("Alloc", &value))
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+
+	var conflictErr *errs.TypeConflictError
+	require.ErrorAs(t, store.AddCounter("Alloc", &delta), &conflictErr)
+	assert.Equal(t, errs.TypeConflictError{Name: "Alloc", ExistingType: "gauge", RequestedType: "counter"}, *conflictErr)
+
+	require.ErrorAs(t, store.SetGauge("PollCount", &value), &conflictErr)
+	assert.Equal(t, "counter", conflictErr.ExistingType)
+
+	tx, err := store.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetGauge("New", &value))
+	require.ErrorAs(t, tx.AddCounter("New", &delta), &conflictErr, "conflicts inside a transaction must be detected")
+	require.ErrorAs(t, tx.AddCounter("Alloc", &delta), &conflictErr)
+	require.NoError(t, tx.RollBack())
+}
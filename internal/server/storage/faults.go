@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// ErrInjectedFault оборачивают все ошибки, которые вернул faultyStorage.
+var ErrInjectedFault = errors.New("injected fault")
+
+type (
+	FaultSettings struct {
+		Latency       time.Duration // добавляется к каждому вызову
+		LatencyJitter time.Duration // случайная добавка к Latency от 0 до LatencyJitter
+		ErrorRate     float64       // доля вызовов с временной ошибкой соединения
+		FailureRate   float64       // доля вызовов с постоянной ошибкой хранилища
+
+		// Rand возвращает число из [0, 1). Если не задан, используется math/rand.
+		Rand  func() float64
+		Clock clock.Clock
+	}
+
+	// faultyStorage для хаос-тестов: задерживает вызовы и возвращает ошибки с заданной вероятностью.
+	// Временные ошибки похожи на обрыв соединения, поэтому их считают breaker и метрики ошибок.
+	faultyStorage struct {
+		models.Storage
+		settings FaultSettings
+		log      logger.Logger
+	}
+
+	faultyTx struct {
+		models.StorageTx
+		store *faultyStorage
+	}
+)
+
+func InjectFaults(store models.Storage, settings FaultSettings, log logger.Logger) models.Storage {
+	if settings.Rand == nil {
+		settings.Rand = rand.Float64
+	}
+	settings.Clock = clock.Or(settings.Clock)
+
+	return &faultyStorage{Storage: store, settings: settings, log: log}
+}
+
+func (s *faultyStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *faultyStorage) NewTx() (models.StorageTx, error) {
+	if err := s.inject("NewTx"); err != nil {
+		return nil, err
+	}
+
+	t, err := s.Storage.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyTx{StorageTx: t, store: s}, nil
+}
+
+func (s *faultyStorage) SetGauge(name string, value *float64) error {
+	if err := s.inject("SetGauge"); err != nil {
+		return err
+	}
+
+	return s.Storage.SetGauge(name, value)
+}
+
+func (s *faultyStorage) AddGauge(name string, delta *float64) error {
+	if err := s.inject("AddGauge"); err != nil {
+		return err
+	}
+
+	return AddGauge(s.Storage, name, delta)
+}
+
+func (s *faultyStorage) AddCounter(name string, value *int64) error {
+	if err := s.inject("AddCounter"); err != nil {
+		return err
+	}
+
+	return s.Storage.AddCounter(name, value)
+}
+
+func (s *faultyStorage) GetGauge(name string) (*float64, error) {
+	if err := s.inject("GetGauge"); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetGauge(name)
+}
+
+func (s *faultyStorage) GetCounter(name string) (*int64, error) {
+	if err := s.inject("GetCounter"); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetCounter(name)
+}
+
+func (s *faultyStorage) GetAll() ([]models.MetricsValue, error) {
+	if err := s.inject("GetAll"); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetAll()
+}
+
+func (s *faultyStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	if err := s.inject("Search"); err != nil {
+		return nil, err
+	}
+
+	return search(s.Storage, pattern, regex)
+}
+
+func (s *faultyStorage) GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	if err := s.inject("GetTop"); err != nil {
+		return nil, err
+	}
+
+	return Top(s.Storage, mType, n, desc)
+}
+
+func (s *faultyStorage) GetGaugeStats(name string) (*models.GaugeStats, error) {
+	if err := s.inject("GetGaugeStats"); err != nil {
+		return nil, err
+	}
+
+	return GaugeStats(s.Storage, name)
+}
+
+func (s *faultyStorage) ResetGaugeStats(name string) error {
+	if err := s.inject("ResetGaugeStats"); err != nil {
+		return err
+	}
+
+	return ResetGaugeStats(s.Storage, name)
+}
+
+func (s *faultyStorage) Ping(ctx context.Context) error {
+	if err := s.inject("Ping"); err != nil {
+		return err
+	}
+
+	return s.Storage.Ping(ctx)
+}
+
+func (t *faultyTx) SetGauge(name string, value *float64) error {
+	if err := t.store.inject("Tx.SetGauge"); err != nil {
+		return err
+	}
+
+	return t.StorageTx.SetGauge(name, value)
+}
+
+func (t *faultyTx) AddCounter(name string, value *int64) error {
+	if err := t.store.inject("Tx.AddCounter"); err != nil {
+		return err
+	}
+
+	return t.StorageTx.AddCounter(name, value)
+}
+
+func (t *faultyTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *faultyTx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	if err := t.store.inject("Tx.UpsertBatch"); err != nil {
+		return nil, err
+	}
+
+	return upsertBatch(t.StorageTx, objects)
+}
+
+func (t *faultyTx) Commit() error {
+	if err := t.store.inject("Tx.Commit"); err != nil {
+		return err
+	}
+
+	return t.StorageTx.Commit()
+}
+
+// inject выдерживает задержку и решает, вернуть ли ошибку вместо вызова хранилища.
+func (s *faultyStorage) inject(method string) error {
+	if delay := s.delay(); delay > 0 {
+		_ = clock.Sleep(context.Background(), s.settings.Clock, delay)
+	}
+
+	switch roll := s.settings.Rand(); {
+	case roll < s.settings.ErrorRate:
+		s.log.Debugf("Injected a transient error into %s.", method)
+		return fmt.Errorf("%w: %s: connection reset: %w", ErrInjectedFault, method, driver.ErrBadConn)
+	case roll < s.settings.ErrorRate+s.settings.FailureRate:
+		s.log.Debugf("Injected a storage failure into %s.", method)
+		return fmt.Errorf("%w: %s: storage failure", ErrInjectedFault, method)
+	default:
+		return nil
+	}
+}
+
+func (s *faultyStorage) delay() time.Duration {
+	delay := s.settings.Latency
+	if s.settings.LatencyJitter > 0 {
+		delay += time.Duration(s.settings.Rand() * float64(s.settings.LatencyJitter))
+	}
+
+	return delay
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func sequence(values ...float64) func() float64 {
+	return func() float64 {
+		value := values[0]
+		values = values[1:]
+		return value
+	}
+}
+
+func TestFaultyStorage(t *testing.T) {
+	store := InjectFaults(memstorage.NewMem(), FaultSettings{
+		ErrorRate:   0.5,
+		FailureRate: 0.25,
+		Rand:        sequence(0.1, 0.6, 0.9, 0.9, 0.9),
+	}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	value := 1.5
+
+	err := store.SetGauge("Alloc", &value)
+	require.ErrorIs(t, err, ErrInjectedFault)
+	require.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, "connection", errorClass(err))
+
+	err = store.SetGauge("Alloc", &value)
+	require.ErrorIs(t, err, ErrInjectedFault)
+	require.NotErrorIs(t, err, driver.ErrBadConn)
+
+	require.NoError(t, store.SetGauge("Alloc", &value))
+
+	txx, err := store.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, txx.SetGauge("Alloc", &value))
+}
+
+func TestFaultyStorageLatency(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	store := InjectFaults(memstorage.NewMem(), FaultSettings{
+		Latency:       time.Second,
+		LatencyJitter: time.Second,
+		Rand:          func() float64 { return 0.5 },
+		Clock:         clk,
+	}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+	done := make(chan error)
+	go func() {
+		_, err := store.GetAll()
+		done <- err
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(1500 * time.Millisecond)
+	require.NoError(t, <-done)
+}
+
+func TestFaultyStorageOpensBreaker(t *testing.T) {
+	log := logger.Wrap(zaptest.NewLogger(t).Sugar())
+	store := Break(InjectFaults(memstorage.NewMem(), FaultSettings{ErrorRate: 1}, log), "faults_test", breaker.Settings{
+		Threshold:   0.5,
+		Window:      2,
+		MinRequests: 2,
+		OpenTimeout: time.Hour,
+	}, log)
+
+	for i := 0; i < 2; i++ {
+		_, err := store.GetAll()
+		require.ErrorIs(t, err, ErrInjectedFault)
+	}
+
+	_, err := store.GetAll()
+	require.ErrorIs(t, err, breaker.ErrOpen)
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type gaugeStatsKeeper interface {
+	GetGaugeStats(name string) (*models.GaugeStats, error)
+	ResetGaugeStats(name string) error
+}
+
+func findGaugeStatsKeeper(store models.Storage) (gaugeStatsKeeper, error) {
+	for {
+		if keeper, ok := store.(gaugeStatsKeeper); ok {
+			return keeper, nil
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return nil, errs.ErrGaugeStatsUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
+
+// GaugeStats возвращает статистику gauge. Если хранилище её не ведёт, возвращает errs.ErrGaugeStatsUnsupported.
+func GaugeStats(store models.Storage, name string) (*models.GaugeStats, error) {
+	keeper, err := findGaugeStatsKeeper(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return keeper.GetGaugeStats(name)
+}
+
+// ResetGaugeStats начинает статистику gauge заново. Если хранилище её не ведёт, возвращает errs.ErrGaugeStatsUnsupported.
+func ResetGaugeStats(store models.Storage, name string) error {
+	keeper, err := findGaugeStatsKeeper(store)
+	if err != nil {
+		return err
+	}
+
+	return keeper.ResetGaugeStats(name)
+}
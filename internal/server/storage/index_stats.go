@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type indexStatser interface {
+	IndexStats(ctx context.Context) ([]models.IndexStats, error)
+}
+
+// IndexStats возвращает статистику использования индексов хранилища под обёртками.
+// Если хранилище без индексов, возвращает errs.ErrIndexStatsUnsupported.
+func IndexStats(ctx context.Context, store models.Storage) ([]models.IndexStats, error) {
+	for {
+		if statser, ok := store.(indexStatser); ok {
+			return statser.IndexStats(ctx)
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return nil, errs.ErrIndexStatsUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+type (
+	instrumentedStorage struct {
+		models.Storage
+		backend string
+	}
+
+	instrumentedTx struct {
+		models.StorageTx
+		backend string
+	}
+)
+
+func Instrument(store models.Storage, backend string) models.Storage {
+	return &instrumentedStorage{Storage: store, backend: backend}
+}
+
+func (s *instrumentedStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *instrumentedStorage) NewTx() (models.StorageTx, error) {
+	var t models.StorageTx
+
+	err := observe(s.backend, "NewTx", func() (err error) {
+		t, err = s.Storage.NewTx()
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentedTx{StorageTx: t, backend: s.backend}, nil
+}
+
+func (s *instrumentedStorage) SetGauge(name string, value *float64) error {
+	return observe(s.backend, "SetGauge", func() error {
+		return s.Storage.SetGauge(name, value)
+	})
+}
+
+func (s *instrumentedStorage) AddGauge(name string, delta *float64) error {
+	return observe(s.backend, "AddGauge", func() error {
+		return AddGauge(s.Storage, name, delta)
+	})
+}
+
+func (s *instrumentedStorage) AddCounter(name string, value *int64) error {
+	return observe(s.backend, "AddCounter", func() error {
+		return s.Storage.AddCounter(name, value)
+	})
+}
+
+func (s *instrumentedStorage) GetGauge(name string) (value *float64, err error) {
+	err = observe(s.backend, "GetGauge", func() (err error) {
+		value, err = s.Storage.GetGauge(name)
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) GetCounter(name string) (value *int64, err error) {
+	err = observe(s.backend, "GetCounter", func() (err error) {
+		value, err = s.Storage.GetCounter(name)
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) GetAll() (metrics []models.MetricsValue, err error) {
+	err = observe(s.backend, "GetAll", func() (err error) {
+		metrics, err = s.Storage.GetAll()
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) Search(pattern string, regex bool) (metrics []models.MetricsValue, err error) {
+	err = observe(s.backend, "Search", func() (err error) {
+		metrics, err = search(s.Storage, pattern, regex)
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) GetTop(mType string, n int, desc bool) (metrics []models.MetricsValue, err error) {
+	err = observe(s.backend, "GetTop", func() (err error) {
+		metrics, err = Top(s.Storage, mType, n, desc)
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) GetGaugeStats(name string) (stats *models.GaugeStats, err error) {
+	err = observe(s.backend, "GetGaugeStats", func() (err error) {
+		stats, err = GaugeStats(s.Storage, name)
+		return
+	})
+	return
+}
+
+func (s *instrumentedStorage) ResetGaugeStats(name string) error {
+	return observe(s.backend, "ResetGaugeStats", func() error {
+		return ResetGaugeStats(s.Storage, name)
+	})
+}
+
+func (s *instrumentedStorage) Ping(ctx context.Context) error {
+	return observe(s.backend, "Ping", func() error {
+		return s.Storage.Ping(ctx)
+	})
+}
+
+func (t *instrumentedTx) SetGauge(name string, value *float64) error {
+	return observe(t.backend, "Tx.SetGauge", func() error {
+		return t.StorageTx.SetGauge(name, value)
+	})
+}
+
+func (t *instrumentedTx) AddCounter(name string, value *int64) error {
+	return observe(t.backend, "Tx.AddCounter", func() error {
+		return t.StorageTx.AddCounter(name, value)
+	})
+}
+
+func (t *instrumentedTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *instrumentedTx) UpsertBatch(objects []models.MetricsUpdate) (values []models.MetricsValue, err error) {
+	err = observe(t.backend, "Tx.UpsertBatch", func() (err error) {
+		values, err = upsertBatch(t.StorageTx, objects)
+		return
+	})
+	return
+}
+
+func (t *instrumentedTx) Commit() error {
+	return observe(t.backend, "Tx.Commit", t.StorageTx.Commit)
+}
+
+func (t *instrumentedTx) RollBack() error {
+	return observe(t.backend, "Tx.RollBack", t.StorageTx.RollBack)
+}
+
+func observe(backend, method string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+
+	selfmetrics.StorageQueryDuration.Observe(time.Since(start).Seconds(), backend, method)
+	if err != nil {
+		selfmetrics.StorageErrors.Inc(backend, method, errorClass(err))
+	}
+
+	return err
+}
+
+func errorClass(err error) string {
+	var (
+		pgErr  *pgconn.PgError
+		netErr net.Error
+	)
+
+	switch {
+	case isNotFound(err):
+		return "not_found"
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &netErr), errors.Is(err, sql.ErrConnDone), errors.Is(err, driver.ErrBadConn):
+		return "connection"
+	case errors.As(err, &pgErr):
+		return "database"
+	default:
+		return "other"
+	}
+}
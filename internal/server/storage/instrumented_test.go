@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+)
+
+func TestInstrumentedStorage(t *testing.T) {
+	store := Instrument(memstorage.NewMem(), "test")
+
+	value := 1.5
+	require.NoError(t, store.SetGauge("Test", &value))
+
+	_, err := store.GetGauge("Test")
+	require.NoError(t, err)
+
+	_, err = store.GetCounter("Missing")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidCounterName)
+
+	tx, err := store.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.SetGauge("Test", &value))
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, uint64(1), selfmetrics.StorageQueryDuration.Count("test", "SetGauge"))
+	assert.Equal(t, uint64(1), selfmetrics.StorageQueryDuration.Count("test", "GetGauge"))
+	assert.Equal(t, uint64(1), selfmetrics.StorageQueryDuration.Count("test", "Tx.Commit"))
+	assert.Equal(t, float64(1), selfmetrics.StorageErrors.Value("test", "GetCounter", "not_found"))
+	assert.Equal(t, float64(0), selfmetrics.StorageErrors.Value("test", "GetGauge", "not_found"))
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantedClass string
+	}{
+		{
+			name:        "Not found",
+			err:         fmt.Errorf("get: %w", sql.ErrNoRows),
+			wantedClass: "not_found",
+		},
+		{
+			name:        "Timeout",
+			err:         context.DeadlineExceeded,
+			wantedClass: "timeout",
+		},
+		{
+			name:        "Canceled",
+			err:         context.Canceled,
+			wantedClass: "canceled",
+		},
+		{
+			name:        "Other",
+			err:         errors.New("test error"),
+			wantedClass: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantedClass, errorClass(tt.err))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+var ErrNameNormalizationUnsupported = errors.New("storage does not support metric name normalization")
+
+type (
+	normalizedStorage struct {
+		models.Storage
+	}
+
+	normalizedTx struct {
+		models.StorageTx
+	}
+
+	nameNormalizer interface {
+		NormalizeNames() (int, error)
+	}
+
+	unwrapper interface {
+		Unwrap() models.Storage
+	}
+)
+
+// Normalize приводит имена метрик к единому виду при записи и чтении (см. policy.MetricName).
+func Normalize(store models.Storage) models.Storage {
+	return &normalizedStorage{Storage: store}
+}
+
+// NormalizeNames переводит уже сохранённые метрики со смешанным регистром в нижний, объединяя дубликаты.
+func NormalizeNames(store models.Storage) (int, error) {
+	for {
+		if normalizer, ok := store.(nameNormalizer); ok {
+			return normalizer.NormalizeNames()
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return 0, ErrNameNormalizationUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
+
+func (s *normalizedStorage) Unwrap() models.Storage {
+	return s.Storage
+}
+
+func (s *normalizedStorage) NewTx() (models.StorageTx, error) {
+	t, err := s.Storage.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return &normalizedTx{StorageTx: t}, nil
+}
+
+func (s *normalizedStorage) SetGauge(name string, value *float64) error {
+	return s.Storage.SetGauge(policy.MetricName(name), value)
+}
+
+func (s *normalizedStorage) AddGauge(name string, delta *float64) error {
+	return AddGauge(s.Storage, policy.MetricName(name), delta)
+}
+
+func (s *normalizedStorage) AddCounter(name string, value *int64) error {
+	return s.Storage.AddCounter(policy.MetricName(name), value)
+}
+
+func (s *normalizedStorage) GetGauge(name string) (*float64, error) {
+	return s.Storage.GetGauge(policy.MetricName(name))
+}
+
+func (s *normalizedStorage) GetCounter(name string) (*int64, error) {
+	return s.Storage.GetCounter(policy.MetricName(name))
+}
+
+func (s *normalizedStorage) Search(pattern string, regex bool) ([]models.MetricsValue, error) {
+	return search(s.Storage, pattern, regex)
+}
+
+func (s *normalizedStorage) GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	return Top(s.Storage, mType, n, desc)
+}
+
+func (s *normalizedStorage) GetGaugeStats(name string) (*models.GaugeStats, error) {
+	return GaugeStats(s.Storage, policy.MetricName(name))
+}
+
+func (s *normalizedStorage) ResetGaugeStats(name string) error {
+	return ResetGaugeStats(s.Storage, policy.MetricName(name))
+}
+
+func (t *normalizedTx) SetGauge(name string, value *float64) error {
+	return t.StorageTx.SetGauge(policy.MetricName(name), value)
+}
+
+func (t *normalizedTx) AddCounter(name string, value *int64) error {
+	return t.StorageTx.AddCounter(policy.MetricName(name), value)
+}
+
+func (t *normalizedTx) Unwrap() models.StorageTx {
+	return t.StorageTx
+}
+
+func (t *normalizedTx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	normalized := make([]models.MetricsUpdate, len(objects))
+	for i, obj := range objects {
+		obj.ID = policy.MetricName(obj.ID)
+		normalized[i] = obj
+	}
+
+	return upsertBatch(t.StorageTx, normalized)
+}
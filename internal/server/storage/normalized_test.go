@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+)
+
+func TestNormalizedStorage(t *testing.T) {
+	config.Config.CaseInsensitiveNames = true
+	defer func() { config.Config.CaseInsensitiveNames = false }()
+
+	inner := memstorage.NewMem()
+	store := Normalize(inner)
+
+	delta := int64(2)
+	require.NoError(t, store.AddCounter("PollCount", &delta))
+	require.NoError(t, store.AddCounter("pollcount", &delta))
+
+	tx, err := store.NewTx()
+	require.NoError(t, err)
+	require.NoError(t, tx.AddCounter("POLLCOUNT", &delta))
+	require.NoError(t, tx.Commit())
+
+	value, err := store.GetCounter("pollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *value)
+
+	value, err = inner.GetCounter("pollcount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *value)
+}
+
+func TestNormalizeNames(t *testing.T) {
+	inner := memstorage.NewMem()
+
+	for name, delta := range map[string]int64{"PollCount": 1, "pollcount": 2, "POLLCOUNT": 3} {
+		delta := delta
+		require.NoError(t, inner.AddCounter(name, &delta))
+	}
+
+	alloc, lowerAlloc := 1.5, 2.5
+	require.NoError(t, inner.SetGauge("Alloc", &alloc))
+	require.NoError(t, inner.SetGauge("alloc", &lowerAlloc))
+	require.NoError(t, inner.SetGauge("HeapInuse", &alloc))
+
+	count, err := NormalizeNames(Normalize(Instrument(inner, "test")))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	metrics, err := inner.GetAll()
+	require.NoError(t, err)
+	assert.Len(t, metrics, 3)
+
+	counter, err := inner.GetCounter("pollcount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), *counter)
+
+	gauge, err := inner.GetGauge("alloc")
+	require.NoError(t, err)
+	assert.Equal(t, lowerAlloc, *gauge)
+
+	gauge, err = inner.GetGauge("heapinuse")
+	require.NoError(t, err)
+	assert.Equal(t, alloc, *gauge)
+}
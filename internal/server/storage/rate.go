@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+type counterHistoryGetter interface {
+	GetCounterHistory(name string, since time.Time) ([]models.CounterSample, error)
+}
+
+// CounterHistory возвращает записи истории counter начиная с since, от старых к новым.
+// Если хранилище не ведёт историю, возвращает errs.ErrHistoryUnsupported.
+func CounterHistory(store models.Storage, name string, since time.Time) ([]models.CounterSample, error) {
+	for {
+		if getter, ok := store.(counterHistoryGetter); ok {
+			return getter.GetCounterHistory(name, since)
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return nil, errs.ErrHistoryUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
+
+func (s *normalizedStorage) GetCounterHistory(name string, since time.Time) ([]models.CounterSample, error) {
+	return CounterHistory(s.Storage, policy.MetricName(name), since)
+}
+
+// CounterRate считает скорость роста counter по записям истории. Уменьшение значения считается сбросом:
+// counter начал счёт заново, поэтому приростом считается всё новое значение.
+func CounterRate(name string, window time.Duration, samples []models.CounterSample) models.CounterRate {
+	rate := models.CounterRate{ID: name, Window: window.Seconds(), Samples: len(samples)}
+	if len(samples) == 0 {
+		return rate
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	rate.From, rate.To = first.CreatedAt.UTC(), last.CreatedAt.UTC()
+
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1].Delta, samples[i].Delta
+		if cur < prev {
+			rate.Resets++
+			rate.Increase += float64(cur)
+			continue
+		}
+
+		rate.Increase += float64(cur) - float64(prev)
+	}
+
+	if seconds := last.CreatedAt.Sub(first.CreatedAt).Seconds(); len(samples) > 1 && seconds > 0 {
+		perSecond := rate.Increase / seconds
+		rate.Rate = &perSecond
+	}
+
+	return rate
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+func TestCounterRate(t *testing.T) {
+	start := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	sample := func(delta int64, offset time.Duration) models.CounterSample {
+		return models.CounterSample{Delta: delta, CreatedAt: start.Add(offset)}
+	}
+
+	tests := []struct {
+		name           string
+		samples        []models.CounterSample
+		wantedRate     *float64
+		wantedIncrease float64
+		wantedResets   int
+	}{
+		{
+			name:    "Without samples",
+			samples: nil,
+		},
+		{
+			name:    "One sample",
+			samples: []models.CounterSample{sample(10, 0)},
+		},
+		{
+			name:           "Monotonic",
+			samples:        []models.CounterSample{sample(10, 0), sample(40, 10*time.Second), sample(70, 20*time.Second)},
+			wantedRate:     getPointer(3.0),
+			wantedIncrease: 60,
+		},
+		{
+			name:           "With reset",
+			samples:        []models.CounterSample{sample(100, 0), sample(150, 10*time.Second), sample(20, 20*time.Second), sample(50, 30*time.Second)},
+			wantedRate:     getPointer(100.0 / 30),
+			wantedIncrease: 100,
+			wantedResets:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := CounterRate("PollCount", 5*time.Minute, tt.samples)
+
+			assert.Equal(t, "PollCount", rate.ID)
+			assert.Equal(t, 300.0, rate.Window)
+			assert.Equal(t, len(tt.samples), rate.Samples)
+			assert.Equal(t, tt.wantedIncrease, rate.Increase)
+			assert.Equal(t, tt.wantedResets, rate.Resets)
+
+			if tt.wantedRate == nil {
+				assert.Nil(t, rate.Rate)
+				return
+			}
+
+			require.NotNil(t, rate.Rate)
+			assert.InDelta(t, *tt.wantedRate, *rate.Rate, 1e-9)
+		})
+	}
+}
+
+func getPointer[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+type searcher interface {
+	Search(pattern string, regex bool) ([]models.MetricsValue, error)
+}
+
+// Search возвращает метрики, имена которых подходят под glob или регулярное выражение, отсортированные по имени.
+// Если хранилище не умеет искать само, метрики отбираются после GetAll.
+func Search(store models.Storage, pattern string, regex bool) ([]models.MetricsValue, error) {
+	metrics, err := search(store, pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].ID != metrics[j].ID {
+			return metrics[i].ID < metrics[j].ID
+		}
+		return metrics[i].MType < metrics[j].MType
+	})
+
+	return metrics, nil
+}
+
+// search возвращает найденные метрики без сортировки. Обёртки хранилищ сами реализуют Search через search,
+// чтобы поиск проходил через них, как и остальные запросы.
+func search(store models.Storage, pattern string, regex bool) ([]models.MetricsValue, error) {
+	if s, ok := store.(searcher); ok {
+		return s.Search(pattern, regex)
+	}
+
+	matcher, err := policy.NameMatcher(pattern, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := store.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []models.MetricsValue
+	for _, metric := range all {
+		if matcher.MatchString(metric.ID) {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/breaker"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestSearchThroughWrappers(t *testing.T) {
+	value := 1.5
+
+	t.Run("Instrumented", func(t *testing.T) {
+		store := Instrument(memstorage.NewMem(), "search_test")
+		require.NoError(t, store.SetGauge("Alloc", &value))
+
+		metrics, err := Search(store, "All*", false)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+
+		assert.Equal(t, uint64(1), selfmetrics.StorageQueryDuration.Count("search_test", "Search"))
+	})
+
+	t.Run("Breaker", func(t *testing.T) {
+		failing := &failingStorage{Storage: memstorage.NewMem(), err: driver.ErrBadConn}
+		store := Break(failing, "search_test", breaker.Settings{
+			Threshold:   0.5,
+			Window:      4,
+			MinRequests: 2,
+			OpenTimeout: time.Hour,
+		}, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+
+		for i := 0; i < 2; i++ {
+			require.ErrorIs(t, store.SetGauge("Alloc", &value), driver.ErrBadConn)
+		}
+
+		_, err := Search(store, "*", false)
+		require.ErrorIs(t, err, breaker.ErrOpen)
+	})
+
+	t.Run("Coalescing", func(t *testing.T) {
+		store := coalesce(memstorage.NewMem(), time.Hour, clock.NewFake(time.Unix(0, 0)), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+		defer store.Close()
+
+		written, pending := int64(2), int64(3)
+		require.NoError(t, store.Storage.AddCounter("PollCount", &written))
+		require.NoError(t, store.AddCounter("PollCount", &pending))
+		require.NoError(t, store.AddCounter("PollNew", &pending))
+		require.NoError(t, store.AddCounter("Other", &pending))
+
+		metrics, err := Search(store, "Poll*", false)
+		require.NoError(t, err)
+
+		total, fresh := int64(5), int64(3)
+		assert.Equal(t, []models.MetricsValue{
+			{ID: "PollCount", MType: "counter", Delta: &total, UpdatedAt: metrics[0].UpdatedAt},
+			{ID: "PollNew", MType: "counter", Delta: &fresh},
+		}, metrics)
+	})
+}
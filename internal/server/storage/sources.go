@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+var ErrSourcesUnsupported = errors.New("storage does not support per-agent attribution")
+
+type sourceTracker interface {
+	RecordSources(agentID string, updates []models.MetricsUpdate) error
+	GetSources(mType, name string) ([]models.MetricSource, error)
+}
+
+// RecordSources запоминает, какой агент прислал обновления. Запись идёт мимо обёрток с метриками и breaker:
+// это вспомогательные данные, и их ошибка не должна влиять на учёт ошибок основного хранилища.
+func RecordSources(store models.Storage, agentID string, updates []models.MetricsUpdate) error {
+	tracker, err := findSourceTracker(store)
+	if err != nil {
+		return err
+	}
+
+	return tracker.RecordSources(agentID, updates)
+}
+
+// GetSources возвращает последние обновления метрики от каждого агента, начиная с самого свежего.
+func GetSources(store models.Storage, mType, name string) ([]models.MetricSource, error) {
+	tracker, err := findSourceTracker(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return tracker.GetSources(mType, name)
+}
+
+func findSourceTracker(store models.Storage) (sourceTracker, error) {
+	for {
+		if tracker, ok := store.(sourceTracker); ok {
+			return tracker, nil
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return nil, ErrSourcesUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
+
+func (s *normalizedStorage) RecordSources(agentID string, updates []models.MetricsUpdate) error {
+	normalized := make([]models.MetricsUpdate, len(updates))
+	for i, update := range updates {
+		update.ID = policy.MetricName(update.ID)
+		normalized[i] = update
+	}
+
+	return RecordSources(s.Storage, agentID, normalized)
+}
+
+func (s *normalizedStorage) GetSources(mType, name string) ([]models.MetricSource, error) {
+	return GetSources(s.Storage, mType, policy.MetricName(name))
+}
@@ -1,36 +1,88 @@
 package storage
 
 import (
+	"fmt"
+
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/database_storage"
-	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/file_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
-	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/database"
 	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
 func Setup(log logger.Logger) (models.Storage, error) {
-	if config.Config.DatabaseDSN != "" {
-		db, err := database.New()
-		if err != nil {
-			return nil, err
-		}
+	store, err := setupBackend(log)
+	if err != nil {
+		return nil, err
+	}
+
+	if window := config.Config.CounterCoalesceWindow; window > 0 {
+		store = Coalesce(store, window, log.Named("storage"))
+	}
 
-		return dbstorage.New(db, log)
-	} else if config.Config.FileStoragePath != "" {
-		fs, err := filestorage.New(log)
+	// Кэш стоит над Coalesce, чтобы его сбрасывали и ещё не записанные в бэкенд счётчики
+	if ttl := config.Config.ReadCacheTTL; ttl > 0 {
+		store = Cache(store, ttl)
+	}
+
+	if config.Config.RejectTypeConflicts {
+		store = RejectTypeConflicts(store)
+	}
+
+	if config.Config.CaseInsensitiveNames {
+		store = Normalize(store)
+	}
+
+	return store, nil
+}
+
+func setupBackend(log logger.Logger) (models.Storage, error) {
+	if dsn := config.Config.StorageDSN; dsn != "" {
+		return openBackend(dsn, log)
+	}
+
+	// Без STORAGE_DSN бэкенд выбирается по старым настройкам DATABASE_DSN и FILE_STORAGE_PATH
+	if dsn := config.Config.DatabaseDSN; dsn != "" {
+		store, err := openDatabase(dsn, log)
 		if err != nil {
 			return nil, err
 		}
 
-		if err = fs.Restore(); err != nil {
+		return wrapBackend(store, mustBackend("postgres"), log), nil
+	} else if path := config.Config.FileStoragePath; path != "" {
+		store, err := openFile(path, log)
+		if err != nil {
 			return nil, err
 		}
-		fs.Start()
 
-		return fs, nil
+		return wrapBackend(store, mustBackend("file"), log), nil
 	} else {
-		return memstorage.NewMem(), nil
+		return wrapBackend(memstorage.NewMem(), mustBackend("memory"), log), nil
 	}
 }
+
+// instrument оборачивает бэкенд метриками, а в режиме отладки сначала внедряет в него сбои,
+// чтобы их видели метрики, breaker и хендлеры.
+func instrument(store models.Storage, backend string, log logger.Logger) models.Storage {
+	if config.Config.DebugFaults {
+		log.Errorf("Fault injection is enabled for the %s storage: latency %s (+%s), error rate %v, failure rate %v.",
+			backend, config.Config.FaultLatency, config.Config.FaultLatencyJitter, config.Config.FaultErrorRate, config.Config.FaultFailureRate)
+
+		store = InjectFaults(store, FaultSettings{
+			Latency:       config.Config.FaultLatency,
+			LatencyJitter: config.Config.FaultLatencyJitter,
+			ErrorRate:     config.Config.FaultErrorRate,
+			FailureRate:   config.Config.FaultFailureRate,
+		}, log.Named("storage").With("backend", backend))
+	}
+
+	return Instrument(store, backend)
+}
+
+func mustBackend(scheme string) Backend {
+	backend, ok := lookupBackend(scheme)
+	if !ok {
+		panic(fmt.Sprintf("storage: built-in backend %q is not registered", scheme))
+	}
+
+	return backend
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type (
+	sizeEstimator interface {
+		EstimateSize(ctx context.Context) (int64, error)
+	}
+
+	cacheStatser interface {
+		CacheHitRates(ctx context.Context) (map[string]float64, error)
+	}
+)
+
+// Stats собирает сводку по хранилищу: число метрик по типам и время обновлений считаются по GetAll,
+// размер и попадания в кэш - у хранилищ под обёртками, которые умеют их отдавать.
+func Stats(ctx context.Context, store models.Storage) (models.StoreStats, error) {
+	stats := models.StoreStats{
+		Backend: store.String(),
+		Metrics: map[string]int{string(models.GaugeType): 0, string(models.CounterType): 0},
+	}
+
+	metrics, err := store.GetAll()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, metric := range metrics {
+		stats.Metrics[metric.MType]++
+		stats.Total++
+
+		if at := metric.UpdatedAt; at != nil {
+			if stats.OldestUpdate == nil || at.Before(*stats.OldestUpdate) {
+				stats.OldestUpdate = at
+			}
+			if stats.NewestUpdate == nil || at.After(*stats.NewestUpdate) {
+				stats.NewestUpdate = at
+			}
+		}
+	}
+
+	for inner := store; ; {
+		if estimator, ok := inner.(sizeEstimator); ok && stats.SizeBytes == nil {
+			size, err := estimator.EstimateSize(ctx)
+			if err != nil {
+				return stats, err
+			}
+			stats.SizeBytes = &size
+		}
+
+		if statser, ok := inner.(cacheStatser); ok && stats.CacheHitRates == nil {
+			if stats.CacheHitRates, err = statser.CacheHitRates(ctx); err != nil {
+				return stats, err
+			}
+		}
+
+		wrapped, ok := inner.(unwrapper)
+		if !ok {
+			return stats, nil
+		}
+		inner = wrapped.Unwrap()
+	}
+}
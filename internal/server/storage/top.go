@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"math"
+	"sort"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type topGetter interface {
+	GetTop(mType string, n int, desc bool) ([]models.MetricsValue, error)
+}
+
+// Top возвращает n метрик типа mType с наибольшими (desc) или наименьшими значениями, при равенстве - по имени.
+// NaN, как и в Postgres, больше любого числа. Если хранилище не умеет отбирать метрики само, они сортируются после GetAll.
+func Top(store models.Storage, mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	if getter, ok := store.(topGetter); ok {
+		return getter.GetTop(mType, n, desc)
+	}
+
+	return topAll(store, mType, n, desc)
+}
+
+func topAll(store models.Storage, mType string, n int, desc bool) ([]models.MetricsValue, error) {
+	all, err := store.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]models.MetricsValue, 0, len(all))
+	for _, metric := range all {
+		if metric.MType == mType {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if c := compareMetrics(metrics[i], metrics[j]); c != 0 {
+			return (c > 0) == desc
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+
+	if len(metrics) > n {
+		metrics = metrics[:n]
+	}
+
+	return metrics, nil
+}
+
+// compareMetrics сравнивает значения метрик одного типа. NaN больше любого числа и равен другому NaN.
+func compareMetrics(a, b models.MetricsValue) int {
+	if a.Delta != nil || b.Delta != nil {
+		x, y := metricDelta(a), metricDelta(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	x, y := metricValue(a), metricValue(b)
+	switch {
+	case math.IsNaN(x) || math.IsNaN(y):
+		if math.IsNaN(x) == math.IsNaN(y) {
+			return 0
+		}
+		if math.IsNaN(x) {
+			return 1
+		}
+		return -1
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func metricDelta(metric models.MetricsValue) int64 {
+	if metric.Delta == nil {
+		return 0
+	}
+	return *metric.Delta
+}
+
+func metricValue(metric models.MetricsValue) float64 {
+	if metric.Value == nil {
+		return 0
+	}
+	return *metric.Value
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func topIDs(metrics []models.MetricsValue) []string {
+	ids := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		ids = append(ids, metric.ID)
+	}
+
+	return ids
+}
+
+func TestTop(t *testing.T) {
+	t.Run("NaN is greater than any number", func(t *testing.T) {
+		store := memstorage.NewMem()
+		for name, value := range map[string]float64{"A": 1, "B": math.NaN(), "C": math.Inf(1), "D": math.NaN(), "E": -1} {
+			value := value
+			require.NoError(t, store.SetGauge(name, &value))
+		}
+
+		// Повторяем, чтобы неустойчивый порядок из map не прошёл случайно
+		for i := 0; i < 10; i++ {
+			metrics, err := Top(store, "gauge", 5, true)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"B", "D", "C", "A", "E"}, topIDs(metrics))
+
+			metrics, err = Top(store, "gauge", 5, false)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"E", "A", "C", "B", "D"}, topIDs(metrics))
+		}
+	})
+
+	t.Run("Instrumented", func(t *testing.T) {
+		store := Instrument(memstorage.NewMem(), "top_test")
+
+		_, err := Top(store, "gauge", 1, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, uint64(1), selfmetrics.StorageQueryDuration.Count("top_test", "GetTop"))
+	})
+
+	t.Run("Coalescing ranks pending deltas", func(t *testing.T) {
+		store := coalesce(memstorage.NewMem(), time.Hour, clock.NewFake(time.Unix(0, 0)), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+		defer store.Close()
+
+		big, small := int64(10), int64(1)
+		require.NoError(t, store.Storage.AddCounter("Written", &small))
+		require.NoError(t, store.AddCounter("Pending", &big))
+
+		metrics, err := Top(store, "counter", 1, true)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		assert.Equal(t, "Pending", metrics[0].ID)
+		assert.Equal(t, big, *metrics[0].Delta)
+	})
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+var ErrUpdatedAtUnsupported = errors.New("storage does not track metric update times")
+
+type updatedAtGetter interface {
+	GetUpdatedAt(mType, name string) (time.Time, error)
+}
+
+// UpdatedAt возвращает время последней записи метрики из хранилища под всеми обёртками.
+func UpdatedAt(store models.Storage, mType, name string) (time.Time, error) {
+	for {
+		if getter, ok := store.(updatedAtGetter); ok {
+			return getter.GetUpdatedAt(mType, name)
+		}
+
+		wrapped, ok := store.(unwrapper)
+		if !ok {
+			return time.Time{}, ErrUpdatedAtUnsupported
+		}
+		store = wrapped.Unwrap()
+	}
+}
+
+func (s *normalizedStorage) GetUpdatedAt(mType, name string) (time.Time, error) {
+	return UpdatedAt(s.Storage, mType, policy.MetricName(name))
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type (
+	batchUpserter interface {
+		UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error)
+	}
+
+	txUnwrapper interface {
+		Unwrap() models.StorageTx
+	}
+
+	batchKey struct {
+		id    string
+		mType string
+	}
+)
+
+// UpsertBatch записывает пакет метрик одной транзакцией и возвращает итоговые значения его метрик в порядке
+// их первого появления. Транзакция базы делает это одним запросом, в остальных хранилищах метрики пишутся
+// по одной, а значения читаются после Commit.
+func UpsertBatch(store models.Storage, objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	tx, err := store.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	if canUpsertBatch(tx) {
+		values, err := tx.(batchUpserter).UpsertBatch(objects)
+		if err != nil {
+			return nil, errors.Join(err, tx.RollBack())
+		}
+
+		if err = tx.Commit(); err != nil {
+			return nil, err
+		}
+
+		return values, nil
+	}
+
+	for _, obj := range objects {
+		if obj.MType == string(models.GaugeType) {
+			err = tx.SetGauge(obj.ID, obj.Value)
+		} else {
+			err = tx.AddCounter(obj.ID, obj.Delta)
+		}
+
+		if err != nil {
+			return nil, errors.Join(err, tx.RollBack())
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return readBatch(store, objects)
+}
+
+// canUpsertBatch проверяет, что транзакция под всеми обёртками умеет записывать пакет сама.
+// Обёртки транзакций реализуют UpsertBatch всегда и передают пакет дальше.
+func canUpsertBatch(tx models.StorageTx) bool {
+	for {
+		if _, ok := tx.(txUnwrapper); !ok {
+			_, ok = tx.(batchUpserter)
+			return ok
+		}
+		tx = tx.(txUnwrapper).Unwrap()
+	}
+}
+
+func upsertBatch(tx models.StorageTx, objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	return tx.(batchUpserter).UpsertBatch(objects)
+}
+
+func readBatch(store models.Storage, objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	seen := make(map[batchKey]bool)
+
+	values := make([]models.MetricsValue, 0, len(objects))
+	for _, obj := range objects {
+		key := batchKey{id: obj.ID, mType: obj.MType}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		value := models.MetricsValue{ID: obj.ID, MType: obj.MType}
+
+		var err error
+		if obj.MType == string(models.GaugeType) {
+			value.Value, err = store.GetGauge(obj.ID)
+		} else {
+			value.Delta, err = store.GetCounter(obj.ID)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/mem_storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+type (
+	batchStorage struct {
+		models.Storage
+		batches [][]models.MetricsUpdate
+	}
+
+	batchTx struct {
+		models.StorageTx
+		store *batchStorage
+	}
+)
+
+func (s *batchStorage) NewTx() (models.StorageTx, error) {
+	t, err := s.Storage.NewTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchTx{StorageTx: t, store: s}, nil
+}
+
+func (t *batchTx) UpsertBatch(objects []models.MetricsUpdate) ([]models.MetricsValue, error) {
+	t.store.batches = append(t.store.batches, objects)
+
+	values := make([]models.MetricsValue, 0, len(objects))
+	for _, obj := range objects {
+		values = append(values, models.MetricsValue{ID: obj.ID, MType: obj.MType, Delta: obj.Delta, Value: obj.Value})
+	}
+
+	return values, nil
+}
+
+func TestUpsertBatch(t *testing.T) {
+	value, delta := 1.5, int64(2)
+
+	t.Run("Fallback reads values after commit", func(t *testing.T) {
+		store := memstorage.NewMem()
+		require.NoError(t, store.AddCounter("PollCount", &delta))
+
+		values, err := UpsertBatch(store, []models.MetricsUpdate{
+			{ID: "PollCount", MType: string(models.CounterType), Delta: &delta},
+			{ID: "Alloc", MType: string(models.GaugeType), Value: &value},
+			{ID: "PollCount", MType: string(models.CounterType), Delta: &delta},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, values, 2)
+		assert.Equal(t, "PollCount", values[0].ID)
+		assert.Equal(t, int64(6), *values[0].Delta)
+		assert.Equal(t, "Alloc", values[1].ID)
+		assert.Equal(t, 1.5, *values[1].Value)
+	})
+
+	t.Run("Batch passes through wrappers", func(t *testing.T) {
+		config.Config.CaseInsensitiveNames = true
+		defer func() { config.Config.CaseInsensitiveNames = false }()
+
+		inner := &batchStorage{Storage: memstorage.NewMem()}
+		store := Normalize(RejectTypeConflicts(Cache(Instrument(inner, "memory"), time.Minute)))
+
+		values, err := UpsertBatch(store, []models.MetricsUpdate{{ID: "Alloc", MType: string(models.GaugeType), Value: &value}})
+		require.NoError(t, err)
+
+		require.Len(t, inner.batches, 1)
+		assert.Equal(t, "alloc", inner.batches[0][0].ID)
+		assert.Equal(t, []models.MetricsValue{{ID: "alloc", MType: "gauge", Value: &value}}, values)
+
+		_, err = UpsertBatch(store, []models.MetricsUpdate{
+			{ID: "Sys", MType: string(models.GaugeType), Value: &value},
+			{ID: "Sys", MType: string(models.CounterType), Delta: &delta},
+		})
+
+		var conflictErr *errs.TypeConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Len(t, inner.batches, 1, "a conflicting batch must not reach the storage")
+	})
+}
@@ -0,0 +1,567 @@
+// Package testutil содержит вспомогательные типы для тестов кода, который встраивает обработчики сервера.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/policy"
+)
+
+// Имена методов, под которыми FakeStorage записывает вызовы и ищет запрограммированные ошибки.
+const (
+	MethodNewTx      = "NewTx"
+	MethodSetGauge   = "SetGauge"
+	MethodAddGauge   = "AddGauge"
+	MethodAddCounter = "AddCounter"
+	MethodGetGauge   = "GetGauge"
+	MethodGetCounter = "GetCounter"
+	MethodGetAll     = "GetAll"
+	MethodPing       = "Ping"
+	MethodClose      = "Close"
+
+	MethodGetGaugeStats   = "GetGaugeStats"
+	MethodResetGaugeStats = "ResetGaugeStats"
+
+	MethodTxSetGauge   = "Tx.SetGauge"
+	MethodTxAddCounter = "Tx.AddCounter"
+	MethodTxCommit     = "Tx.Commit"
+	MethodTxRollBack   = "Tx.RollBack"
+)
+
+type (
+	// Call описывает один вызов хранилища: метод, имя метрики (если есть) и возвращённую ошибку.
+	Call struct {
+		Method string
+		Name   string
+		Err    error
+	}
+
+	// Snapshot содержит значения всех метрик хранилища на момент вызова FakeStorage.Snapshot.
+	Snapshot struct {
+		Gauges   map[string]float64
+		Counters map[string]int64
+	}
+
+	// FakeStorage детерминированная реализация models.Storage в памяти: записывает вызовы,
+	// возвращает запрограммированные ошибки и позволяет сравнивать состояние со снимком.
+	FakeStorage struct {
+		gauges   map[string]float64
+		counters map[string]int64
+		stats    map[string]*models.GaugeStats
+		sums     map[string]float64
+
+		calls  []Call
+		errors map[string]error
+		once   map[string][]error
+
+		mx sync.Mutex
+	}
+
+	fakeTx struct {
+		storage *FakeStorage
+		rows    []models.MetricsUpdate
+	}
+)
+
+var (
+	_ models.Storage   = (*FakeStorage)(nil)
+	_ models.StorageTx = (*fakeTx)(nil)
+)
+
+func NewFakeStorage() *FakeStorage {
+	return &FakeStorage{
+		gauges:   make(map[string]float64),
+		counters: make(map[string]int64),
+		stats:    make(map[string]*models.GaugeStats),
+		sums:     make(map[string]float64),
+		errors:   make(map[string]error),
+		once:     make(map[string][]error),
+	}
+}
+
+// FailOn заставляет все последующие вызовы метода возвращать err. Передача nil снимает ошибку.
+func (s *FakeStorage) FailOn(method string, err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err == nil {
+		delete(s.errors, method)
+		return
+	}
+	s.errors[method] = err
+}
+
+// FailOnce заставляет следующий вызов метода вернуть err. Повторные вызовы ставят ошибки в очередь.
+func (s *FakeStorage) FailOnce(method string, err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.once[method] = append(s.once[method], err)
+}
+
+// SetGauges и SetCounters заполняют хранилище без записи вызовов.
+func (s *FakeStorage) SetGauges(values map[string]float64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for name, value := range values {
+		s.gauges[name] = value
+	}
+}
+
+func (s *FakeStorage) SetCounters(values map[string]int64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for name, value := range values {
+		s.counters[name] = value
+	}
+}
+
+// Calls возвращает копию всех записанных вызовов в порядке их выполнения.
+func (s *FakeStorage) Calls() []Call {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// CallsOf возвращает записанные вызовы одного метода.
+func (s *FakeStorage) CallsOf(method string) []Call {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Reset очищает метрики, записанные вызовы и запрограммированные ошибки.
+func (s *FakeStorage) Reset() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.gauges = make(map[string]float64)
+	s.counters = make(map[string]int64)
+	s.stats = make(map[string]*models.GaugeStats)
+	s.sums = make(map[string]float64)
+	s.calls = nil
+	s.errors = make(map[string]error)
+	s.once = make(map[string][]error)
+}
+
+// Snapshot возвращает копию текущих значений метрик.
+func (s *FakeStorage) Snapshot() Snapshot {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	snapshot := Snapshot{
+		Gauges:   make(map[string]float64, len(s.gauges)),
+		Counters: make(map[string]int64, len(s.counters)),
+	}
+	for name, value := range s.gauges {
+		snapshot.Gauges[name] = value
+	}
+	for name, value := range s.counters {
+		snapshot.Counters[name] = value
+	}
+
+	return snapshot
+}
+
+// AssertSnapshot сравнивает текущие значения метрик с ожидаемыми и помечает тест проваленным при расхождении.
+// Nil-карта в expected считается пустой.
+func (s *FakeStorage) AssertSnapshot(t testing.TB, expected Snapshot) bool {
+	t.Helper()
+
+	actual := s.Snapshot()
+	ok := true
+
+	for _, diff := range diffMaps("gauge", expected.Gauges, actual.Gauges) {
+		t.Errorf("FakeStorage: %s", diff)
+		ok = false
+	}
+	for _, diff := range diffMaps("counter", expected.Counters, actual.Counters) {
+		t.Errorf("FakeStorage: %s", diff)
+		ok = false
+	}
+
+	return ok
+}
+
+func diffMaps[V comparable](kind string, expected, actual map[string]V) []string {
+	var diffs []string
+
+	for _, name := range sortedKeys(expected) {
+		value, ok := actual[name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s %q: expected %v, not found", kind, name, expected[name]))
+		} else if value != expected[name] {
+			diffs = append(diffs, fmt.Sprintf("%s %q: expected %v, got %v", kind, name, expected[name], value))
+		}
+	}
+
+	for _, name := range sortedKeys(actual) {
+		if _, ok := expected[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s %q: unexpected value %v", kind, name, actual[name]))
+		}
+	}
+
+	return diffs
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// record записывает вызов и возвращает ошибку, запрограммированную для метода. Вызывается под s.mx.
+func (s *FakeStorage) record(method, name string) error {
+	var err error
+	if queue := s.once[method]; len(queue) > 0 {
+		err, s.once[method] = queue[0], queue[1:]
+	} else {
+		err = s.errors[method]
+	}
+
+	s.calls = append(s.calls, Call{Method: method, Name: name, Err: err})
+	return err
+}
+
+// finish обновляет ошибку последнего записанного вызова, если она возникла уже после проверки программы.
+func (s *FakeStorage) finish(err error) error {
+	if err != nil && len(s.calls) > 0 {
+		s.calls[len(s.calls)-1].Err = err
+	}
+
+	return err
+}
+
+func (s *FakeStorage) NewTx() (models.StorageTx, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodNewTx, ""); err != nil {
+		return nil, err
+	}
+
+	return &fakeTx{storage: s}, nil
+}
+
+func (s *FakeStorage) SetGauge(name string, value *float64) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodSetGauge, name); err != nil {
+		return err
+	}
+	s.gauges[name] = *value
+	s.observeGauge(name, *value)
+
+	return nil
+}
+
+func (s *FakeStorage) AddGauge(name string, delta *float64) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodAddGauge, name); err != nil {
+		return err
+	}
+
+	next, keep, err := policy.AddGauge(s.gauges[name], *delta)
+	if err != nil || !keep {
+		return s.finish(err)
+	}
+	s.gauges[name] = next
+	s.observeGauge(name, next)
+
+	return nil
+}
+
+func (s *FakeStorage) AddCounter(name string, value *int64) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodAddCounter, name); err != nil {
+		return err
+	}
+	if value == nil {
+		return s.finish(errs.ErrCounterMissingDelta)
+	}
+
+	next, err := s.nextCounter(name, *value)
+	if err != nil {
+		return s.finish(err)
+	}
+	s.counters[name] = next
+
+	return nil
+}
+
+func (s *FakeStorage) nextCounter(name string, delta int64) (int64, error) {
+	current, ok := s.counters[name]
+	if !ok {
+		return policy.CounterDelta(delta)
+	}
+
+	return policy.AddCounter(current, delta)
+}
+
+func (s *FakeStorage) GetGauge(name string) (*float64, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodGetGauge, name); err != nil {
+		return nil, err
+	}
+
+	value, ok := s.gauges[name]
+	if !ok {
+		return nil, s.finish(errs.ErrStorageInvalidGaugeName)
+	}
+
+	return &value, nil
+}
+
+func (s *FakeStorage) GetCounter(name string) (*int64, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodGetCounter, name); err != nil {
+		return nil, err
+	}
+
+	value, ok := s.counters[name]
+	if !ok {
+		return nil, s.finish(errs.ErrStorageInvalidCounterName)
+	}
+
+	return &value, nil
+}
+
+// GetAll возвращает метрики в детерминированном порядке: сначала gauge, затем counter, каждые по имени.
+func (s *FakeStorage) GetAll() ([]models.MetricsValue, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodGetAll, ""); err != nil {
+		return nil, err
+	}
+
+	values := make([]models.MetricsValue, 0, len(s.gauges)+len(s.counters))
+	for _, name := range sortedKeys(s.gauges) {
+		value := s.gauges[name]
+		values = append(values, models.MetricsValue{
+			ID:    name,
+			MType: string(models.GaugeType),
+			Value: &value,
+		})
+	}
+	for _, name := range sortedKeys(s.counters) {
+		delta := s.counters[name]
+		values = append(values, models.MetricsValue{
+			ID:    name,
+			MType: string(models.CounterType),
+			Delta: &delta,
+		})
+	}
+
+	return values, nil
+}
+
+// GetGaugeStats возвращает статистику gauge. Since у FakeStorage всегда нулевой, чтобы результат был детерминированным.
+func (s *FakeStorage) GetGaugeStats(name string) (*models.GaugeStats, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodGetGaugeStats, name); err != nil {
+		return nil, err
+	}
+
+	stats, ok := s.stats[name]
+	if !ok {
+		return nil, s.finish(errs.ErrStorageInvalidGaugeName)
+	}
+
+	result := *stats
+	return &result, nil
+}
+
+func (s *FakeStorage) ResetGaugeStats(name string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.record(MethodResetGaugeStats, name); err != nil {
+		return err
+	}
+
+	if _, ok := s.stats[name]; !ok {
+		return s.finish(errs.ErrStorageInvalidGaugeName)
+	}
+
+	s.stats[name] = &models.GaugeStats{ID: name}
+	s.sums[name] = 0
+
+	return nil
+}
+
+func (s *FakeStorage) observeGauge(name string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	stats, ok := s.stats[name]
+	if !ok {
+		stats = &models.GaugeStats{ID: name}
+		s.stats[name] = stats
+	}
+
+	if stats.Count == 0 || value < stats.Min {
+		stats.Min = value
+	}
+	if stats.Count == 0 || value > stats.Max {
+		stats.Max = value
+	}
+	stats.Count++
+	s.sums[name] += value
+	stats.Avg = s.sums[name] / float64(stats.Count)
+}
+
+func (s *FakeStorage) GetMiddleware() gin.HandlerFunc {
+	return func(_ *gin.Context) {}
+}
+
+func (s *FakeStorage) Ping(_ context.Context) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.record(MethodPing, "")
+}
+
+func (s *FakeStorage) String() string {
+	return "FakeStorage"
+}
+
+func (s *FakeStorage) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.record(MethodClose, "")
+}
+
+func (tx *fakeTx) SetGauge(name string, value *float64) error {
+	tx.storage.mx.Lock()
+	defer tx.storage.mx.Unlock()
+
+	if err := tx.storage.record(MethodTxSetGauge, name); err != nil {
+		return err
+	}
+
+	tx.rows = append(tx.rows, models.MetricsUpdate{
+		ID:    name,
+		MType: string(models.GaugeType),
+		Value: value,
+	})
+
+	return nil
+}
+
+func (tx *fakeTx) AddCounter(name string, value *int64) error {
+	tx.storage.mx.Lock()
+	defer tx.storage.mx.Unlock()
+
+	if err := tx.storage.record(MethodTxAddCounter, name); err != nil {
+		return err
+	}
+	if value == nil {
+		return tx.storage.finish(errs.ErrCounterMissingDelta)
+	}
+	if _, err := policy.CounterDelta(*value); err != nil {
+		return tx.storage.finish(err)
+	}
+
+	tx.rows = append(tx.rows, models.MetricsUpdate{
+		ID:    name,
+		MType: string(models.CounterType),
+		Delta: value,
+	})
+
+	return nil
+}
+
+// Commit применяет записи транзакции целиком: при ошибке счётчика хранилище не меняется.
+func (tx *fakeTx) Commit() error {
+	tx.storage.mx.Lock()
+	defer tx.storage.mx.Unlock()
+
+	if err := tx.storage.record(MethodTxCommit, ""); err != nil {
+		return err
+	}
+
+	counters := make(map[string]int64)
+	for _, row := range tx.rows {
+		if row.MType != string(models.CounterType) {
+			continue
+		}
+
+		var (
+			value int64
+			err   error
+		)
+		if current, ok := counters[row.ID]; ok {
+			value, err = policy.AddCounter(current, *row.Delta)
+		} else {
+			value, err = tx.storage.nextCounter(row.ID, *row.Delta)
+		}
+		if err != nil {
+			return tx.storage.finish(err)
+		}
+
+		counters[row.ID] = value
+	}
+
+	for _, row := range tx.rows {
+		if row.MType == string(models.GaugeType) {
+			tx.storage.gauges[row.ID] = *row.Value
+			tx.storage.observeGauge(row.ID, *row.Value)
+		}
+	}
+	for name, value := range counters {
+		tx.storage.counters[name] = value
+	}
+
+	tx.rows = nil
+	return nil
+}
+
+func (tx *fakeTx) RollBack() error {
+	tx.storage.mx.Lock()
+	defer tx.storage.mx.Unlock()
+
+	if err := tx.storage.record(MethodTxRollBack, ""); err != nil {
+		return err
+	}
+
+	tx.rows = nil
+	return nil
+}
@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/errs"
+)
+
+func getPointerFloat64(v float64) *float64 {
+	return &v
+}
+
+func getPointerInt64(v int64) *int64 {
+	return &v
+}
+
+func TestFakeStorage(t *testing.T) {
+	storage := NewFakeStorage()
+
+	require.NoError(t, storage.SetGauge("Alloc", getPointerFloat64(1.5)))
+	require.NoError(t, storage.AddCounter("PollCount", getPointerInt64(2)))
+	require.NoError(t, storage.AddCounter("PollCount", getPointerInt64(3)))
+
+	_, err := storage.GetGauge("Unknown")
+	require.ErrorIs(t, err, errs.ErrStorageInvalidGaugeName)
+
+	metrics, err := storage.GetAll()
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, "Alloc", metrics[0].ID)
+	require.Equal(t, "PollCount", metrics[1].ID)
+	require.Equal(t, int64(5), *metrics[1].Delta)
+
+	storage.AssertSnapshot(t, Snapshot{
+		Gauges:   map[string]float64{"Alloc": 1.5},
+		Counters: map[string]int64{"PollCount": 5},
+	})
+
+	require.Equal(t, []Call{
+		{Method: MethodSetGauge, Name: "Alloc"},
+		{Method: MethodAddCounter, Name: "PollCount"},
+		{Method: MethodAddCounter, Name: "PollCount"},
+		{Method: MethodGetGauge, Name: "Unknown", Err: errs.ErrStorageInvalidGaugeName},
+		{Method: MethodGetAll},
+	}, storage.Calls())
+	require.Len(t, storage.CallsOf(MethodAddCounter), 2)
+}
+
+func TestFakeStorageErrors(t *testing.T) {
+	storage := NewFakeStorage()
+	errFail := errors.New("fail")
+
+	storage.FailOnce(MethodSetGauge, errFail)
+	require.ErrorIs(t, storage.SetGauge("Alloc", getPointerFloat64(1)), errFail)
+	require.NoError(t, storage.SetGauge("Alloc", getPointerFloat64(2)))
+
+	storage.FailOn(MethodPing, errFail)
+	require.ErrorIs(t, storage.Ping(nil), errFail) //nolint:staticcheck
+	require.ErrorIs(t, storage.Ping(nil), errFail) //nolint:staticcheck
+
+	storage.FailOn(MethodPing, nil)
+	require.NoError(t, storage.Ping(context.Background()))
+
+	storage.AssertSnapshot(t, Snapshot{Gauges: map[string]float64{"Alloc": 2}})
+
+	storage.Reset()
+	require.Empty(t, storage.Calls())
+	storage.AssertSnapshot(t, Snapshot{})
+}
+
+func TestFakeStorageTx(t *testing.T) {
+	storage := NewFakeStorage()
+	storage.SetCounters(map[string]int64{"PollCount": 1})
+
+	txx, err := storage.NewTx()
+	require.NoError(t, err)
+
+	require.NoError(t, txx.AddCounter("PollCount", getPointerInt64(10)))
+	require.NoError(t, txx.SetGauge("Alloc", getPointerFloat64(3)))
+
+	storage.AssertSnapshot(t, Snapshot{Counters: map[string]int64{"PollCount": 1}})
+
+	storage.FailOnce(MethodTxCommit, errFailCommit)
+	require.ErrorIs(t, txx.Commit(), errFailCommit)
+	storage.AssertSnapshot(t, Snapshot{Counters: map[string]int64{"PollCount": 1}})
+
+	require.NoError(t, txx.Commit())
+	storage.AssertSnapshot(t, Snapshot{
+		Gauges:   map[string]float64{"Alloc": 3},
+		Counters: map[string]int64{"PollCount": 11},
+	})
+}
+
+var errFailCommit = errors.New("commit failed")
+
+func TestFakeStorageAssertSnapshot(t *testing.T) {
+	storage := NewFakeStorage()
+	storage.SetGauges(map[string]float64{"Alloc": 1})
+
+	fake := &testing.T{}
+	require.False(t, storage.AssertSnapshot(fake, Snapshot{Gauges: map[string]float64{"Alloc": 2}}))
+	require.True(t, fake.Failed())
+}
@@ -0,0 +1,36 @@
+package tlscert
+
+import (
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Способы подтверждения владения доменом.
+const (
+	ChallengeTLSALPN = "tls-alpn-01" // через сам HTTPS-листенер, он должен быть доступен снаружи на порту 443
+	ChallengeHTTP    = "http-01"     // через отдельный HTTP-листенер, доступный снаружи на порту 80
+)
+
+type ACMEOptions struct {
+	Domains      []string
+	Email        string
+	CacheDir     string
+	DirectoryURL string // пустой - Let's Encrypt
+}
+
+// NewACME создаёт менеджер, который получает и продлевает сертификаты только для Domains.
+// Сертификаты и ключ аккаунта хранятся в CacheDir, чтобы не запрашивать их заново при каждом запуске.
+func NewACME(opts ACMEOptions) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(opts.Domains...),
+		Cache:      autocert.DirCache(opts.CacheDir),
+		Email:      opts.Email,
+	}
+
+	if opts.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: opts.DirectoryURL}
+	}
+
+	return m
+}
@@ -0,0 +1,110 @@
+// Package tlscert отдаёт TLS-листенеру сертификат из файлов и подменяет его, когда файлы меняются,
+// чтобы обновление сертификата не требовало перезапуска сервера.
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type (
+	Reloader struct {
+		certFile, keyFile string
+		clock             clock.Clock
+		log               logger.Logger
+
+		cert  atomic.Pointer[tls.Certificate]
+		stamp atomic.Pointer[stamp]
+	}
+
+	// stamp - время изменения и размеры файлов, по которым Watch замечает их замену.
+	stamp struct {
+		certMod, keyMod   time.Time
+		certSize, keySize int64
+	}
+)
+
+// Load читает сертификат и ключ. Ошибка здесь означает, что сервер нельзя запускать с TLS.
+func Load(certFile, keyFile string, clk clock.Clock, log logger.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clock: clock.Or(clk), log: log}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate подходит для tls.Config.GetCertificate: новые соединения получают последний загруженный сертификат.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload перечитывает файлы. Если пара не загрузилась, например файлы заменены не до конца,
+// продолжает действовать прежний сертификат.
+func (r *Reloader) Reload() error {
+	st, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate %s: %w", r.certFile, err)
+	}
+
+	r.cert.Store(&cert)
+	r.stamp.Store(st)
+
+	return nil
+}
+
+// Watch раз в interval проверяет файлы и перезагружает сертификат, если они изменились, до отмены ctx.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		st, err := r.stat()
+		if err != nil {
+			r.log.Errorf("Failed to check the tls certificate files: %s", err)
+			continue
+		}
+
+		if *st == *r.stamp.Load() {
+			continue
+		}
+
+		if err = r.Reload(); err != nil {
+			r.log.Errorf("Failed to reload the tls certificate, keeping the previous one: %s", err)
+			continue
+		}
+		r.log.Infof("The tls certificate %s has been reloaded.", r.certFile)
+	}
+}
+
+func (r *Reloader) stat() (*stamp, error) {
+	cert, err := os.Stat(r.certFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := os.Stat(r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stamp{certMod: cert.ModTime(), keyMod: key.ModTime(), certSize: cert.Size(), keySize: key.Size()}, nil
+}
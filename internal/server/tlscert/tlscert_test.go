@@ -0,0 +1,93 @@
+package tlscert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// writePair пишет самоподписанный сертификат с заданным серийным номером и отодвигает время изменения файлов,
+// чтобы замену было видно даже на файловых системах с грубыми отметками времени.
+func writePair(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func serial(t *testing.T, r *Reloader) int64 {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return parsed.SerialNumber.Int64()
+}
+
+func TestReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePair(t, certFile, keyFile, 1, time.Now().Add(-time.Hour))
+
+	clk := clock.NewFake(time.Now())
+	r, err := Load(certFile, keyFile, clk, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), serial(t, r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, time.Minute)
+	clk.BlockUntil(1)
+
+	// Наполовину заменённая пара не подменяет рабочий сертификат
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0600))
+	require.Error(t, r.Reload())
+	assert.Equal(t, int64(1), serial(t, r))
+
+	writePair(t, certFile, keyFile, 2, time.Now())
+	require.Eventually(t, func() bool {
+		clk.Advance(time.Minute)
+		return serial(t, r) == 2
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestLoadMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Load(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), nil, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
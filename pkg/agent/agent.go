@@ -0,0 +1,184 @@
+// Package agent позволяет встроить отправку метрик в другую программу вместо запуска отдельного агента.
+//
+// Настройки агента общие на процесс (см. config.Config), поэтому одновременно в процессе
+// может работать только один Agent.
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/collectors"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/metrics_updater"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/buildinfo"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+const userAgentApp = "metrics-agent"
+
+type (
+	// Config - настройки агента, те же, что задаются флагами и переменными окружения.
+	Config = config.Settings
+
+	Metric     = metrics.Metric
+	MetricType = metrics.MetricType
+
+	// MetricsCollector - источник метрик для коллектора агента, см. Register.
+	MetricsCollector = collectors.MetricsCollector
+	CollectorFactory = collectors.CollectorFactory
+
+	// Collector собирает метрики раз в PollInterval, пока не отменён ctx, и отдаёт последний снимок.
+	Collector interface {
+		Run(ctx context.Context)
+		GetMetrics() []Metric
+	}
+
+	// Reporter отправляет метрики коллектора на сервер раз в ReportInterval, пока не отменён ctx.
+	Reporter interface {
+		Run(ctx context.Context)
+	}
+
+	Agent struct {
+		collector Collector
+		reporter  Reporter
+		log       logger.Logger
+		reloads   chan reload
+	}
+
+	reload struct {
+		cfg    Config
+		result chan error
+	}
+
+	// reconfigurable - коллектор, который умеет применять новые настройки, см. collectors.Collector.
+	reconfigurable interface {
+		Reconfigure() error
+	}
+)
+
+var (
+	GaugeType   = metrics.GaugeType
+	CounterType = metrics.CounterType
+)
+
+// DefaultConfig возвращает настройки по умолчанию, как у запуска без флагов.
+func DefaultConfig() Config {
+	return config.Defaults()
+}
+
+// Register добавляет источник метрик, который выбирается по имени в Config.Collectors.
+// Вызывать нужно до New, обычно из init пакета с источником.
+func Register(name string, factory CollectorFactory) {
+	collectors.Register(name, factory)
+}
+
+// NewCollector возвращает коллектор источников, выбранных в текущих настройках.
+func NewCollector(log logger.Logger) (Collector, error) {
+	return collectors.NewCollector(log)
+}
+
+// NewReporter возвращает отправителя метрик из col на сервер из текущих настроек.
+func NewReporter(col Collector, log logger.Logger) Reporter {
+	client := resty.New().
+		SetHeader("User-Agent", buildinfo.Get().UserAgent(userAgentApp))
+
+	return metricsupdater.New(client, col, log)
+}
+
+// New применяет настройки и собирает агент. Если col равен nil, используется NewCollector,
+// если log равен nil, логгер создаётся по настройкам логирования из cfg.
+func New(cfg Config, col Collector, log logger.Logger) (*Agent, error) {
+	if err := config.Set(cfg); err != nil {
+		return nil, err
+	}
+
+	if log == nil {
+		var err error
+		if log, err = logger.New(config.LoggerOptions()); err != nil {
+			return nil, err
+		}
+	}
+
+	if col == nil {
+		var err error
+		if col, err = NewCollector(log.Named("collector")); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Agent{
+		collector: col,
+		reporter:  NewReporter(col, log.Named("exporter")),
+		log:       log,
+		reloads:   make(chan reload),
+	}, nil
+}
+
+// Run собирает и отправляет метрики до отмены ctx.
+func (a *Agent) Run(ctx context.Context) error {
+	a.log.Debugf("Metrics reporter successfully initialized.")
+
+	for {
+		runCtx, stop := context.WithCancel(ctx)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.collector.Run(runCtx)
+		}()
+		go func() {
+			defer wg.Done()
+			a.reporter.Run(runCtx)
+		}()
+
+		select {
+		case <-ctx.Done():
+			stop()
+			wg.Wait()
+
+			return nil
+		case r := <-a.reloads:
+			// Настройки общие на процесс, поэтому меняются, только когда сбор и отправка остановлены
+			stop()
+			wg.Wait()
+
+			r.result <- a.apply(r.cfg)
+		}
+	}
+}
+
+// Reload применяет новые настройки к работающему агенту без перезапуска: сбор и отправка
+// ненадолго останавливаются, а источники метрик сохраняют своё состояние. Настройки логирования
+// не меняются. Работает, только пока работает Run; при ошибке остаются прежние настройки.
+func (a *Agent) Reload(ctx context.Context, cfg Config) error {
+	r := reload{cfg: cfg, result: make(chan error, 1)}
+
+	select {
+	case a.reloads <- r:
+		return <-r.result
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *Agent) apply(cfg Config) error {
+	saved := config.Config
+	if err := config.Set(cfg); err != nil {
+		return err
+	}
+
+	if col, ok := a.collector.(reconfigurable); ok {
+		if err := col.Reconfigure(); err != nil {
+			config.Config = saved
+			return err
+		}
+	}
+
+	a.reporter = NewReporter(a.collector, a.log.Named("exporter"))
+	return nil
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+type staticCollector struct {
+	metrics []Metric
+}
+
+func (c staticCollector) Run(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func (c staticCollector) GetMetrics() []Metric {
+	return c.metrics
+}
+
+func TestAgent(t *testing.T) {
+	received := make(chan []Metric, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/updates", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("User-Agent"), "metrics-agent"))
+		assert.Equal(t, "test-agent", r.Header.Get("X-Agent-ID"))
+
+		var batch []Metric
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+
+		select {
+		case received <- batch:
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Address = strings.TrimPrefix(srv.URL, "http://")
+	cfg.ReportInterval = 1
+	cfg.AgentID = "test-agent"
+
+	value := 42.5
+	col := staticCollector{metrics: []Metric{{ID: "Custom", MType: GaugeType, Value: &value}}}
+
+	a, err := New(cfg, col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+
+	select {
+	case batch := <-received:
+		assert.Equal(t, col.metrics, batch)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent did not report metrics")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.LogSink = "nowhere"
+
+	_, err := New(cfg, nil, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
+
+func TestAgentReload(t *testing.T) {
+	agentIDs := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case agentIDs <- r.Header.Get("X-Agent-ID"):
+		default:
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Address = srv.URL
+	cfg.ReportInterval = 1
+	cfg.AgentID = "before"
+
+	value := 1.0
+	col := staticCollector{metrics: []Metric{{ID: "Custom", MType: GaugeType, Value: &value}}}
+
+	a, err := New(cfg, col, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+
+	assert.Equal(t, "before", <-agentIDs)
+
+	invalid := cfg
+	invalid.ReportInterval = 0
+	require.Error(t, a.Reload(ctx, invalid))
+
+	cfg.AgentID = "after"
+	require.NoError(t, a.Reload(ctx, cfg))
+
+	// Отправка, начатая до перезагрузки, ещё могла уйти со старым идентификатором
+	require.Eventually(t, func() bool { return <-agentIDs == "after" }, 5*time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
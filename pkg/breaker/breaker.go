@@ -0,0 +1,203 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+type (
+	Settings struct {
+		// Threshold - доля неудачных вызовов в окне, после которой breaker размыкается.
+		Threshold   float64
+		Window      int
+		MinRequests int
+		OpenTimeout time.Duration
+
+		// IsFailure решает, считать ли ошибку отказом зависимости. Если не задан, отказом считается любая ошибка.
+		IsFailure     func(err error) bool
+		OnStateChange func(from, to State)
+
+		// Clock отсчитывает OpenTimeout. Если не задан, используются реальные часы.
+		Clock clock.Clock
+	}
+
+	// Breaker считает исходы последних Window вызовов. В состоянии Open вызовы сразу получают ErrOpen,
+	// через OpenTimeout один пробный вызов (HalfOpen) решает, замкнуть breaker или снова разомкнуть.
+	Breaker struct {
+		settings Settings
+
+		mx       sync.Mutex
+		state    State
+		openedAt time.Time
+		probing  bool
+
+		outcomes []bool
+		next     int
+		count    int
+		failures int
+
+		clock clock.Clock
+	}
+)
+
+func New(settings Settings) *Breaker {
+	if settings.Window <= 0 {
+		settings.Window = 1
+	}
+
+	return &Breaker{
+		settings: settings,
+		outcomes: make([]bool, settings.Window),
+		clock:    clock.Or(settings.Clock),
+	}
+}
+
+func (b *Breaker) State() State {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return b.state
+}
+
+// RetryAfter возвращает, через сколько breaker пропустит пробный вызов.
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.state != Open {
+		return 0
+	}
+
+	if left := b.settings.OpenTimeout - b.clock.Since(b.openedAt); left > 0 {
+		return left
+	}
+
+	return 0
+}
+
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.before(); err != nil {
+		return err
+	}
+
+	err := fn()
+	b.after(err != nil && (b.settings.IsFailure == nil || b.settings.IsFailure(err)))
+
+	return err
+}
+
+func (b *Breaker) before() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.clock.Since(b.openedAt) < b.settings.OpenTimeout {
+			return ErrOpen
+		}
+
+		b.setState(HalfOpen)
+		b.probing = true
+
+		return nil
+	case HalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (b *Breaker) after(failed bool) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.state == HalfOpen {
+		b.probing = false
+
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+			b.setState(Closed)
+		}
+
+		return
+	}
+
+	if b.state == Open {
+		return
+	}
+
+	if b.count == len(b.outcomes) && b.outcomes[b.next] {
+		b.failures--
+	}
+	if b.count < len(b.outcomes) {
+		b.count++
+	}
+
+	b.outcomes[b.next] = failed
+	b.next = (b.next + 1) % len(b.outcomes)
+
+	if failed {
+		b.failures++
+	}
+
+	if b.count >= b.settings.MinRequests && float64(b.failures)/float64(b.count) >= b.settings.Threshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.clock.Now()
+	b.reset()
+	b.setState(Open)
+}
+
+func (b *Breaker) reset() {
+	for i := range b.outcomes {
+		b.outcomes[i] = false
+	}
+	b.next, b.count, b.failures = 0, 0, 0
+}
+
+func (b *Breaker) setState(state State) {
+	if b.state == state {
+		return
+	}
+
+	from := b.state
+	b.state = state
+
+	if b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, state)
+	}
+}
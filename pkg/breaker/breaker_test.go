@@ -0,0 +1,60 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+func TestBreaker(t *testing.T) {
+	errFailure := errors.New("connection refused")
+	errIgnored := errors.New("not found")
+
+	clk := clock.NewFake(time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC))
+
+	var transitions []string
+	b := New(Settings{
+		Threshold:   0.5,
+		Window:      4,
+		MinRequests: 4,
+		OpenTimeout: time.Minute,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, errIgnored)
+		},
+		OnStateChange: func(from, to State) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+		Clock: clk,
+	})
+
+	fail := func() error { return errFailure }
+	succeed := func() error { return nil }
+
+	require.ErrorIs(t, b.Do(fail), errFailure)
+	require.ErrorIs(t, b.Do(func() error { return errIgnored }), errIgnored)
+	require.NoError(t, b.Do(succeed))
+	assert.Equal(t, Closed, b.State(), "not enough failures to open")
+
+	require.ErrorIs(t, b.Do(fail), errFailure)
+	assert.Equal(t, Open, b.State())
+	assert.Equal(t, time.Minute, b.RetryAfter())
+
+	calls := 0
+	require.ErrorIs(t, b.Do(func() error { calls++; return nil }), ErrOpen)
+	assert.Zero(t, calls, "open breaker must not call the function")
+
+	clk.Advance(time.Minute)
+	require.ErrorIs(t, b.Do(fail), errFailure)
+	assert.Equal(t, Open, b.State(), "failed probe must open the breaker again")
+
+	clk.Advance(time.Minute)
+	require.NoError(t, b.Do(succeed))
+	assert.Equal(t, Closed, b.State())
+
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}, transitions)
+}
@@ -0,0 +1,58 @@
+package buildinfo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+const notAvailable = "N/A"
+
+type Info struct {
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Commit  string `json:"commit"`
+}
+
+var (
+	mx      sync.RWMutex
+	current = New("", "", "")
+)
+
+func New(version, date, commit string) Info {
+	return Info{
+		Version: valueOrNA(version),
+		Date:    valueOrNA(date),
+		Commit:  valueOrNA(commit),
+	}
+}
+
+func Set(info Info) {
+	mx.Lock()
+	defer mx.Unlock()
+
+	current = info
+}
+
+func Get() Info {
+	mx.RLock()
+	defer mx.RUnlock()
+
+	return current
+}
+
+func (i Info) Print(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Build version: %s\nBuild date: %s\nBuild commit: %s\n", i.Version, i.Date, i.Commit)
+}
+
+func (i Info) UserAgent(app string) string {
+	return fmt.Sprintf("%s/%s (%s)", app, i.Version, i.Commit)
+}
+
+func valueOrNA(value string) string {
+	if value == "" {
+		return notAvailable
+	}
+
+	return value
+}
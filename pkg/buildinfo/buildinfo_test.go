@@ -0,0 +1,43 @@
+package buildinfo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+	tests := []struct {
+		name            string
+		info            Info
+		wantedBanner    string
+		wantedUserAgent string
+	}{
+		{
+			name:            "Without ldflags",
+			info:            New("", "", ""),
+			wantedBanner:    "Build version: N/A\nBuild date: N/A\nBuild commit: N/A\n",
+			wantedUserAgent: "agent/N/A (N/A)",
+		},
+		{
+			name:            "With ldflags",
+			info:            New("v1.2.0", "2023-10-15", "8ee203b"),
+			wantedBanner:    "Build version: v1.2.0\nBuild date: 2023-10-15\nBuild commit: 8ee203b\n",
+			wantedUserAgent: "agent/v1.2.0 (8ee203b)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			tt.info.Print(buf)
+
+			assert.Equal(t, tt.wantedBanner, buf.String())
+			assert.Equal(t, tt.wantedUserAgent, tt.info.UserAgent("agent"))
+
+			Set(tt.info)
+			assert.Equal(t, tt.info, Get())
+		})
+	}
+}
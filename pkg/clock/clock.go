@@ -0,0 +1,82 @@
+// Package clock абстрагирует время, чтобы паузы, тикеры и метки времени можно было подменять в тестах.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	Clock interface {
+		Now() time.Time
+		Since(t time.Time) time.Duration
+
+		NewTimer(d time.Duration) Timer
+		NewTicker(d time.Duration) Ticker
+	}
+
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+	}
+
+	Ticker interface {
+		C() <-chan time.Time
+		Stop()
+	}
+
+	realClock  struct{}
+	realTimer  struct{ *time.Timer }
+	realTicker struct{ *time.Ticker }
+)
+
+// Real возвращает часы, которые используют пакет time.
+func Real() Clock {
+	return realClock{}
+}
+
+// Or возвращает c или реальные часы, если c не задан.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+
+	return c
+}
+
+// Sleep ждёт d по часам c. Возвращает ошибку контекста, если он отменён раньше.
+func Sleep(ctx context.Context, c Clock, d time.Duration) error {
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
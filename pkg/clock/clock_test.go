@@ -0,0 +1,79 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeTimer(t *testing.T) {
+	start := time.Date(2023, time.October, 15, 13, 30, 0, 0, time.UTC)
+	clk := NewFake(start)
+
+	timer := clk.NewTimer(time.Second)
+	clk.Advance(500 * time.Millisecond)
+
+	select {
+	case <-timer.C():
+		t.Fatal("the timer fired too early")
+	default:
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-timer.C())
+	assert.Equal(t, 0, clk.Waiters())
+	assert.False(t, timer.Stop())
+
+	stopped := clk.NewTimer(time.Second)
+	assert.True(t, stopped.Stop())
+	clk.Advance(time.Minute)
+
+	select {
+	case <-stopped.C():
+		t.Fatal("the stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+
+	ticker := clk.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clk.Advance(time.Second)
+	<-ticker.C()
+
+	// Пропущенные срабатывания не копятся
+	clk.Advance(3 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("the ticker buffered more than one tick")
+	default:
+	}
+
+	clk.Advance(time.Second)
+	assert.Equal(t, time.Unix(5, 0), <-ticker.C())
+}
+
+func TestSleep(t *testing.T) {
+	clk := NewFake(time.Unix(0, 0))
+
+	done := make(chan error)
+	go func() {
+		done <- Sleep(context.Background(), clk, time.Minute)
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	require.NoError(t, <-done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, Sleep(ctx, clk, time.Minute), context.Canceled)
+	assert.Equal(t, 0, clk.Waiters())
+}
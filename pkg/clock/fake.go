@@ -0,0 +1,169 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Fake часы для тестов: время стоит на месте, пока его не сдвинут через Advance или Set.
+	// Таймеры и тикеры срабатывают при сдвиге, если их время наступило.
+	Fake struct {
+		mx      sync.Mutex
+		now     time.Time
+		waiters []*fakeWaiter
+		changed chan struct{}
+	}
+
+	fakeWaiter struct {
+		clock    *Fake
+		deadline time.Time
+		period   time.Duration // 0 у таймера
+		c        chan time.Time
+	}
+
+	fakeTimer  struct{ *fakeWaiter }
+	fakeTicker struct{ *fakeWaiter }
+)
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return fakeTimer{f.add(d, 0)}
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	return fakeTicker{f.add(d, d)}
+}
+
+// Advance сдвигает время на d и срабатывает таймеры и тикеры, время которых наступило.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set переводит часы на now. Время назад не идёт: более раннее значение игнорируется.
+func (f *Fake) Set(now time.Time) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if now.Before(f.now) {
+		return
+	}
+	f.now = now
+
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+
+	waiters := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(now) {
+			waiters = append(waiters, w)
+			continue
+		}
+
+		// Как и у time.Ticker, пропущенные срабатывания не копятся: в канале не больше одного значения
+		select {
+		case w.c <- w.deadline:
+		default:
+		}
+
+		if w.period > 0 {
+			for !w.deadline.After(now) {
+				w.deadline = w.deadline.Add(w.period)
+			}
+			waiters = append(waiters, w)
+		}
+	}
+	f.waiters = waiters
+}
+
+// Waiters возвращает число активных таймеров и тикеров.
+func (f *Fake) Waiters() int {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return len(f.waiters)
+}
+
+// BlockUntil ждёт, пока число активных таймеров и тикеров не станет не меньше n.
+// Нужен, чтобы сдвигать время только после того, как проверяемый код начал ждать.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mx.Lock()
+		count, changed := len(f.waiters), f.changed
+		f.mx.Unlock()
+
+		if count >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	w := &fakeWaiter{clock: f, deadline: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.c <- f.now
+		return w
+	}
+
+	f.waiters = append(f.waiters, w)
+	f.notify()
+
+	return w
+}
+
+func (f *Fake) remove(w *fakeWaiter) bool {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	for i, waiter := range f.waiters {
+		if waiter == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.notify()
+			return true
+		}
+	}
+
+	return false
+}
+
+// notify будит BlockUntil. Вызывается под f.mx.
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+func (t fakeTimer) Stop() bool {
+	return t.clock.remove(t.fakeWaiter)
+}
+
+func (t fakeTicker) Stop() {
+	t.clock.remove(t.fakeWaiter)
+}
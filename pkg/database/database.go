@@ -1,12 +1,37 @@
 package database
 
 import (
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
 )
 
-func New() (*sqlx.DB, error) {
-	return sqlx.Open("pgx", config.Config.DatabaseDSN)
+// New открывает пул соединений к PostgreSQL по dsn с параметрами TLS из настроек. С -db-slow-query
+// запросы дольше порога пишутся в log.
+func New(dsn string, log logger.Logger) (*sqlx.DB, error) {
+	params, err := tlsParams()
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, err = withParams(dsn, params)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := config.Config.DatabaseSlowQuery
+	if threshold <= 0 {
+		return sqlx.Open("pgx", dsn)
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connConfig.Tracer = newSlowQueryTracer(threshold, log)
+
+	return sqlx.NewDb(stdlib.OpenDB(*connConfig), "pgx"), nil
 }
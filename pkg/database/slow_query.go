@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// maxLoggedSQL - длина текста запроса в журнале медленных запросов.
+const maxLoggedSQL = 200
+
+type (
+	queryNameKey struct{}
+	slowQueryKey struct{}
+
+	// slowQueryTracer пишет в журнал и считает в self-metrics запросы дольше threshold. Значения параметров
+	// не пишутся: в них бывают имена метрик клиентов и ключи API.
+	slowQueryTracer struct {
+		threshold time.Duration
+		log       logger.Logger
+		clock     clock.Clock
+	}
+
+	slowQuery struct {
+		name  string
+		sql   string
+		args  []any
+		start time.Time
+	}
+)
+
+// WithQueryName подписывает запросы в ctx именем операции хранилища для журнала медленных запросов.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+func newSlowQueryTracer(threshold time.Duration, log logger.Logger) *slowQueryTracer {
+	return &slowQueryTracer{threshold: threshold, log: log, clock: clock.Real()}
+}
+
+// TraceQueryStart запоминает начало запроса. У подготовленных запросов pgx передаёт вместо текста имя вроде pgx_3,
+// поэтому операция хранилища берётся из WithQueryName.
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	if name == "" {
+		name = "query"
+	}
+
+	return context.WithValue(ctx, slowQueryKey{}, &slowQuery{name: name, sql: data.SQL, args: data.Args, start: t.clock.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(slowQueryKey{}).(*slowQuery)
+	if !ok {
+		return
+	}
+
+	duration := t.clock.Since(query.start)
+	if duration < t.threshold {
+		return
+	}
+
+	selfmetrics.StorageSlowQueries.Inc(query.name)
+
+	status := "ok"
+	if data.Err != nil {
+		status = data.Err.Error()
+	}
+	t.log.Infof("Slow query %s took %s (threshold %s, %s): %s [%s]", query.name, duration, t.threshold, status, shortSQL(query.sql), redactArgs(query.args))
+}
+
+// shortSQL сжимает пробелы в тексте запроса и обрезает его до maxLoggedSQL.
+func shortSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxLoggedSQL {
+		sql = sql[:maxLoggedSQL] + "..."
+	}
+
+	return sql
+}
+
+// redactArgs описывает параметры запроса без значений: тип и, для строк и массивов, длину.
+func redactArgs(args []any) string {
+	parts := make([]string, 0, len(args))
+	for i, arg := range args {
+		var kind string
+		switch v := arg.(type) {
+		case nil:
+			kind = "null"
+		case string:
+			kind = fmt.Sprintf("string(%d)", len(v))
+		case []byte:
+			kind = fmt.Sprintf("bytes(%d)", len(v))
+		case []string:
+			kind = fmt.Sprintf("[]string(%d)", len(v))
+		case []int64:
+			kind = fmt.Sprintf("[]int64(%d)", len(v))
+		case []float64:
+			kind = fmt.Sprintf("[]float64(%d)", len(v))
+		default:
+			kind = fmt.Sprintf("%T", v)
+		}
+		parts = append(parts, fmt.Sprintf("$%d=%s", i+1, kind))
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/selfmetrics"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestSlowQueryTracer(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	clk := clock.NewFake(time.Unix(0, 0))
+
+	tracer := newSlowQueryTracer(time.Second, logger.Wrap(zap.New(core).Sugar()))
+	tracer.clock = clk
+
+	query := func(name string, d time.Duration) {
+		ctx := WithQueryName(context.Background(), name)
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+			SQL:  "SELECT value\n\t\tFROM metrics WHERE name = $1 AND value > $2",
+			Args: []any{"api-key-secret", 1.5},
+		})
+		clk.Advance(d)
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	}
+
+	before := selfmetrics.StorageSlowQueries.Value("GetGauge")
+
+	query("GetGauge", time.Millisecond*10)
+	assert.Empty(t, logs.All(), "fast queries must not be logged")
+
+	query("GetGauge", time.Second*2)
+	entries := logs.FilterMessageSnippet("Slow query GetGauge").All()
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Message, "took 2s")
+	assert.Contains(t, entries[0].Message, "SELECT value FROM metrics WHERE name = $1 AND value > $2")
+	assert.Contains(t, entries[0].Message, "$1=string(14), $2=float64")
+	assert.NotContains(t, entries[0].Message, "api-key-secret")
+
+	assert.Equal(t, before+1, selfmetrics.StorageSlowQueries.Value("GetGauge"))
+}
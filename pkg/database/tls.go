@@ -0,0 +1,107 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+var (
+	ErrInvalidSSLMode     = errors.New("invalid database sslmode")
+	ErrInvalidSSLRootCert = errors.New("invalid database sslrootcert")
+	ErrInvalidSSLCertKey  = errors.New("invalid database sslcert/sslkey")
+)
+
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+func tlsParams() (map[string]string, error) {
+	params := make(map[string]string)
+
+	if mode := config.Config.DatabaseSSLMode; mode != "" {
+		if !sslModes[mode] {
+			return nil, fmt.Errorf("%w: %q, expected one of disable, allow, prefer, require, verify-ca, verify-full", ErrInvalidSSLMode, mode)
+		}
+		params["sslmode"] = mode
+	}
+
+	if rootCert := config.Config.DatabaseSSLRootCert; rootCert != "" {
+		pem, err := os.ReadFile(rootCert)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSSLRootCert, err)
+		}
+
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s contains no PEM certificates", ErrInvalidSSLRootCert, rootCert)
+		}
+		params["sslrootcert"] = rootCert
+	}
+
+	cert, key := config.Config.DatabaseSSLCert, config.Config.DatabaseSSLKey
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, fmt.Errorf("%w: both the client certificate and the key must be set", ErrInvalidSSLCertKey)
+		}
+
+		if _, err := tls.LoadX509KeyPair(cert, key); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSSLCertKey, err)
+		}
+		params["sslcert"] = cert
+		params["sslkey"] = key
+	}
+
+	return params, nil
+}
+
+func withParams(dsn string, params map[string]string) (string, error) {
+	if len(params) == 0 {
+		return dsn, nil
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+
+		query := u.Query()
+		for k, v := range params {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+
+		return u.String(), nil
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(dsn)
+
+	for _, k := range keys {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(params[k])
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(fmt.Sprintf("%s='%s'", k, value))
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+const (
+	waitInitialBackoff = time.Millisecond * 500
+	waitMaxBackoff     = time.Second * 5
+	waitPingTimeout    = time.Second * 5
+)
+
+// waitClock отсчитывает паузы и общий таймаут ожидания, в тестах подменяется.
+var waitClock = clock.Real()
+
+// Wait ждёт, пока база данных станет доступна, увеличивая паузу между попытками.
+// При нулевом DatabaseWaitTimeout делается ровно одна попытка.
+func Wait(ctx context.Context, db *sqlx.DB, log logger.Logger) error {
+	timeout := config.Config.DatabaseWaitTimeout
+	start := waitClock.Now()
+
+	backoff := waitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx, db)
+		if err == nil {
+			return nil
+		}
+
+		if timeout <= 0 {
+			return err
+		}
+
+		left := timeout - waitClock.Since(start)
+		if left <= 0 {
+			return fmt.Errorf("database is unavailable after %s: %w", timeout, err)
+		}
+
+		delay := backoff
+		if delay > left {
+			delay = left
+		}
+
+		log.Errorf("The database is unavailable (attempt %d), retrying in %s: %s", attempt, delay, err)
+
+		if sleepErr := clock.Sleep(ctx, waitClock, delay); sleepErr != nil {
+			return fmt.Errorf("database is unavailable: %w", errors.Join(err, sleepErr))
+		}
+
+		backoff *= 2
+		if backoff > waitMaxBackoff {
+			backoff = waitMaxBackoff
+		}
+	}
+}
+
+func ping(ctx context.Context, db *sqlx.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, waitPingTimeout)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestWait(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close()) // Порт гарантированно никто не слушает
+
+	db, err := sqlx.Open("pgx", "postgres://user:pass@"+addr+"/db?connect_timeout=1")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name        string
+		waitTimeout time.Duration
+		minDuration time.Duration
+	}{
+		{
+			name: "Fail fast",
+		},
+		{
+			name:        "Wait with backoff",
+			waitTimeout: time.Minute,
+			minDuration: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.DatabaseWaitTimeout = tt.waitTimeout
+			defer func() { config.Config.DatabaseWaitTimeout = 0 }()
+
+			start := time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)
+			clk := clock.NewFake(start)
+
+			waitClock = clk
+			defer func() { waitClock = clock.Real() }()
+
+			done := make(chan error)
+			go func() {
+				done <- Wait(context.Background(), db, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+			}()
+
+			var err error
+		loop:
+			for {
+				select {
+				case err = <-done:
+					break loop
+				case <-time.After(time.Millisecond):
+					if clk.Waiters() > 0 {
+						clk.Advance(time.Second)
+					}
+				}
+			}
+
+			assert.Error(t, err)
+			assert.GreaterOrEqual(t, clk.Since(start), tt.minDuration)
+		})
+	}
+}
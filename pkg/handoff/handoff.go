@@ -0,0 +1,46 @@
+// Package handoff передаёт слушающие сокеты новому процессу сервера, чтобы обновить бинарник без простоя.
+// Новый процесс наследует сокеты, сообщает о готовности, и только после этого старый процесс
+// перестаёт принимать соединения и дорабатывает начатые запросы.
+package handoff
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+const (
+	// listenersEnv - имена унаследованных сокетов через запятую, их дескрипторы идут по порядку с 3.
+	listenersEnv = "METRICS_HANDOFF_LISTENERS"
+	// readyEnv - дескриптор канала, в который новый процесс пишет, что готов.
+	readyEnv = "METRICS_HANDOFF_READY"
+)
+
+var ErrNotSupported = errors.New("socket handoff is not supported on this platform")
+
+var (
+	inheritOnce sync.Once
+	inheritErr  error
+
+	inheritedMx sync.Mutex
+	inherited   map[string]net.Listener
+)
+
+// Listener возвращает унаследованный от старого процесса сокет с именем name. Каждый сокет отдаётся
+// один раз; если процесс запущен не через Upgrade, возвращает nil.
+func Listener(name string) (net.Listener, error) {
+	inheritOnce.Do(func() {
+		inherited, inheritErr = inherit()
+	})
+	if inheritErr != nil {
+		return nil, inheritErr
+	}
+
+	inheritedMx.Lock()
+	defer inheritedMx.Unlock()
+
+	listener := inherited[name]
+	delete(inherited, name)
+
+	return listener, nil
+}
@@ -0,0 +1,163 @@
+//go:build !windows
+
+package handoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+func inherit() (map[string]net.Listener, error) {
+	names := os.Getenv(listenersEnv)
+	if names == "" {
+		return nil, nil
+	}
+	_ = os.Unsetenv(listenersEnv) // процессы, которые запустит этот, сокеты не наследуют
+
+	listeners := make(map[string]net.Listener)
+	for i, name := range strings.Split(names, ",") {
+		file := os.NewFile(uintptr(3+i), name)
+
+		listener, err := net.FileListener(file)
+		_ = file.Close() // FileListener работает с копией дескриптора
+		if err != nil {
+			return nil, fmt.Errorf("inherited socket %s: %w", name, err)
+		}
+
+		// Файл сокета теперь принадлежит этому процессу, его и удалять при остановке
+		if unix, ok := listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(true)
+		}
+		listeners[name] = listener
+	}
+
+	return listeners, nil
+}
+
+// Inherited сообщает, что процесс запущен через Upgrade и должен вызвать Ready.
+func Inherited() bool {
+	return os.Getenv(readyEnv) != ""
+}
+
+// Ready сообщает старому процессу, что новый начал принимать соединения. Без Upgrade ничего не делает.
+func Ready() error {
+	value := os.Getenv(readyEnv)
+	if value == "" {
+		return nil
+	}
+	_ = os.Unsetenv(readyEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", readyEnv, err)
+	}
+
+	pipe := os.NewFile(uintptr(fd), "handoff-ready")
+	_, err = pipe.Write([]byte{1})
+
+	return errors.Join(err, pipe.Close())
+}
+
+// Upgrade запускает новый процесс из текущего исполняемого файла с теми же аргументами и окружением,
+// передаёт ему сокеты listeners и ждёт, пока он вызовет Ready. Если новый процесс завершился раньше
+// или не успел до отмены ctx, он останавливается, а сокеты остаются только у текущего процесса.
+func Upgrade(ctx context.Context, listeners map[string]net.Listener) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	names := make([]string, 0, len(listeners))
+	for name := range listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+
+	for _, name := range names {
+		l, ok := listeners[name].(fileListener)
+		if !ok {
+			return 0, fmt.Errorf("socket %s cannot be passed to another process", name)
+		}
+
+		file, err := l.File()
+		if err != nil {
+			return 0, fmt.Errorf("socket %s: %w", name, err)
+		}
+		files = append(files, file)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(os.Environ(),
+		listenersEnv+"="+strings.Join(names, ","),
+		readyEnv+"="+strconv.Itoa(3+len(files)),
+	)
+
+	err = cmd.Start()
+	_ = readyW.Close() // иначе чтение не увидит EOF, если новый процесс упадёт
+	if err != nil {
+		return 0, err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := readyR.Read(buf); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = fmt.Errorf("the new process exited before it was ready")
+			}
+			ready <- err
+
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return 0, err
+	}
+
+	// Файлы unix-сокетов теперь нужны новому процессу, закрытие здесь не должно их удалять
+	for _, listener := range listeners {
+		if unix, ok := listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+	}
+
+	return cmd.Process.Pid, nil
+}
@@ -0,0 +1,76 @@
+//go:build !windows
+
+package handoff
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const childEnv = "HANDOFF_TEST_CHILD"
+
+// TestMain в дочернем процессе, запущенном через Upgrade, играет роль нового сервера:
+// принимает унаследованный сокет, сообщает о готовности и отвечает на одно соединение.
+func TestMain(m *testing.M) {
+	if os.Getenv(childEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	listener, err := Listener("main")
+	if err != nil || listener == nil {
+		os.Exit(2)
+	}
+
+	if err = Ready(); err != nil {
+		os.Exit(3)
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		os.Exit(4)
+	}
+
+	_, _ = conn.Write([]byte("child\n"))
+	_ = conn.Close()
+	os.Exit(0)
+}
+
+func TestUpgrade(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Setenv(childEnv, "1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pid, err := Upgrade(ctx, map[string]net.Listener{"main": listener})
+	require.NoError(t, err)
+	assert.NotEqual(t, os.Getpid(), pid)
+
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "child\n", line)
+}
+
+func TestListener_notInherited(t *testing.T) {
+	assert.False(t, Inherited())
+
+	listener, err := Listener("main")
+	require.NoError(t, err)
+	assert.Nil(t, listener)
+}
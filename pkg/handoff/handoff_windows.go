@@ -0,0 +1,24 @@
+//go:build windows
+
+package handoff
+
+import (
+	"context"
+	"net"
+)
+
+func inherit() (map[string]net.Listener, error) {
+	return nil, nil
+}
+
+func Inherited() bool {
+	return false
+}
+
+func Ready() error {
+	return nil
+}
+
+func Upgrade(_ context.Context, _ map[string]net.Listener) (int, error) {
+	return 0, ErrNotSupported
+}
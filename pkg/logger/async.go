@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type (
+	// asyncCore пишет записи в фоне через ограниченную очередь, чтобы медленный диск или сеть не тормозили
+	// вызывающий код. Если очередь заполнена, запись отбрасывается, а позже пишется их количество.
+	asyncCore struct {
+		zapcore.Core
+		queue *asyncQueue
+	}
+
+	asyncQueue struct {
+		core    zapcore.Core
+		ch      chan func()
+		dropped atomic.Int64
+	}
+)
+
+func newAsyncCore(core zapcore.Core, size int) *asyncCore {
+	q := &asyncQueue{core: core, ch: make(chan func(), size)}
+	go q.run()
+
+	return &asyncCore{Core: core, queue: q}
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &asyncCore{Core: c.Core.With(fields), queue: c.queue}
+}
+
+func (c *asyncCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *asyncCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	// Panic и Fatal завершают работу: пишем их сразу, после всего, что уже в очереди
+	if entry.Level > zapcore.ErrorLevel {
+		c.queue.flush()
+		return c.Core.Write(entry, fields)
+	}
+
+	select {
+	case c.queue.ch <- func() { _ = c.Core.Write(entry, fields) }:
+	default:
+		c.queue.dropped.Add(1)
+	}
+
+	return nil
+}
+
+func (c *asyncCore) Sync() error {
+	c.queue.flush()
+	return c.Core.Sync()
+}
+
+func (q *asyncQueue) run() {
+	for fn := range q.ch {
+		fn()
+
+		if dropped := q.dropped.Swap(0); dropped > 0 {
+			_ = q.core.Write(zapcore.Entry{
+				Level:   zapcore.WarnLevel,
+				Time:    time.Now(),
+				Message: fmt.Sprintf("%d log entries were dropped: the async log queue is full", dropped),
+			}, nil)
+		}
+	}
+}
+
+// flush ждёт, пока будут записаны все записи, поставленные в очередь до вызова.
+func (q *asyncQueue) flush() {
+	done := make(chan struct{})
+	q.ch <- func() { close(done) }
+	<-done
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// blockingCore ждёт разрешения на каждую запись, изображая медленный приёмник.
+type blockingCore struct {
+	zapcore.Core
+	release chan struct{}
+}
+
+func (c *blockingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *blockingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	<-c.release
+	return c.Core.Write(entry, fields)
+}
+
+func TestAsync(t *testing.T) {
+	observed, logs := observer.New(zap.DebugLevel)
+	slow := &blockingCore{Core: observed, release: make(chan struct{})}
+
+	core := newAsyncCore(slow, 2)
+	log := Wrap(zap.New(core).Sugar())
+
+	// Первая запись забирается воркером и ждёт, две ложатся в очередь, остальные отбрасываются
+	log.Infof("first")
+	require.Eventually(t, func() bool { return len(core.queue.ch) == 0 }, time.Second, time.Millisecond)
+	for _, msg := range []string{"second", "third", "fourth", "fifth"} {
+		log.Infof(msg)
+	}
+	assert.Zero(t, logs.Len(), "writes must not wait for the slow sink")
+
+	close(slow.release)
+	require.NoError(t, log.Sync())
+
+	var messages []string
+	for _, entry := range logs.AllUntimed() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"first", "2 log entries were dropped: the async log queue is full", "second", "third"}, messages)
+}
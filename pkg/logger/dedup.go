@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+var digitsRe = regexp.MustCompile(`\d+`)
+
+type (
+	// dedupCore схлопывает повторяющиеся предупреждения и ошибки: первая запись за окно пишется как есть,
+	// остальные только считаются, а по истечении окна пишется последняя из них с числом повторов.
+	dedupCore struct {
+		zapcore.Core
+		state *dedupState
+	}
+
+	dedupState struct {
+		window time.Duration
+		clock  clock.Clock
+
+		mx   sync.Mutex
+		seen map[string]*dedupEntry
+	}
+
+	dedupEntry struct {
+		core       zapcore.Core
+		entry      zapcore.Entry
+		fields     []zapcore.Field
+		first      time.Time
+		suppressed int
+	}
+)
+
+func newDedupCore(core zapcore.Core, window time.Duration, clk clock.Clock) *dedupCore {
+	state := &dedupState{window: window, clock: clk, seen: make(map[string]*dedupEntry)}
+	go state.run()
+
+	return &dedupCore{Core: core, state: state}
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *dedupCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *dedupCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	// Panic и Fatal завершают работу, их нельзя откладывать
+	if entry.Level < zapcore.WarnLevel || entry.Level > zapcore.ErrorLevel {
+		return c.Core.Write(entry, fields)
+	}
+
+	// Номера попыток, задержки и прочие числа не делают ошибку новой
+	key := fmt.Sprintf("%s\x00%s\x00%s", entry.Level, entry.LoggerName, digitsRe.ReplaceAllString(entry.Message, "#"))
+
+	s := c.state
+	s.mx.Lock()
+
+	now := s.clock.Now()
+	if e, ok := s.seen[key]; ok && now.Sub(e.first) < s.window {
+		e.core, e.entry, e.fields = c.Core, entry, fields
+		e.suppressed++
+		s.mx.Unlock()
+
+		return nil
+	}
+
+	previous := s.seen[key]
+	s.seen[key] = &dedupEntry{core: c.Core, entry: entry, fields: fields, first: now}
+	s.mx.Unlock()
+
+	if err := s.summarize(previous); err != nil {
+		return err
+	}
+
+	return c.Core.Write(entry, fields)
+}
+
+func (c *dedupCore) Sync() error {
+	if err := c.state.flush(true); err != nil {
+		return err
+	}
+
+	return c.Core.Sync()
+}
+
+func (s *dedupState) run() {
+	ticker := s.clock.NewTicker(s.window)
+	defer ticker.Stop()
+
+	for range ticker.C() {
+		_ = s.flush(false) // Писать ошибку записи лога некуда
+	}
+}
+
+// flush пишет сводки по записям, окно которых истекло, а при all - по всем.
+func (s *dedupState) flush(all bool) error {
+	s.mx.Lock()
+
+	var expired []*dedupEntry
+
+	now := s.clock.Now()
+	for key, e := range s.seen {
+		if all || now.Sub(e.first) >= s.window {
+			expired = append(expired, e)
+			delete(s.seen, key)
+		}
+	}
+	s.mx.Unlock()
+
+	var err error
+	for _, e := range expired {
+		if summaryErr := s.summarize(e); summaryErr != nil {
+			err = summaryErr
+		}
+	}
+
+	return err
+}
+
+func (s *dedupState) summarize(e *dedupEntry) error {
+	if e == nil || e.suppressed == 0 {
+		return nil
+	}
+
+	entry := e.entry
+	entry.Message = fmt.Sprintf("%s (repeated %d times in the last %s)", entry.Message, e.suppressed, s.window)
+
+	return e.core.Write(entry, e.fields)
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+func TestDedup(t *testing.T) {
+	observed, logs := observer.New(zap.DebugLevel)
+
+	clk := clock.NewFake(time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC))
+	core := newDedupCore(observed, time.Minute, clk)
+
+	log := Wrap(zap.New(core).Sugar())
+
+	for attempt := 1; attempt <= 4; attempt++ {
+		log.Errorf("Failed to execute GetAll (attempt %d): connection refused", attempt)
+		log.Infof("Request %d", attempt)
+	}
+	log.Errorf("Another error")
+
+	var messages []string
+	for _, entry := range logs.AllUntimed() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{
+		"Failed to execute GetAll (attempt 1): connection refused",
+		"Request 1", "Request 2", "Request 3", "Request 4",
+		"Another error",
+	}, messages)
+
+	// Сводку пишет тикер окна
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	require.Eventually(t, func() bool { return logs.Len() == 7 }, time.Second, time.Millisecond)
+
+	last := logs.AllUntimed()[logs.Len()-1]
+	assert.Equal(t, "Failed to execute GetAll (attempt 4): connection refused (repeated 3 times in the last 1m0s)", last.Message)
+	assert.Equal(t, 7, logs.Len(), "entries without repeats must not produce a summary")
+
+	log.Errorf("Failed to execute GetAll (attempt 5): connection refused")
+	assert.Equal(t, 8, logs.Len(), "a new window must start with a full entry")
+}
@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type Encoding string
+
+const (
+	EncodingConsole Encoding = ""
+	EncodingJSON    Encoding = "json"
+)
+
+func ParseEncoding(s string) (Encoding, error) {
+	switch enc := Encoding(strings.ToLower(s)); enc {
+	case EncodingConsole, "console":
+		return EncodingConsole, nil
+	case EncodingJSON:
+		return enc, nil
+	default:
+		return "", fmt.Errorf("unknown log encoding %q: expected console or json", s)
+	}
+}
+
+// ParseTimeFormat понимает iso8601, rfc3339, rfc3339nano, epoch, millis, nanos или layout из пакета time.
+func ParseTimeFormat(s string) (zapcore.TimeEncoder, error) {
+	switch strings.ToLower(s) {
+	case "", "iso8601":
+		return zapcore.ISO8601TimeEncoder, nil
+	case "rfc3339":
+		return zapcore.RFC3339TimeEncoder, nil
+	case "rfc3339nano":
+		return zapcore.RFC3339NanoTimeEncoder, nil
+	case "epoch":
+		return zapcore.EpochTimeEncoder, nil
+	case "millis":
+		return zapcore.EpochMillisTimeEncoder, nil
+	case "nanos":
+		return zapcore.EpochNanosTimeEncoder, nil
+	}
+
+	if !strings.ContainsAny(s, "0123456789") {
+		return nil, fmt.Errorf("unknown log time format %q: expected iso8601, rfc3339, rfc3339nano, epoch, millis, nanos or a time layout", s)
+	}
+
+	return zapcore.TimeEncoderOfLayout(s), nil
+}
+
+// newEncoder собирает энкодер для stderr и файла. Цветные уровни включаются только для консоли в терминале.
+func (opts Options) newEncoder(colored bool) (zapcore.Encoder, error) {
+	cfg, err := opts.encoderConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Encoding == EncodingJSON {
+		cfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+		return zapcore.NewJSONEncoder(cfg), nil
+	}
+
+	if colored {
+		cfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+
+	return zapcore.NewConsoleEncoder(cfg), nil
+}
+
+// newSinkEncoder собирает энкодер тела сообщения для syslog/journald: время и уровень проставляет приёмник.
+func (opts Options) newSinkEncoder() (zapcore.Encoder, error) {
+	cfg, err := opts.encoderConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.TimeKey = ""
+	cfg.LevelKey = ""
+
+	if opts.Encoding == EncodingJSON {
+		return zapcore.NewJSONEncoder(cfg), nil
+	}
+
+	return zapcore.NewConsoleEncoder(cfg), nil
+}
+
+func (opts Options) encoderConfig() (zapcore.EncoderConfig, error) {
+	cfg := zap.NewDevelopmentEncoderConfig()
+	if opts.Encoding == EncodingJSON {
+		cfg = zap.NewProductionEncoderConfig()
+	}
+
+	timeEncoder, err := ParseTimeFormat(opts.TimeFormat)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.EncodeTime = timeEncoder
+
+	return cfg, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONEncoding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+
+	log, err := New(Options{File: path, Encoding: EncodingJSON, TimeFormat: "2006-01-02"})
+	require.NoError(t, err)
+
+	log.Named("storage").With("backend", "file").Infof("saved %d metrics", 3)
+	require.NoError(t, log.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &entry))
+
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "storage", entry["logger"])
+	assert.Equal(t, "saved 3 metrics", entry["msg"])
+	assert.Equal(t, "file", entry["backend"])
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, entry["ts"])
+}
+
+func TestParseTimeFormat(t *testing.T) {
+	for _, format := range []string{"", "iso8601", "RFC3339", "rfc3339nano", "epoch", "millis", "nanos", "15:04:05.000"} {
+		_, err := ParseTimeFormat(format)
+		require.NoError(t, err, format)
+	}
+
+	_, err := ParseTimeFormat("unix")
+	require.Error(t, err)
+
+	_, err = ParseEncoding("logfmt")
+	require.Error(t, err)
+}
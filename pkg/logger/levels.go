@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels задаёт уровни логирования по именам логгеров (см. Logger.Named). Пустое имя - уровень по умолчанию.
+// Имя действует и на дочерние логгеры: "storage" покрывает "storage.dbstorage".
+type Levels map[string]zapcore.Level
+
+// ParseLevels разбирает строку вида "info,dbstorage=debug,handlers=warn".
+func ParseLevels(s string) (Levels, error) {
+	levels := make(Levels)
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			name, value = "", part
+		}
+
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", part, err)
+		}
+
+		levels[strings.TrimSpace(name)] = level
+	}
+
+	return levels, nil
+}
+
+func (l Levels) levelFor(name string) zapcore.Level {
+	for {
+		if level, ok := l[name]; ok {
+			return level
+		}
+
+		if name == "" {
+			return zapcore.DebugLevel
+		}
+
+		if idx := strings.LastIndexByte(name, '.'); idx >= 0 {
+			name = name[:idx]
+		} else {
+			name = ""
+		}
+	}
+}
+
+func (l Levels) min() zapcore.Level {
+	lowest := l.levelFor("")
+	for _, level := range l {
+		if level < lowest {
+			lowest = level
+		}
+	}
+
+	return lowest
+}
+
+// levelCore отбрасывает записи ниже уровня, заданного для их логгера.
+type levelCore struct {
+	zapcore.Core
+	levels Levels
+	min    zapcore.Level
+}
+
+func newLevelCore(core zapcore.Core, levels Levels) *levelCore {
+	return &levelCore{Core: core, levels: levels, min: levels.min()}
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return level >= c.min && c.Core.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels, min: c.min}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levels.levelFor(entry.LoggerName) {
+		return checked
+	}
+
+	return c.Core.Check(entry, checked)
+}
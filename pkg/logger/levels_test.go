@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLevels(t *testing.T) {
+	levels, err := ParseLevels("warn, dbstorage=debug,handlers=info")
+	require.NoError(t, err)
+	assert.Equal(t, Levels{"": zapcore.WarnLevel, "dbstorage": zapcore.DebugLevel, "handlers": zapcore.InfoLevel}, levels)
+
+	observed, logs := observer.New(zap.DebugLevel)
+	log := Wrap(zap.New(newLevelCore(observed, levels)).Sugar())
+
+	log.Named("dbstorage").Debugf("dbstorage debug")
+	log.Named("dbstorage").Named("tx").With("backend", "database").Debugf("dbstorage child debug")
+	log.Named("handlers").Debugf("handlers debug")
+	log.Named("handlers").Infof("handlers info")
+	log.Named("collector").Infof("collector info")
+	log.Infof("root info")
+	log.Errorf("root error")
+
+	var messages []string
+	for _, entry := range logs.AllUntimed() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"dbstorage debug", "dbstorage child debug", "handlers info", "root error"}, messages)
+
+	_, err = ParseLevels("dbstorage=verbose")
+	require.Error(t, err)
+}
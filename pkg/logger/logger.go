@@ -1,15 +1,24 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"fmt"
+	"os"
 
-func New() (Logger, error) {
-	l, err := zap.NewDevelopment()
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+// New собирает логгер по опциям. Нулевые Options дают прежний development-логгер в stderr.
+func New(opts Options) (Logger, error) {
+	core, err := newCore(opts)
 	if err != nil {
 		return nil, err
 	}
-	l.Core().Enabled(zap.DebugLevel)
 
-	return l.Sugar(), nil
+	l := zap.New(core, zap.Development(), zap.AddCaller(), zap.AddStacktrace(zap.WarnLevel))
+	return Wrap(l.Sugar()), nil
 }
 
 type Logger interface {
@@ -18,4 +27,81 @@ type Logger interface {
 	Panicf(template string, args ...interface{})
 	Debugf(template string, args ...interface{})
 	Sync() error
+
+	// With добавляет к каждой записи структурированные поля, переданные парами ключ-значение.
+	With(args ...interface{}) Logger
+	// Named добавляет к имени логгера ещё один сегмент.
+	Named(name string) Logger
+}
+
+type zapLogger struct {
+	*zap.SugaredLogger
+}
+
+// Wrap приводит логгер zap к Logger.
+func Wrap(l *zap.SugaredLogger) Logger {
+	return zapLogger{SugaredLogger: l}
+}
+
+func (l zapLogger) With(args ...interface{}) Logger {
+	return zapLogger{SugaredLogger: l.SugaredLogger.With(args...)}
+}
+
+func (l zapLogger) Named(name string) Logger {
+	return zapLogger{SugaredLogger: l.SugaredLogger.Named(name)}
+}
+
+func newCore(opts Options) (zapcore.Core, error) {
+	if opts.AsyncBuffer < 0 {
+		return nil, fmt.Errorf("invalid log async buffer %d: must not be negative", opts.AsyncBuffer)
+	}
+
+	var cores []zapcore.Core
+
+	output, err := opts.output()
+	if err != nil {
+		return nil, err
+	}
+
+	if output != nil {
+		encoder, err := opts.newEncoder(opts.File == "" && isTerminal(os.Stderr))
+		if err != nil {
+			return nil, err
+		}
+
+		cores = append(cores, zapcore.NewCore(encoder, output, zap.DebugLevel))
+	}
+
+	sink, err := opts.sinkCore()
+	if err != nil {
+		return nil, err
+	}
+
+	if sink != nil {
+		cores = append(cores, sink)
+	}
+
+	if opts.ErrorTrackerDSN != "" {
+		tracker, err := newTrackerCore(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		cores = append(cores, tracker)
+	}
+
+	core := zapcore.NewTee(cores...)
+	if opts.AsyncBuffer > 0 {
+		core = newAsyncCore(core, opts.AsyncBuffer)
+	}
+
+	if opts.DedupWindow > 0 {
+		core = newDedupCore(core, opts.DedupWindow, clock.Or(opts.Clock))
+	}
+
+	if len(opts.Levels) > 0 {
+		core = newLevelCore(core, opts.Levels)
+	}
+
+	return core, nil
 }
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithNamed(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	log := Wrap(zap.New(core).Sugar())
+
+	log.Named("storage").With("backend", "database").Named("dbstorage").Errorf("failed: %d", 1)
+	log.Infof("plain")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "storage.dbstorage", entries[0].LoggerName)
+	assert.Equal(t, "failed: 1", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"backend": "database"}, entries[0].ContextMap())
+
+	assert.Empty(t, entries[1].LoggerName)
+	assert.Empty(t, entries[1].Context)
+}
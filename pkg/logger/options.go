@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+type (
+	Options struct {
+		// Levels - уровни по логгерам. Без уровня по умолчанию пишется всё, начиная с debug.
+		Levels Levels
+
+		// File - путь к файлу логов. Пустая строка - писать в stderr.
+		File     string
+		Rotation Rotation
+
+		// Encoding - формат записей: console (по умолчанию) или json.
+		Encoding Encoding
+		// TimeFormat - формат времени, см. ParseTimeFormat. По умолчанию ISO8601.
+		TimeFormat string
+
+		// Sink - внешний приёмник логов (syslog или journald). Если задан, в stderr логи не пишутся.
+		Sink Sink
+		// SyslogAddress - адрес вида udp://host:514 или unix:///dev/log. Пустой - локальный syslog.
+		SyslogAddress string
+		// JournaldSocket переопределяет путь к сокету journald.
+		JournaldSocket string
+		// Tag - идентификатор программы в syslog/journald. По умолчанию имя исполняемого файла.
+		Tag string
+
+		// ErrorTrackerDSN - DSN Sentry или URL, куда отправляются записи уровня error и выше. Пустой - не отправлять.
+		ErrorTrackerDSN string
+
+		// AsyncBuffer - размер очереди фоновой записи логов. 0 - писать синхронно.
+		AsyncBuffer int
+
+		// DedupWindow - окно, в котором одинаковые предупреждения и ошибки схлопываются в одну запись
+		// с числом повторов. 0 отключает схлопывание.
+		DedupWindow time.Duration
+
+		// Clock используется для ротации и окна схлопывания. Если не задан, используются реальные часы.
+		Clock clock.Clock
+	}
+
+	// Rotation описывает ротацию файла логов. Нулевое значение отключает ротацию.
+	Rotation struct {
+		MaxSize    int           // Размер файла в мегабайтах, после которого он ротируется
+		MaxBackups int           // Сколько старых файлов хранить (0 - все)
+		MaxAge     time.Duration // Сколько хранить старые файлы (0 - без ограничения)
+		Compress   bool          // Сжимать старые файлы gzip
+	}
+)
+
+// output возвращает stderr или файл логов. Если логи уходят только во внешний приёмник, возвращает nil.
+func (opts Options) output() (zapcore.WriteSyncer, error) {
+	r := opts.Rotation
+	if r.MaxSize < 0 || r.MaxBackups < 0 || r.MaxAge < 0 {
+		return nil, fmt.Errorf("invalid log rotation %+v: values must not be negative", r)
+	}
+
+	if opts.File == "" {
+		if opts.Sink != SinkStderr {
+			return nil, nil
+		}
+
+		return zapcore.Lock(os.Stderr), nil
+	}
+
+	file, err := newRotatingFile(opts.File, r, clock.Or(opts.Clock))
+	if err != nil {
+		return nil, err
+	}
+
+	return zapcore.AddSync(file), nil
+}
@@ -0,0 +1,251 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+const (
+	megabyte         = 1024 * 1024
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// rotatingFile пишет логи в файл и, когда он дорастает до MaxSize, переименовывает его в
+// <name>-<время><ext> и открывает новый. Старые файлы сжимаются и удаляются в фоне.
+type rotatingFile struct {
+	path     string
+	rotation Rotation
+
+	mx   sync.Mutex
+	file *os.File
+	size int64
+
+	millOnce sync.Once
+	millCh   chan struct{}
+
+	clock clock.Clock
+}
+
+func newRotatingFile(path string, rotation Rotation, clk clock.Clock) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	r := &rotatingFile{path: path, rotation: rotation, clock: clk}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if limit := int64(r.rotation.MaxSize) * megabyte; limit > 0 && r.size > 0 && r.size+int64(len(p)) > limit {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+
+	return n, err
+}
+
+func (r *rotatingFile) Sync() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	return r.file.Sync()
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(err, file.Close())
+	}
+
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(r.path, r.backupName(r.clock.Now())); err != nil {
+		return err
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.millOnce.Do(func() {
+		r.millCh = make(chan struct{}, 1)
+		go r.millLoop()
+	})
+
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (r *rotatingFile) backupName(t time.Time) string {
+	dir, base := filepath.Split(r.path)
+	ext := filepath.Ext(base)
+
+	return filepath.Join(dir, strings.TrimSuffix(base, ext)+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+func (r *rotatingFile) millLoop() {
+	for range r.millCh {
+		_ = r.mill() // Ошибки обслуживания старых файлов некуда писать, кроме самого лога
+	}
+}
+
+type backup struct {
+	path string
+	time time.Time
+}
+
+// mill сжимает старые файлы и удаляет те, что не проходят по MaxBackups и MaxAge.
+func (r *rotatingFile) mill() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var (
+		errs   []error
+		remove []backup
+	)
+
+	if r.rotation.MaxBackups > 0 && len(backups) > r.rotation.MaxBackups {
+		remove = append(remove, backups[r.rotation.MaxBackups:]...)
+		backups = backups[:r.rotation.MaxBackups]
+	}
+
+	if r.rotation.MaxAge > 0 {
+		threshold := r.clock.Now().Add(-r.rotation.MaxAge)
+
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.time.Before(threshold) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	for _, b := range remove {
+		if err = os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	if r.rotation.Compress {
+		for _, b := range backups {
+			if strings.HasSuffix(b.path, compressSuffix) {
+				continue
+			}
+
+			if err = compressFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// backups возвращает старые файлы логов от новых к старым.
+func (r *rotatingFile) backups() ([]backup, error) {
+	dir, base := filepath.Split(r.path)
+	if dir == "" {
+		dir = "."
+	}
+
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressSuffix), ext)
+
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backup{path: filepath.Join(dir, name), time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	return backups, nil
+}
+
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return errors.Join(err, gz.Close(), dst.Close(), os.Remove(path+compressSuffix))
+	}
+
+	if err = errors.Join(gz.Close(), dst.Close()); err != nil {
+		return errors.Join(err, os.Remove(path+compressSuffix))
+	}
+
+	if err = src.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+
+	clk := clock.NewFake(time.Date(2023, time.October, 1, 12, 0, 0, 0, time.Local))
+	r, err := newRotatingFile(path, Rotation{MaxSize: 1, MaxBackups: 2, Compress: true}, clk)
+	require.NoError(t, err)
+
+	line := bytes.Repeat([]byte("x"), megabyte/2+1)
+	for i := 0; i < 5; i++ {
+		clk.Advance(time.Second)
+		_, err = r.Write(line)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Sync())
+
+	require.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(dir, "server-*.log*"))
+		compressed, _ := filepath.Glob(filepath.Join(dir, "server-*.log.gz"))
+		return len(matches) == 2 && len(compressed) == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(line)), info.Size())
+
+	// Старые файлы сверх MaxAge удаляются
+	r.rotation.MaxAge = time.Nanosecond
+	clk.Advance(time.Second)
+	require.NoError(t, r.mill())
+
+	matches, err := filepath.Glob(filepath.Join(dir, "server-*"))
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestNewWithFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "agent.log")
+
+	log, err := New(Options{File: path})
+	require.NoError(t, err)
+
+	log.Named("agent").With("attempt", 2).Infof("hello %s", "world")
+	require.NoError(t, log.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "agent")
+	assert.Contains(t, string(data), "hello world")
+	assert.Contains(t, string(data), `{"attempt": 2}`)
+
+	_, err = New(Options{File: path, Rotation: Rotation{MaxSize: -1}})
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type Sink string
+
+const (
+	SinkStderr   Sink = ""
+	SinkSyslog   Sink = "syslog"
+	SinkJournald Sink = "journald"
+)
+
+func ParseSink(s string) (Sink, error) {
+	switch sink := Sink(strings.ToLower(s)); sink {
+	case SinkStderr, "stderr":
+		return SinkStderr, nil
+	case SinkSyslog, SinkJournald:
+		return sink, nil
+	default:
+		return "", fmt.Errorf("unknown log sink %q: expected stderr, syslog or journald", s)
+	}
+}
+
+// Уровни важности syslog (RFC 5424), их же понимает journald.
+const (
+	priorityEmerg   = 0
+	priorityCrit    = 2
+	priorityErr     = 3
+	priorityWarning = 4
+	priorityInfo    = 6
+	priorityDebug   = 7
+)
+
+func levelPriority(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return priorityDebug
+	case zapcore.InfoLevel:
+		return priorityInfo
+	case zapcore.WarnLevel:
+		return priorityWarning
+	case zapcore.ErrorLevel:
+		return priorityErr
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return priorityCrit
+	default:
+		return priorityEmerg
+	}
+}
+
+// priorityCore отдаёт каждую запись во внешний приёмник вместе с её уровнем важности.
+type priorityCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	write func(entry zapcore.Entry, priority int, message string) error
+}
+
+func newPriorityCore(opts Options, level zapcore.LevelEnabler, write func(zapcore.Entry, int, string) error) (*priorityCore, error) {
+	enc, err := opts.newSinkEncoder()
+	if err != nil {
+		return nil, err
+	}
+
+	return &priorityCore{LevelEnabler: level, enc: enc, write: write}, nil
+}
+
+func (c *priorityCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(clone.enc)
+	}
+
+	return &clone
+}
+
+func (c *priorityCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *priorityCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	return c.write(entry, levelPriority(entry.Level), strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func (c *priorityCore) Sync() error {
+	return nil
+}
+
+func (opts Options) tag() string {
+	if opts.Tag != "" {
+		return opts.Tag
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+func (opts Options) sinkCore() (zapcore.Core, error) {
+	switch opts.Sink {
+	case SinkSyslog:
+		return newSyslogCore(opts)
+	case SinkJournald:
+		return newJournaldCore(opts)
+	case SinkStderr:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown log sink %q", opts.Sink)
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+func newJournaldCore(opts Options) (zapcore.Core, error) {
+	socket := opts.JournaldSocket
+	if socket == "" {
+		socket = journaldSocket
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	tag := opts.tag()
+	return newPriorityCore(opts, zap.DebugLevel, func(entry zapcore.Entry, priority int, message string) error {
+		var buf bytes.Buffer
+
+		writeJournaldField(&buf, "PRIORITY", strconv.Itoa(priority))
+		writeJournaldField(&buf, "SYSLOG_IDENTIFIER", tag)
+		writeJournaldField(&buf, "MESSAGE", message)
+
+		if entry.LoggerName != "" {
+			writeJournaldField(&buf, "LOGGER", entry.LoggerName)
+		}
+
+		if entry.Caller.Defined {
+			writeJournaldField(&buf, "CODE_FILE", entry.Caller.File)
+			writeJournaldField(&buf, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+			writeJournaldField(&buf, "CODE_FUNC", entry.Caller.Function)
+		}
+
+		_, err := conn.Write(buf.Bytes())
+		return err
+	})
+}
+
+// writeJournaldField пишет поле в нативном протоколе journald. Многострочные значения
+// передаются в бинарном виде: имя, перевод строки, длина (uint64 LE) и сами данные.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+
+		return
+	}
+
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
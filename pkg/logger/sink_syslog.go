@@ -0,0 +1,44 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogCore(opts Options) (zapcore.Core, error) {
+	var network, address string
+	if opts.SyslogAddress != "" {
+		var ok bool
+		if network, address, ok = strings.Cut(opts.SyslogAddress, "://"); !ok {
+			return nil, fmt.Errorf("invalid syslog address %q: expected network://address", opts.SyslogAddress)
+		}
+	}
+
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_DAEMON, opts.tag())
+	if err != nil {
+		return nil, err
+	}
+
+	return newPriorityCore(opts, zap.DebugLevel, func(_ zapcore.Entry, priority int, message string) error {
+		switch priority {
+		case priorityDebug:
+			return w.Debug(message)
+		case priorityInfo:
+			return w.Info(message)
+		case priorityWarning:
+			return w.Warning(message)
+		case priorityErr:
+			return w.Err(message)
+		case priorityCrit:
+			return w.Crit(message)
+		default:
+			return w.Emerg(message)
+		}
+	})
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func newSyslogCore(_ Options) (zapcore.Core, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,86 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournaldSink(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	log, err := New(Options{Sink: SinkJournald, JournaldSocket: socket, Tag: "metrics-server"})
+	require.NoError(t, err)
+
+	log.Named("storage").Errorf("first line\nsecond line")
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	datagram := buf[:n]
+
+	assert.Contains(t, string(datagram), "PRIORITY=3\n")
+	assert.Contains(t, string(datagram), "SYSLOG_IDENTIFIER=metrics-server\n")
+	assert.Contains(t, string(datagram), "LOGGER=storage\n")
+
+	idx := bytes.Index(datagram, []byte("MESSAGE\n"))
+	require.GreaterOrEqual(t, idx, 0, "multiline message must use the binary format")
+
+	size := binary.LittleEndian.Uint64(datagram[idx+len("MESSAGE\n"):])
+	message := string(datagram[idx+len("MESSAGE\n")+8:][:size])
+	assert.Contains(t, message, "first line\nsecond line")
+}
+
+func TestSyslogSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	log, err := New(Options{Sink: SinkSyslog, SyslogAddress: "udp://" + conn.LocalAddr().String(), Tag: "metrics-agent"})
+	require.NoError(t, err)
+
+	log.Infof("hello")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// LOG_DAEMON (3) * 8 + LOG_INFO (6)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<30>"), string(buf[:n]))
+	assert.Contains(t, string(buf[:n]), "metrics-agent")
+	assert.Contains(t, string(buf[:n]), "hello")
+
+	_, err = New(Options{Sink: SinkSyslog, SyslogAddress: "localhost:514"})
+	require.Error(t, err)
+}
+
+func TestParseSink(t *testing.T) {
+	for _, s := range []string{"", "stderr", "STDERR"} {
+		sink, err := ParseSink(s)
+		require.NoError(t, err)
+		assert.Equal(t, SinkStderr, sink)
+	}
+
+	sink, err := ParseSink("journald")
+	require.NoError(t, err)
+	assert.Equal(t, SinkJournald, sink)
+
+	_, err = ParseSink("kafka")
+	require.Error(t, err)
+}
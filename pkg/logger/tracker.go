@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	trackerTimeout = time.Second * 5
+	trackerBuffer  = 100
+)
+
+type (
+	// trackerCore отправляет ошибки в Sentry или в произвольный HTTP-эндпоинт. DSN вида
+	// https://<key>@host/<project> считается Sentry, любой другой URL получает событие тем же JSON.
+	trackerCore struct {
+		zapcore.LevelEnabler
+
+		endpoint string
+		auth     string
+		tag      string
+		client   *http.Client
+
+		fields []zapcore.Field
+	}
+
+	trackerEvent struct {
+		EventID    string            `json:"event_id"`
+		Timestamp  string            `json:"timestamp"`
+		Level      string            `json:"level"`
+		Logger     string            `json:"logger,omitempty"`
+		Platform   string            `json:"platform"`
+		Message    string            `json:"message"`
+		ServerName string            `json:"server_name,omitempty"`
+		Culprit    string            `json:"culprit,omitempty"`
+		Tags       map[string]string `json:"tags,omitempty"`
+		Extra      map[string]any    `json:"extra,omitempty"`
+	}
+)
+
+func newTrackerCore(opts Options) (zapcore.Core, error) {
+	u, err := url.Parse(opts.ErrorTrackerDSN)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid error tracker dsn: expected http(s)://[key@]host/path")
+	}
+
+	core := &trackerCore{
+		LevelEnabler: zap.ErrorLevel,
+		endpoint:     u.String(),
+		tag:          opts.tag(),
+		client:       &http.Client{Timeout: trackerTimeout},
+	}
+
+	if key := u.User.Username(); key != "" {
+		project := strings.Trim(u.Path, "/")
+		if project == "" {
+			return nil, fmt.Errorf("invalid sentry dsn: project id is missing")
+		}
+
+		core.endpoint = fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project)
+		core.auth = fmt.Sprintf("Sentry sentry_version=7, sentry_client=go-metricts/1.0, sentry_key=%s", key)
+	}
+
+	return newAsyncCore(core, trackerBuffer), nil
+}
+
+func (c *trackerCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(append([]zapcore.Field(nil), c.fields...), fields...)
+
+	return &clone
+}
+
+func (c *trackerCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *trackerCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(entry.Level) {
+		return nil
+	}
+
+	extra := zapcore.NewMapObjectEncoder()
+	for _, field := range append(append([]zapcore.Field(nil), c.fields...), fields...) {
+		field.AddTo(extra)
+	}
+
+	if entry.Stack != "" {
+		extra.Fields["stacktrace"] = entry.Stack
+	}
+
+	event := trackerEvent{
+		EventID:   newEventID(),
+		Timestamp: entry.Time.UTC().Format(time.RFC3339),
+		Level:     trackerLevel(entry.Level),
+		Logger:    entry.LoggerName,
+		Platform:  "go",
+		Message:   entry.Message,
+		Tags:      map[string]string{"service": c.tag},
+		Extra:     extra.Fields,
+	}
+	event.ServerName, _ = os.Hostname()
+
+	if entry.Caller.Defined {
+		event.Culprit = entry.Caller.Function
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.auth != "" {
+		req.Header.Set("X-Sentry-Auth", c.auth)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("error tracker responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (c *trackerCore) Sync() error {
+	return nil
+}
+
+func trackerLevel(level zapcore.Level) string {
+	if level > zapcore.ErrorLevel {
+		return "fatal"
+	}
+
+	return "error"
+}
+
+func newEventID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorTracker(t *testing.T) {
+	var (
+		paths  []string
+		auth   string
+		events []trackerEvent
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event trackerEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		paths = append(paths, r.URL.Path)
+		auth = r.Header.Get("X-Sentry-Auth")
+		events = append(events, event)
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://public-key@", 1) + "/42"
+
+	log, err := New(Options{File: t.TempDir() + "/server.log", ErrorTrackerDSN: dsn, Tag: "metrics-server"})
+	require.NoError(t, err)
+
+	log.Named("handlers").With("request_id", "req-42").Infof("not forwarded")
+	log.Named("handlers").With("request_id", "req-42").Errorf("Failed to save metrics: %s", "timeout")
+	require.NoError(t, log.Sync())
+
+	require.Len(t, events, 1)
+	assert.Equal(t, []string{"/api/42/store/"}, paths)
+	assert.Contains(t, auth, "sentry_key=public-key")
+
+	event := events[0]
+	assert.Len(t, event.EventID, 32)
+	assert.Equal(t, "error", event.Level)
+	assert.Equal(t, "handlers", event.Logger)
+	assert.Equal(t, "Failed to save metrics: timeout", event.Message)
+	assert.Equal(t, "metrics-server", event.Tags["service"])
+	assert.Equal(t, "req-42", event.Extra["request_id"])
+	assert.Contains(t, event.Extra["stacktrace"], "TestErrorTracker")
+
+	_, err = New(Options{ErrorTrackerDSN: "sentry.io/42"})
+	require.Error(t, err)
+}
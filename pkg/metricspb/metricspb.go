@@ -0,0 +1,174 @@
+// Package metricspb кодирует метрики в protobuf по схеме metrics.proto. Сообщений всего два,
+// поэтому код написан вручную поверх protowire и не требует protoc.
+package metricspb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContentType - тип тела запросов и ответов в protobuf.
+const ContentType = "application/x-protobuf"
+
+const (
+	fieldID        protowire.Number = 1
+	fieldType      protowire.Number = 2
+	fieldDelta     protowire.Number = 3
+	fieldValue     protowire.Number = 4
+	fieldUpdatedAt protowire.Number = 5
+
+	fieldMetrics protowire.Number = 1
+)
+
+var ErrInvalidMessage = errors.New("invalid protobuf message")
+
+type Metric struct {
+	ID        string
+	Type      string
+	Delta     *int64
+	Value     *float64
+	UpdatedAt *time.Time
+}
+
+func Marshal(metric Metric) []byte {
+	return appendMetric(nil, metric)
+}
+
+func Unmarshal(b []byte) (Metric, error) {
+	var metric Metric
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return Metric{}, wireError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == fieldID && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			metric.ID, b = value, b[n:]
+		case num == fieldType && typ == protowire.BytesType:
+			value, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			metric.Type, b = value, b[n:]
+		case num == fieldDelta && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			delta := protowire.DecodeZigZag(value)
+			metric.Delta, b = &delta, b[n:]
+		case num == fieldValue && typ == protowire.Fixed64Type:
+			value, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			gauge := math.Float64frombits(value)
+			metric.Value, b = &gauge, b[n:]
+		case num == fieldUpdatedAt && typ == protowire.VarintType:
+			value, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			updatedAt := time.Unix(0, int64(value))
+			metric.UpdatedAt, b = &updatedAt, b[n:]
+		default:
+			// Неизвестные поля пропускаем, чтобы старый сервер понимал новых агентов
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return Metric{}, wireError(n)
+			}
+			b = b[n:]
+		}
+	}
+
+	return metric, nil
+}
+
+func MarshalList(metrics []Metric) []byte {
+	var b []byte
+	for _, metric := range metrics {
+		b = protowire.AppendTag(b, fieldMetrics, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendMetric(nil, metric))
+	}
+
+	return b
+}
+
+func UnmarshalList(b []byte) ([]Metric, error) {
+	metrics := make([]Metric, 0)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, wireError(n)
+		}
+		b = b[n:]
+
+		if num != fieldMetrics || typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return nil, wireError(n)
+			}
+			b = b[n:]
+
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, wireError(n)
+		}
+		b = b[n:]
+
+		metric, err := Unmarshal(value)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, nil
+}
+
+func appendMetric(b []byte, metric Metric) []byte {
+	if metric.ID != "" {
+		b = protowire.AppendTag(b, fieldID, protowire.BytesType)
+		b = protowire.AppendString(b, metric.ID)
+	}
+
+	if metric.Type != "" {
+		b = protowire.AppendTag(b, fieldType, protowire.BytesType)
+		b = protowire.AppendString(b, metric.Type)
+	}
+
+	if metric.Delta != nil {
+		b = protowire.AppendTag(b, fieldDelta, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeZigZag(*metric.Delta))
+	}
+
+	if metric.Value != nil {
+		b = protowire.AppendTag(b, fieldValue, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(*metric.Value))
+	}
+
+	if metric.UpdatedAt != nil {
+		b = protowire.AppendTag(b, fieldUpdatedAt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(metric.UpdatedAt.UnixNano()))
+	}
+
+	return b
+}
+
+func wireError(n int) error {
+	return fmt.Errorf("%w: %w", ErrInvalidMessage, protowire.ParseError(n))
+}
@@ -0,0 +1,63 @@
+package metricspb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestMarshal(t *testing.T) {
+	value := 1.5
+	encoded := Marshal(Metric{ID: "a", Type: "gauge", Value: &value})
+
+	// Сверяем с байтами, которые выдаёт protoc для той же схемы
+	assert.Equal(t, []byte{
+		0x0a, 0x01, 'a',
+		0x12, 0x05, 'g', 'a', 'u', 'g', 'e',
+		0x21, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f,
+	}, encoded)
+}
+
+func TestRoundTrip(t *testing.T) {
+	delta := int64(-5)
+	value := 123.456
+	updatedAt := time.Unix(1700000000, 123).UTC()
+
+	metrics := []Metric{
+		{ID: "PollCount", Type: "counter", Delta: &delta},
+		{ID: "Alloc", Type: "gauge", Value: &value, UpdatedAt: &updatedAt},
+	}
+
+	decoded, err := UnmarshalList(MarshalList(metrics))
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+
+	assert.Equal(t, metrics[0], decoded[0])
+	assert.Equal(t, "Alloc", decoded[1].ID)
+	assert.Equal(t, value, *decoded[1].Value)
+	assert.True(t, updatedAt.Equal(*decoded[1].UpdatedAt))
+}
+
+func TestUnmarshal(t *testing.T) {
+	// Неизвестное поле 15 пропускается
+	b := protowire.AppendTag(nil, 15, protowire.BytesType)
+	b = protowire.AppendString(b, "unknown")
+	b = append(b, Marshal(Metric{ID: "a", Type: "counter"})...)
+
+	metric, err := Unmarshal(b)
+	require.NoError(t, err)
+	assert.Equal(t, Metric{ID: "a", Type: "counter"}, metric)
+
+	_, err = Unmarshal([]byte{0x0a, 0x05, 'a'})
+	require.ErrorIs(t, err, ErrInvalidMessage)
+
+	_, err = UnmarshalList([]byte{0x0a, 0x05})
+	require.ErrorIs(t, err, ErrInvalidMessage)
+
+	metrics, err := UnmarshalList(nil)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
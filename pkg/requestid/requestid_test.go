@@ -0,0 +1,25 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 32)
+	assert.NotEqual(t, id, New())
+	assert.True(t, Valid(id))
+
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("with space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", maxLength+1)))
+
+	ctx := With(context.Background(), id)
+	assert.Equal(t, id, From(ctx))
+	assert.Empty(t, From(context.Background()))
+}
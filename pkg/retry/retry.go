@@ -0,0 +1,88 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+type Backoff string
+
+const (
+	Constant    Backoff = "constant"
+	Linear      Backoff = "linear"
+	Exponential Backoff = "exponential"
+)
+
+// Policy описывает повторы операции. Нулевое значение выполняет операцию ровно один раз.
+type Policy struct {
+	MaxAttempts     int
+	Backoff         Backoff
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+
+	// Retryable решает, стоит ли повторять операцию после ошибки. Если не задан, повторяются все ошибки.
+	Retryable func(err error) bool
+	OnRetry   func(attempt int, delay time.Duration, err error)
+
+	// Clock отсчитывает паузы между попытками. Если не задан, используются реальные часы.
+	Clock clock.Clock
+}
+
+func ParseBackoff(value string) (Backoff, error) {
+	switch backoff := Backoff(value); backoff {
+	case Constant, Linear, Exponential:
+		return backoff, nil
+	default:
+		return "", fmt.Errorf("unknown backoff %q: expected constant, linear or exponential", value)
+	}
+}
+
+// Delay возвращает паузу после неудачной попытки с номером attempt (начиная с 1).
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.InitialInterval
+
+	switch p.Backoff {
+	case Linear:
+		delay *= time.Duration(attempt)
+	case Exponential:
+		for i := 1; i < attempt && (p.MaxInterval <= 0 || delay < p.MaxInterval); i++ {
+			delay *= 2
+		}
+	}
+
+	if p.MaxInterval > 0 && delay > p.MaxInterval {
+		delay = p.MaxInterval
+	}
+
+	return delay
+}
+
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	clk := clock.Or(p.Clock)
+	start := clk.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+
+		delay := p.Delay(attempt)
+		if p.MaxElapsedTime > 0 && clk.Since(start)+delay > p.MaxElapsedTime {
+			return err
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+
+		if sleepErr := clock.Sleep(ctx, clk, delay); sleepErr != nil {
+			return errors.Join(err, sleepErr)
+		}
+	}
+}
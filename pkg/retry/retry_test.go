@@ -0,0 +1,157 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       Policy
+		wantedDelays []time.Duration
+	}{
+		{
+			name:         "Constant",
+			policy:       Policy{Backoff: Constant, InitialInterval: time.Second},
+			wantedDelays: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:         "Linear",
+			policy:       Policy{Backoff: Linear, InitialInterval: time.Second},
+			wantedDelays: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name:         "Exponential",
+			policy:       Policy{Backoff: Exponential, InitialInterval: time.Second},
+			wantedDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:         "Exponential with max interval",
+			policy:       Policy{Backoff: Exponential, InitialInterval: time.Second, MaxInterval: 3 * time.Second},
+			wantedDelays: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, delay := range tt.wantedDelays {
+				assert.Equal(t, delay, tt.policy.Delay(i+1))
+			}
+		})
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	tests := []struct {
+		name           string
+		policy         Policy
+		errs           []error
+		wantedAttempts int
+		wantedErr      error
+	}{
+		{
+			name:           "Zero policy",
+			errs:           []error{errTemporary, nil},
+			wantedAttempts: 1,
+			wantedErr:      errTemporary,
+		},
+		{
+			name:           "Success after retries",
+			policy:         Policy{MaxAttempts: 3},
+			errs:           []error{errTemporary, errTemporary, nil},
+			wantedAttempts: 3,
+		},
+		{
+			name:           "Attempts exhausted",
+			policy:         Policy{MaxAttempts: 2},
+			errs:           []error{errTemporary, errTemporary, nil},
+			wantedAttempts: 2,
+			wantedErr:      errTemporary,
+		},
+		{
+			name: "Not retryable error",
+			policy: Policy{MaxAttempts: 3, Retryable: func(err error) bool {
+				return !errors.Is(err, errPermanent)
+			}},
+			errs:           []error{errTemporary, errPermanent, nil},
+			wantedAttempts: 2,
+			wantedErr:      errPermanent,
+		},
+		{
+			name:           "Max elapsed time",
+			policy:         Policy{MaxAttempts: 3, InitialInterval: time.Hour, MaxElapsedTime: time.Minute},
+			errs:           []error{errTemporary, nil},
+			wantedAttempts: 1,
+			wantedErr:      errTemporary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			err := tt.policy.Do(context.Background(), func(_ context.Context) error {
+				attempts++
+				return tt.errs[attempts-1]
+			})
+
+			assert.Equal(t, tt.wantedAttempts, attempts)
+			assert.ErrorIs(t, err, tt.wantedErr)
+			if tt.wantedErr == nil {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := Policy{
+		MaxAttempts:     3,
+		InitialInterval: time.Hour,
+		OnRetry: func(_ int, _ time.Duration, _ error) {
+			cancel()
+		},
+	}
+
+	err := policy.Do(ctx, func(_ context.Context) error {
+		return errors.New("temporary")
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPolicyDoClock(t *testing.T) {
+	clk := clock.NewFake(time.Unix(0, 0))
+	policy := Policy{
+		MaxAttempts:     3,
+		Backoff:         Exponential,
+		InitialInterval: time.Hour,
+		Clock:           clk,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- policy.Do(context.Background(), func(_ context.Context) error {
+			return errors.New("temporary")
+		})
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(time.Hour)
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Hour)
+
+	require.Error(t, <-done)
+	assert.Equal(t, time.Unix(0, 0).Add(3*time.Hour), clk.Now())
+}
@@ -0,0 +1,138 @@
+// Package secrets подставляет секреты в настройки по ссылкам, чтобы сами секреты не попадали в аргументы процесса:
+//
+//	file:/run/secrets/key    - содержимое файла без завершающего перевода строки
+//	env:METRICS_KEY          - значение другой переменной окружения
+//	vault:secret/metrics#key - поле секрета из HashiCorp Vault (VAULT_ADDR, VAULT_TOKEN)
+//
+// Значение file:// ссылкой не считается: это DSN файлового хранилища.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Tag - тег поля настроек, значения которого можно задавать ссылкой.
+const Tag = "secret"
+
+var ErrNotFound = errors.New("secret not found")
+
+// VaultClient используется для запросов к Vault, его можно подменить в тестах.
+var VaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Resolve возвращает секрет по ссылке. Значения, которые не похожи на ссылку, возвращаются как есть.
+func Resolve(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "file:") && !strings.HasPrefix(value, "file://"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, name)
+		}
+		return resolved, nil
+	case strings.HasPrefix(value, "vault:"):
+		return fromVault(ctx, strings.TrimPrefix(value, "vault:"))
+	default:
+		return value, nil
+	}
+}
+
+// ResolveStruct подставляет секреты во все строковые поля структуры по указателю settings, отмеченные тегом secret.
+func ResolveStruct(ctx context.Context, settings any) error {
+	value := reflect.ValueOf(settings).Elem()
+	typ := value.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Tag.Get(Tag) == "" || field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		resolved, err := Resolve(ctx, value.Field(i).String())
+		if err != nil {
+			name := field.Tag.Get("env")
+			if name == "" {
+				name = field.Name
+			}
+			return fmt.Errorf("resolve %s: %w", name, err)
+		}
+		value.Field(i).SetString(resolved)
+	}
+
+	return nil
+}
+
+// fromVault читает поле секрета KV версии 1 или 2 по ссылке вида path#field.
+func fromVault(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q: expected vault:path#field", ref)
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("VAULT_ADDR is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := VaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: vault path %s", ErrNotFound, path)
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with status code %d for %s", resp.StatusCode, path)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode vault response: %w", err)
+	}
+
+	data := body.Data
+	// В KV версии 2 поля секрета лежат во вложенном data
+	if nested, ok := data["data"]; ok {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(nested, &fields) == nil {
+			data = fields
+		}
+	}
+
+	raw, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("%w: field %s of vault path %s", ErrNotFound, field, path)
+	}
+
+	var secret string
+	if err = json.Unmarshal(raw, &secret); err != nil {
+		return "", fmt.Errorf("field %s of vault path %s is not a string", field, path)
+	}
+
+	return secret, nil
+}
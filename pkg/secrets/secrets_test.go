@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/metrics":
+			_, _ = w.Write([]byte(`{"data": {"data": {"key": "from-vault-v2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/metrics":
+			_, _ = w.Write([]byte(`{"data": {"key": "from-vault-v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("METRICS_TEST_SECRET", "from-env")
+
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0600))
+
+	tests := []struct {
+		name        string
+		value       string
+		wantedValue string
+		wantedErr   error
+		wantErr     bool
+	}{
+		{name: "Plain value", value: "plain", wantedValue: "plain"},
+		{name: "File storage DSN", value: "file:///tmp/metrics.json", wantedValue: "file:///tmp/metrics.json"},
+		{name: "File", value: "file:" + path, wantedValue: "from-file"},
+		{name: "Missing file", value: "file:" + path + ".missing", wantErr: true},
+		{name: "Env", value: "env:METRICS_TEST_SECRET", wantedValue: "from-env"},
+		{name: "Missing env", value: "env:METRICS_TEST_MISSING", wantedErr: ErrNotFound},
+		{name: "Vault KV v2", value: "vault:secret/data/metrics#key", wantedValue: "from-vault-v2"},
+		{name: "Vault KV v1", value: "vault:kv/metrics#key", wantedValue: "from-vault-v1"},
+		{name: "Vault missing field", value: "vault:kv/metrics#password", wantedErr: ErrNotFound},
+		{name: "Vault missing path", value: "vault:kv/other#key", wantedErr: ErrNotFound},
+		{name: "Vault without field", value: "vault:kv/metrics", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := Resolve(context.Background(), tt.value)
+			switch {
+			case tt.wantedErr != nil:
+				require.ErrorIs(t, err, tt.wantedErr)
+			case tt.wantErr:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantedValue, value)
+			}
+		})
+	}
+}
+
+func TestResolveStruct(t *testing.T) {
+	t.Setenv("METRICS_TEST_SECRET", "from-env")
+
+	settings := struct {
+		Key     string `env:"KEY" secret:"true"`
+		DSN     string `env:"DATABASE_DSN" secret:"dsn"`
+		Address string `env:"ADDRESS"`
+	}{
+		Key:     "env:METRICS_TEST_SECRET",
+		DSN:     "postgres://localhost/metrics",
+		Address: "env:METRICS_TEST_SECRET",
+	}
+
+	require.NoError(t, ResolveStruct(context.Background(), &settings))
+	assert.Equal(t, "from-env", settings.Key)
+	assert.Equal(t, "postgres://localhost/metrics", settings.DSN)
+	assert.Equal(t, "env:METRICS_TEST_SECRET", settings.Address, "fields without the secret tag are left as is")
+
+	settings.Key = "env:METRICS_TEST_MISSING"
+	err := ResolveStruct(context.Background(), &settings)
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "KEY")
+}
@@ -0,0 +1,14 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+)
+
+// trustProxies настраивает, откуда gin берёт адрес клиента. Заголовку верим, только если соединение
+// пришло от доверенного прокси, иначе адрес берётся из соединения.
+func trustProxies(engine *gin.Engine) error {
+	engine.RemoteIPHeaders = []string{config.ClientIPHeader()}
+	return engine.SetTrustedProxies(config.Config.TrustedProxies)
+}
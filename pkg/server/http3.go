@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/handoff"
+)
+
+// startHTTP3 слушает UDP-порт адреса сервера для HTTP/3 (QUIC): агентам в сетях с потерями пакетов
+// не мешает блокировка очереди TCP. Маршруты и настройки TLS те же, что у HTTPS. На unix-сокете HTTP/3 нет.
+func (s *Server) startHTTP3(handler http.Handler, tlsConfig *tls.Config, addr net.Addr) error {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: tcpAddr.IP, Port: tcpAddr.Port, Zone: tcpAddr.Zone})
+	if err != nil {
+		// При обновлении UDP-порт держит старый процесс, а сокеты передаются только TCP
+		if handoff.Inherited() {
+			s.log.Errorf("HTTP/3 is not served until the next restart: %s", err)
+			return nil
+		}
+
+		return err
+	}
+
+	quicConfig := &quic.Config{}
+	if config.Config.IdleTimeout > 0 {
+		quicConfig.MaxIdleTimeout = config.Config.IdleTimeout
+	}
+
+	s.h3Srv = &http3.Server{Handler: handler, TLSConfig: tlsConfig, QuicConfig: quicConfig, Port: tcpAddr.Port}
+	go func() {
+		if err := s.h3Srv.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Errorf("The HTTP/3 listener has stopped: %s", err)
+		}
+	}()
+	s.log.Debugf("HTTP/3 is served on: udp %s", conn.LocalAddr())
+
+	return nil
+}
+
+// altSvc объявляет клиентам HTTPS, что тот же сервер доступен по HTTP/3.
+func (s *Server) altSvc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			_ = s.h3Srv.SetQuicHeaders(w.Header()) // ошибка только до запуска listener, тогда заголовок не нужен
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
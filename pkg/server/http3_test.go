@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// tlsTestConfig возвращает настройки тестового сервера с самоподписанным сертификатом на localhost.
+func tlsTestConfig(t *testing.T) Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.TLSCertFile, cfg.TLSKeyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	cfg.TLSReloadInterval = 0
+
+	require.NoError(t, os.WriteFile(cfg.TLSCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(cfg.TLSKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return cfg
+}
+
+func TestServerHTTP3(t *testing.T) {
+	cfg := tlsTestConfig(t)
+	cfg.HTTP3 = true
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	url := "https://" + srv.Addr().String()
+	clientTLS := &tls.Config{InsecureSkipVerify: true} //nolint:gosec // самоподписанный сертификат теста
+
+	// Listener HTTP/3 регистрируется в фоне, до этого Alt-Svc не отправляется
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	assert.Eventually(t, func() bool {
+		resp, err := client.Get(url + "/ping")
+		if err != nil {
+			return false
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		return resp.Header.Get("Alt-Svc") != ""
+	}, time.Second*5, time.Millisecond*20)
+
+	transport := &http3.RoundTripper{TLSClientConfig: clientTLS}
+	defer transport.Close()
+	h3 := &http.Client{Transport: transport}
+
+	resp, err := h3.Post(url+"/update/gauge/Alloc/1.5", "text/plain", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, resp.ProtoMajor)
+
+	resp, err = h3.Get(url + "/value/gauge/Alloc")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "1.5", string(body))
+	assert.Empty(t, resp.Header.Get("Alt-Svc"), "HTTP/3 responses must not advertise themselves")
+}
+
+func TestServerHTTP3Disabled(t *testing.T) {
+	cfg := tlsTestConfig(t)
+	require.False(t, cfg.HTTP3, "HTTP/3 must be disabled by default")
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}} //nolint:gosec // самоподписанный сертификат теста
+
+	resp, err := client.Get(fmt.Sprintf("https://%s/ping", srv.Addr()))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Empty(t, resp.Header.Get("Alt-Svc"))
+}
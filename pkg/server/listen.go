@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/handoff"
+)
+
+// listen слушает TCP-адрес или unix-сокет, если адрес задан как unix:/path. Если сокет с именем name
+// передал старый процесс при обновлении (см. Upgrade), используется он. Файл сокета удаляется,
+// когда закрывается listener.
+func (s *Server) listen(address, name string) (net.Listener, error) {
+	listener, err := handoff.Listener(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if listener == nil {
+		if listener, err = listenAddress(address); err != nil {
+			return nil, err
+		}
+	}
+
+	s.sockets[name] = listener
+	return listener, nil
+}
+
+func listenAddress(address string) (net.Listener, error) {
+	path, ok := config.SocketPath(address)
+	if !ok {
+		// В net.ListenConfig ноль означает интервал по умолчанию, а отключает проверки отрицательное значение
+		keepAlive := config.Config.TCPKeepAlive
+		if keepAlive == 0 {
+			keepAlive = -1
+		}
+
+		lc := net.ListenConfig{KeepAlive: keepAlive}
+		return lc.Listen(context.Background(), "tcp", address)
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(path, config.SocketMode()); err != nil {
+		return nil, errors.Join(err, listener.Close())
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket удаляет сокет, оставшийся после аварийной остановки. Сокет, который кто-то
+// слушает, и файлы, которые не являются сокетами, не трогает.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("socket %s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
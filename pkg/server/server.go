@@ -0,0 +1,457 @@
+// Package server позволяет встроить сервер метрик в другую программу.
+//
+// Настройки сервера общие на процесс (см. config.Config), поэтому одновременно в процессе
+// может работать только один Server.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/access"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/auth"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/handlers"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/lockout"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/middlewares"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/record"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/router"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/stale"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/storage"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/tlscert"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/handoff"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+// Config - настройки сервера, те же, что задаются флагами и переменными окружения.
+type Config = config.Settings
+
+// StorageBackend - хранилище, которое выбирается по схеме Config.StorageDSN, см. RegisterStorage.
+type StorageBackend = storage.Backend
+
+// ErrServerStarted возвращает Start, если сервер уже запущен.
+var ErrServerStarted = errors.New("server is already started")
+
+type Server struct {
+	log      logger.Logger
+	store    models.Storage
+	router   *router.Router
+	recorder *record.Writer
+	access   *access.Guard
+	cert     *tlscert.Reloader
+	acme     *autocert.Manager
+	acmeSrv  *http.Server
+
+	admin         *gin.Engine
+	adminSrv      *http.Server
+	adminListener net.Listener
+
+	stopBackground context.CancelFunc
+
+	srv      *http.Server
+	h3Srv    *http3.Server
+	listener net.Listener
+	sockets  map[string]net.Listener // без TLS, для передачи новому процессу
+	serveErr chan error
+	shutdown chan struct{}
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// DefaultConfig возвращает настройки по умолчанию, как у запуска без флагов.
+func DefaultConfig() Config {
+	return config.Defaults()
+}
+
+// RegisterStorage добавляет хранилище для схемы scheme://. Вызывать нужно до New, обычно из init пакета с хранилищем.
+func RegisterStorage(scheme string, backend StorageBackend) {
+	storage.RegisterBackend(scheme, backend)
+}
+
+// New применяет настройки, открывает хранилище и настраивает маршруты. Если log равен nil,
+// логгер создаётся по настройкам логирования из cfg.
+func New(cfg Config, log logger.Logger) (*Server, error) {
+	if err := config.Set(cfg); err != nil {
+		return nil, err
+	}
+
+	if log == nil {
+		var err error
+		if log, err = logger.New(config.LoggerOptions()); err != nil {
+			return nil, err
+		}
+	}
+
+	store, err := storage.Setup(log)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Selected storage: %s", store)
+
+	s := &Server{
+		log:      log,
+		store:    store,
+		router:   router.New(store, log),
+		sockets:  make(map[string]net.Listener),
+		serveErr: make(chan error, 1),
+		shutdown: make(chan struct{}),
+	}
+
+	if err = trustProxies(s.router.Engine); err != nil {
+		return nil, errors.Join(err, store.Close())
+	}
+
+	if config.TLSEnabled() {
+		if s.cert, err = tlscert.Load(config.Config.TLSCertFile, config.Config.TLSKeyFile, nil, log.Named("tls")); err != nil {
+			return nil, errors.Join(err, store.Close())
+		}
+	} else if config.ACMEEnabled() {
+		s.acme = tlscert.NewACME(tlscert.ACMEOptions{
+			Domains:      config.Config.ACMEDomains,
+			Email:        config.Config.ACMEEmail,
+			CacheDir:     config.Config.ACMECacheDir,
+			DirectoryURL: config.Config.ACMEDirectoryURL,
+		})
+		log.Infof("Certificates for %s are obtained via ACME (%s) and cached in %s.", strings.Join(config.Config.ACMEDomains, ", "), config.Config.ACMEChallenge, config.Config.ACMECacheDir)
+	}
+
+	s.router.SetLockout(lockout.New(lockout.Options{
+		Threshold: config.Config.AuthFailureThreshold,
+		Window:    config.Config.AuthFailureWindow,
+		BlockFor:  config.Config.AuthBlockDuration,
+	}, log.Named("audit")))
+
+	if config.Config.APIKeys != "" {
+		keys, err := openAPIKeys(store)
+		if err != nil {
+			return nil, errors.Join(err, store.Close())
+		}
+
+		s.router.SetAPIKeys(auth.New(keys, nil))
+		log.Infof("API keys are checked, keys are kept in %s.", config.Config.APIKeys)
+	}
+
+	if path := config.Config.AccessListFile; path != "" {
+		if s.access, err = access.Open(path); err != nil {
+			return nil, errors.Join(err, store.Close())
+		}
+
+		s.router.SetAccessGuard(s.access)
+		log.Infof("Client addresses are checked against the access list %s.", path)
+	}
+
+	if path := config.Config.RecordFile; path != "" {
+		if s.recorder, err = record.Open(path); err != nil {
+			return nil, errors.Join(err, store.Close())
+		}
+
+		s.router.SetRecorder(s.recorder)
+		log.Infof("Accepted update requests are recorded to %s.", path)
+	}
+
+	if config.Config.AdminAddress != "" {
+		s.admin = gin.New()
+		if err = trustProxies(s.admin); err != nil {
+			return nil, errors.Join(err, s.closeRecorder(), store.Close())
+		}
+
+		middlewares.SetupAdmin(s.admin, s.router)
+		s.router.SetAdmin(s.admin)
+	}
+
+	// Фоновые задачи запускаются последними, чтобы ошибка настройки выше их не оставила
+	background, stopBackground := context.WithCancel(context.Background())
+	s.stopBackground = stopBackground
+
+	if s.cert != nil && config.Config.TLSReloadInterval > 0 {
+		go s.cert.Watch(background, config.Config.TLSReloadInterval)
+	}
+
+	if interval := config.Config.StaleCheckInterval; interval > 0 {
+		monitor := stale.New(store, stale.Options{
+			CheckInterval: interval,
+			Factor:        config.Config.StaleFactor,
+			WebhookURL:    config.Config.StaleWebhookURL,
+		}, log.Named("stale"))
+		s.router.SetStaleMonitor(monitor)
+
+		go monitor.Run(background)
+	}
+
+	middlewares.Setup(s.router)
+	handlers.Setup(s.router)
+
+	return s, nil
+}
+
+func openAPIKeys(store models.Storage) (auth.KeyStore, error) {
+	if path, ok := config.APIKeysFile(); ok {
+		return auth.OpenFile(path, nil)
+	}
+
+	keys, err := storage.APIKeyStore(store)
+	if err != nil {
+		return nil, fmt.Errorf("api keys in the database need the database storage: %w", err)
+	}
+
+	return keys, nil
+}
+
+// ReloadAccessList перечитывает файл со списками подсетей. Если файл не задан, ничего не делает.
+func (s *Server) ReloadAccessList() error {
+	if s.access == nil {
+		return nil
+	}
+
+	return s.access.Reload()
+}
+
+// ReloadCertificate перечитывает файлы TLS-сертификата. Без TLS ничего не делает.
+func (s *Server) ReloadCertificate() error {
+	if s.cert == nil {
+		return nil
+	}
+
+	return s.cert.Reload()
+}
+
+// Handler возвращает обработчик всех маршрутов сервера, чтобы подключить его к своему http.Server.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+func (s *Server) Storage() models.Storage {
+	return s.store
+}
+
+// Start начинает слушать адрес из настроек и возвращается, когда сервер готов принимать запросы.
+// После отмены ctx сервер перестаёт принимать запросы, хранилище при этом закрывает только Shutdown.
+func (s *Server) Start(ctx context.Context) error {
+	if s.srv != nil {
+		return ErrServerStarted
+	}
+
+	listener, err := s.listen(config.Config.Address, "server")
+	if err != nil {
+		return err
+	}
+	listener = limitConnections(listener)
+
+	handler := handlerTimeout(s.router)
+	if tlsConfig := s.tlsConfig(); tlsConfig != nil {
+		if config.Config.HTTP3 {
+			if err = s.startHTTP3(handler, tlsConfig, listener.Addr()); err != nil {
+				return errors.Join(err, listener.Close())
+			}
+		}
+
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	if s.h3Srv != nil {
+		handler = s.altSvc(handler)
+	}
+
+	if s.acme != nil && config.Config.ACMEChallenge == tlscert.ChallengeHTTP {
+		if err = s.startACMEHTTP(); err != nil {
+			return errors.Join(err, listener.Close(), s.closeHTTP3())
+		}
+	}
+
+	if s.admin != nil {
+		if err = s.startAdmin(); err != nil {
+			return errors.Join(err, listener.Close(), s.closeHTTP3(), s.shutdownACMEHTTP())
+		}
+	}
+
+	if config.Config.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	s.listener = listener
+	s.srv = newHTTPServer(handler)
+
+	go func() {
+		if err := s.srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.serveErr <- err
+		}
+	}()
+	s.log.Debugf("Server routing is configured and sent to launch on: %s", listener.Addr())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.srv.Shutdown(context.Background())
+			_ = s.closeHTTP3()
+		case <-s.shutdown:
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) tlsConfig() *tls.Config {
+	switch {
+	case s.cert != nil:
+		return &tls.Config{
+			GetCertificate: s.cert.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"h2", "http/1.1"},
+		}
+	case s.acme != nil:
+		tlsConfig := s.acme.TLSConfig() // в NextProtos есть h2 и acme-tls/1 для проверки tls-alpn-01
+		tlsConfig.MinVersion = tls.VersionTLS12
+
+		return tlsConfig
+	default:
+		return nil
+	}
+}
+
+// startACMEHTTP слушает ACME_HTTP_ADDRESS для проверок http-01, остальные запросы перенаправляются на HTTPS.
+func (s *Server) startACMEHTTP() error {
+	listener, err := s.listen(config.Config.ACMEHTTPAddress, "acme")
+	if err != nil {
+		return err
+	}
+
+	s.acmeSrv = &http.Server{Handler: s.acme.HTTPHandler(nil), ReadHeaderTimeout: time.Second * 10}
+	go func() {
+		if err := s.acmeSrv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Errorf("The ACME http-01 listener has stopped: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// startAdmin слушает ADMIN_ADDRESS для служебных маршрутов. TLS здесь нет: адрес обычно локальный.
+func (s *Server) startAdmin() error {
+	listener, err := s.listen(config.Config.AdminAddress, "admin")
+	if err != nil {
+		return err
+	}
+
+	s.adminListener = listener
+	s.adminSrv = newHTTPServer(s.admin)
+	go func() {
+		if err := s.adminSrv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Errorf("The admin listener has stopped: %s", err)
+		}
+	}()
+	s.log.Debugf("Admin routes are served on: %s", listener.Addr())
+
+	return nil
+}
+
+func (s *Server) closeHTTP3() error {
+	if s.h3Srv == nil {
+		return nil
+	}
+
+	return s.h3Srv.Close()
+}
+
+func (s *Server) shutdownACMEHTTP() error {
+	if s.acmeSrv == nil {
+		return nil
+	}
+
+	return s.acmeSrv.Close()
+}
+
+// AdminAddr возвращает адрес отдельного служебного listener или nil, если его нет.
+func (s *Server) AdminAddr() net.Addr {
+	if s.adminListener == nil {
+		return nil
+	}
+
+	return s.adminListener.Addr()
+}
+
+// Upgrade запускает новый процесс сервера из того же исполняемого файла и передаёт ему слушающие
+// сокеты. Возвращается, когда новый процесс готов принимать запросы; после этого текущий
+// нужно остановить через Shutdown. При ошибке сокеты остаются у текущего процесса.
+func (s *Server) Upgrade(ctx context.Context) (pid int, err error) {
+	if s.srv == nil {
+		return 0, fmt.Errorf("the server is not started")
+	}
+
+	return handoff.Upgrade(ctx, s.sockets)
+}
+
+// Addr возвращает адрес, который слушает запущенный сервер, или nil до Start.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+
+	return s.listener.Addr()
+}
+
+// Err возвращает канал, в который попадает ошибка, если сервер перестал принимать соединения.
+func (s *Server) Err() <-chan error {
+	return s.serveErr
+}
+
+// Stopped сообщает, что сервер попросили остановиться через API; true - с перезапуском.
+func (s *Server) Stopped() <-chan bool {
+	return s.router.Stopped()
+}
+
+// Shutdown дожидается обработки текущих запросов и закрывает хранилище и файл записи. Повторные вызовы
+// ничего не делают и возвращают результат первого.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.doShutdown(ctx)
+	})
+
+	return s.shutdownErr
+}
+
+func (s *Server) doShutdown(ctx context.Context) error {
+	close(s.shutdown)
+
+	s.stopBackground()
+
+	var err error
+	if s.srv != nil {
+		err = s.srv.Shutdown(ctx)
+	}
+
+	// Мягкой остановки у HTTP/3 нет: соединения закрываются сразу, агенты повторят запросы
+	err = errors.Join(err, s.closeHTTP3())
+
+	if s.acmeSrv != nil {
+		err = errors.Join(err, s.acmeSrv.Shutdown(ctx))
+	}
+
+	if s.adminSrv != nil {
+		err = errors.Join(err, s.adminSrv.Shutdown(ctx))
+	}
+
+	return errors.Join(err, s.closeRecorder(), s.store.Close())
+}
+
+func (s *Server) closeRecorder() error {
+	if s.recorder == nil {
+		return nil
+	}
+
+	return s.recorder.Close()
+}
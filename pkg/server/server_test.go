@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/agent/endpoints"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Address = "127.0.0.1:0"
+	cfg.FileStoragePath = ""
+	cfg.Restore = false
+
+	return cfg
+}
+
+func TestServer(t *testing.T) {
+	srv, err := New(testConfig(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, srv.Start(ctx))
+	require.ErrorIs(t, srv.Start(ctx), ErrServerStarted)
+
+	url := "http://" + srv.Addr().String()
+
+	resp, err := http.Post(url+"/update/gauge/Alloc/1.5", "text/plain", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(url + "/value/gauge/Alloc")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "1.5", string(body))
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	require.NoError(t, srv.Shutdown(context.Background()), "a repeated shutdown must do nothing")
+
+	_, err = http.Get(url + "/value/gauge/Alloc")
+	require.Error(t, err, "the server must not accept requests after shutdown")
+}
+
+func TestServerHandler(t *testing.T) {
+	srv, err := New(testConfig(), logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/update/counter/PollCount/2", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	value, err := srv.Storage().GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *value)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.CounterPolicy = "unknown"
+
+	_, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
+
+func TestServerTLSRequiresKey(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSCertFile = "cert.pem"
+
+	_, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.Error(t, err)
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metrics") // путь к сокету ограничен ~100 байтами, t.TempDir бывает длиннее
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.sock")
+
+	// Сокет, оставшийся после аварийной остановки, не мешает запуску
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	cfg := testConfig()
+	cfg.Address = "unix:" + path
+	cfg.UnixSocketMode = "0600"
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	client := &http.Client{Transport: endpoints.Transport([]string{cfg.Address}, false)}
+	resp, err := client.Post(endpoints.HTTPURL(cfg.Address)+"/update/gauge/Alloc/1.5", "text/plain", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist, "the socket file is removed on shutdown")
+}
+
+func TestServerUnixSocketInUse(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metrics")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.sock")
+
+	busy, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer busy.Close()
+
+	cfg := testConfig()
+	cfg.Address = "unix:" + path
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	require.ErrorContains(t, srv.Start(context.Background()), "already in use")
+}
+
+func TestServerH2C(t *testing.T) {
+	cfg := testConfig()
+	cfg.H2C = true
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	url := "http://" + srv.Addr().String()
+	client := &http.Client{Transport: endpoints.Transport([]string{url}, true)}
+
+	resp, err := client.Post(url+"/update/gauge/Alloc/1.5", "text/plain", nil)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+
+	// Клиенты без h2c по-прежнему работают по HTTP/1.1
+	resp, err = http.Get(url + "/value/gauge/Alloc")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, 1, resp.ProtoMajor)
+}
+
+func TestServerAdminListener(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminAddress = "127.0.0.1:0"
+	cfg.AdminKey = "secret"
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	status := func(method, url string) int {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.StatusCode
+	}
+
+	public := "http://" + srv.Addr().String()
+	admin := "http://" + srv.AdminAddr().String()
+
+	assert.Equal(t, http.StatusOK, status(http.MethodPost, public+"/update/gauge/Alloc/1.5"))
+	for _, path := range []string{"/ping", "/metrics", "/admin/config", "/debug/pprof/"} {
+		assert.Equal(t, http.StatusBadRequest, status(http.MethodGet, public+path), path)
+	}
+
+	for _, path := range []string{"/ping", "/readyz", "/metrics", "/api/buildinfo", "/admin/config", "/debug/pprof/", "/debug/pprof/heap"} {
+		assert.Equal(t, http.StatusOK, status(http.MethodGet, admin+path), path)
+	}
+	assert.Equal(t, http.StatusBadRequest, status(http.MethodPost, admin+"/update/gauge/Alloc/1.5"))
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/netutil"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/models"
+)
+
+// newHTTPServer создаёт http.Server с таймаутами из настроек, чтобы медленные клиенты
+// не держали соединения бесконечно.
+func newHTTPServer(handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       config.Config.ReadTimeout,
+		ReadHeaderTimeout: config.Config.ReadHeaderTimeout,
+		WriteTimeout:      config.Config.WriteTimeout,
+		IdleTimeout:       config.Config.IdleTimeout,
+	}
+	srv.SetKeepAlivesEnabled(config.Config.KeepAlive)
+
+	return srv
+}
+
+// limitConnections ограничивает число одновременных соединений, чтобы тысячи простаивающих агентов
+// не исчерпали файловые дескрипторы. Лишние соединения ждут в очереди ядра, пока не освободится место.
+func limitConnections(listener net.Listener) net.Listener {
+	if config.Config.MaxConnections <= 0 {
+		return listener
+	}
+
+	return netutil.LimitListener(listener, config.Config.MaxConnections)
+}
+
+// handlerTimeout ограничивает время обработки запроса: по его истечении контекст запроса
+// отменяется, а клиент получает 503.
+func handlerTimeout(handler http.Handler) http.Handler {
+	timeout := config.Config.HandlerTimeout
+	if timeout <= 0 {
+		return handler
+	}
+
+	body, _ := json.Marshal(models.ErrorResponse{Error: "The request took too long to handle."})
+	return http.TimeoutHandler(handler, timeout, string(body))
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/internal/server/config"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestServerReadHeaderTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadHeaderTimeout = 100 * time.Millisecond
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Заголовки так и не дописываются до конца: сервер должен закрыть соединение сам
+	_, err = conn.Write([]byte("GET /ping HTTP/1.1\r\nHost: localhost\r\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err, "the server must close the connection before the read deadline")
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	saved := config.Config
+	defer func() { config.Config = saved }()
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	config.Config.HandlerTimeout = 0
+	assert.IsType(t, slow, handlerTimeout(slow))
+
+	config.Config.HandlerTimeout = 50 * time.Millisecond
+	w := httptest.NewRecorder()
+	handlerTimeout(slow).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error":"The request took too long to handle."}`, w.Body.String())
+}
+
+func TestServerMaxConnections(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConnections = 1
+
+	srv, err := New(cfg, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	defer func() { require.NoError(t, srv.Shutdown(context.Background())) }()
+
+	request := func(conn net.Conn) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			if _, err := conn.Write([]byte("GET /ping HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+				done <- err
+				return
+			}
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err == nil {
+				err = resp.Body.Close()
+			}
+			done <- err
+		}()
+
+		return done
+	}
+
+	first, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	require.NoError(t, <-request(first))
+
+	second, err := net.Dial("tcp", srv.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+
+	// Первое соединение живо и занимает единственное место, второе ждёт
+	done := request(second)
+	select {
+	case err = <-done:
+		t.Fatalf("the second connection was served over the limit: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, first.Close())
+	require.NoError(t, <-done)
+}
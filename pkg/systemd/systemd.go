@@ -0,0 +1,91 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Notify отправляет состояние в сокет NOTIFY_SOCKET. Если сервис запущен не через systemd, возвращает false без ошибки.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// watchdogClock отсчитывает интервал пингов watchdog, в тестах подменяется.
+var watchdogClock = clock.Real()
+
+// RunWatchdog пингует systemd, пока проходит проверка здоровья. Блокируется до отмены ctx.
+func RunWatchdog(ctx context.Context, check func(context.Context) error, log logger.Logger) {
+	interval, ok := WatchdogInterval()
+	if !ok {
+		return
+	}
+
+	ticker := watchdogClock.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	log.Debugf("The systemd watchdog is enabled with interval %s.", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			checkCtx, cancel := context.WithTimeout(ctx, interval/2)
+			err := check(checkCtx)
+			cancel()
+
+			if err != nil {
+				log.Errorf("Health check failed, the systemd watchdog is not notified: %s", err)
+				continue
+			}
+
+			if _, err = Notify(Watchdog); err != nil {
+				log.Errorf("Failed to notify the systemd watchdog: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/clock"
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	sent, err := Notify(Ready)
+	require.NoError(t, err)
+	require.False(t, sent)
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	sent, err = Notify(Ready)
+	require.NoError(t, err)
+	require.True(t, sent)
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, Ready, string(buf[:n]))
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name           string
+		usec           string
+		pid            string
+		wantedInterval time.Duration
+		wantedOk       bool
+	}{
+		{
+			name: "Disabled",
+		},
+		{
+			name:           "Enabled",
+			usec:           "30000000",
+			wantedInterval: time.Second * 30,
+			wantedOk:       true,
+		},
+		{
+			name:           "Current process",
+			usec:           "1000",
+			pid:            strconv.Itoa(os.Getpid()),
+			wantedInterval: time.Millisecond,
+			wantedOk:       true,
+		},
+		{
+			name: "Another process",
+			usec: "1000",
+			pid:  "1",
+		},
+		{
+			name: "Invalid value",
+			usec: "invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+
+			interval, ok := WatchdogInterval()
+			assert.Equal(t, tt.wantedOk, ok)
+			assert.Equal(t, tt.wantedInterval, interval)
+		})
+	}
+}
+
+func TestRunWatchdog(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	clk := clock.NewFake(time.Unix(0, 0))
+	watchdogClock = clk
+	defer func() { watchdogClock = clock.Real() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RunWatchdog(ctx, func(context.Context) error { return nil }, logger.Wrap(zaptest.NewLogger(t).Sugar()))
+	}()
+
+	clk.BlockUntil(1)
+	clk.Advance(15 * time.Second)
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, Watchdog, string(buf[:n]))
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,27 @@
+package winservice
+
+import (
+	"errors"
+	"os"
+)
+
+var (
+	ErrNotSupported     = errors.New("windows services are not supported on this platform")
+	ErrAlreadyInstalled = errors.New("service is already installed")
+)
+
+// HandleCommand обрабатывает подкоманды install и uninstall. Аргументы после install передаются сервису при запуске.
+func HandleCommand(name, displayName string) (bool, error) {
+	if len(os.Args) < 2 {
+		return false, nil
+	}
+
+	switch os.Args[1] {
+	case "install":
+		return true, Install(name, displayName, os.Args[2:]...)
+	case "uninstall":
+		return true, Uninstall(name)
+	default:
+		return false, nil
+	}
+}
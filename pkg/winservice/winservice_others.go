@@ -0,0 +1,29 @@
+//go:build !windows
+
+package winservice
+
+import (
+	"context"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+func IsService() (bool, error) {
+	return false, nil
+}
+
+func Run(_ string, _ func(ctx context.Context) error) error {
+	return ErrNotSupported
+}
+
+func Install(_, _ string, _ ...string) error {
+	return ErrNotSupported
+}
+
+func Uninstall(_ string) error {
+	return ErrNotSupported
+}
+
+func NewLogger(_ string) (logger.Logger, error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+package winservice
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantedHandled bool
+		wantedErr     error
+	}{
+		{
+			name: "Without command",
+			args: []string{"server"},
+		},
+		{
+			name: "Unknown command",
+			args: []string{"server", "dump"},
+		},
+		{
+			name:          "Install",
+			args:          []string{"server", "install", "-a", ":8080"},
+			wantedHandled: true,
+			wantedErr:     ErrNotSupported,
+		},
+		{
+			name:          "Uninstall",
+			args:          []string{"server", "uninstall"},
+			wantedHandled: true,
+			wantedErr:     ErrNotSupported,
+		},
+	}
+
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = tt.args
+
+			handled, err := HandleCommand("metrics-server", "Metrics server")
+			assert.Equal(t, tt.wantedHandled, handled)
+			assert.ErrorIs(t, err, tt.wantedErr)
+		})
+	}
+}
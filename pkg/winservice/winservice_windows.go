@@ -0,0 +1,207 @@
+//go:build windows
+
+package winservice
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+
+	"github.com/k-orolevsk-y/go-metricts-tpl/pkg/logger"
+)
+
+const (
+	eventID       = 1
+	exitCodeError = 1
+)
+
+type (
+	handler struct {
+		run func(ctx context.Context) error
+	}
+
+	eventLogger struct {
+		log    *eventlog.Log
+		name   string
+		fields string
+	}
+)
+
+func IsService() (bool, error) {
+	return svc.IsWindowsService()
+}
+
+// Run запускает run под управлением Service Control Manager. Контекст отменяется при остановке сервиса.
+func Run(name string, run func(ctx context.Context) error) error {
+	return svc.Run(name, &handler{run: run})
+}
+
+func (h *handler) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+
+	changes <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.run(ctx)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: accepted}
+
+	for {
+		select {
+		case err := <-done:
+			changes <- svc.Status{State: svc.StopPending}
+			return exitCode(err)
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+
+				return exitCode(<-done)
+			}
+		}
+	}
+}
+
+func exitCode(err error) (bool, uint32) {
+	if err != nil {
+		return true, exitCodeError
+	}
+
+	return false, 0
+}
+
+func Install(name, displayName string, args ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return ErrAlreadyInstalled
+	}
+
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: displayName,
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = s.Delete()
+		return err
+	}
+
+	return nil
+}
+
+func Uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err = s.Delete(); err != nil {
+		return err
+	}
+
+	return eventlog.Remove(name)
+}
+
+// NewLogger возвращает логгер, который пишет в журнал событий Windows: у сервиса нет консоли для stderr.
+func NewLogger(name string) (logger.Logger, error) {
+	log, err := eventlog.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventLogger{log: log}, nil
+}
+
+func (l *eventLogger) Infof(template string, args ...interface{}) {
+	_ = l.log.Info(eventID, l.format(template, args...))
+}
+
+func (l *eventLogger) Errorf(template string, args ...interface{}) {
+	_ = l.log.Error(eventID, l.format(template, args...))
+}
+
+func (l *eventLogger) Panicf(template string, args ...interface{}) {
+	msg := l.format(template, args...)
+	_ = l.log.Error(eventID, msg)
+
+	panic(msg)
+}
+
+func (l *eventLogger) Debugf(_ string, _ ...interface{}) {}
+
+func (l *eventLogger) Sync() error {
+	return nil
+}
+
+// With дописывает поля в конец сообщения: у журнала событий нет структурированных полей.
+func (l *eventLogger) With(args ...interface{}) logger.Logger {
+	clone := *l
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			clone.fields += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+		} else {
+			clone.fields += fmt.Sprintf(" %v", args[i])
+		}
+	}
+
+	return &clone
+}
+
+func (l *eventLogger) Named(name string) logger.Logger {
+	clone := *l
+	if clone.name == "" {
+		clone.name = name
+	} else {
+		clone.name += "." + name
+	}
+
+	return &clone
+}
+
+func (l *eventLogger) format(template string, args ...interface{}) string {
+	msg := fmt.Sprintf(template, args...)
+	if l.name != "" {
+		msg = l.name + "\t" + msg
+	}
+
+	if l.fields != "" {
+		msg += "\t{" + strings.TrimPrefix(l.fields, " ") + "}"
+	}
+
+	return msg
+}